@@ -0,0 +1,283 @@
+package attendances
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"base/app/models"
+	"base/core/router"
+	"base/core/storage"
+	"base/core/types"
+)
+
+type AttendanceController struct {
+	Service *AttendanceService
+	Storage *storage.ActiveStorage
+}
+
+func NewAttendanceController(service *AttendanceService, storage *storage.ActiveStorage) *AttendanceController {
+	return &AttendanceController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *AttendanceController) Routes(router *router.RouterGroup) {
+	// Instructor endpoints
+	router.GET("/live_sessions/:id/attendance", c.Get)                // Attendance sheet of a session
+	router.PUT("/live_sessions/:id/attendance", c.Mark)               // Bulk mark students
+	router.POST("/live_sessions/:id/attendance/code", c.GenerateCode) // Short-lived check-in code
+	router.GET("/course_runs/:id/attendance_report", c.Report)        // Cohort report (JSON or CSV)
+
+	// Student endpoints
+	router.POST("/live_sessions/:id/check_in", c.CheckIn) // Self check-in with the instructor's code
+}
+
+// GetAttendance godoc
+// @Summary Get the attendance of a LiveSession
+// @Description Get the marked records and the attendees not yet marked; course staff only
+// @Tags App/Attendance
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "LiveSession id"
+// @Success 200 {object} SessionAttendance
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /live_sessions/{id}/attendance [get]
+func (c *AttendanceController) Get(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	result, err := c.Service.GetSessionAttendance(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// MarkAttendance godoc
+// @Summary Mark attendance of a LiveSession
+// @Description Set the status (present, late, absent, excused) of several students at once; course staff only
+// @Tags App/Attendance
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "LiveSession id"
+// @Param attendance body models.BulkMarkAttendanceRequest true "Attendance records"
+// @Success 200 {object} SessionAttendance
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /live_sessions/{id}/attendance [put]
+func (c *AttendanceController) Mark(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.BulkMarkAttendanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateBulkMarkRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	result, err := c.Service.MarkAttendance(uint(id), userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// GenerateCheckInCode godoc
+// @Summary Generate a check-in code
+// @Description Generate a short-lived code the instructor shows to students for self check-in; course staff only
+// @Tags App/Attendance
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "LiveSession id"
+// @Success 201 {object} models.AttendanceCheckInCodeResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /live_sessions/{id}/attendance/code [post]
+func (c *AttendanceController) GenerateCode(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	item, err := c.Service.GenerateCode(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// CheckIn godoc
+// @Summary Check in to a LiveSession
+// @Description Mark the current user present with the instructor's code; check-ins after the grace period count as late. After 5 wrong codes for a session only the instructor can mark the student.
+// @Tags App/Attendance
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "LiveSession id"
+// @Param check_in body models.CheckInRequest true "Check-in code"
+// @Success 200 {object} models.AttendanceResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 429 {object} types.ErrorResponse
+// @Router /live_sessions/{id}/check_in [post]
+func (c *AttendanceController) CheckIn(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.CheckInRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateCheckInRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.CheckIn(uint(id), userId, strings.TrimSpace(req.Code))
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// AttendanceReport godoc
+// @Summary Attendance report of a CourseRun
+// @Description Per-student attendance counts and rate for a cohort, as JSON or CSV; course staff only
+// @Tags App/Attendance
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json,text/csv
+// @Param id path int true "CourseRun id"
+// @Param format query string false "Output format (json, csv)"
+// @Success 200 {object} AttendanceReport
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /course_runs/{id}/attendance_report [get]
+func (c *AttendanceController) Report(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	format := ctx.Query("format")
+	if format != "" && format != "json" && format != "csv" {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid format. Use 'json' or 'csv'"})
+	}
+
+	report, err := c.Service.GetReport(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	if format != "csv" {
+		return ctx.JSON(http.StatusOK, report)
+	}
+
+	data, err := reportCSV(report)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to render report"})
+	}
+
+	filename := fmt.Sprintf("attendance-course-run-%d.csv", report.CourseRunId)
+	ctx.SetHeader("Content-Disposition", `attachment; filename="`+filename+`"`)
+	return ctx.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// reportCSV renders the report with one row per student
+func reportCSV(report *AttendanceReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"student_id", "first_name", "last_name", "email", "sessions_held",
+		"present", "late", "absent", "excused", "unmarked", "percent", "meets_minimum"})
+	for _, row := range report.Students {
+		w.Write([]string{
+			strconv.FormatUint(uint64(row.StudentId), 10),
+			row.FirstName,
+			row.LastName,
+			row.Email,
+			strconv.Itoa(report.SessionsHeld),
+			strconv.Itoa(row.Present),
+			strconv.Itoa(row.Late),
+			strconv.Itoa(row.Absent),
+			strconv.Itoa(row.Excused),
+			strconv.Itoa(row.Unmarked),
+			strconv.FormatFloat(row.Percent, 'f', 2, 64),
+			strconv.FormatBool(row.MeetsMinimum),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// handleError maps attendance errors to HTTP responses
+func (c *AttendanceController) handleError(ctx *router.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrNotCourseStaff), errors.Is(err, ErrNotSessionAttendee):
+		return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalidCheckInCode):
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrCheckInAttempts):
+		return ctx.JSON(http.StatusTooManyRequests, types.ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "record not found"):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	}
+	return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: err.Error()})
+}
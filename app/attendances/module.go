@@ -0,0 +1,53 @@
+package attendances
+
+import (
+	"base/app/models"
+	"base/core/module"
+	"base/core/router"
+
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Service    *AttendanceService
+	Controller *AttendanceController
+}
+
+// Init creates and initializes the Attendance module with all dependencies
+func Init(deps module.Dependencies) module.Module {
+	// Initialize service and controller
+	service := NewAttendanceService(deps.DB, deps.Emitter, deps.Storage, deps.Logger)
+	controller := NewAttendanceController(service, deps.Storage)
+
+	// Lifetime of check-in codes and grace period before check-ins count as late
+	service.CodeTTL = deps.Config.AttendanceCodeTTL
+	service.LateAfter = deps.Config.AttendanceLateAfter
+
+	// Create module
+	mod := &Module{
+		DB:         deps.DB,
+		Service:    service,
+		Controller: controller,
+	}
+
+	return mod
+}
+
+// Routes registers the module routes
+func (m *Module) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+func (m *Module) Migrate() error {
+	return m.DB.AutoMigrate(&models.Attendance{}, &models.AttendanceCheckInCode{}, &models.AttendanceCheckInAttempt{})
+}
+
+func (m *Module) GetModels() []any {
+	return []any{
+		&models.Attendance{},
+		&models.AttendanceCheckInCode{},
+		&models.AttendanceCheckInAttempt{},
+	}
+}
@@ -0,0 +1,522 @@
+package attendances
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"base/app/models"
+	"base/core/app/profile"
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MarkAttendanceEvent    = "attendances.mark"
+	CheckInAttendanceEvent = "attendances.check_in"
+	GenerateCodeEvent      = "attendances.code"
+)
+
+const (
+	// DefaultCodeTTL is how long a check-in code stays valid
+	DefaultCodeTTL = 5 * time.Minute
+	// DefaultLateAfter is the grace period after the start before a check-in counts as late
+	DefaultLateAfter = 10 * time.Minute
+	// DefaultMaxCheckInFailures is how many wrong codes a student may enter per session
+	DefaultMaxCheckInFailures = 5
+)
+
+var (
+	ErrNotCourseStaff        = errors.New("only course staff can manage attendance")
+	ErrNotSessionAttendee    = errors.New("student is not enrolled for this session")
+	ErrInvalidCheckInCode    = errors.New("check-in code is invalid or has expired")
+	ErrCheckInAttempts       = errors.New("too many wrong check-in codes; ask the instructor to mark your attendance")
+	ErrCertificateIneligible = errors.New("attendance is below the minimum required for a certificate")
+)
+
+// SessionAttendance lists the marked records and the attendees still to be marked
+type SessionAttendance struct {
+	Records  []*models.AttendanceResponse `json:"records"`
+	Unmarked []*profile.UserModelResponse `json:"unmarked"`
+}
+
+// StudentAttendanceSummary aggregates a student's attendance over a course run
+type StudentAttendanceSummary struct {
+	StudentId    uint    `json:"student_id"`
+	FirstName    string  `json:"first_name"`
+	LastName     string  `json:"last_name"`
+	Email        string  `json:"email"`
+	Present      int     `json:"present"`
+	Late         int     `json:"late"`
+	Absent       int     `json:"absent"`
+	Excused      int     `json:"excused"`
+	Unmarked     int     `json:"unmarked"`
+	Percent      float64 `json:"percent"`
+	MeetsMinimum bool    `json:"meets_minimum"`
+}
+
+// AttendanceReport is the attendance of every student of a course run
+type AttendanceReport struct {
+	CourseRunId          uint                        `json:"course_run_id"`
+	MinAttendancePercent int                         `json:"min_attendance_percent"`
+	SessionsHeld         int                         `json:"sessions_held"`
+	Students             []*StudentAttendanceSummary `json:"students"`
+}
+
+type AttendanceService struct {
+	DB                 *gorm.DB
+	Emitter            *emitter.Emitter
+	Storage            *storage.ActiveStorage
+	Logger             logger.Logger
+	CodeTTL            time.Duration
+	LateAfter          time.Duration
+	MaxCheckInFailures int
+}
+
+func NewAttendanceService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *AttendanceService {
+	return &AttendanceService{
+		DB:                 db,
+		Logger:             logger,
+		Emitter:            emitter,
+		Storage:            storage,
+		CodeTTL:            DefaultCodeTTL,
+		LateAfter:          DefaultLateAfter,
+		MaxCheckInFailures: DefaultMaxCheckInFailures,
+	}
+}
+
+// getSession loads a live session together with its course
+func (s *AttendanceService) getSession(id uint) (*models.LiveSession, error) {
+	session := &models.LiveSession{}
+	if err := s.DB.Preload("Course").First(session, id).Error; err != nil {
+		s.Logger.Error("failed to get live session",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+	return session, nil
+}
+
+// requireStaff ensures the user teaches the course or administers the platform
+func (s *AttendanceService) requireStaff(course *models.Course, userId uint) error {
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		return err
+	}
+	if !course.IsStaff(user) {
+		return ErrNotCourseStaff
+	}
+	return nil
+}
+
+// attendeeIds returns the students expected at a session
+func (s *AttendanceService) attendeeIds(session *models.LiveSession) ([]uint, error) {
+	query := s.DB.Model(&models.Enrollment{})
+	if session.CourseRunId != nil {
+		query = query.Where("course_run_id = ?", *session.CourseRunId)
+	} else {
+		query = query.Where("course_id = ?", session.CourseId)
+	}
+
+	var ids []uint
+	if err := query.Distinct().Pluck("student_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// isAttendee reports whether the student is expected at the session
+func (s *AttendanceService) isAttendee(session *models.LiveSession, studentId uint) (bool, error) {
+	ids, err := s.attendeeIds(session)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range ids {
+		if id == studentId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetSessionAttendance returns the attendance sheet of a session for course staff
+func (s *AttendanceService) GetSessionAttendance(sessionId, userId uint) (*SessionAttendance, error) {
+	session, err := s.getSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireStaff(session.Course, userId); err != nil {
+		return nil, err
+	}
+
+	var records []*models.Attendance
+	if err := s.DB.Preload("Student").Where("live_session_id = ?", sessionId).
+		Order("id asc").Find(&records).Error; err != nil {
+		s.Logger.Error("failed to get attendance",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(sessionId)))
+		return nil, err
+	}
+
+	ids, err := s.attendeeIds(session)
+	if err != nil {
+		return nil, err
+	}
+	marked := make(map[uint]bool, len(records))
+	for _, record := range records {
+		marked[record.StudentId] = true
+	}
+	var pending []uint
+	for _, id := range ids {
+		if !marked[id] {
+			pending = append(pending, id)
+		}
+	}
+
+	result := &SessionAttendance{
+		Records:  make([]*models.AttendanceResponse, 0, len(records)),
+		Unmarked: make([]*profile.UserModelResponse, 0, len(pending)),
+	}
+	for _, record := range records {
+		result.Records = append(result.Records, record.ToResponse())
+	}
+	if len(pending) > 0 {
+		var students []*profile.User
+		if err := s.DB.Where("id IN ?", pending).Order("id asc").Find(&students).Error; err != nil {
+			return nil, err
+		}
+		for _, student := range students {
+			result.Unmarked = append(result.Unmarked, student.ToModelResponse())
+		}
+	}
+
+	return result, nil
+}
+
+// MarkAttendance records the status of several students of a session at once
+func (s *AttendanceService) MarkAttendance(sessionId, userId uint, req *models.BulkMarkAttendanceRequest) (*SessionAttendance, error) {
+	session, err := s.getSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireStaff(session.Course, userId); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.attendeeIds(session)
+	if err != nil {
+		return nil, err
+	}
+	attendees := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		attendees[id] = true
+	}
+	for _, record := range req.Records {
+		if !attendees[record.StudentId] {
+			return nil, fmt.Errorf("%w: %d", ErrNotSessionAttendee, record.StudentId)
+		}
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, record := range req.Records {
+			item := &models.Attendance{}
+			err := tx.Where("live_session_id = ? AND student_id = ?", sessionId, record.StudentId).First(item).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			item.LiveSessionId = sessionId
+			item.StudentId = record.StudentId
+			item.Status = record.Status
+			item.Note = record.Note
+			item.MarkedById = &userId
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.Logger.Error("failed to mark attendance",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(sessionId)))
+		return nil, err
+	}
+
+	s.Emitter.Emit(MarkAttendanceEvent, session)
+
+	return s.GetSessionAttendance(sessionId, userId)
+}
+
+// GenerateCode creates a short-lived check-in code for a session
+func (s *AttendanceService) GenerateCode(sessionId, userId uint) (*models.AttendanceCheckInCode, error) {
+	session, err := s.getSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireStaff(session.Course, userId); err != nil {
+		return nil, err
+	}
+
+	code, err := generateCheckInCode()
+	if err != nil {
+		return nil, err
+	}
+	item := &models.AttendanceCheckInCode{
+		Code:          code,
+		ExpiresAt:     time.Now().Add(s.CodeTTL),
+		LiveSessionId: sessionId,
+		CreatedById:   userId,
+	}
+	if err := s.DB.Create(item).Error; err != nil {
+		s.Logger.Error("failed to create check-in code",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(sessionId)))
+		return nil, err
+	}
+
+	s.Emitter.Emit(GenerateCodeEvent, item)
+
+	return item, nil
+}
+
+// CheckIn marks the student present, or late once the grace period has passed.
+// A student gets MaxCheckInFailures wrong codes per session, after which only the
+// instructor can mark them, so a code cannot be guessed.
+func (s *AttendanceService) CheckIn(sessionId, studentId uint, code string) (*models.Attendance, error) {
+	session, err := s.getSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.isAttendee(session, studentId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotSessionAttendee
+	}
+
+	// Count the attempt before checking it, so concurrent guesses cannot exceed the limit
+	attempt := &models.AttendanceCheckInAttempt{LiveSessionId: sessionId, StudentId: studentId}
+	if err := s.DB.Create(attempt).Error; err != nil {
+		return nil, err
+	}
+	var attempts int64
+	if err := s.DB.Model(&models.AttendanceCheckInAttempt{}).
+		Where("live_session_id = ? AND student_id = ?", sessionId, studentId).
+		Count(&attempts).Error; err != nil {
+		return nil, err
+	}
+	if attempts > int64(s.MaxCheckInFailures) {
+		if err := s.DB.Delete(attempt).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrCheckInAttempts
+	}
+
+	now := time.Now()
+	var count int64
+	if err := s.DB.Model(&models.AttendanceCheckInCode{}).
+		Where("live_session_id = ? AND code = ? AND expires_at > ?", sessionId, code, now).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrInvalidCheckInCode
+	}
+	if err := s.DB.Delete(attempt).Error; err != nil {
+		return nil, err
+	}
+
+	item := &models.Attendance{}
+	err = s.DB.Where("live_session_id = ? AND student_id = ?", sessionId, studentId).First(item).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	// A repeated check-in keeps the original time and status
+	if item.Id != 0 && item.IsAttended() {
+		return item, nil
+	}
+
+	status := models.AttendancePresent
+	if now.After(session.StartsAt.Time.Add(s.LateAfter)) {
+		status = models.AttendanceLate
+	}
+	item.LiveSessionId = sessionId
+	item.StudentId = studentId
+	item.Status = status
+	item.CheckedInAt = &now
+	if err := s.DB.Save(item).Error; err != nil {
+		s.Logger.Error("failed to check in",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(sessionId)),
+			logger.Int("student_id", int(studentId)))
+		return nil, err
+	}
+
+	s.Emitter.Emit(CheckInAttendanceEvent, item)
+
+	return item, nil
+}
+
+// GetReport summarises the attendance of every student of a course run
+func (s *AttendanceService) GetReport(runId, userId uint) (*AttendanceReport, error) {
+	run := &models.CourseRun{}
+	if err := s.DB.Preload("Course").First(run, runId).Error; err != nil {
+		s.Logger.Error("failed to get course run",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(runId)))
+		return nil, err
+	}
+	if err := s.requireStaff(run.Course, userId); err != nil {
+		return nil, err
+	}
+
+	var enrollments []*models.Enrollment
+	if err := s.DB.Where("course_run_id = ?", runId).Order("id asc").Find(&enrollments).Error; err != nil {
+		return nil, err
+	}
+	studentIds := make([]uint, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		studentIds = append(studentIds, enrollment.StudentId)
+	}
+
+	sessionIds, err := heldSessionIds(s.DB, run, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AttendanceReport{
+		CourseRunId:          run.Id,
+		MinAttendancePercent: run.MinAttendancePercent,
+		SessionsHeld:         len(sessionIds),
+		Students:             make([]*StudentAttendanceSummary, 0, len(studentIds)),
+	}
+	if len(studentIds) == 0 {
+		return report, nil
+	}
+
+	var students []*profile.User
+	if err := s.DB.Where("id IN ?", studentIds).Order("last_name asc, first_name asc").Find(&students).Error; err != nil {
+		return nil, err
+	}
+	var records []*models.Attendance
+	if len(sessionIds) > 0 {
+		if err := s.DB.Where("live_session_id IN ? AND student_id IN ?", sessionIds, studentIds).
+			Find(&records).Error; err != nil {
+			return nil, err
+		}
+	}
+	byStudent := make(map[uint][]*models.Attendance)
+	for _, record := range records {
+		byStudent[record.StudentId] = append(byStudent[record.StudentId], record)
+	}
+
+	for _, student := range students {
+		summary := summarize(byStudent[student.Id], len(sessionIds), run.MinAttendancePercent)
+		summary.StudentId = student.Id
+		summary.FirstName = student.FirstName
+		summary.LastName = student.LastName
+		summary.Email = student.Email
+		report.Students = append(report.Students, summary)
+	}
+
+	return report, nil
+}
+
+// CheckCertificateEligibility returns ErrCertificateIneligible when the enrollment's
+// course run requires a minimum attendance the student has not reached. Without
+// an enrollment or a course run there is no minimum to check.
+func CheckCertificateEligibility(db *gorm.DB, enrollmentId uint) error {
+	if enrollmentId == 0 {
+		return nil
+	}
+	enrollment := &models.Enrollment{}
+	if err := db.First(enrollment, enrollmentId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if enrollment.CourseRunId == nil {
+		return nil
+	}
+
+	run := &models.CourseRun{}
+	if err := db.First(run, *enrollment.CourseRunId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if run.MinAttendancePercent <= 0 {
+		return nil
+	}
+
+	sessionIds, err := heldSessionIds(db, run, time.Now())
+	if err != nil {
+		return err
+	}
+	var records []*models.Attendance
+	if len(sessionIds) > 0 {
+		if err := db.Where("live_session_id IN ? AND student_id = ?", sessionIds, enrollment.StudentId).
+			Find(&records).Error; err != nil {
+			return err
+		}
+	}
+
+	if !summarize(records, len(sessionIds), run.MinAttendancePercent).MeetsMinimum {
+		return ErrCertificateIneligible
+	}
+	return nil
+}
+
+// heldSessionIds returns the sessions of a course run that have already started,
+// including the course-wide sessions shared by every cohort
+func heldSessionIds(db *gorm.DB, run *models.CourseRun, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&models.LiveSession{}).
+		Where("(course_run_id = ? OR (course_run_id IS NULL AND course_id = ?)) AND starts_at <= ?", run.Id, run.CourseId, now).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// summarize counts a student's records; excused sessions do not count against the rate
+func summarize(records []*models.Attendance, held int, minPercent int) *StudentAttendanceSummary {
+	summary := &StudentAttendanceSummary{}
+	for _, record := range records {
+		switch record.Status {
+		case models.AttendancePresent:
+			summary.Present++
+		case models.AttendanceLate:
+			summary.Late++
+		case models.AttendanceAbsent:
+			summary.Absent++
+		case models.AttendanceExcused:
+			summary.Excused++
+		}
+	}
+	summary.Unmarked = held - summary.Present - summary.Late - summary.Absent - summary.Excused
+
+	summary.Percent = 100
+	if counted := held - summary.Excused; counted > 0 {
+		rate := float64(summary.Present+summary.Late) / float64(counted) * 100
+		summary.Percent = math.Round(rate*100) / 100
+	}
+	summary.MeetsMinimum = summary.Percent >= float64(minPercent)
+	return summary
+}
+
+// generateCheckInCode returns a random six digit code
+func generateCheckInCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,58 @@
+package attendances
+
+import (
+	"base/app/models"
+	"base/core/validator"
+)
+
+// Global validator instance using Base core validator wrapper
+var validate = validator.New()
+
+// ValidateBulkMarkRequest validates the bulk marking request
+func ValidateBulkMarkRequest(req *models.BulkMarkAttendanceRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateCheckInRequest validates the self check-in request
+func ValidateCheckInRequest(req *models.CheckInRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateID validates if the ID is valid
+func ValidateID(id uint) error {
+	if id == 0 {
+		return validator.ValidationErrors{
+			{
+				Field:   "id",
+				Tag:     "required",
+				Value:   "0",
+				Message: "id cannot be zero",
+			},
+		}
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package course_certificates
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"base/app/attendances"
 	"base/app/models"
 	"base/core/router"
 	"base/core/storage"
@@ -46,6 +48,7 @@ func (c *CourseCertificateController) Routes(router *router.RouterGroup) {
 // @Param course-certificates body models.CreateCourseCertificateRequest true "Create CourseCertificate request"
 // @Success 201 {object} models.CourseCertificateResponse
 // @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
 // @Failure 500 {object} types.ErrorResponse
 // @Router /course-certificates [post]
 func (c *CourseCertificateController) Create(ctx *router.Context) error {
@@ -56,6 +59,9 @@ func (c *CourseCertificateController) Create(ctx *router.Context) error {
 
 	item, err := c.Service.Create(&req)
 	if err != nil {
+		if errors.Is(err, attendances.ErrCertificateIneligible) {
+			return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+		}
 		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create item: " + err.Error()})
 	}
 
@@ -205,6 +211,9 @@ func (c *CourseCertificateController) Update(ctx *router.Context) error {
 		if strings.Contains(err.Error(), "record not found") {
 			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
 		}
+		if errors.Is(err, attendances.ErrCertificateIneligible) {
+			return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+		}
 		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update item: " + err.Error()})
 	}
 
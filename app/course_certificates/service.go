@@ -3,6 +3,7 @@ package course_certificates
 import (
 	"math"
 
+	"base/app/attendances"
 	"base/app/models"
 	"base/core/emitter"
 	"base/core/logger"
@@ -68,6 +69,11 @@ func (s *CourseCertificateService) applySorting(query *gorm.DB, sortBy *string,
 }
 
 func (s *CourseCertificateService) Create(req *models.CreateCourseCertificateRequest) (*models.CourseCertificate, error) {
+	// Cohorts may require a minimum attendance before a certificate is issued
+	if err := attendances.CheckCertificateEligibility(s.DB, req.EnrollmentId); err != nil {
+		return nil, err
+	}
+
 	item := &models.CourseCertificate{
 		EnrollmentId:   req.EnrollmentId,
 		CertificateUrl: req.CertificateUrl,
@@ -101,7 +107,10 @@ func (s *CourseCertificateService) Update(id uint, req *models.UpdateCourseCerti
 
 	// Update fields directly on the model
 	// For foreign key relationships
-	if req.EnrollmentId != 0 {
+	if req.EnrollmentId != 0 && req.EnrollmentId != item.EnrollmentId {
+		if err := attendances.CheckCertificateEligibility(s.DB, req.EnrollmentId); err != nil {
+			return nil, err
+		}
 		item.EnrollmentId = req.EnrollmentId
 	}
 	// For non-pointer string fields
@@ -1,6 +1,7 @@
 package course_resources
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -33,6 +34,9 @@ func (c *CourseResourceController) Routes(router *router.RouterGroup) {
 	router.DELETE("/course-resources/:id", c.Delete) // Delete
 
 	//Upload endpoints for each file field
+	router.PUT("/course-resources/:id/file", c.UploadFile)    // Upload the private file
+	router.DELETE("/course-resources/:id/file", c.RemoveFile) // Remove the file
+	router.GET("/course-resources/:id/download", c.Download)  // Signed, expiring download URL
 }
 
 // CreateCourseResource godoc
@@ -64,7 +68,7 @@ func (c *CourseResourceController) Create(ctx *router.Context) error {
 
 // GetCourseResource godoc
 // @Summary Get a CourseResource
-// @Description Get a CourseResource by its id
+// @Description Get a CourseResource by its id. file_url is only shown to students enrolled in the course and course staff; downloads go through /course-resources/{id}/download.
 // @Tags App/CourseResource
 // @Security ApiKeyAuth
 // @Security BearerAuth
@@ -81,9 +85,9 @@ func (c *CourseResourceController) Get(ctx *router.Context) error {
 		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
 	}
 
-	item, err := c.Service.GetById(uint(id))
+	item, err := c.Service.GetForUser(uint(id), ctx.GetUint("user_id"))
 	if err != nil {
-		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+		return c.handleError(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, item.ToResponse())
@@ -91,7 +95,7 @@ func (c *CourseResourceController) Get(ctx *router.Context) error {
 
 // ListCourseResources godoc
 // @Summary List course-resources
-// @Description Get a list of course-resources
+// @Description Get a list of course-resources. file_url is only shown for courses the user is enrolled in or staff of.
 // @Tags App/CourseResource
 // @Security ApiKeyAuth
 // @Security BearerAuth
@@ -140,7 +144,7 @@ func (c *CourseResourceController) List(ctx *router.Context) error {
 		}
 	}
 
-	paginatedResponse, err := c.Service.GetAll(page, limit, sortBy, sortOrder)
+	paginatedResponse, err := c.Service.GetAll(ctx.GetUint("user_id"), page, limit, sortBy, sortOrder)
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
 	}
@@ -240,3 +244,123 @@ func (c *CourseResourceController) Delete(ctx *router.Context) error {
 	ctx.Status(http.StatusNoContent)
 	return nil
 }
+
+// UploadCourseResourceFile godoc
+// @Summary Upload a CourseResource file
+// @Description Upload the file of a CourseResource. Files are private and can only be downloaded through signed URLs
+// @Tags App/CourseResource
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "CourseResource id"
+// @Param file formData file true "Resource file"
+// @Success 200 {object} models.CourseResourceResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /course-resources/{id}/file [put]
+func (c *CourseResourceController) UploadFile(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "File is required"})
+	}
+
+	item, err := c.Service.UploadFile(uint(id), userId, file)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// RemoveCourseResourceFile godoc
+// @Summary Remove a CourseResource file
+// @Description Delete the uploaded file of a CourseResource
+// @Tags App/CourseResource
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "CourseResource id"
+// @Success 200 {object} models.CourseResourceResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /course-resources/{id}/file [delete]
+func (c *CourseResourceController) RemoveFile(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	item, err := c.Service.RemoveFile(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DownloadCourseResource godoc
+// @Summary Download a CourseResource
+// @Description Get a signed download URL that expires, for students enrolled in the course and course staff
+// @Tags App/CourseResource
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "CourseResource id"
+// @Success 200 {object} models.CourseResourceDownloadResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /course-resources/{id}/download [get]
+func (c *CourseResourceController) Download(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	download, err := c.Service.Download(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.SetHeader("Cache-Control", "no-store")
+	return ctx.JSON(http.StatusOK, download)
+}
+
+func (c *CourseResourceController) handleError(ctx *router.Context, err error) error {
+	if status, ok := storage.QuotaStatus(err); ok {
+		return ctx.JSON(status, types.ErrorResponse{Error: err.Error()})
+	}
+	switch {
+	case errors.Is(err, ErrNotCourseStaff), errors.Is(err, ErrNotEnrolled):
+		return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrNoFile):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "record not found"):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	case strings.Contains(err.Error(), "not allowed"), strings.Contains(err.Error(), "exceeds maximum"):
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	default:
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: err.Error()})
+	}
+}
@@ -21,6 +21,9 @@ func Init(deps module.Dependencies) module.Module {
 	service := NewCourseResourceService(deps.DB, deps.Emitter, deps.Storage, deps.Logger)
 	controller := NewCourseResourceController(service, deps.Storage)
 
+	// How long signed download URLs stay valid
+	service.DownloadTTL = deps.Config.CourseResourceDownloadTTL
+
 	// Create module
 	mod := &Module{
 		DB:         deps.DB,
@@ -1,9 +1,13 @@
 package course_resources
 
 import (
+	"errors"
 	"math"
+	"mime/multipart"
+	"time"
 
 	"base/app/models"
+	"base/core/app/profile"
 	"base/core/emitter"
 	"base/core/logger"
 	"base/core/storage"
@@ -16,22 +20,55 @@ const (
 	CreateCourseResourceEvent = "courseresources.create"
 	UpdateCourseResourceEvent = "courseresources.update"
 	DeleteCourseResourceEvent = "courseresources.delete"
+
+	UploadCourseResourceFileEvent = "courseresources.file.uploaded"
+	DeleteCourseResourceFileEvent = "courseresources.file.deleted"
+)
+
+// DefaultDownloadTTL is how long a signed download URL stays valid
+const DefaultDownloadTTL = 15 * time.Minute
+
+var (
+	ErrNotCourseStaff = errors.New("only the course instructor or an administrator can manage resource files")
+	ErrNotEnrolled    = errors.New("you must be enrolled in the course to download this resource")
+	ErrNoFile         = errors.New("resource has no file")
 )
 
 type CourseResourceService struct {
-	DB      *gorm.DB
-	Emitter *emitter.Emitter
-	Storage *storage.ActiveStorage
-	Logger  logger.Logger
+	DB          *gorm.DB
+	Emitter     *emitter.Emitter
+	Storage     *storage.ActiveStorage
+	Logger      logger.Logger
+	DownloadTTL time.Duration
 }
 
-func NewCourseResourceService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *CourseResourceService {
-	return &CourseResourceService{
-		DB:      db,
-		Logger:  logger,
-		Emitter: emitter,
-		Storage: storage,
+func NewCourseResourceService(db *gorm.DB, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage, logger logger.Logger) *CourseResourceService {
+	service := &CourseResourceService{
+		DB:          db,
+		Logger:      logger,
+		Emitter:     emitter,
+		Storage:     activeStorage,
+		DownloadTTL: DefaultDownloadTTL,
 	}
+
+	// Resource files are paid course material, so they are stored privately
+	if activeStorage != nil {
+		activeStorage.RegisterAttachment("course_resource", storage.AttachmentConfig{
+			Table:             "course_resources",
+			Field:             "file",
+			Path:              "course_resources",
+			AllowedExtensions: []string{".pdf", ".doc", ".docx", ".ppt", ".pptx", ".xls", ".xlsx", ".txt", ".md", ".zip", ".epub", ".mp3", ".mp4", ".jpg", ".jpeg", ".png"},
+			MaxFileSize:       500 << 20, // 500MB
+			Private:           true,
+			Authorize:         service.authorizeStaff,
+			OnAttach:          service.setFile,
+			OnUpdate:          service.refreshFile,
+			OwnerType:         "course",
+			Owner:             service.courseOf,
+		})
+	}
+
+	return service
 }
 
 // applySorting applies sorting to the query based on the sort and order parameters
@@ -152,6 +189,13 @@ func (s *CourseResourceService) Delete(id uint) error {
 	}
 
 	// Delete file attachments if any
+	if item.File != nil {
+		if err := s.Storage.Delete(item.File); err != nil {
+			s.Logger.Error("failed to delete courseresource file",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+		}
+	}
 
 	if err := s.DB.Delete(item).Error; err != nil {
 		s.Logger.Error("failed to delete courseresource",
@@ -180,7 +224,21 @@ func (s *CourseResourceService) GetById(id uint) (*models.CourseResource, error)
 	return item, nil
 }
 
-func (s *CourseResourceService) GetAll(page *int, limit *int, sortBy *string, sortOrder *string) (*types.PaginatedResponse, error) {
+// GetForUser gets a CourseResource as a user may see it: its link is left out unless
+// they are enrolled in the course or course staff, as Download requires
+func (s *CourseResourceService) GetForUser(id, userId uint) (*models.CourseResource, error) {
+	item, err := s.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.hideLinks(userId, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// GetAll lists resources; links are left out as GetForUser does
+func (s *CourseResourceService) GetAll(userId uint, page *int, limit *int, sortBy *string, sortOrder *string) (*types.PaginatedResponse, error) {
 	var items []*models.CourseResource
 	var total int64
 
@@ -220,6 +278,9 @@ func (s *CourseResourceService) GetAll(page *int, limit *int, sortBy *string, so
 			logger.String("error", err.Error()))
 		return nil, err
 	}
+	if err := s.hideLinks(userId, items...); err != nil {
+		return nil, err
+	}
 
 	// Convert to response type
 	responses := make([]*models.CourseResourceListResponse, len(items))
@@ -263,3 +324,214 @@ func (s *CourseResourceService) GetAllForSelect() ([]*models.CourseResource, err
 
 	return items, nil
 }
+
+// UploadFile stores a file for the resource, replacing the previous one
+func (s *CourseResourceService) UploadFile(id, userId uint, file *multipart.FileHeader) (*models.CourseResource, error) {
+	if err := s.authorizeStaff(userId, id); err != nil {
+		return nil, err
+	}
+	item := &models.CourseResource{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.Storage.Attach(item, "file", file, userId)
+	if err != nil {
+		s.Logger.Error("failed to upload courseresource file",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	previous := item.File
+	if err := s.setFile(attachment); err != nil {
+		_ = s.Storage.Delete(attachment)
+		return nil, err
+	}
+	if previous != nil {
+		_ = s.Storage.Delete(previous)
+	}
+
+	return s.GetById(id)
+}
+
+// RemoveFile deletes the resource's file
+func (s *CourseResourceService) RemoveFile(id, userId uint) (*models.CourseResource, error) {
+	if err := s.authorizeStaff(userId, id); err != nil {
+		return nil, err
+	}
+	item := &models.CourseResource{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		return nil, err
+	}
+	if item.File == nil {
+		return nil, ErrNoFile
+	}
+
+	if err := s.Storage.Delete(item.File); err != nil {
+		s.Logger.Error("failed to delete courseresource file",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+	if err := s.DB.Model(item).Update("file", nil).Error; err != nil {
+		return nil, err
+	}
+
+	// Emit file deleted event
+	s.Emitter.Emit(DeleteCourseResourceFileEvent, item)
+
+	return s.GetById(id)
+}
+
+// Download issues a download link to enrolled students and course staff. Uploaded
+// files get a signed URL that expires; external links are returned as they are.
+func (s *CourseResourceService) Download(id, userId uint) (*models.CourseResourceDownloadResponse, error) {
+	item, err := s.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	if item.File == nil && item.FileUrl == "" {
+		return nil, ErrNoFile
+	}
+
+	allowed, err := s.courseAccess(userId, item.CourseId)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed[item.CourseId] {
+		return nil, ErrNotEnrolled
+	}
+
+	if item.File == nil {
+		return &models.CourseResourceDownloadResponse{Url: item.FileUrl}, nil
+	}
+
+	url, err := s.Storage.SignedURL(item.File, s.DownloadTTL)
+	if err != nil {
+		s.Logger.Error("failed to sign courseresource download",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+	expiresAt := time.Now().Add(s.DownloadTTL)
+	return &models.CourseResourceDownloadResponse{
+		Url:       url,
+		Filename:  item.File.Filename,
+		ExpiresAt: &expiresAt,
+	}, nil
+}
+
+// hideLinks clears the external links of resources whose course the user is
+// neither enrolled in nor staff of
+func (s *CourseResourceService) hideLinks(userId uint, items ...*models.CourseResource) error {
+	var courseIds []uint
+	for _, item := range items {
+		if item.FileUrl != "" {
+			courseIds = append(courseIds, item.CourseId)
+		}
+	}
+	if len(courseIds) == 0 {
+		return nil
+	}
+
+	allowed, err := s.courseAccess(userId, courseIds...)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if !allowed[item.CourseId] {
+			item.FileUrl = ""
+		}
+	}
+	return nil
+}
+
+// courseAccess reports which of the courses a user is enrolled in or staff of
+func (s *CourseResourceService) courseAccess(userId uint, courseIds ...uint) (map[uint]bool, error) {
+	allowed := make(map[uint]bool, len(courseIds))
+	if userId == 0 {
+		return allowed, nil
+	}
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return allowed, nil
+		}
+		return nil, err
+	}
+	// Administrators manage every course
+	if user.IsAdmin() {
+		for _, id := range courseIds {
+			allowed[id] = true
+		}
+		return allowed, nil
+	}
+
+	var taught, enrolled []uint
+	if err := s.DB.Model(&models.Course{}).
+		Where("id IN ? AND instructor_id = ?", courseIds, userId).
+		Pluck("id", &taught).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.Enrollment{}).
+		Where("course_id IN ? AND student_id = ?", courseIds, userId).
+		Distinct().Pluck("course_id", &enrolled).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range append(taught, enrolled...) {
+		allowed[id] = true
+	}
+	return allowed, nil
+}
+
+// authorizeStaff allows the course instructor and administrators to manage a resource's file
+func (s *CourseResourceService) authorizeStaff(userId, id uint) error {
+	item := &models.CourseResource{}
+	if err := s.DB.Preload("Course").First(item, id).Error; err != nil {
+		return err
+	}
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		return err
+	}
+	if item.Course == nil || !item.Course.IsStaff(user) {
+		return ErrNotCourseStaff
+	}
+	return nil
+}
+
+// refreshFile updates the copy of the file kept on the resource when its file moves
+func (s *CourseResourceService) refreshFile(attachment *storage.Attachment) error {
+	return s.DB.Model(&models.CourseResource{}).Where("id = ?", attachment.ModelId).Update("file", attachment).Error
+}
+
+// setFile stores an attachment on its resource
+func (s *CourseResourceService) setFile(attachment *storage.Attachment) error {
+	item := &models.CourseResource{}
+	if err := s.DB.First(item, attachment.ModelId).Error; err != nil {
+		return err
+	}
+	item.File = attachment
+	item.UploadedAt = types.DateTime{Time: attachment.CreatedAt}
+	if err := s.DB.Model(item).Select("file", "uploaded_at").Updates(item).Error; err != nil {
+		s.Logger.Error("failed to set courseresource file",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(item.Id)))
+		return err
+	}
+
+	// Emit file uploaded event
+	s.Emitter.Emit(UploadCourseResourceFileEvent, item)
+
+	return nil
+}
+
+// courseOf returns the course of a resource, whose storage quota its file counts against
+func (s *CourseResourceService) courseOf(resourceId uint) (uint, error) {
+	item := &models.CourseResource{}
+	if err := s.DB.Select("course_id").First(item, resourceId).Error; err != nil {
+		return 0, err
+	}
+	return item.CourseId, nil
+}
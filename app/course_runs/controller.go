@@ -0,0 +1,420 @@
+package course_runs
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"base/app/models"
+	"base/core/app/verification"
+	"base/core/router"
+	"base/core/storage"
+	"base/core/types"
+	"base/core/validator"
+)
+
+type CourseRunController struct {
+	Service      *CourseRunService
+	Storage      *storage.ActiveStorage
+	Verification *verification.VerificationService
+}
+
+func NewCourseRunController(service *CourseRunService, storage *storage.ActiveStorage, verification *verification.VerificationService) *CourseRunController {
+	return &CourseRunController{
+		Service:      service,
+		Storage:      storage,
+		Verification: verification,
+	}
+}
+
+func (c *CourseRunController) Routes(router *router.RouterGroup) {
+	// Main CRUD endpoints - specific routes MUST come before parameterized routes
+	router.GET("/course_runs", c.List)          // Paginated list
+	router.POST("/course_runs", c.Create)       // Create
+	router.GET("/course_runs/all", c.ListAll)   // Unpaginated list - MUST be before /:id
+	router.GET("/course_runs/:id", c.Get)       // Get by ID - MUST be after /all
+	router.PUT("/course_runs/:id", c.Update)    // Update
+	router.DELETE("/course_runs/:id", c.Delete) // Delete
+
+	// Cohort sign-up and waitlist endpoints
+	router.POST("/course_runs/:id/join", c.Join, verification.Require(c.Verification, verification.ActionEnroll))   // Enroll or join the waitlist
+	router.POST("/course_runs/:id/leave", c.Leave)                                                                  // Leave the cohort or its waitlist
+	router.POST("/course_runs/:id/claim", c.Claim, verification.Require(c.Verification, verification.ActionEnroll)) // Claim an offered seat
+	router.GET("/course_runs/:id/waitlist", c.ListWaitlist)                                                         // Waitlist in FIFO order
+}
+
+// CreateCourseRun godoc
+// @Summary Create a new CourseRun
+// @Description Create a new CourseRun (cohort) with the input payload
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param course_runs body models.CreateCourseRunRequest true "Create CourseRun request"
+// @Success 201 {object} models.CourseRunResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /course_runs [post]
+func (c *CourseRunController) Create(ctx *router.Context) error {
+	var req models.CreateCourseRunRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateCourseRunCreateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Create(&req)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create item: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusCreated, c.Service.ToResponse(item))
+}
+
+// GetCourseRun godoc
+// @Summary Get a CourseRun
+// @Description Get a CourseRun by its id, including seat usage
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "CourseRun id"
+// @Success 200 {object} models.CourseRunResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /course_runs/{id} [get]
+func (c *CourseRunController) Get(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	item, err := c.Service.GetById(uint(id))
+	if err != nil {
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	}
+
+	return ctx.JSON(http.StatusOK, c.Service.ToResponse(item))
+}
+
+// ListCourseRuns godoc
+// @Summary List course_runs
+// @Description Get a list of course_runs
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Param course_id query int false "Filter by course id"
+// @Param sort query string false "Sort field (id, created_at, updated_at,title,starts_at,ends_at,enrollment_opens_at,enrollment_closes_at,capacity,)"
+// @Param order query string false "Sort order (asc, desc)"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /course_runs [get]
+func (c *CourseRunController) List(ctx *router.Context) error {
+	var page, limit *int
+	var sortBy, sortOrder *string
+	var courseId *uint
+
+	// Parse page parameter
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid page number"})
+		}
+	}
+
+	// Parse limit parameter
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid limit number"})
+		}
+	}
+
+	// Parse course filter
+	if courseStr := ctx.Query("course_id"); courseStr != "" {
+		parsed, err := strconv.ParseUint(courseStr, 10, 32)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid course_id"})
+		}
+		value := uint(parsed)
+		courseId = &value
+	}
+
+	// Parse sort parameters
+	if sortStr := ctx.Query("sort"); sortStr != "" {
+		sortBy = &sortStr
+	}
+
+	if orderStr := ctx.Query("order"); orderStr != "" {
+		if orderStr == "asc" || orderStr == "desc" {
+			sortOrder = &orderStr
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid sort order. Use 'asc' or 'desc'"})
+		}
+	}
+
+	paginatedResponse, err := c.Service.GetAll(page, limit, sortBy, sortOrder, courseId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ListAllCourseRuns godoc
+// @Summary List all course_runs for select options
+// @Description Get a simplified list of all course_runs with id and name only (for dropdowns/select boxes)
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.CourseRunSelectOption
+// @Failure 500 {object} types.ErrorResponse
+// @Router /course_runs/all [get]
+func (c *CourseRunController) ListAll(ctx *router.Context) error {
+	items, err := c.Service.GetAllForSelect()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch select options: " + err.Error()})
+	}
+
+	// Convert to select options
+	var selectOptions []*models.CourseRunSelectOption
+	for _, item := range items {
+		selectOptions = append(selectOptions, item.ToSelectOption())
+	}
+
+	return ctx.JSON(http.StatusOK, selectOptions)
+}
+
+// UpdateCourseRun godoc
+// @Summary Update a CourseRun
+// @Description Update a CourseRun by its id
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "CourseRun id"
+// @Param course_runs body models.UpdateCourseRunRequest true "Update CourseRun request"
+// @Success 200 {object} models.CourseRunResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /course_runs/{id} [put]
+func (c *CourseRunController) Update(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	var req models.UpdateCourseRunRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	item, err := c.Service.Update(uint(id), &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+		}
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: validationErrs})
+		}
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update item: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, c.Service.ToResponse(item))
+}
+
+// DeleteCourseRun godoc
+// @Summary Delete a CourseRun
+// @Description Delete a CourseRun by its id
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "CourseRun id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /course_runs/{id} [delete]
+func (c *CourseRunController) Delete(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	if err := c.Service.Delete(uint(id)); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to delete item: " + err.Error()})
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// JoinCourseRun godoc
+// @Summary Join a CourseRun
+// @Description Enroll the current user in a cohort, or add them to its waitlist when all seats are taken
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "CourseRun id"
+// @Success 201 {object} JoinResult
+// @Success 202 {object} JoinResult
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Router /course_runs/{id}/join [post]
+func (c *CourseRunController) Join(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	result, err := c.Service.Join(uint(id), userId)
+	if err != nil {
+		return c.handleSignupError(ctx, err)
+	}
+
+	if result.Status == "waitlisted" {
+		return ctx.JSON(http.StatusAccepted, result)
+	}
+	return ctx.JSON(http.StatusCreated, result)
+}
+
+// LeaveCourseRun godoc
+// @Summary Leave a CourseRun
+// @Description Remove the current user from a cohort or its waitlist; the freed seat is offered to the next student
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "CourseRun id"
+// @Success 204
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /course_runs/{id}/leave [post]
+func (c *CourseRunController) Leave(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.Service.Leave(uint(id), userId); err != nil {
+		return c.handleSignupError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// ClaimCourseRunSeat godoc
+// @Summary Claim an offered seat
+// @Description Claim a seat offered to the current user from the waitlist before its claim deadline
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "CourseRun id"
+// @Success 201 {object} models.EnrollmentResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Failure 410 {object} types.ErrorResponse
+// @Router /course_runs/{id}/claim [post]
+func (c *CourseRunController) Claim(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	enrollment, err := c.Service.Claim(uint(id), userId)
+	if err != nil {
+		return c.handleSignupError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, enrollment.ToResponse())
+}
+
+// ListCourseRunWaitlist godoc
+// @Summary List a CourseRun waitlist
+// @Description Get the waiting and offered entries of a cohort's waitlist in FIFO order
+// @Tags App/CourseRun
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "CourseRun id"
+// @Success 200 {array} models.CourseRunWaitlistEntryResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /course_runs/{id}/waitlist [get]
+func (c *CourseRunController) ListWaitlist(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	items, err := c.Service.GetWaitlist(uint(id))
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch waitlist: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, items)
+}
+
+// handleSignupError maps sign-up errors to HTTP responses
+func (c *CourseRunController) handleSignupError(ctx *router.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrEnrollmentClosed):
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrAlreadyEnrolled):
+		return ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrNoPendingOffer):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrOfferExpired):
+		return ctx.JSON(http.StatusGone, types.ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "record not found"):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	}
+	return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,82 @@
+package course_runs
+
+import (
+	"time"
+
+	"base/app/models"
+	"base/core/app/verification"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/router"
+	"base/core/scheduler"
+
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Service    *CourseRunService
+	Controller *CourseRunController
+	Logger     logger.Logger
+}
+
+// Init creates and initializes the CourseRun module with all dependencies
+func Init(deps module.Dependencies) module.Module {
+	// Initialize service and controller
+	service := NewCourseRunService(deps.DB, deps.Emitter, deps.Storage, deps.Logger, deps.EmailSender)
+	// Unverified users may not enroll or purchase, as the verification policy says
+	verifier := verification.NewVerificationService(deps.DB, deps.EmailSender, deps.Logger, deps.Config)
+	controller := NewCourseRunController(service, deps.Storage, verifier)
+
+	// Claim window for seats offered from the waitlist
+	service.ClaimWindow = deps.Config.CourseRunClaimWindow
+
+	// Create module
+	mod := &Module{
+		DB:         deps.DB,
+		Service:    service,
+		Controller: controller,
+		Logger:     deps.Logger,
+	}
+
+	return mod
+}
+
+// Routes registers the module routes
+func (m *Module) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+// Init registers the waitlist task that enforces claim deadlines
+func (m *Module) Init() error {
+	mod, err := module.GetModule("scheduler")
+	if err != nil {
+		m.Logger.Warn("Scheduler not available, waitlist offers will not expire automatically")
+		return nil
+	}
+
+	schedulerModule, ok := mod.(*scheduler.Module)
+	if !ok {
+		return nil
+	}
+
+	return schedulerModule.GetScheduler().RegisterTask(&scheduler.Task{
+		Name:        "course_runs.waitlist",
+		Description: "Expire unclaimed waitlist offers and promote the next students",
+		Schedule:    &scheduler.IntervalSchedule{Interval: 5 * time.Minute},
+		Handler:     m.Service.ProcessWaitlists,
+		Enabled:     true,
+	})
+}
+
+func (m *Module) Migrate() error {
+	return m.DB.AutoMigrate(&models.CourseRun{}, &models.CourseRunWaitlistEntry{})
+}
+
+func (m *Module) GetModels() []any {
+	return []any{
+		&models.CourseRun{},
+		&models.CourseRunWaitlistEntry{},
+	}
+}
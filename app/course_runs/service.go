@@ -0,0 +1,705 @@
+package course_runs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"time"
+
+	"base/app/models"
+	"base/core/app/profile"
+	"base/core/email"
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	CreateCourseRunEvent     = "course_runs.create"
+	UpdateCourseRunEvent     = "course_runs.update"
+	DeleteCourseRunEvent     = "course_runs.delete"
+	JoinCourseRunEvent       = "course_runs.join"
+	WaitlistCourseRunEvent   = "course_runs.waitlist"
+	PromoteWaitlistEvent     = "course_runs.waitlist.promote"
+	ExpireWaitlistOfferEvent = "course_runs.waitlist.expire"
+)
+
+// DefaultClaimWindow is how long a promoted student has to claim a freed seat
+const DefaultClaimWindow = 48 * time.Hour
+
+var (
+	ErrEnrollmentClosed = errors.New("enrollment for this course run is closed")
+	ErrAlreadyEnrolled  = errors.New("student is already enrolled in this course run")
+	ErrNoPendingOffer   = errors.New("no pending seat offer for this student")
+	ErrOfferExpired     = errors.New("seat offer has expired")
+)
+
+// JoinResult describes the outcome of a sign-up to a course run
+type JoinResult struct {
+	Status        string                                 `json:"status"` // enrolled or waitlisted
+	Enrollment    *models.EnrollmentResponse             `json:"enrollment,omitempty"`
+	WaitlistEntry *models.CourseRunWaitlistEntryResponse `json:"waitlist_entry,omitempty"`
+}
+
+type CourseRunService struct {
+	DB          *gorm.DB
+	Emitter     *emitter.Emitter
+	Storage     *storage.ActiveStorage
+	Logger      logger.Logger
+	EmailSender email.Sender
+	ClaimWindow time.Duration
+}
+
+func NewCourseRunService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger, emailSender email.Sender) *CourseRunService {
+	return &CourseRunService{
+		DB:          db,
+		Logger:      logger,
+		Emitter:     emitter,
+		Storage:     storage,
+		EmailSender: emailSender,
+		ClaimWindow: DefaultClaimWindow,
+	}
+}
+
+// applySorting applies sorting to the query based on the sort and order parameters
+func (s *CourseRunService) applySorting(query *gorm.DB, sortBy *string, sortOrder *string) {
+	// Valid sortable fields for CourseRun
+	validSortFields := map[string]string{
+		"id":                   "id",
+		"created_at":           "created_at",
+		"updated_at":           "updated_at",
+		"title":                "title",
+		"starts_at":            "starts_at",
+		"ends_at":              "ends_at",
+		"enrollment_opens_at":  "enrollment_opens_at",
+		"enrollment_closes_at": "enrollment_closes_at",
+		"capacity":             "capacity",
+	}
+
+	// Default sorting - if sort_order exists, always use it for custom ordering
+	defaultSortBy := "id"
+	defaultSortOrder := "desc"
+
+	// Determine sort field
+	sortField := defaultSortBy
+	if sortBy != nil && *sortBy != "" {
+		if field, exists := validSortFields[*sortBy]; exists {
+			sortField = field
+		}
+	}
+
+	// Determine sort direction (order parameter)
+	sortDirection := defaultSortOrder
+	if sortOrder != nil && (*sortOrder == "asc" || *sortOrder == "desc") {
+		sortDirection = *sortOrder
+	}
+
+	// Apply sorting
+	query.Order(sortField + " " + sortDirection)
+}
+
+func (s *CourseRunService) Create(req *models.CreateCourseRunRequest) (*models.CourseRun, error) {
+	item := &models.CourseRun{
+		CourseId:             req.CourseId,
+		Title:                req.Title,
+		StartsAt:             req.StartsAt,
+		EndsAt:               req.EndsAt,
+		EnrollmentOpensAt:    req.EnrollmentOpensAt,
+		EnrollmentClosesAt:   req.EnrollmentClosesAt,
+		Capacity:             req.Capacity,
+		MinAttendancePercent: req.MinAttendancePercent,
+	}
+
+	if err := s.DB.Create(item).Error; err != nil {
+		s.Logger.Error("failed to create course run", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateCourseRunEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+func (s *CourseRunService) Update(id uint, req *models.UpdateCourseRunRequest) (*models.CourseRun, error) {
+	item := &models.CourseRun{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find course run for update",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	// Validate request
+	if err := ValidateCourseRunUpdateRequest(req, id); err != nil {
+		return nil, err
+	}
+
+	// Update fields directly on the model
+	// For foreign key relationships
+	if req.CourseId != 0 {
+		item.CourseId = req.CourseId
+	}
+	if req.Title != "" {
+		item.Title = req.Title
+	}
+	// For custom DateTime fields
+	if !req.StartsAt.IsZero() {
+		item.StartsAt = req.StartsAt
+	}
+	if !req.EndsAt.IsZero() {
+		item.EndsAt = req.EndsAt
+	}
+	if !req.EnrollmentOpensAt.IsZero() {
+		item.EnrollmentOpensAt = req.EnrollmentOpensAt
+	}
+	if !req.EnrollmentClosesAt.IsZero() {
+		item.EnrollmentClosesAt = req.EnrollmentClosesAt
+	}
+	// Capacity can be set to 0 (unlimited), so it is a pointer
+	if req.Capacity != nil {
+		item.Capacity = *req.Capacity
+	}
+	if req.MinAttendancePercent != nil {
+		item.MinAttendancePercent = *req.MinAttendancePercent
+	}
+
+	if err := s.DB.Save(item).Error; err != nil {
+		s.Logger.Error("failed to update course run",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	// A capacity increase may free seats for waitlisted students
+	if _, err := s.PromoteWaitlist(item.Id); err != nil {
+		s.Logger.Error("failed to promote waitlist after update",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+	}
+
+	result, err := s.GetById(item.Id)
+	if err != nil {
+		s.Logger.Error("failed to get updated course run",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateCourseRunEvent, result)
+
+	return result, nil
+}
+
+func (s *CourseRunService) Delete(id uint) error {
+	item := &models.CourseRun{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find course run for deletion",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return err
+	}
+
+	if err := s.DB.Delete(item).Error; err != nil {
+		s.Logger.Error("failed to delete course run",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return err
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteCourseRunEvent, item)
+
+	return nil
+}
+
+func (s *CourseRunService) GetById(id uint) (*models.CourseRun, error) {
+	item := &models.CourseRun{}
+
+	query := item.Preload(s.DB)
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get course run",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// ToResponse converts a course run to its response including seat usage
+func (s *CourseRunService) ToResponse(item *models.CourseRun) *models.CourseRunResponse {
+	response := item.ToResponse()
+	if response == nil {
+		return nil
+	}
+	response.SeatsTaken, _ = s.seatsTaken(s.DB, item.Id)
+	s.DB.Model(&models.CourseRunWaitlistEntry{}).
+		Where("course_run_id = ? AND status = ?", item.Id, models.WaitlistStatusWaiting).
+		Count(&response.WaitlistCount)
+	return response
+}
+
+func (s *CourseRunService) GetAll(page *int, limit *int, sortBy *string, sortOrder *string, courseId *uint) (*types.PaginatedResponse, error) {
+	var items []*models.CourseRun
+	var total int64
+
+	query := s.DB.Model(&models.CourseRun{})
+	if courseId != nil {
+		query = query.Where("course_id = ?", *courseId)
+	}
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count course runs",
+			logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Apply pagination if provided
+	if page != nil && limit != nil {
+		offset := (*page - 1) * *limit
+		query = query.Offset(offset).Limit(*limit)
+	}
+
+	// Apply sorting
+	s.applySorting(query, sortBy, sortOrder)
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get course runs",
+			logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Convert to response type
+	responses := make([]*models.CourseRunListResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToListResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetAllForSelect gets all items for select box/dropdown options (simplified response)
+func (s *CourseRunService) GetAllForSelect() ([]*models.CourseRun, error) {
+	var items []*models.CourseRun
+
+	query := s.DB.Model(&models.CourseRun{})
+
+	// Only select the necessary fields for select options
+	query = query.Select("id, title")
+
+	// Order by name/title for better UX
+	query = query.Order("title ASC")
+
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("Failed to fetch items for select", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// seatsTaken counts active enrollments plus seats held by pending offers
+func (s *CourseRunService) seatsTaken(tx *gorm.DB, runId uint) (int64, error) {
+	var enrolled, offered int64
+	if err := tx.Model(&models.Enrollment{}).Where("course_run_id = ?", runId).Count(&enrolled).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&models.CourseRunWaitlistEntry{}).
+		Where("course_run_id = ? AND status = ?", runId, models.WaitlistStatusOffered).
+		Count(&offered).Error; err != nil {
+		return 0, err
+	}
+	return enrolled + offered, nil
+}
+
+// lockRun loads the course run with a row lock so seat counting is serialized
+func (s *CourseRunService) lockRun(tx *gorm.DB, runId uint) (*models.CourseRun, error) {
+	run := &models.CourseRun{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(run, runId).Error; err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Join signs a student up for a course run, or places them on the waitlist when it is full
+func (s *CourseRunService) Join(runId, studentId uint) (*JoinResult, error) {
+	var enrollment *models.Enrollment
+	var entry *models.CourseRunWaitlistEntry
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		run, err := s.lockRun(tx, runId)
+		if err != nil {
+			return err
+		}
+		if !run.IsEnrollmentOpen(time.Now()) {
+			return ErrEnrollmentClosed
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Enrollment{}).
+			Where("course_run_id = ? AND student_id = ?", runId, studentId).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyEnrolled
+		}
+
+		// Already queued or holding an offer: return the current entry
+		current := &models.CourseRunWaitlistEntry{}
+		err = tx.Where("course_run_id = ? AND student_id = ? AND status IN ?", runId, studentId,
+			[]string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}).First(current).Error
+		if err == nil {
+			entry = current
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		taken, err := s.seatsTaken(tx, runId)
+		if err != nil {
+			return err
+		}
+
+		if run.Capacity == 0 || taken < int64(run.Capacity) {
+			enrollment = &models.Enrollment{
+				StudentId:   studentId,
+				CourseId:    run.CourseId,
+				CourseRunId: &run.Id,
+				EnrolledAt:  types.Now(),
+			}
+			return tx.Create(enrollment).Error
+		}
+
+		entry = &models.CourseRunWaitlistEntry{
+			CourseRunId: runId,
+			StudentId:   studentId,
+			Status:      models.WaitlistStatusWaiting,
+		}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to join course run",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(runId)),
+			logger.Int("student_id", int(studentId)))
+		return nil, err
+	}
+
+	if enrollment != nil {
+		s.Emitter.Emit(JoinCourseRunEvent, enrollment)
+		return &JoinResult{Status: "enrolled", Enrollment: enrollment.ToResponse()}, nil
+	}
+
+	s.Emitter.Emit(WaitlistCourseRunEvent, entry)
+	return &JoinResult{Status: "waitlisted", WaitlistEntry: s.entryResponse(entry)}, nil
+}
+
+// Leave removes a student's enrollment or waitlist entry and offers the freed seat
+func (s *CourseRunService) Leave(runId, studentId uint) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("course_run_id = ? AND student_id = ?", runId, studentId).Delete(&models.Enrollment{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+
+		result = tx.Model(&models.CourseRunWaitlistEntry{}).
+			Where("course_run_id = ? AND student_id = ? AND status IN ?", runId, studentId,
+				[]string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}).
+			Update("status", models.WaitlistStatusCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.PromoteWaitlist(runId)
+	return err
+}
+
+// Claim converts a pending seat offer into an enrollment
+func (s *CourseRunService) Claim(runId, studentId uint) (*models.Enrollment, error) {
+	var enrollment *models.Enrollment
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		run, err := s.lockRun(tx, runId)
+		if err != nil {
+			return err
+		}
+
+		entry := &models.CourseRunWaitlistEntry{}
+		if err := tx.Where("course_run_id = ? AND student_id = ? AND status = ?", runId, studentId,
+			models.WaitlistStatusOffered).First(entry).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoPendingOffer
+			}
+			return err
+		}
+		if entry.ClaimDeadline != nil && time.Now().After(*entry.ClaimDeadline) {
+			return ErrOfferExpired
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Enrollment{}).
+			Where("course_run_id = ? AND student_id = ?", runId, studentId).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyEnrolled
+		}
+
+		enrollment = &models.Enrollment{
+			StudentId:   studentId,
+			CourseId:    run.CourseId,
+			CourseRunId: &run.Id,
+			EnrolledAt:  types.Now(),
+		}
+		if err := tx.Create(enrollment).Error; err != nil {
+			return err
+		}
+		// The offer may have expired since it was loaded
+		result := tx.Model(&models.CourseRunWaitlistEntry{}).
+			Where("id = ? AND status = ?", entry.Id, models.WaitlistStatusOffered).
+			Update("status", models.WaitlistStatusClaimed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOfferExpired
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.Emitter.Emit(JoinCourseRunEvent, enrollment)
+	return enrollment, nil
+}
+
+// GetWaitlist returns the open waitlist entries of a course run in FIFO order
+func (s *CourseRunService) GetWaitlist(runId uint) ([]*models.CourseRunWaitlistEntryResponse, error) {
+	var items []*models.CourseRunWaitlistEntry
+	query := (&models.CourseRunWaitlistEntry{}).Preload(s.DB)
+	if err := query.Where("course_run_id = ? AND status IN ?", runId,
+		[]string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}).
+		Order("created_at ASC, id ASC").Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get course run waitlist",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(runId)))
+		return nil, err
+	}
+
+	responses := make([]*models.CourseRunWaitlistEntryResponse, len(items))
+	position := 0
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+		if item.Status == models.WaitlistStatusWaiting {
+			position++
+			responses[i].Position = position
+		}
+	}
+	return responses, nil
+}
+
+// entryResponse builds the response for a waitlist entry including its queue position
+func (s *CourseRunService) entryResponse(entry *models.CourseRunWaitlistEntry) *models.CourseRunWaitlistEntryResponse {
+	response := entry.ToResponse()
+	if entry.Status == models.WaitlistStatusWaiting {
+		var ahead int64
+		s.DB.Model(&models.CourseRunWaitlistEntry{}).
+			Where("course_run_id = ? AND status = ? AND id < ?", entry.CourseRunId, models.WaitlistStatusWaiting, entry.Id).
+			Count(&ahead)
+		response.Position = int(ahead) + 1
+	}
+	return response
+}
+
+// PromoteWaitlist offers every free seat of a course run to the next waiting students in FIFO order
+func (s *CourseRunService) PromoteWaitlist(runId uint) ([]*models.CourseRunWaitlistEntry, error) {
+	var promoted []*models.CourseRunWaitlistEntry
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		run, err := s.lockRun(tx, runId)
+		if err != nil {
+			return err
+		}
+		if run.Capacity == 0 {
+			// Unlimited runs never queue, but release anyone left waiting
+			return s.offerNext(tx, run, -1, &promoted)
+		}
+
+		taken, err := s.seatsTaken(tx, runId)
+		if err != nil {
+			return err
+		}
+		free := int64(run.Capacity) - taken
+		if free <= 0 {
+			return nil
+		}
+		return s.offerNext(tx, run, int(free), &promoted)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range promoted {
+		s.Emitter.Emit(PromoteWaitlistEvent, entry)
+		if err := s.sendOfferEmail(entry); err != nil {
+			s.Logger.Error("failed to send waitlist offer email",
+				logger.String("error", err.Error()),
+				logger.Int("entry_id", int(entry.Id)))
+		}
+	}
+
+	return promoted, nil
+}
+
+// offerNext marks up to limit waiting entries as offered (limit < 0 means all)
+func (s *CourseRunService) offerNext(tx *gorm.DB, run *models.CourseRun, limit int, promoted *[]*models.CourseRunWaitlistEntry) error {
+	var waiting []*models.CourseRunWaitlistEntry
+	query := tx.Where("course_run_id = ? AND status = ?", run.Id, models.WaitlistStatusWaiting).
+		Order("created_at ASC, id ASC")
+	if limit >= 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&waiting).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deadline := now.Add(s.ClaimWindow)
+	for _, entry := range waiting {
+		entry.Status = models.WaitlistStatusOffered
+		entry.OfferedAt = &now
+		entry.ClaimDeadline = &deadline
+		if err := tx.Save(entry).Error; err != nil {
+			return err
+		}
+		entry.CourseRun = run
+		*promoted = append(*promoted, entry)
+	}
+	return nil
+}
+
+// ProcessWaitlists expires unclaimed offers and hands free seats to the next students.
+// It runs as a scheduled task so claim deadlines are enforced without user traffic.
+func (s *CourseRunService) ProcessWaitlists(ctx context.Context) error {
+	now := time.Now()
+
+	var expired []*models.CourseRunWaitlistEntry
+	if err := s.DB.Where("status = ? AND claim_deadline < ?", models.WaitlistStatusOffered, now).
+		Find(&expired).Error; err != nil {
+		return fmt.Errorf("failed to find expired waitlist offers: %w", err)
+	}
+	for _, entry := range expired {
+		// Only an offer still unclaimed past its deadline expires; one claimed
+		// since it was loaded stays claimed
+		result := s.DB.Model(&models.CourseRunWaitlistEntry{}).
+			Where("id = ? AND status = ? AND claim_deadline < ?", entry.Id, models.WaitlistStatusOffered, now).
+			Update("status", models.WaitlistStatusExpired)
+		if result.Error != nil {
+			return fmt.Errorf("failed to expire waitlist offer %d: %w", entry.Id, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		entry.Status = models.WaitlistStatusExpired
+		s.Emitter.Emit(ExpireWaitlistOfferEvent, entry)
+	}
+
+	// Seats may also be freed outside this module (e.g. enrollment deletion),
+	// so check every run that still has students waiting.
+	var runIds []uint
+	if err := s.DB.Model(&models.CourseRunWaitlistEntry{}).
+		Where("status = ?", models.WaitlistStatusWaiting).
+		Distinct().Pluck("course_run_id", &runIds).Error; err != nil {
+		return fmt.Errorf("failed to find course runs with waitlists: %w", err)
+	}
+	for _, runId := range runIds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.PromoteWaitlist(runId); err != nil {
+			s.Logger.Error("failed to promote waitlist",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(runId)))
+		}
+	}
+
+	return nil
+}
+
+// sendOfferEmail notifies a promoted student that a seat is held for them
+func (s *CourseRunService) sendOfferEmail(entry *models.CourseRunWaitlistEntry) error {
+	if s.EmailSender == nil {
+		return fmt.Errorf("email sender not configured")
+	}
+
+	var student profile.User
+	if err := s.DB.First(&student, entry.StudentId).Error; err != nil {
+		return err
+	}
+
+	title := "A seat is available for you"
+	if entry.CourseRun != nil && entry.CourseRun.Title != "" {
+		title = fmt.Sprintf("A seat is available in %s", entry.CourseRun.Title)
+	}
+	content := fmt.Sprintf(`
+		<p>Hi %s,</p>
+		<p>Good news! A seat has opened up in a cohort you were waitlisted for.</p>
+		<p>Your seat is held until <strong>%s</strong>. Claim it before then, otherwise it will be offered to the next student on the waitlist.</p>
+	`, html.EscapeString(student.FirstName), entry.ClaimDeadline.UTC().Format("Mon, 02 Jan 2006 15:04 MST"))
+
+	return s.EmailSender.Send(email.Message{
+		To:      []string{student.Email},
+		From:    "no-reply@base.al",
+		Subject: title,
+		Body:    content,
+		IsHTML:  true,
+	})
+}
@@ -0,0 +1,74 @@
+package course_runs
+
+import (
+	"base/app/models"
+	"base/core/validator"
+)
+
+// Global validator instance using Base core validator wrapper
+var validate = validator.New()
+
+// ValidateCourseRunCreateRequest validates the create request
+func ValidateCourseRunCreateRequest(req *models.CreateCourseRunRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateCourseRunUpdateRequest validates the update request
+func ValidateCourseRunUpdateRequest(req *models.UpdateCourseRunRequest, id uint) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	if id == 0 {
+		return validator.ValidationErrors{
+			{
+				Field:   "id",
+				Tag:     "required",
+				Value:   "0",
+				Message: "id cannot be zero",
+			},
+		}
+	}
+
+	// All fields are optional; only the ones present are checked
+	return validate.Validate(req)
+}
+
+// ValidateCourseRunDeleteRequest validates the delete request
+func ValidateCourseRunDeleteRequest(id uint) error {
+	return ValidateID(id)
+}
+
+// ValidateID validates if the ID is valid
+func ValidateID(id uint) error {
+	if id == 0 {
+		return validator.ValidationErrors{
+			{
+				Field:   "id",
+				Tag:     "required",
+				Value:   "0",
+				Message: "id cannot be zero",
+			},
+		}
+	}
+	return nil
+}
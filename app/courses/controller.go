@@ -1,6 +1,7 @@
 package courses
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -33,6 +34,11 @@ func (c *CourseController) Routes(router *router.RouterGroup) {
 	router.DELETE("/courses/:id", c.Delete) // Delete
 
 	//Upload endpoints for each file field
+	router.PUT("/courses/:id/thumbnail", c.UploadThumbnail)    // Upload the thumbnail image
+	router.DELETE("/courses/:id/thumbnail", c.RemoveThumbnail) // Remove the thumbnail
+
+	// Storage taken by the course's files against its quota
+	router.GET("/courses/:id/storage", c.StorageUsage)
 }
 
 // CreateCourse godoc
@@ -240,3 +246,122 @@ func (c *CourseController) Delete(ctx *router.Context) error {
 	ctx.Status(http.StatusNoContent)
 	return nil
 }
+
+// UploadCourseThumbnail godoc
+// @Summary Upload a Course thumbnail
+// @Description Upload the thumbnail image of a Course. Resized variants (thumb, medium, webp) are generated and listed in the thumbnail's variants
+// @Tags App/Course
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Course id"
+// @Param thumbnail formData file true "Thumbnail image"
+// @Success 200 {object} models.CourseResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /courses/{id}/thumbnail [put]
+func (c *CourseController) UploadThumbnail(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	file, err := ctx.FormFile("thumbnail")
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Thumbnail is required"})
+	}
+
+	item, err := c.Service.UploadThumbnail(uint(id), userId, file)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// RemoveCourseThumbnail godoc
+// @Summary Remove a Course thumbnail
+// @Description Delete the uploaded thumbnail of a Course and its variants
+// @Tags App/Course
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Course id"
+// @Success 200 {object} models.CourseResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /courses/{id}/thumbnail [delete]
+func (c *CourseController) RemoveThumbnail(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	item, err := c.Service.RemoveThumbnail(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// CourseStorageUsage godoc
+// @Summary Get the storage usage of a Course
+// @Description Get the storage taken by the thumbnail, lesson videos and resources of a Course and the course quota
+// @Tags App/Course
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Course id"
+// @Success 200 {object} storage.Usage
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /courses/{id}/storage [get]
+func (c *CourseController) StorageUsage(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	usage, err := c.Service.StorageUsage(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, usage)
+}
+
+func (c *CourseController) handleError(ctx *router.Context, err error) error {
+	if status, ok := storage.QuotaStatus(err); ok {
+		return ctx.JSON(status, types.ErrorResponse{Error: err.Error()})
+	}
+	switch {
+	case errors.Is(err, ErrNotCourseStaff):
+		return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrNoThumbnail):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "record not found"):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	case strings.Contains(err.Error(), "not allowed"), strings.Contains(err.Error(), "exceeds maximum"):
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	default:
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: err.Error()})
+	}
+}
@@ -1,9 +1,12 @@
 package courses
 
 import (
+	"errors"
 	"math"
+	"mime/multipart"
 
 	"base/app/models"
+	"base/core/app/profile"
 	"base/core/emitter"
 	"base/core/logger"
 	"base/core/storage"
@@ -16,6 +19,14 @@ const (
 	CreateCourseEvent = "courses.create"
 	UpdateCourseEvent = "courses.update"
 	DeleteCourseEvent = "courses.delete"
+
+	UploadCourseThumbnailEvent = "courses.thumbnail.uploaded"
+	DeleteCourseThumbnailEvent = "courses.thumbnail.deleted"
+)
+
+var (
+	ErrNotCourseStaff = errors.New("only the course instructor or an administrator can change the thumbnail")
+	ErrNoThumbnail    = errors.New("course has no thumbnail")
 )
 
 type CourseService struct {
@@ -25,13 +36,37 @@ type CourseService struct {
 	Logger  logger.Logger
 }
 
-func NewCourseService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *CourseService {
-	return &CourseService{
+func NewCourseService(db *gorm.DB, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage, logger logger.Logger) *CourseService {
+	service := &CourseService{
 		DB:      db,
 		Logger:  logger,
 		Emitter: emitter,
-		Storage: storage,
-	}
+		Storage: activeStorage,
+	}
+
+	// Thumbnails are shown in catalog cards and course pages, so smaller copies are
+	// generated on upload
+	if activeStorage != nil {
+		activeStorage.RegisterAttachment("course", storage.AttachmentConfig{
+			Table:             "courses",
+			Field:             "thumbnail",
+			Path:              "courses",
+			AllowedExtensions: []string{".jpg", ".jpeg", ".png", ".webp"},
+			MaxFileSize:       10 << 20, // 10MB
+			Authorize:         service.authorizeStaff,
+			OnAttach:          service.setThumbnail,
+			OnUpdate:          service.refreshThumbnail,
+			OwnerType:         "course",
+			Owner:             func(courseId uint) (uint, error) { return courseId, nil },
+			Variants: []storage.Variant{
+				{Name: "thumb", Width: 320, Height: 180, Fit: storage.FitCover, Format: storage.FormatJPEG},
+				{Name: "medium", Width: 800, Format: storage.FormatJPEG},
+				{Name: "webp", Width: 800, Format: storage.FormatWebP},
+			},
+		})
+	}
+
+	return service
 }
 
 // applySorting applies sorting to the query based on the sort and order parameters
@@ -193,6 +228,9 @@ func (s *CourseService) Delete(id uint) error {
 	}
 
 	// Delete file attachments if any
+	if item.Thumbnail != nil {
+		_ = s.Storage.Delete(item.Thumbnail)
+	}
 
 	if err := s.DB.Delete(item).Error; err != nil {
 		s.Logger.Error("failed to delete course",
@@ -304,3 +342,116 @@ func (s *CourseService) GetAllForSelect() ([]*models.Course, error) {
 
 	return items, nil
 }
+
+// UploadThumbnail replaces the course thumbnail. The thumbnail URL is set to the
+// uploaded image so clients reading thumbnail_url keep working.
+func (s *CourseService) UploadThumbnail(id, userId uint, file *multipart.FileHeader) (*models.Course, error) {
+	if err := s.authorizeStaff(userId, id); err != nil {
+		return nil, err
+	}
+	item := &models.Course{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.Storage.Attach(item, "thumbnail", file, userId)
+	if err != nil {
+		s.Logger.Error("failed to upload course thumbnail",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	previous := item.Thumbnail
+	if err := s.setThumbnail(attachment); err != nil {
+		_ = s.Storage.Delete(attachment)
+		return nil, err
+	}
+	if previous != nil {
+		_ = s.Storage.Delete(previous)
+	}
+
+	return s.GetById(id)
+}
+
+// RemoveThumbnail deletes the uploaded thumbnail and its variants
+func (s *CourseService) RemoveThumbnail(id, userId uint) (*models.Course, error) {
+	if err := s.authorizeStaff(userId, id); err != nil {
+		return nil, err
+	}
+	item := &models.Course{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		return nil, err
+	}
+	if item.Thumbnail == nil {
+		return nil, ErrNoThumbnail
+	}
+
+	if err := s.Storage.Delete(item.Thumbnail); err != nil {
+		s.Logger.Error("failed to delete course thumbnail",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+	if err := s.DB.Model(item).Select("thumbnail", "thumbnail_url").
+		Updates(map[string]any{"thumbnail": nil, "thumbnail_url": ""}).Error; err != nil {
+		return nil, err
+	}
+
+	// Emit thumbnail deleted event
+	s.Emitter.Emit(DeleteCourseThumbnailEvent, item)
+
+	return s.GetById(id)
+}
+
+// StorageUsage returns the storage taken by the files of a course; only its staff
+// may see it
+func (s *CourseService) StorageUsage(id, userId uint) (*storage.Usage, error) {
+	if err := s.authorizeStaff(userId, id); err != nil {
+		return nil, err
+	}
+	return s.Storage.OwnerUsage("course", id)
+}
+
+// authorizeStaff checks that a user teaches the course or administers the platform
+func (s *CourseService) authorizeStaff(userId, id uint) error {
+	item := &models.Course{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		return err
+	}
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		return err
+	}
+	if !item.IsStaff(user) {
+		return ErrNotCourseStaff
+	}
+	return nil
+}
+
+// refreshThumbnail updates the copy of the thumbnail kept on the course when its file moves
+func (s *CourseService) refreshThumbnail(attachment *storage.Attachment) error {
+	return s.DB.Model(&models.Course{}).Where("id = ?", attachment.ModelId).
+		Updates(map[string]any{"thumbnail": attachment, "thumbnail_url": attachment.URL}).Error
+}
+
+// setThumbnail stores an attachment as the course thumbnail
+func (s *CourseService) setThumbnail(attachment *storage.Attachment) error {
+	item := &models.Course{}
+	if err := s.DB.First(item, attachment.ModelId).Error; err != nil {
+		return err
+	}
+	item.Thumbnail = attachment
+	item.ThumbnailUrl = attachment.URL
+	if err := s.DB.Model(item).Select("thumbnail", "thumbnail_url").Updates(item).Error; err != nil {
+		s.Logger.Error("failed to set course thumbnail",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(item.Id)))
+		return err
+	}
+
+	// Emit thumbnail uploaded event
+	s.Emitter.Emit(UploadCourseThumbnailEvent, item)
+
+	return nil
+}
@@ -0,0 +1,690 @@
+package discussions
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"base/app/models"
+	"base/core/router"
+	"base/core/storage"
+	"base/core/types"
+)
+
+type DiscussionController struct {
+	Service *DiscussionService
+	Storage *storage.ActiveStorage
+}
+
+func NewDiscussionController(service *DiscussionService, storage *storage.ActiveStorage) *DiscussionController {
+	return &DiscussionController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *DiscussionController) Routes(router *router.RouterGroup) {
+	// Thread endpoints
+	router.GET("/discussions", c.List)          // Paginated list of a course's threads
+	router.POST("/discussions", c.Create)       // Open a thread
+	router.GET("/discussions/:id", c.Get)       // Thread with nested replies
+	router.PUT("/discussions/:id", c.Update)    // Edit (author or staff)
+	router.DELETE("/discussions/:id", c.Delete) // Delete (author or staff)
+
+	// Moderation and Q&A endpoints
+	router.PUT("/discussions/:id/moderate", c.Moderate) // Lock, pin or mark answered (staff)
+	router.POST("/discussions/:id/accept", c.Accept)    // Accept an answer
+	router.DELETE("/discussions/:id/accept", c.Unaccept)
+
+	// Participation endpoints
+	router.POST("/discussions/:id/replies", c.Reply)
+	router.POST("/discussions/:id/upvote", c.UpvoteThread)
+	router.DELETE("/discussions/:id/upvote", c.RemoveThreadUpvote)
+	router.POST("/discussions/:id/follow", c.Follow)
+	router.DELETE("/discussions/:id/follow", c.Unfollow)
+
+	// Reply endpoints
+	router.PUT("/discussion_replies/:id", c.UpdateReply)
+	router.DELETE("/discussion_replies/:id", c.DeleteReply)
+	router.POST("/discussion_replies/:id/upvote", c.UpvoteReply)
+	router.DELETE("/discussion_replies/:id/upvote", c.RemoveReplyUpvote)
+}
+
+// VoteResponse reports the upvote count after a vote change
+type VoteResponse struct {
+	Upvoted     bool `json:"upvoted"`
+	UpvoteCount int  `json:"upvote_count"`
+}
+
+// ListDiscussions godoc
+// @Summary List discussion threads
+// @Description Get the threads of a course, pinned first; enrolled students and course staff only
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param course_id query int true "Course id"
+// @Param lesson_id query int false "Filter by lesson id"
+// @Param answered query bool false "Filter by answered state"
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Param sort query string false "Sort field (id, created_at, last_activity_at, upvote_count, reply_count)"
+// @Param order query string false "Sort order (asc, desc)"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /discussions [get]
+func (c *DiscussionController) List(ctx *router.Context) error {
+	var page, limit *int
+	var sortBy, sortOrder *string
+	var lessonId *uint
+	var answered *bool
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	// Parse course parameter
+	courseId, err := strconv.ParseUint(ctx.Query("course_id"), 10, 32)
+	if err != nil || courseId == 0 {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "course_id is required"})
+	}
+
+	// Parse lesson filter
+	if lessonStr := ctx.Query("lesson_id"); lessonStr != "" {
+		parsed, err := strconv.ParseUint(lessonStr, 10, 32)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid lesson_id"})
+		}
+		value := uint(parsed)
+		lessonId = &value
+	}
+
+	// Parse answered filter
+	if answeredStr := ctx.Query("answered"); answeredStr != "" {
+		value, err := strconv.ParseBool(answeredStr)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid answered value"})
+		}
+		answered = &value
+	}
+
+	// Parse page parameter
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid page number"})
+		}
+	}
+
+	// Parse limit parameter
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid limit number"})
+		}
+	}
+
+	// Parse sort parameters
+	if sortStr := ctx.Query("sort"); sortStr != "" {
+		sortBy = &sortStr
+	}
+
+	if orderStr := ctx.Query("order"); orderStr != "" {
+		if orderStr == "asc" || orderStr == "desc" {
+			sortOrder = &orderStr
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid sort order. Use 'asc' or 'desc'"})
+		}
+	}
+
+	paginatedResponse, err := c.Service.GetAll(userId, uint(courseId), lessonId, answered, page, limit, sortBy, sortOrder)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// CreateDiscussion godoc
+// @Summary Open a discussion thread
+// @Description Ask a question or start a discussion on a course or one of its lessons; @username mentions notify the user
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param discussions body models.CreateDiscussionThreadRequest true "Create thread request"
+// @Success 201 {object} models.DiscussionThreadResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /discussions [post]
+func (c *DiscussionController) Create(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.CreateDiscussionThreadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateThreadCreateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Create(userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, item)
+}
+
+// GetDiscussion godoc
+// @Summary Get a discussion thread
+// @Description Get a thread with its nested replies
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Thread id"
+// @Success 200 {object} models.DiscussionThreadResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id} [get]
+func (c *DiscussionController) Get(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	item, err := c.Service.GetById(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item)
+}
+
+// UpdateDiscussion godoc
+// @Summary Update a discussion thread
+// @Description Edit the title or body of a thread; its author or course staff only
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Thread id"
+// @Param discussions body models.UpdateDiscussionThreadRequest true "Update thread request"
+// @Success 200 {object} models.DiscussionThreadResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id} [put]
+func (c *DiscussionController) Update(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.UpdateDiscussionThreadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateThreadUpdateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Update(uint(id), userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item)
+}
+
+// DeleteDiscussion godoc
+// @Summary Delete a discussion thread
+// @Description Delete a thread and its replies; its author or course staff only
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Thread id"
+// @Success 204
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id} [delete]
+func (c *DiscussionController) Delete(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.Service.Delete(uint(id), userId); err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// ModerateDiscussion godoc
+// @Summary Moderate a discussion thread
+// @Description Lock, pin or mark a thread as answered; course staff only
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Thread id"
+// @Param moderation body models.ModerateDiscussionThreadRequest true "Moderation flags"
+// @Success 200 {object} models.DiscussionThreadResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id}/moderate [put]
+func (c *DiscussionController) Moderate(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.ModerateDiscussionThreadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	item, err := c.Service.Moderate(uint(id), userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item)
+}
+
+// AcceptAnswer godoc
+// @Summary Accept an answer
+// @Description Mark a reply as the accepted answer; the thread author or course staff only
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Thread id"
+// @Param accept body models.AcceptDiscussionReplyRequest true "Accepted reply"
+// @Success 200 {object} models.DiscussionThreadResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id}/accept [post]
+func (c *DiscussionController) Accept(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.AcceptDiscussionReplyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateAcceptRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Accept(uint(id), userId, req.ReplyId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item)
+}
+
+// UnacceptAnswer godoc
+// @Summary Clear the accepted answer
+// @Description Remove the accepted answer of a thread; the thread author or course staff only
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Thread id"
+// @Success 200 {object} models.DiscussionThreadResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id}/accept [delete]
+func (c *DiscussionController) Unaccept(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	item, err := c.Service.Unaccept(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item)
+}
+
+// ReplyToDiscussion godoc
+// @Summary Reply to a discussion thread
+// @Description Post a reply, optionally nested under another reply; followers and @mentioned users are notified by email
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Thread id"
+// @Param reply body models.CreateDiscussionReplyRequest true "Create reply request"
+// @Success 201 {object} models.DiscussionReplyResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 423 {object} types.ErrorResponse
+// @Router /discussions/{id}/replies [post]
+func (c *DiscussionController) Reply(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.CreateDiscussionReplyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateReplyCreateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Reply(uint(id), userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// UpdateDiscussionReply godoc
+// @Summary Update a reply
+// @Description Edit a reply; its author or course staff only
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Reply id"
+// @Param reply body models.UpdateDiscussionReplyRequest true "Update reply request"
+// @Success 200 {object} models.DiscussionReplyResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussion_replies/{id} [put]
+func (c *DiscussionController) UpdateReply(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.UpdateDiscussionReplyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateReplyUpdateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.UpdateReply(uint(id), userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DeleteDiscussionReply godoc
+// @Summary Delete a reply
+// @Description Delete a reply; its author or course staff only. Nested replies are kept.
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Reply id"
+// @Success 204
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussion_replies/{id} [delete]
+func (c *DiscussionController) DeleteReply(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.Service.DeleteReply(uint(id), userId); err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// UpvoteDiscussion godoc
+// @Summary Upvote a thread
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Thread id"
+// @Success 200 {object} VoteResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id}/upvote [post]
+func (c *DiscussionController) UpvoteThread(ctx *router.Context) error {
+	return c.vote(ctx, models.DiscussionVoteThread, true)
+}
+
+// RemoveDiscussionUpvote godoc
+// @Summary Remove an upvote from a thread
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Thread id"
+// @Success 200 {object} VoteResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id}/upvote [delete]
+func (c *DiscussionController) RemoveThreadUpvote(ctx *router.Context) error {
+	return c.vote(ctx, models.DiscussionVoteThread, false)
+}
+
+// UpvoteDiscussionReply godoc
+// @Summary Upvote a reply
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Reply id"
+// @Success 200 {object} VoteResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussion_replies/{id}/upvote [post]
+func (c *DiscussionController) UpvoteReply(ctx *router.Context) error {
+	return c.vote(ctx, models.DiscussionVoteReply, true)
+}
+
+// RemoveDiscussionReplyUpvote godoc
+// @Summary Remove an upvote from a reply
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Reply id"
+// @Success 200 {object} VoteResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussion_replies/{id}/upvote [delete]
+func (c *DiscussionController) RemoveReplyUpvote(ctx *router.Context) error {
+	return c.vote(ctx, models.DiscussionVoteReply, false)
+}
+
+// vote handles the upvote endpoints of threads and replies
+func (c *DiscussionController) vote(ctx *router.Context, targetType string, up bool) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	count, err := c.Service.Vote(targetType, uint(id), userId, up)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, VoteResponse{Upvoted: up, UpvoteCount: count})
+}
+
+// FollowDiscussion godoc
+// @Summary Follow a thread
+// @Description Receive an email for every new reply in the thread
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Thread id"
+// @Success 204
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /discussions/{id}/follow [post]
+func (c *DiscussionController) Follow(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.Service.Follow(uint(id), userId); err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// UnfollowDiscussion godoc
+// @Summary Unfollow a thread
+// @Description Stop receiving emails about new replies in the thread
+// @Tags App/Discussion
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Thread id"
+// @Success 204
+// @Failure 400 {object} types.ErrorResponse
+// @Router /discussions/{id}/follow [delete]
+func (c *DiscussionController) Unfollow(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.Service.Unfollow(uint(id), userId); err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// handleError maps discussion errors to HTTP responses
+func (c *DiscussionController) handleError(ctx *router.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrNotParticipant), errors.Is(err, ErrNotAllowed), errors.Is(err, ErrNotModerator):
+		return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrThreadLocked):
+		return ctx.JSON(http.StatusLocked, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalidLesson), errors.Is(err, ErrInvalidParent):
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "record not found"):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	}
+	return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,55 @@
+package discussions
+
+import (
+	"base/app/models"
+	"base/core/module"
+	"base/core/router"
+
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Service    *DiscussionService
+	Controller *DiscussionController
+}
+
+// Init creates and initializes the Discussion module with all dependencies
+func Init(deps module.Dependencies) module.Module {
+	// Initialize service and controller
+	service := NewDiscussionService(deps.DB, deps.Emitter, deps.Storage, deps.Logger, deps.EmailSender)
+	controller := NewDiscussionController(service, deps.Storage)
+
+	// Create module
+	mod := &Module{
+		DB:         deps.DB,
+		Service:    service,
+		Controller: controller,
+	}
+
+	return mod
+}
+
+// Routes registers the module routes
+func (m *Module) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+func (m *Module) Migrate() error {
+	return m.DB.AutoMigrate(
+		&models.DiscussionThread{},
+		&models.DiscussionReply{},
+		&models.DiscussionVote{},
+		&models.DiscussionFollow{},
+	)
+}
+
+func (m *Module) GetModels() []any {
+	return []any{
+		&models.DiscussionThread{},
+		&models.DiscussionReply{},
+		&models.DiscussionVote{},
+		&models.DiscussionFollow{},
+	}
+}
@@ -0,0 +1,156 @@
+package discussions
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"base/app/models"
+	"base/core/app/profile"
+	"base/core/email"
+	"base/core/logger"
+)
+
+// mentionPattern matches @username at the start of the text or after whitespace or punctuation
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+
+// maxMentions caps how many users a single post can notify
+const maxMentions = 20
+
+// extractMentions returns the distinct usernames mentioned in a text
+func extractMentions(text string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		username := strings.TrimRight(match[1], ".-")
+		key := strings.ToLower(username)
+		if username == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		usernames = append(usernames, username)
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+	return usernames
+}
+
+// notifyMentions emails users mentioned in a post who can see the course discussion,
+// skipping the author and anyone in skip; it returns the notified user ids
+func (s *DiscussionService) notifyMentions(thread *models.DiscussionThread, author *profile.User, body string, skip map[uint]bool) map[uint]bool {
+	notified := make(map[uint]bool)
+	usernames := extractMentions(body)
+	if len(usernames) == 0 {
+		return notified
+	}
+
+	var users []*profile.User
+	if err := s.DB.Where("username IN ?", usernames).Find(&users).Error; err != nil {
+		s.Logger.Error("failed to load mentioned users",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(thread.Id)))
+		return notified
+	}
+
+	for _, user := range users {
+		if user.Id == author.Id || skip[user.Id] {
+			continue
+		}
+		// Mentioning someone outside the course must not leak the discussion
+		if _, err := s.participantOf(thread.CourseId, user.Id); err != nil {
+			continue
+		}
+		subject := fmt.Sprintf("%s mentioned you in \"%s\"", displayName(author), thread.Title)
+		if err := s.sendEmail(user, subject, thread, author, body); err != nil {
+			s.Logger.Error("failed to send mention notification",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(thread.Id)),
+				logger.Int("user_id", int(user.Id)))
+			continue
+		}
+		notified[user.Id] = true
+	}
+	return notified
+}
+
+// notifyReply emails mentioned users and then the followers of the thread about a new reply
+func (s *DiscussionService) notifyReply(thread *models.DiscussionThread, reply *models.DiscussionReply, author *profile.User) {
+	// Mentioned users get a single, more specific email
+	notified := s.notifyMentions(thread, author, reply.Body, nil)
+
+	var followerIds []uint
+	if err := s.DB.Model(&models.DiscussionFollow{}).
+		Where("thread_id = ? AND user_id <> ?", thread.Id, author.Id).
+		Pluck("user_id", &followerIds).Error; err != nil {
+		s.Logger.Error("failed to load thread followers",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(thread.Id)))
+		return
+	}
+	if len(followerIds) == 0 {
+		return
+	}
+
+	var followers []*profile.User
+	if err := s.DB.Where("id IN ?", followerIds).Find(&followers).Error; err != nil {
+		s.Logger.Error("failed to load thread followers",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(thread.Id)))
+		return
+	}
+
+	subject := fmt.Sprintf("New reply in \"%s\"", thread.Title)
+	for _, follower := range followers {
+		if notified[follower.Id] {
+			continue
+		}
+		if err := s.sendEmail(follower, subject, thread, author, reply.Body); err != nil {
+			s.Logger.Error("failed to send reply notification",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(thread.Id)),
+				logger.Int("user_id", int(follower.Id)))
+		}
+	}
+}
+
+// sendEmail sends a discussion notification quoting the post
+func (s *DiscussionService) sendEmail(to *profile.User, subject string, thread *models.DiscussionThread, author *profile.User, body string) error {
+	if s.EmailSender == nil {
+		return fmt.Errorf("email sender not configured")
+	}
+
+	content := fmt.Sprintf(`
+		<p>Hi %s,</p>
+		<p><strong>%s</strong> wrote in <strong>%s</strong>:</p>
+		<blockquote>%s</blockquote>
+	`, html.EscapeString(to.FirstName), html.EscapeString(displayName(author)),
+		html.EscapeString(thread.Title), strings.ReplaceAll(html.EscapeString(excerpt(body, 500)), "\n", "<br>"))
+
+	return s.EmailSender.Send(email.Message{
+		To:      []string{to.Email},
+		From:    "no-reply@base.al",
+		Subject: subject,
+		Body:    content,
+		IsHTML:  true,
+	})
+}
+
+// displayName returns the user's full name, falling back to the username
+func displayName(user *profile.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		return user.Username
+	}
+	return name
+}
+
+// excerpt shortens text to at most n runes
+func excerpt(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,707 @@
+package discussions
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"base/app/models"
+	"base/core/app/profile"
+	"base/core/email"
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateDiscussionThreadEvent = "discussions.create"
+	UpdateDiscussionThreadEvent = "discussions.update"
+	DeleteDiscussionThreadEvent = "discussions.delete"
+	CreateDiscussionReplyEvent  = "discussions.reply"
+	AcceptDiscussionReplyEvent  = "discussions.accept"
+)
+
+var (
+	ErrNotParticipant = errors.New("only enrolled students and course staff can take part in discussions")
+	ErrNotAllowed     = errors.New("you are not allowed to change this post")
+	ErrNotModerator   = errors.New("only course staff can moderate discussions")
+	ErrThreadLocked   = errors.New("thread is locked")
+	ErrInvalidLesson  = errors.New("lesson does not belong to this course")
+	ErrInvalidParent  = errors.New("parent reply does not belong to this thread")
+)
+
+// participant describes the current user's role in a course discussion
+type participant struct {
+	User   *profile.User
+	Course *models.Course
+	Staff  bool
+}
+
+type DiscussionService struct {
+	DB          *gorm.DB
+	Emitter     *emitter.Emitter
+	Storage     *storage.ActiveStorage
+	Logger      logger.Logger
+	EmailSender email.Sender
+}
+
+func NewDiscussionService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger, emailSender email.Sender) *DiscussionService {
+	return &DiscussionService{
+		DB:          db,
+		Logger:      logger,
+		Emitter:     emitter,
+		Storage:     storage,
+		EmailSender: emailSender,
+	}
+}
+
+// applySorting applies sorting to the query; pinned threads always come first
+func (s *DiscussionService) applySorting(query *gorm.DB, sortBy *string, sortOrder *string) {
+	// Valid sortable fields for DiscussionThread
+	validSortFields := map[string]string{
+		"id":               "id",
+		"created_at":       "created_at",
+		"last_activity_at": "last_activity_at",
+		"upvote_count":     "upvote_count",
+		"reply_count":      "reply_count",
+	}
+
+	defaultSortBy := "last_activity_at"
+	defaultSortOrder := "desc"
+
+	// Determine sort field
+	sortField := defaultSortBy
+	if sortBy != nil && *sortBy != "" {
+		if field, exists := validSortFields[*sortBy]; exists {
+			sortField = field
+		}
+	}
+
+	// Determine sort direction (order parameter)
+	sortDirection := defaultSortOrder
+	if sortOrder != nil && (*sortOrder == "asc" || *sortOrder == "desc") {
+		sortDirection = *sortOrder
+	}
+
+	// Apply sorting
+	query.Order("is_pinned desc").Order(sortField + " " + sortDirection).Order("id desc")
+}
+
+// participantOf loads the user and course and checks that the user is enrolled or course staff
+func (s *DiscussionService) participantOf(courseId, userId uint) (*participant, error) {
+	course := &models.Course{}
+	if err := s.DB.First(course, courseId).Error; err != nil {
+		return nil, err
+	}
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		return nil, err
+	}
+
+	p := &participant{User: user, Course: course, Staff: course.IsStaff(user)}
+	if p.Staff {
+		return p, nil
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.Enrollment{}).
+		Where("course_id = ? AND student_id = ?", courseId, userId).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNotParticipant
+	}
+	return p, nil
+}
+
+// threadFor loads a thread and the user's role in its course
+func (s *DiscussionService) threadFor(id, userId uint) (*models.DiscussionThread, *participant, error) {
+	thread := &models.DiscussionThread{}
+	if err := s.DB.First(thread, id).Error; err != nil {
+		return nil, nil, err
+	}
+	p, err := s.participantOf(thread.CourseId, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return thread, p, nil
+}
+
+// GetAll returns the threads of a course, optionally narrowed to a lesson or answered state
+func (s *DiscussionService) GetAll(userId, courseId uint, lessonId *uint, answered *bool, page *int, limit *int, sortBy *string, sortOrder *string) (*types.PaginatedResponse, error) {
+	if _, err := s.participantOf(courseId, userId); err != nil {
+		return nil, err
+	}
+
+	var items []*models.DiscussionThread
+	var total int64
+
+	query := s.DB.Model(&models.DiscussionThread{}).Where("course_id = ?", courseId)
+	if lessonId != nil {
+		query = query.Where("lesson_id = ?", *lessonId)
+	}
+	if answered != nil {
+		query = query.Where("is_answered = ?", *answered)
+	}
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count discussion threads",
+			logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Apply pagination
+	offset := (*page - 1) * *limit
+	query = query.Offset(offset).Limit(*limit)
+
+	// Apply sorting
+	s.applySorting(query, sortBy, sortOrder)
+
+	// Execute query
+	if err := query.Preload("Author").Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get discussion threads",
+			logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Convert to response type
+	responses := make([]*models.DiscussionThreadListResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToListResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetById returns a thread with its nested replies as seen by the user
+func (s *DiscussionService) GetById(id, userId uint) (*models.DiscussionThreadResponse, error) {
+	if _, _, err := s.threadFor(id, userId); err != nil {
+		return nil, err
+	}
+
+	thread := &models.DiscussionThread{}
+	if err := thread.Preload(s.DB).First(thread, id).Error; err != nil {
+		s.Logger.Error("failed to get discussion thread",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	var replies []*models.DiscussionReply
+	if err := s.DB.Preload("Author").Where("thread_id = ?", id).
+		Order("created_at asc, id asc").Find(&replies).Error; err != nil {
+		s.Logger.Error("failed to get discussion replies",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	replyIds := make([]uint, len(replies))
+	for i, reply := range replies {
+		replyIds[i] = reply.Id
+	}
+	votedReplies, err := s.votedTargets(userId, models.DiscussionVoteReply, replyIds)
+	if err != nil {
+		return nil, err
+	}
+	votedThreads, err := s.votedTargets(userId, models.DiscussionVoteThread, []uint{id})
+	if err != nil {
+		return nil, err
+	}
+	var following int64
+	if err := s.DB.Model(&models.DiscussionFollow{}).
+		Where("thread_id = ? AND user_id = ?", id, userId).Count(&following).Error; err != nil {
+		return nil, err
+	}
+
+	response := thread.ToResponse()
+	response.Upvoted = votedThreads[id]
+	response.Following = following > 0
+	response.Replies = buildReplyTree(replies, thread.AcceptedReplyId, votedReplies)
+	return response, nil
+}
+
+// votedTargets returns which of the given threads or replies the user upvoted
+func (s *DiscussionService) votedTargets(userId uint, targetType string, ids []uint) (map[uint]bool, error) {
+	voted := make(map[uint]bool)
+	if len(ids) == 0 {
+		return voted, nil
+	}
+	var targetIds []uint
+	if err := s.DB.Model(&models.DiscussionVote{}).
+		Where("user_id = ? AND target_type = ? AND target_id IN ?", userId, targetType, ids).
+		Pluck("target_id", &targetIds).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range targetIds {
+		voted[id] = true
+	}
+	return voted, nil
+}
+
+// buildReplyTree nests replies under their parents; replies whose parent was deleted move to the top level
+func buildReplyTree(replies []*models.DiscussionReply, acceptedId *uint, voted map[uint]bool) []*models.DiscussionReplyResponse {
+	nodes := make(map[uint]*models.DiscussionReplyResponse, len(replies))
+	for _, reply := range replies {
+		node := reply.ToResponse()
+		node.IsAccepted = acceptedId != nil && *acceptedId == reply.Id
+		node.Upvoted = voted[reply.Id]
+		nodes[reply.Id] = node
+	}
+
+	roots := make([]*models.DiscussionReplyResponse, 0)
+	for _, reply := range replies {
+		node := nodes[reply.Id]
+		if reply.ParentId != nil {
+			if parent, ok := nodes[*reply.ParentId]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
+
+// Create opens a new thread; the author follows it automatically
+func (s *DiscussionService) Create(userId uint, req *models.CreateDiscussionThreadRequest) (*models.DiscussionThreadResponse, error) {
+	p, err := s.participantOf(req.CourseId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if req.LessonId != nil {
+		var count int64
+		if err := s.DB.Model(&models.Lesson{}).
+			Where("id = ? AND course_id = ?", *req.LessonId, req.CourseId).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrInvalidLesson
+		}
+	}
+
+	item := &models.DiscussionThread{
+		CourseId:       req.CourseId,
+		LessonId:       req.LessonId,
+		AuthorId:       userId,
+		Title:          req.Title,
+		Body:           req.Body,
+		LastActivityAt: time.Now(),
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.DiscussionFollow{ThreadId: item.Id, UserId: userId}).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to create discussion thread", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateDiscussionThreadEvent, item)
+
+	go s.notifyMentions(item, p.User, item.Body, nil)
+
+	return s.GetById(item.Id, userId)
+}
+
+// Update edits a thread; only its author or course staff may do so
+func (s *DiscussionService) Update(id, userId uint, req *models.UpdateDiscussionThreadRequest) (*models.DiscussionThreadResponse, error) {
+	item, p, err := s.threadFor(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	if item.AuthorId != userId && !p.Staff {
+		return nil, ErrNotAllowed
+	}
+	if item.IsLocked && !p.Staff {
+		return nil, ErrThreadLocked
+	}
+
+	if req.Title != "" {
+		item.Title = req.Title
+	}
+	if req.Body != "" {
+		item.Body = req.Body
+	}
+
+	if err := s.DB.Save(item).Error; err != nil {
+		s.Logger.Error("failed to update discussion thread",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateDiscussionThreadEvent, item)
+
+	return s.GetById(item.Id, userId)
+}
+
+// Moderate locks, pins or marks a thread as answered; course staff only
+func (s *DiscussionService) Moderate(id, userId uint, req *models.ModerateDiscussionThreadRequest) (*models.DiscussionThreadResponse, error) {
+	item, p, err := s.threadFor(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Staff {
+		return nil, ErrNotModerator
+	}
+
+	if req.IsLocked != nil {
+		item.IsLocked = *req.IsLocked
+	}
+	if req.IsPinned != nil {
+		item.IsPinned = *req.IsPinned
+	}
+	if req.IsAnswered != nil {
+		item.IsAnswered = *req.IsAnswered
+	}
+
+	if err := s.DB.Save(item).Error; err != nil {
+		s.Logger.Error("failed to moderate discussion thread",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	s.Emitter.Emit(UpdateDiscussionThreadEvent, item)
+
+	return s.GetById(item.Id, userId)
+}
+
+// Delete removes a thread with its replies; only its author or course staff may do so
+func (s *DiscussionService) Delete(id, userId uint) error {
+	item, p, err := s.threadFor(id, userId)
+	if err != nil {
+		return err
+	}
+	if item.AuthorId != userId && !p.Staff {
+		return ErrNotAllowed
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("thread_id = ?", id).Delete(&models.DiscussionReply{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("thread_id = ?", id).Delete(&models.DiscussionFollow{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(item).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to delete discussion thread",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return err
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteDiscussionThreadEvent, item)
+
+	return nil
+}
+
+// Reply adds a reply to a thread, notifies followers and mentioned users, and
+// marks the thread answered when course staff replies
+func (s *DiscussionService) Reply(threadId, userId uint, req *models.CreateDiscussionReplyRequest) (*models.DiscussionReply, error) {
+	thread, p, err := s.threadFor(threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if thread.IsLocked && !p.Staff {
+		return nil, ErrThreadLocked
+	}
+	if req.ParentId != nil {
+		var count int64
+		if err := s.DB.Model(&models.DiscussionReply{}).
+			Where("id = ? AND thread_id = ?", *req.ParentId, threadId).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrInvalidParent
+		}
+	}
+
+	item := &models.DiscussionReply{
+		ThreadId:           threadId,
+		ParentId:           req.ParentId,
+		AuthorId:           userId,
+		Body:               req.Body,
+		IsInstructorAnswer: p.Staff,
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		updates := map[string]any{
+			"reply_count":      gorm.Expr("reply_count + 1"),
+			"last_activity_at": item.CreatedAt,
+		}
+		if p.Staff {
+			updates["is_answered"] = true
+		}
+		if err := tx.Model(&models.DiscussionThread{}).Where("id = ?", threadId).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Repliers follow the thread so they hear about answers
+		follow := &models.DiscussionFollow{ThreadId: threadId, UserId: userId}
+		return tx.Where(follow).FirstOrCreate(follow).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to create discussion reply",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(threadId)))
+		return nil, err
+	}
+
+	s.Emitter.Emit(CreateDiscussionReplyEvent, item)
+
+	go s.notifyReply(thread, item, p.User)
+
+	item.Author = p.User
+	return item, nil
+}
+
+// UpdateReply edits a reply; only its author or course staff may do so
+func (s *DiscussionService) UpdateReply(id, userId uint, req *models.UpdateDiscussionReplyRequest) (*models.DiscussionReply, error) {
+	item := &models.DiscussionReply{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		return nil, err
+	}
+	thread, p, err := s.threadFor(item.ThreadId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if item.AuthorId != userId && !p.Staff {
+		return nil, ErrNotAllowed
+	}
+	if thread.IsLocked && !p.Staff {
+		return nil, ErrThreadLocked
+	}
+
+	item.Body = req.Body
+	if err := s.DB.Save(item).Error; err != nil {
+		s.Logger.Error("failed to update discussion reply",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	if err := s.DB.Preload("Author").First(item, id).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteReply removes a reply; nested replies stay and move up to the top level
+func (s *DiscussionService) DeleteReply(id, userId uint) error {
+	item := &models.DiscussionReply{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		return err
+	}
+	thread, p, err := s.threadFor(item.ThreadId, userId)
+	if err != nil {
+		return err
+	}
+	if item.AuthorId != userId && !p.Staff {
+		return ErrNotAllowed
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(item).Error; err != nil {
+			return err
+		}
+		updates := map[string]any{"reply_count": gorm.Expr("reply_count - 1")}
+		if thread.AcceptedReplyId != nil && *thread.AcceptedReplyId == item.Id {
+			updates["accepted_reply_id"] = nil
+			// Stay answered only while a staff reply remains
+			var staffReplies int64
+			if err := tx.Model(&models.DiscussionReply{}).
+				Where("thread_id = ? AND is_instructor_answer = ?", thread.Id, true).
+				Count(&staffReplies).Error; err != nil {
+				return err
+			}
+			updates["is_answered"] = staffReplies > 0
+		}
+		return tx.Model(&models.DiscussionThread{}).Where("id = ?", thread.Id).Updates(updates).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to delete discussion reply",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return err
+	}
+
+	return nil
+}
+
+// Accept marks a reply as the accepted answer; the thread author or course staff may do so
+func (s *DiscussionService) Accept(threadId, userId uint, replyId uint) (*models.DiscussionThreadResponse, error) {
+	thread, p, err := s.threadFor(threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if thread.AuthorId != userId && !p.Staff {
+		return nil, ErrNotAllowed
+	}
+
+	reply := &models.DiscussionReply{}
+	if err := s.DB.Where("id = ? AND thread_id = ?", replyId, threadId).First(reply).Error; err != nil {
+		return nil, err
+	}
+
+	thread.AcceptedReplyId = &reply.Id
+	thread.IsAnswered = true
+	if err := s.DB.Save(thread).Error; err != nil {
+		s.Logger.Error("failed to accept discussion reply",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(threadId)))
+		return nil, err
+	}
+
+	s.Emitter.Emit(AcceptDiscussionReplyEvent, thread)
+
+	return s.GetById(threadId, userId)
+}
+
+// Unaccept clears the accepted answer of a thread
+func (s *DiscussionService) Unaccept(threadId, userId uint) (*models.DiscussionThreadResponse, error) {
+	thread, p, err := s.threadFor(threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if thread.AuthorId != userId && !p.Staff {
+		return nil, ErrNotAllowed
+	}
+
+	var staffReplies int64
+	if err := s.DB.Model(&models.DiscussionReply{}).
+		Where("thread_id = ? AND is_instructor_answer = ?", threadId, true).
+		Count(&staffReplies).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(thread).Updates(map[string]any{
+		"accepted_reply_id": nil,
+		"is_answered":       staffReplies > 0,
+	}).Error; err != nil {
+		s.Logger.Error("failed to clear accepted reply",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(threadId)))
+		return nil, err
+	}
+
+	return s.GetById(threadId, userId)
+}
+
+// Vote adds or removes the user's upvote on a thread or reply; repeated calls are no-ops
+func (s *DiscussionService) Vote(targetType string, targetId, userId uint, up bool) (int, error) {
+	var courseId uint
+	var model any
+	switch targetType {
+	case models.DiscussionVoteThread:
+		thread := &models.DiscussionThread{}
+		if err := s.DB.First(thread, targetId).Error; err != nil {
+			return 0, err
+		}
+		courseId, model = thread.CourseId, thread
+	case models.DiscussionVoteReply:
+		reply := &models.DiscussionReply{}
+		if err := s.DB.Preload("Thread").First(reply, targetId).Error; err != nil {
+			return 0, err
+		}
+		if reply.Thread == nil {
+			return 0, gorm.ErrRecordNotFound
+		}
+		courseId, model = reply.Thread.CourseId, reply
+	default:
+		return 0, errors.New("invalid vote target")
+	}
+	if _, err := s.participantOf(courseId, userId); err != nil {
+		return 0, err
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		vote := &models.DiscussionVote{TargetType: targetType, TargetId: targetId, UserId: userId}
+		var existing int64
+		if err := tx.Model(&models.DiscussionVote{}).Where(vote).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case up && existing == 0:
+			if err := tx.Create(vote).Error; err != nil {
+				return err
+			}
+			return tx.Model(model).UpdateColumn("upvote_count", gorm.Expr("upvote_count + 1")).Error
+		case !up && existing > 0:
+			if err := tx.Where(vote).Delete(&models.DiscussionVote{}).Error; err != nil {
+				return err
+			}
+			return tx.Model(model).UpdateColumn("upvote_count", gorm.Expr("upvote_count - 1")).Error
+		}
+		return nil
+	})
+	if err != nil {
+		s.Logger.Error("failed to record discussion vote",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(targetId)))
+		return 0, err
+	}
+
+	var counts []int
+	if err := s.DB.Model(model).Where("id = ?", targetId).Pluck("upvote_count", &counts).Error; err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return counts[0], nil
+}
+
+// Follow subscribes the user to new replies in a thread
+func (s *DiscussionService) Follow(threadId, userId uint) error {
+	if _, _, err := s.threadFor(threadId, userId); err != nil {
+		return err
+	}
+	follow := &models.DiscussionFollow{ThreadId: threadId, UserId: userId}
+	return s.DB.Where(follow).FirstOrCreate(follow).Error
+}
+
+// Unfollow stops notifications about a thread
+func (s *DiscussionService) Unfollow(threadId, userId uint) error {
+	return s.DB.Where("thread_id = ? AND user_id = ?", threadId, userId).
+		Delete(&models.DiscussionFollow{}).Error
+}
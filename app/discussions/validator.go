@@ -0,0 +1,109 @@
+package discussions
+
+import (
+	"base/app/models"
+	"base/core/validator"
+)
+
+// Global validator instance using Base core validator wrapper
+var validate = validator.New()
+
+// ValidateThreadCreateRequest validates the thread create request
+func ValidateThreadCreateRequest(req *models.CreateDiscussionThreadRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateThreadUpdateRequest validates the thread update request
+func ValidateThreadUpdateRequest(req *models.UpdateDiscussionThreadRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateReplyCreateRequest validates the reply create request
+func ValidateReplyCreateRequest(req *models.CreateDiscussionReplyRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateReplyUpdateRequest validates the reply update request
+func ValidateReplyUpdateRequest(req *models.UpdateDiscussionReplyRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateAcceptRequest validates the accept answer request
+func ValidateAcceptRequest(req *models.AcceptDiscussionReplyRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateID validates if the ID is valid
+func ValidateID(id uint) error {
+	if id == 0 {
+		return validator.ValidationErrors{
+			{
+				Field:   "id",
+				Tag:     "required",
+				Value:   "0",
+				Message: "id cannot be zero",
+			},
+		}
+	}
+	return nil
+}
@@ -1,32 +1,36 @@
 package enrollments
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"base/app/models"
+	"base/core/app/verification"
 	"base/core/router"
 	"base/core/storage"
 	"base/core/types"
 )
 
 type EnrollmentController struct {
-	Service *EnrollmentService
-	Storage *storage.ActiveStorage
+	Service      *EnrollmentService
+	Storage      *storage.ActiveStorage
+	Verification *verification.VerificationService
 }
 
-func NewEnrollmentController(service *EnrollmentService, storage *storage.ActiveStorage) *EnrollmentController {
+func NewEnrollmentController(service *EnrollmentService, storage *storage.ActiveStorage, verification *verification.VerificationService) *EnrollmentController {
 	return &EnrollmentController{
-		Service: service,
-		Storage: storage,
+		Service:      service,
+		Storage:      storage,
+		Verification: verification,
 	}
 }
 
 func (c *EnrollmentController) Routes(router *router.RouterGroup) {
 	// Main CRUD endpoints - specific routes MUST come before parameterized routes
-	router.GET("/enrollments", c.List)          // Paginated list
-	router.POST("/enrollments", c.Create)       // Create
+	router.GET("/enrollments", c.List) // Paginated list
+	router.POST("/enrollments", c.Create, verification.Require(c.Verification, verification.ActionEnroll))
 	router.GET("/enrollments/all", c.ListAll)   // Unpaginated list - MUST be before /:id
 	router.GET("/enrollments/:id", c.Get)       // Get by ID - MUST be after /all
 	router.PUT("/enrollments/:id", c.Update)    // Update
@@ -37,7 +41,7 @@ func (c *EnrollmentController) Routes(router *router.RouterGroup) {
 
 // CreateEnrollment godoc
 // @Summary Create a new Enrollment
-// @Description Create a new Enrollment with the input payload
+// @Description Create a new Enrollment with the input payload. Users must have verified their email when EMAIL_VERIFICATION_REQUIRED_FOR includes "enroll". Cohort enrollments go through /course_runs/{id}/join, so course_run_id is rejected.
 // @Tags App/Enrollment
 // @Security ApiKeyAuth
 // @Security BearerAuth
@@ -46,6 +50,7 @@ func (c *EnrollmentController) Routes(router *router.RouterGroup) {
 // @Param enrollments body models.CreateEnrollmentRequest true "Create Enrollment request"
 // @Success 201 {object} models.EnrollmentResponse
 // @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
 // @Failure 500 {object} types.ErrorResponse
 // @Router /enrollments [post]
 func (c *EnrollmentController) Create(ctx *router.Context) error {
@@ -56,6 +61,9 @@ func (c *EnrollmentController) Create(ctx *router.Context) error {
 
 	item, err := c.Service.Create(&req)
 	if err != nil {
+		if errors.Is(err, ErrCourseRunEnrollment) {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		}
 		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create item: " + err.Error()})
 	}
 
@@ -176,7 +184,7 @@ func (c *EnrollmentController) ListAll(ctx *router.Context) error {
 
 // UpdateEnrollment godoc
 // @Summary Update a Enrollment
-// @Description Update a Enrollment by its id
+// @Description Update a Enrollment by its id. The course run cannot be changed; students join another one at /course_runs/{id}/join.
 // @Tags App/Enrollment
 // @Security ApiKeyAuth
 // @Security BearerAuth
@@ -202,6 +210,9 @@ func (c *EnrollmentController) Update(ctx *router.Context) error {
 
 	item, err := c.Service.Update(uint(id), &req)
 	if err != nil {
+		if errors.Is(err, ErrCourseRunEnrollment) {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		}
 		if strings.Contains(err.Error(), "record not found") {
 			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
 		}
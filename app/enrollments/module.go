@@ -2,6 +2,7 @@ package enrollments
 
 import (
 	"base/app/models"
+	"base/core/app/verification"
 	"base/core/module"
 	"base/core/router"
 
@@ -19,7 +20,9 @@ type Module struct {
 func Init(deps module.Dependencies) module.Module {
 	// Initialize service and controller
 	service := NewEnrollmentService(deps.DB, deps.Emitter, deps.Storage, deps.Logger)
-	controller := NewEnrollmentController(service, deps.Storage)
+	// Unverified users may not enroll or purchase, as the verification policy says
+	verifier := verification.NewVerificationService(deps.DB, deps.EmailSender, deps.Logger, deps.Config)
+	controller := NewEnrollmentController(service, deps.Storage, verifier)
 
 	// Create module
 	mod := &Module{
@@ -1,6 +1,7 @@
 package enrollments
 
 import (
+	"errors"
 	"math"
 
 	"base/app/models"
@@ -18,6 +19,11 @@ const (
 	DeleteEnrollmentEvent = "enrollments.delete"
 )
 
+// ErrCourseRunEnrollment is returned when a request sets a course run; cohort seats
+// are only taken through POST /course_runs/{id}/join, which checks capacity, the
+// enrollment window and the waitlist
+var ErrCourseRunEnrollment = errors.New("course_run_id cannot be set here; join the course run at /course_runs/{id}/join")
+
 type EnrollmentService struct {
 	DB      *gorm.DB
 	Emitter *emitter.Emitter
@@ -69,6 +75,10 @@ func (s *EnrollmentService) applySorting(query *gorm.DB, sortBy *string, sortOrd
 }
 
 func (s *EnrollmentService) Create(req *models.CreateEnrollmentRequest) (*models.Enrollment, error) {
+	if req.CourseRunId != nil {
+		return nil, ErrCourseRunEnrollment
+	}
+
 	item := &models.Enrollment{
 		StudentId:  req.StudentId,
 		CourseId:   req.CourseId,
@@ -111,6 +121,10 @@ func (s *EnrollmentService) Update(id uint, req *models.UpdateEnrollmentRequest)
 	if req.CourseId != 0 {
 		item.CourseId = req.CourseId
 	}
+	// Moving into another course run would skip its seat checks
+	if req.CourseRunId != nil && (item.CourseRunId == nil || *item.CourseRunId != *req.CourseRunId) {
+		return nil, ErrCourseRunEnrollment
+	}
 	// For custom DateTime fields
 	if !req.EnrolledAt.IsZero() {
 		item.EnrolledAt = req.EnrolledAt
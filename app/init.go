@@ -1,15 +1,20 @@
 package app
 
 import (
+	"base/app/attendances"
 	"base/app/course_categories"
 	"base/app/course_certificates"
 	"base/app/course_progress_logs"
 	"base/app/course_resources"
+	"base/app/course_runs"
 	"base/app/course_tag_relations"
 	"base/app/course_tags"
 	"base/app/courses"
+	"base/app/discussions"
 	"base/app/enrollments"
+	"base/app/lesson_notes"
 	"base/app/lessons"
+	"base/app/live_sessions"
 	"base/app/payments"
 	"base/app/reviews"
 	"base/core/app/profile"
@@ -57,6 +62,21 @@ func (am *AppModules) GetAppModules(deps module.Dependencies) map[string]module.
 
 	// Course_certificates module
 	modules["course_certificates"] = course_certificates.Init(deps)
+
+	// Course_runs module
+	modules["course_runs"] = course_runs.Init(deps)
+
+	// Live_sessions module
+	modules["live_sessions"] = live_sessions.Init(deps)
+
+	// Attendances module
+	modules["attendances"] = attendances.Init(deps)
+
+	// Discussions module
+	modules["discussions"] = discussions.Init(deps)
+
+	// Lesson_notes module
+	modules["lesson_notes"] = lesson_notes.Init(deps)
 	return modules
 }
 
@@ -0,0 +1,358 @@
+package lesson_notes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"base/app/models"
+	"base/core/router"
+	"base/core/storage"
+	"base/core/types"
+)
+
+type LessonNoteController struct {
+	Service *LessonNoteService
+	Storage *storage.ActiveStorage
+}
+
+func NewLessonNoteController(service *LessonNoteService, storage *storage.ActiveStorage) *LessonNoteController {
+	return &LessonNoteController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *LessonNoteController) Routes(router *router.RouterGroup) {
+	// Notes are always scoped to the current user
+	router.GET("/me/notes", c.List)             // Own notes, optionally per course or lesson
+	router.GET("/me/notes/export", c.Export)    // Markdown or PDF export - MUST be before /:id
+	router.PUT("/me/notes/:id", c.Update)       // Update
+	router.DELETE("/me/notes/:id", c.Delete)    // Delete
+	router.POST("/lessons/:id/notes", c.Create) // Take a note on a lesson
+
+	// Bookmark endpoints
+	router.GET("/me/bookmarks", c.ListBookmarks)
+	router.PUT("/lessons/:id/bookmark", c.Bookmark)
+	router.DELETE("/lessons/:id/bookmark", c.RemoveBookmark)
+}
+
+// ListNotes godoc
+// @Summary List my notes
+// @Description Get the current user's notes ordered by lesson and video position
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param course_id query int false "Filter by course id"
+// @Param lesson_id query int false "Filter by lesson id"
+// @Success 200 {array} models.LessonNoteResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /me/notes [get]
+func (c *LessonNoteController) List(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	courseId, err := queryID(ctx, "course_id")
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+	lessonId, err := queryID(ctx, "lesson_id")
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	items, err := c.Service.GetNotes(userId, courseId, lessonId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch notes: " + err.Error()})
+	}
+
+	responses := make([]*models.LessonNoteResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+	}
+
+	return ctx.JSON(http.StatusOK, responses)
+}
+
+// CreateNote godoc
+// @Summary Take a note on a lesson
+// @Description Add a private Markdown note to a lesson, optionally at a video position in seconds
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Lesson id"
+// @Param note body models.CreateLessonNoteRequest true "Create note request"
+// @Success 201 {object} models.LessonNoteResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /lessons/{id}/notes [post]
+func (c *LessonNoteController) Create(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.CreateLessonNoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateLessonNoteCreateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Create(uint(id), userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// UpdateNote godoc
+// @Summary Update one of my notes
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Note id"
+// @Param note body models.UpdateLessonNoteRequest true "Update note request"
+// @Success 200 {object} models.LessonNoteResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /me/notes/{id} [put]
+func (c *LessonNoteController) Update(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.UpdateLessonNoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateLessonNoteUpdateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Update(uint(id), userId, &req)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DeleteNote godoc
+// @Summary Delete one of my notes
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Note id"
+// @Success 204
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /me/notes/{id} [delete]
+func (c *LessonNoteController) Delete(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.Service.Delete(uint(id), userId); err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// ExportNotes godoc
+// @Summary Export my notes of a course
+// @Description Download the current user's notes of a course as Markdown (default) or PDF
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce text/markdown,application/pdf
+// @Param course_id query int true "Course id"
+// @Param format query string false "Export format (md, pdf)"
+// @Success 200 {file} file
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /me/notes/export [get]
+func (c *LessonNoteController) Export(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	courseId, err := queryID(ctx, "course_id")
+	if err != nil || courseId == nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "course_id is required"})
+	}
+
+	format := ctx.Query("format")
+	if format == "" || format == "markdown" {
+		format = "md"
+	}
+	if format != "md" && format != "pdf" {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid format. Use 'md' or 'pdf'"})
+	}
+
+	data, contentType, err := c.Service.Export(userId, *courseId, format)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	filename := fmt.Sprintf("notes-course-%d.%s", *courseId, format)
+	ctx.SetHeader("Cache-Control", "private, no-store")
+	ctx.SetHeader("Content-Disposition", `attachment; filename="`+filename+`"`)
+	return ctx.Data(http.StatusOK, contentType, data)
+}
+
+// ListBookmarks godoc
+// @Summary List my bookmarks
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param course_id query int false "Filter by course id"
+// @Success 200 {array} models.LessonBookmarkResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /me/bookmarks [get]
+func (c *LessonNoteController) ListBookmarks(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	courseId, err := queryID(ctx, "course_id")
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	items, err := c.Service.GetBookmarks(userId, courseId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch bookmarks: " + err.Error()})
+	}
+
+	responses := make([]*models.LessonBookmarkResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToResponse()
+	}
+
+	return ctx.JSON(http.StatusOK, responses)
+}
+
+// BookmarkLesson godoc
+// @Summary Bookmark a lesson
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Lesson id"
+// @Success 200 {object} models.LessonBookmarkResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /lessons/{id}/bookmark [put]
+func (c *LessonNoteController) Bookmark(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	item, err := c.Service.Bookmark(uint(id), userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// RemoveLessonBookmark godoc
+// @Summary Remove a lesson bookmark
+// @Tags App/LessonNote
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "Lesson id"
+// @Success 204
+// @Failure 400 {object} types.ErrorResponse
+// @Router /lessons/{id}/bookmark [delete]
+func (c *LessonNoteController) RemoveBookmark(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.Service.RemoveBookmark(uint(id), userId); err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// queryID parses an optional id query parameter
+func queryID(ctx *router.Context, name string) (*uint, error) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil || parsed == 0 {
+		return nil, fmt.Errorf("Invalid %s", name)
+	}
+	value := uint(parsed)
+	return &value, nil
+}
+
+// handleError maps note errors to HTTP responses
+func (c *LessonNoteController) handleError(ctx *router.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrLessonNotAccessible):
+		return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case strings.Contains(err.Error(), "record not found"):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	}
+	return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,48 @@
+package lesson_notes
+
+import (
+	"base/app/models"
+	"base/core/module"
+	"base/core/router"
+
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Service    *LessonNoteService
+	Controller *LessonNoteController
+}
+
+// Init creates and initializes the LessonNote module with all dependencies
+func Init(deps module.Dependencies) module.Module {
+	// Initialize service and controller
+	service := NewLessonNoteService(deps.DB, deps.Emitter, deps.Storage, deps.Logger)
+	controller := NewLessonNoteController(service, deps.Storage)
+
+	// Create module
+	mod := &Module{
+		DB:         deps.DB,
+		Service:    service,
+		Controller: controller,
+	}
+
+	return mod
+}
+
+// Routes registers the module routes
+func (m *Module) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+func (m *Module) Migrate() error {
+	return m.DB.AutoMigrate(&models.LessonNote{}, &models.LessonBookmark{})
+}
+
+func (m *Module) GetModels() []any {
+	return []any{
+		&models.LessonNote{},
+		&models.LessonBookmark{},
+	}
+}
@@ -0,0 +1,168 @@
+package lesson_notes
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Minimal PDF 1.4 writer for note exports: A4 pages of wrapped text using the
+// standard Helvetica fonts, so no font files or third-party libraries are needed.
+// Characters outside the Windows-1252 range are replaced with '?'.
+
+const (
+	pdfPageWidth  = 595.0 // A4 in points
+	pdfPageHeight = 842.0
+	pdfMargin     = 56.0
+)
+
+// pdfLine is a paragraph of text with its style
+type pdfLine struct {
+	Text   string
+	Size   float64
+	Bold   bool
+	Indent float64
+	Space  float64 // Extra space before the paragraph
+}
+
+// writePDF lays the paragraphs out on as many pages as needed
+func writePDF(title string, lines []pdfLine) []byte {
+	var pages [][]byte
+	var page bytes.Buffer
+	y := pdfPageHeight - pdfMargin
+
+	newPage := func() {
+		pages = append(pages, append([]byte(nil), page.Bytes()...))
+		page.Reset()
+		y = pdfPageHeight - pdfMargin
+	}
+
+	for _, line := range lines {
+		leading := line.Size * 1.35
+		width := pdfPageWidth - 2*pdfMargin - line.Indent
+		y -= line.Space
+		for _, text := range wrapText(line.Text, width, line.Size) {
+			if y-leading < pdfMargin {
+				newPage()
+			}
+			y -= leading
+			font := "F1"
+			if line.Bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+				font, line.Size, pdfMargin+line.Indent, y, pdfEscape(text))
+		}
+	}
+	if page.Len() > 0 || len(pages) == 0 {
+		newPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4: catalog, page tree, fonts, info; pages and their contents follow
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (Base) >>", pdfEscape(title)))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, len(offsets), xref)
+
+	return out.Bytes()
+}
+
+// wrapText breaks text into lines that fit the width, using an average glyph width
+func wrapText(text string, width, size float64) []string {
+	maxChars := int(width / (size * 0.5))
+	if maxChars < 1 {
+		maxChars = 1
+	}
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		// Hard-break words longer than a line, such as URLs
+		for len([]rune(word)) > maxChars {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:maxChars]))
+			word = string(runes[maxChars:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case len([]rune(current))+1+len([]rune(word)) <= maxChars:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// winAnsi maps common typographic characters to their Windows-1252 codes
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfEscape encodes text as a PDF literal string in WinAnsiEncoding
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 0x20:
+			// Control characters are dropped
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			b.WriteByte(byte(r))
+		default:
+			if c, ok := winAnsi[r]; ok {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
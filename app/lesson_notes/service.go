@@ -0,0 +1,329 @@
+package lesson_notes
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"base/app/models"
+	"base/core/app/profile"
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateLessonNoteEvent = "lesson_notes.create"
+	UpdateLessonNoteEvent = "lesson_notes.update"
+	DeleteLessonNoteEvent = "lesson_notes.delete"
+)
+
+var ErrLessonNotAccessible = errors.New("you must be enrolled in the course to take notes on this lesson")
+
+type LessonNoteService struct {
+	DB      *gorm.DB
+	Emitter *emitter.Emitter
+	Storage *storage.ActiveStorage
+	Logger  logger.Logger
+}
+
+func NewLessonNoteService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *LessonNoteService {
+	return &LessonNoteService{
+		DB:      db,
+		Logger:  logger,
+		Emitter: emitter,
+		Storage: storage,
+	}
+}
+
+// accessibleLesson loads a lesson the user is enrolled in or teaches
+func (s *LessonNoteService) accessibleLesson(lessonId, userId uint) (*models.Lesson, error) {
+	lesson := &models.Lesson{}
+	if err := s.DB.Preload("Course").First(lesson, lessonId).Error; err != nil {
+		return nil, err
+	}
+
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		return nil, err
+	}
+	if lesson.Course.IsStaff(user) {
+		return lesson, nil
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.Enrollment{}).
+		Where("course_id = ? AND student_id = ?", lesson.CourseId, userId).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrLessonNotAccessible
+	}
+	return lesson, nil
+}
+
+// ownNote loads a note of the user; other users' notes are reported as not found
+func (s *LessonNoteService) ownNote(id, userId uint) (*models.LessonNote, error) {
+	item := &models.LessonNote{}
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userId).First(item).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// GetNotes returns the user's notes ordered by lesson and video position
+func (s *LessonNoteService) GetNotes(userId uint, courseId, lessonId *uint) ([]*models.LessonNote, error) {
+	var items []*models.LessonNote
+
+	query := s.DB.Model(&models.LessonNote{}).
+		Joins("LEFT JOIN lessons ON lessons.id = lesson_notes.lesson_id").
+		Where("lesson_notes.user_id = ?", userId)
+	if courseId != nil {
+		query = query.Where("lesson_notes.course_id = ?", *courseId)
+	}
+	if lessonId != nil {
+		query = query.Where("lesson_notes.lesson_id = ?", *lessonId)
+	}
+	query = query.Order("lesson_notes.course_id asc, lessons.order_number asc, lesson_notes.lesson_id asc").
+		Order("lesson_notes.video_timestamp asc, lesson_notes.created_at asc")
+
+	if err := query.Preload("Lesson").Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get lesson notes",
+			logger.String("error", err.Error()),
+			logger.Int("user_id", int(userId)))
+		return nil, err
+	}
+	return items, nil
+}
+
+func (s *LessonNoteService) Create(lessonId, userId uint, req *models.CreateLessonNoteRequest) (*models.LessonNote, error) {
+	lesson, err := s.accessibleLesson(lessonId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.LessonNote{
+		UserId:         userId,
+		LessonId:       lesson.Id,
+		CourseId:       lesson.CourseId,
+		Body:           req.Body,
+		VideoTimestamp: req.VideoTimestamp,
+	}
+	if err := s.DB.Create(item).Error; err != nil {
+		s.Logger.Error("failed to create lesson note", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateLessonNoteEvent, item)
+
+	item.Lesson = lesson
+	return item, nil
+}
+
+func (s *LessonNoteService) Update(id, userId uint, req *models.UpdateLessonNoteRequest) (*models.LessonNote, error) {
+	item, err := s.ownNote(id, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Body != "" {
+		item.Body = req.Body
+	}
+	if req.VideoTimestamp != nil {
+		item.VideoTimestamp = req.VideoTimestamp
+	}
+
+	if err := s.DB.Save(item).Error; err != nil {
+		s.Logger.Error("failed to update lesson note",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateLessonNoteEvent, item)
+
+	if err := item.Preload(s.DB).First(item, item.Id).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *LessonNoteService) Delete(id, userId uint) error {
+	item, err := s.ownNote(id, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.DB.Delete(item).Error; err != nil {
+		s.Logger.Error("failed to delete lesson note",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return err
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteLessonNoteEvent, item)
+
+	return nil
+}
+
+// GetBookmarks returns the user's bookmarks, optionally for one course
+func (s *LessonNoteService) GetBookmarks(userId uint, courseId *uint) ([]*models.LessonBookmark, error) {
+	var items []*models.LessonBookmark
+	query := s.DB.Where("user_id = ?", userId)
+	if courseId != nil {
+		query = query.Where("course_id = ?", *courseId)
+	}
+	if err := query.Preload("Lesson").Order("created_at desc").Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get lesson bookmarks",
+			logger.String("error", err.Error()),
+			logger.Int("user_id", int(userId)))
+		return nil, err
+	}
+	return items, nil
+}
+
+// Bookmark adds a lesson to the user's bookmarks; bookmarking twice is a no-op
+func (s *LessonNoteService) Bookmark(lessonId, userId uint) (*models.LessonBookmark, error) {
+	lesson, err := s.accessibleLesson(lessonId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.LessonBookmark{}
+	if err := s.DB.Where(models.LessonBookmark{UserId: userId, LessonId: lesson.Id}).
+		Attrs(models.LessonBookmark{CourseId: lesson.CourseId}).
+		FirstOrCreate(item).Error; err != nil {
+		s.Logger.Error("failed to bookmark lesson",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(lessonId)))
+		return nil, err
+	}
+
+	item.Lesson = lesson
+	return item, nil
+}
+
+// RemoveBookmark removes a lesson from the user's bookmarks
+func (s *LessonNoteService) RemoveBookmark(lessonId, userId uint) error {
+	return s.DB.Where("user_id = ? AND lesson_id = ?", userId, lessonId).
+		Delete(&models.LessonBookmark{}).Error
+}
+
+// Export renders the user's notes of a course as Markdown or PDF
+func (s *LessonNoteService) Export(userId, courseId uint, format string) ([]byte, string, error) {
+	course := &models.Course{}
+	if err := s.DB.First(course, courseId).Error; err != nil {
+		return nil, "", err
+	}
+	notes, err := s.GetNotes(userId, &courseId, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	title := "Notes: " + course.Title
+	if format == "pdf" {
+		return writePDF(title, notesPDF(title, notes)), "application/pdf", nil
+	}
+	return []byte(notesMarkdown(title, notes)), "text/markdown; charset=utf-8", nil
+}
+
+// notesMarkdown groups notes under a heading per lesson
+func notesMarkdown(title string, notes []*models.LessonNote) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+	if len(notes) == 0 {
+		b.WriteString("\n_No notes yet._\n")
+	}
+
+	var lessonId uint
+	for _, note := range notes {
+		if note.LessonId != lessonId {
+			lessonId = note.LessonId
+			fmt.Fprintf(&b, "\n## %s\n", lessonTitle(note))
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", noteHeading(note), strings.TrimSpace(note.Body))
+	}
+	return b.String()
+}
+
+// notesPDF lays the notes out as PDF paragraphs, flattening Markdown syntax
+func notesPDF(title string, notes []*models.LessonNote) []pdfLine {
+	lines := []pdfLine{{Text: title, Size: 18, Bold: true}}
+	if len(notes) == 0 {
+		lines = append(lines, pdfLine{Text: "No notes yet.", Size: 11, Space: 12})
+	}
+
+	var lessonId uint
+	for _, note := range notes {
+		if note.LessonId != lessonId {
+			lessonId = note.LessonId
+			lines = append(lines, pdfLine{Text: lessonTitle(note), Size: 14, Bold: true, Space: 16})
+		}
+		lines = append(lines, pdfLine{Text: noteHeading(note), Size: 9, Bold: true, Space: 8})
+		for _, raw := range strings.Split(strings.TrimSpace(note.Body), "\n") {
+			lines = append(lines, markdownLine(raw))
+		}
+	}
+	return lines
+}
+
+var (
+	markdownLink     = regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`)
+	markdownEmphasis = regexp.MustCompile("(\\*\\*|__|`)")
+)
+
+// markdownLine converts one Markdown line to a styled plain-text paragraph
+func markdownLine(raw string) pdfLine {
+	line := pdfLine{Size: 11}
+	text := strings.TrimSpace(raw)
+
+	switch {
+	case strings.HasPrefix(text, "#"):
+		text = strings.TrimSpace(strings.TrimLeft(text, "#"))
+		line.Size, line.Bold, line.Space = 12, true, 4
+	case strings.HasPrefix(text, "- "), strings.HasPrefix(text, "* "), strings.HasPrefix(text, "+ "):
+		text = "• " + strings.TrimSpace(text[2:])
+		line.Indent = 12
+	case strings.HasPrefix(text, "> "):
+		text = strings.TrimSpace(text[2:])
+		line.Indent = 18
+	}
+
+	text = markdownLink.ReplaceAllString(text, "$1 ($2)")
+	text = markdownEmphasis.ReplaceAllString(text, "")
+	line.Text = text
+	return line
+}
+
+// lessonTitle returns the title of the note's lesson
+func lessonTitle(note *models.LessonNote) string {
+	if note.Lesson != nil && note.Lesson.Title != "" {
+		return note.Lesson.Title
+	}
+	return fmt.Sprintf("Lesson %d", note.LessonId)
+}
+
+// noteHeading shows the video position, if any, and the note date
+func noteHeading(note *models.LessonNote) string {
+	date := note.CreatedAt.Format("2006-01-02")
+	if note.VideoTimestamp == nil {
+		return date
+	}
+	return formatTimestamp(*note.VideoTimestamp) + " · " + date
+}
+
+// formatTimestamp formats seconds as m:ss or h:mm:ss
+func formatTimestamp(seconds int) string {
+	h, m, sec := seconds/3600, seconds%3600/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+	}
+	return fmt.Sprintf("%d:%02d", m, sec)
+}
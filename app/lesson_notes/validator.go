@@ -0,0 +1,58 @@
+package lesson_notes
+
+import (
+	"base/app/models"
+	"base/core/validator"
+)
+
+// Global validator instance using Base core validator wrapper
+var validate = validator.New()
+
+// ValidateLessonNoteCreateRequest validates the create request
+func ValidateLessonNoteCreateRequest(req *models.CreateLessonNoteRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateLessonNoteUpdateRequest validates the update request
+func ValidateLessonNoteUpdateRequest(req *models.UpdateLessonNoteRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateID validates if the ID is valid
+func ValidateID(id uint) error {
+	if id == 0 {
+		return validator.ValidationErrors{
+			{
+				Field:   "id",
+				Tag:     "required",
+				Value:   "0",
+				Message: "id cannot be zero",
+			},
+		}
+	}
+	return nil
+}
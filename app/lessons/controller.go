@@ -1,6 +1,7 @@
 package lessons
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -32,6 +33,10 @@ func (c *LessonController) Routes(router *router.RouterGroup) {
 	router.PUT("/lessons/:id", c.Update)    // Update
 	router.DELETE("/lessons/:id", c.Delete) // Delete
 
+	// Video playback tracking
+	router.GET("/lessons/:id/playback", c.GetPlayback)     // Current user's progress
+	router.POST("/lessons/:id/playback", c.RecordPlayback) // Player heartbeat
+
 	//Upload endpoints for each file field
 }
 
@@ -64,7 +69,7 @@ func (c *LessonController) Create(ctx *router.Context) error {
 
 // GetLesson godoc
 // @Summary Get a Lesson
-// @Description Get a Lesson by its id
+// @Description Get a Lesson by its id, including the current user's playback progress and resume position
 // @Tags App/Lesson
 // @Security ApiKeyAuth
 // @Security BearerAuth
@@ -86,7 +91,16 @@ func (c *LessonController) Get(ctx *router.Context) error {
 		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
 	}
 
-	return ctx.JSON(http.StatusOK, item.ToResponse())
+	response := item.ToResponse()
+	if userId := ctx.GetUint("user_id"); userId != 0 {
+		playback, err := c.Service.GetPlayback(item.Id, userId)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch playback: " + err.Error()})
+		}
+		response.Playback = playback.ToResponse()
+	}
+
+	return ctx.JSON(http.StatusOK, response)
 }
 
 // ListLessons godoc
@@ -240,3 +254,87 @@ func (c *LessonController) Delete(ctx *router.Context) error {
 	ctx.Status(http.StatusNoContent)
 	return nil
 }
+
+// GetLessonPlayback godoc
+// @Summary Get my playback of a Lesson
+// @Description Get the current user's watched intervals, percentage and resume position
+// @Tags App/Lesson
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Lesson id"
+// @Success 200 {object} models.LessonPlaybackResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /lessons/{id}/playback [get]
+func (c *LessonController) GetPlayback(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	item, err := c.Service.GetPlayback(uint(id), userId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch playback: " + err.Error()})
+	}
+	if item == nil {
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "No playback recorded"})
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// RecordLessonPlayback godoc
+// @Summary Record a playback heartbeat
+// @Description Report the player position and the spans watched since the last heartbeat. Overlapping spans are merged server-side and measured against the lesson duration when it is set; a reported duration never shortens the stored one. Reaching the completion threshold logs the lesson as completed.
+// @Tags App/Lesson
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "Lesson id"
+// @Param playback body models.PlaybackHeartbeatRequest true "Heartbeat"
+// @Success 200 {object} models.LessonPlaybackResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /lessons/{id}/playback [post]
+func (c *LessonController) RecordPlayback(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req models.PlaybackHeartbeatRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidatePlaybackRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.RecordPlayback(uint(id), userId, &req)
+	if err != nil {
+		if errors.Is(err, ErrNotEnrolled) {
+			return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+		}
+		if strings.Contains(err.Error(), "record not found") {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to record playback: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
@@ -21,6 +21,9 @@ func Init(deps module.Dependencies) module.Module {
 	service := NewLessonService(deps.DB, deps.Emitter, deps.Storage, deps.Logger)
 	controller := NewLessonController(service, deps.Storage)
 
+	// Watched percentage that marks a lesson complete
+	service.CompletionThreshold = deps.Config.PlaybackCompletionThreshold
+
 	// Create module
 	mod := &Module{
 		DB:         deps.DB,
@@ -41,11 +44,12 @@ func (m *Module) Init() error {
 }
 
 func (m *Module) Migrate() error {
-	return m.DB.AutoMigrate(&models.Lesson{})
+	return m.DB.AutoMigrate(&models.Lesson{}, &models.LessonPlayback{})
 }
 
 func (m *Module) GetModels() []any {
 	return []any{
 		&models.Lesson{},
+		&models.LessonPlayback{},
 	}
 }
@@ -1,37 +1,102 @@
 package lessons
 
 import (
+	"errors"
 	"math"
+	"time"
 
 	"base/app/models"
+	"base/core/app/profile"
 	"base/core/emitter"
 	"base/core/logger"
 	"base/core/storage"
 	"base/core/types"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
 	CreateLessonEvent = "lessons.create"
 	UpdateLessonEvent = "lessons.update"
 	DeleteLessonEvent = "lessons.delete"
+
+	CompleteLessonPlaybackEvent = "lessons.playback.complete"
+)
+
+// DefaultCompletionThreshold is the watched percentage that completes a lesson
+const DefaultCompletionThreshold = 90.0
+
+// MaxVideoSize is the largest lesson video accepted for resumable uploads
+const MaxVideoSize int64 = 5 << 30 // 5GB
+
+var (
+	ErrNotEnrolled    = errors.New("you must be enrolled in the course to track playback")
+	ErrNotCourseStaff = errors.New("only the course instructor or an administrator can upload lesson videos")
 )
 
 type LessonService struct {
-	DB      *gorm.DB
-	Emitter *emitter.Emitter
-	Storage *storage.ActiveStorage
-	Logger  logger.Logger
+	DB                  *gorm.DB
+	Emitter             *emitter.Emitter
+	Storage             *storage.ActiveStorage
+	Logger              logger.Logger
+	CompletionThreshold float64
+}
+
+func NewLessonService(db *gorm.DB, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage, logger logger.Logger) *LessonService {
+	service := &LessonService{
+		DB:                  db,
+		Logger:              logger,
+		Emitter:             emitter,
+		Storage:             activeStorage,
+		CompletionThreshold: DefaultCompletionThreshold,
+	}
+
+	// Lesson videos are uploaded through the resumable uploads endpoint
+	if activeStorage != nil {
+		activeStorage.RegisterAttachment("lesson", storage.AttachmentConfig{
+			Table:             "lessons",
+			Field:             "video",
+			Path:              "lessons",
+			AllowedExtensions: []string{".mp4", ".m4v", ".mov", ".webm", ".mkv"},
+			MaxFileSize:       MaxVideoSize,
+			Authorize:         service.authorizeVideoUpload,
+			OnAttach:          service.attachVideo,
+			OnUpdate:          service.attachVideo,
+			OwnerType:         "course",
+			Owner:             service.courseOf,
+		})
+	}
+
+	return service
 }
 
-func NewLessonService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger) *LessonService {
-	return &LessonService{
-		DB:      db,
-		Logger:  logger,
-		Emitter: emitter,
-		Storage: storage,
+// authorizeVideoUpload allows course staff to upload a lesson's video
+func (s *LessonService) authorizeVideoUpload(userId, lessonId uint) error {
+	lesson := &models.Lesson{}
+	if err := s.DB.Preload("Course").First(lesson, lessonId).Error; err != nil {
+		return err
 	}
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		return err
+	}
+	if lesson.Course == nil || !lesson.Course.IsStaff(user) {
+		return ErrNotCourseStaff
+	}
+	return nil
+}
+
+// attachVideo points the lesson at its uploaded video
+func (s *LessonService) attachVideo(attachment *storage.Attachment) error {
+	if err := s.DB.Model(&models.Lesson{}).Where("id = ?", attachment.ModelId).
+		Update("video_url", attachment.URL).Error; err != nil {
+		s.Logger.Error("failed to set lesson video",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(attachment.ModelId)))
+		return err
+	}
+	return nil
 }
 
 // applySorting applies sorting to the query based on the sort and order parameters
@@ -275,3 +340,122 @@ func (s *LessonService) GetAllForSelect() ([]*models.Lesson, error) {
 
 	return items, nil
 }
+
+// GetPlayback returns the user's playback of a lesson, or nil when they have not watched it
+func (s *LessonService) GetPlayback(lessonId, userId uint) (*models.LessonPlayback, error) {
+	item := &models.LessonPlayback{}
+	err := s.DB.Where("lesson_id = ? AND user_id = ?", lessonId, userId).First(item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// RecordPlayback stores a player heartbeat: the current position and the spans watched
+// since the previous heartbeat. Once the merged watched time reaches the completion
+// threshold the lesson is logged as completed for the enrollment.
+func (s *LessonService) RecordPlayback(lessonId, userId uint, req *models.PlaybackHeartbeatRequest) (*models.LessonPlayback, error) {
+	lesson := &models.Lesson{}
+	if err := s.DB.First(lesson, lessonId).Error; err != nil {
+		return nil, err
+	}
+
+	enrollment := &models.Enrollment{}
+	if err := s.DB.Where("course_id = ? AND student_id = ?", lesson.CourseId, userId).
+		Order("id desc").First(enrollment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, err
+	}
+
+	item := &models.LessonPlayback{}
+	completed := false
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("lesson_id = ? AND user_id = ?", lessonId, userId).First(item).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		item.LessonId = lessonId
+		item.UserId = userId
+		item.EnrollmentId = enrollment.Id
+
+		item.Duration = playbackDuration(lesson.Duration, item.Duration, req.Duration)
+		item.Position = req.Position
+		if item.Duration > 0 && item.Position > item.Duration {
+			item.Position = item.Duration
+		}
+		item.AddIntervals(req.Intervals)
+
+		if item.CompletedAt == nil && item.Duration > 0 && item.WatchedPercent >= s.CompletionThreshold {
+			now := time.Now()
+			item.CompletedAt = &now
+			completed = true
+			if err := s.logCompletion(tx, enrollment.Id, lessonId, now); err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(item).Error
+	})
+	if err != nil {
+		s.Logger.Error("failed to record lesson playback",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(lessonId)),
+			logger.Int("user_id", int(userId)))
+		return nil, err
+	}
+
+	if completed {
+		s.Emitter.Emit(CompleteLessonPlaybackEvent, item)
+	}
+
+	return item, nil
+}
+
+// playbackDuration is the video length watched time is measured against. A
+// heartbeat never shortens it, or a client could pass off a few seconds as the
+// whole video. Lesson.Duration is in whole minutes and wins when set; the player
+// may only refine it by less than that minute, and not below half of it.
+func playbackDuration(lessonMinutes int, stored, reported float64) float64 {
+	duration := max(stored, reported)
+	if lessonMinutes <= 0 {
+		return duration
+	}
+	length := float64(lessonMinutes * 60)
+	if duration <= max(length-60, length/2) || duration > length {
+		return length
+	}
+	return duration
+}
+
+// logCompletion creates the lesson's progress log for the enrollment unless one exists
+func (s *LessonService) logCompletion(tx *gorm.DB, enrollmentId, lessonId uint, at time.Time) error {
+	var count int64
+	if err := tx.Model(&models.CourseProgressLog{}).
+		Where("enrollment_id = ? AND lesson_id = ?", enrollmentId, lessonId).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return tx.Create(&models.CourseProgressLog{
+		EnrollmentId: enrollmentId,
+		LessonId:     lessonId,
+		CompletedAt:  types.DateTime{Time: at},
+	}).Error
+}
+
+// courseOf returns the course of a lesson, whose storage quota its video counts against
+func (s *LessonService) courseOf(lessonId uint) (uint, error) {
+	lesson := &models.Lesson{}
+	if err := s.DB.Select("course_id").First(lesson, lessonId).Error; err != nil {
+		return 0, err
+	}
+	return lesson.CourseId, nil
+}
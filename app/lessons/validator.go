@@ -58,6 +58,23 @@ func ValidateLessonDeleteRequest(id uint) error {
 	return ValidateID(id)
 }
 
+// ValidatePlaybackRequest validates a player heartbeat
+func ValidatePlaybackRequest(req *models.PlaybackHeartbeatRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
 // ValidateID validates if the ID is valid
 func ValidateID(id uint) error {
 	if id == 0 {
@@ -0,0 +1,351 @@
+package live_sessions
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"base/app/models"
+	"base/core/router"
+	"base/core/storage"
+	"base/core/types"
+	"base/core/validator"
+)
+
+type LiveSessionController struct {
+	Service *LiveSessionService
+	Storage *storage.ActiveStorage
+}
+
+func NewLiveSessionController(service *LiveSessionService, storage *storage.ActiveStorage) *LiveSessionController {
+	return &LiveSessionController{
+		Service: service,
+		Storage: storage,
+	}
+}
+
+func (c *LiveSessionController) Routes(router *router.RouterGroup) {
+	// Main CRUD endpoints - specific routes MUST come before parameterized routes
+	router.GET("/live_sessions", c.List)          // Paginated list
+	router.POST("/live_sessions", c.Create)       // Create
+	router.GET("/live_sessions/all", c.ListAll)   // Unpaginated list - MUST be before /:id
+	router.GET("/live_sessions/:id", c.Get)       // Get by ID - MUST be after /all
+	router.PUT("/live_sessions/:id", c.Update)    // Update
+	router.DELETE("/live_sessions/:id", c.Delete) // Delete
+
+	// Personal calendar feed
+	router.GET("/me/calendar", c.GetFeed)            // Feed subscription URL
+	router.POST("/me/calendar/rotate", c.RotateFeed) // Issue a new feed token
+	router.GET("/calendar/:token", c.Feed)           // Token-authenticated .ics feed
+}
+
+// CreateLiveSession godoc
+// @Summary Create a new LiveSession
+// @Description Create a new LiveSession attached to a course or cohort
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param live_sessions body models.CreateLiveSessionRequest true "Create LiveSession request"
+// @Success 201 {object} models.LiveSessionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /live_sessions [post]
+func (c *LiveSessionController) Create(ctx *router.Context) error {
+	var req models.CreateLiveSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := ValidateLiveSessionCreateRequest(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: err})
+	}
+
+	item, err := c.Service.Create(&req)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to create item: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusCreated, item.ToResponse())
+}
+
+// GetLiveSession godoc
+// @Summary Get a LiveSession
+// @Description Get a LiveSession by its id
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "LiveSession id"
+// @Success 200 {object} models.LiveSessionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Router /live_sessions/{id} [get]
+func (c *LiveSessionController) Get(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	item, err := c.Service.GetById(uint(id))
+	if err != nil {
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// ListLiveSessions godoc
+// @Summary List live_sessions
+// @Description Get a list of live_sessions
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page"
+// @Param course_id query int false "Filter by course id"
+// @Param course_run_id query int false "Filter by course run id"
+// @Param sort query string false "Sort field (id, created_at, updated_at,title,starts_at,duration,)"
+// @Param order query string false "Sort order (asc, desc)"
+// @Success 200 {object} types.PaginatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /live_sessions [get]
+func (c *LiveSessionController) List(ctx *router.Context) error {
+	var page, limit *int
+	var sortBy, sortOrder *string
+	var courseId, courseRunId *uint
+
+	// Parse page parameter
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		if pageNum, err := strconv.Atoi(pageStr); err == nil && pageNum > 0 {
+			page = &pageNum
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid page number"})
+		}
+	}
+
+	// Parse limit parameter
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		if limitNum, err := strconv.Atoi(limitStr); err == nil && limitNum > 0 {
+			limit = &limitNum
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid limit number"})
+		}
+	}
+
+	// Parse course and cohort filters
+	if courseStr := ctx.Query("course_id"); courseStr != "" {
+		parsed, err := strconv.ParseUint(courseStr, 10, 32)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid course_id"})
+		}
+		value := uint(parsed)
+		courseId = &value
+	}
+	if runStr := ctx.Query("course_run_id"); runStr != "" {
+		parsed, err := strconv.ParseUint(runStr, 10, 32)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid course_run_id"})
+		}
+		value := uint(parsed)
+		courseRunId = &value
+	}
+
+	// Parse sort parameters
+	if sortStr := ctx.Query("sort"); sortStr != "" {
+		sortBy = &sortStr
+	}
+
+	if orderStr := ctx.Query("order"); orderStr != "" {
+		if orderStr == "asc" || orderStr == "desc" {
+			sortOrder = &orderStr
+		} else {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid sort order. Use 'asc' or 'desc'"})
+		}
+	}
+
+	paginatedResponse, err := c.Service.GetAll(page, limit, sortBy, sortOrder, courseId, courseRunId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch items: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, paginatedResponse)
+}
+
+// ListAllLiveSessions godoc
+// @Summary List all live_sessions for select options
+// @Description Get a simplified list of all live_sessions with id and name only (for dropdowns/select boxes)
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.LiveSessionSelectOption
+// @Failure 500 {object} types.ErrorResponse
+// @Router /live_sessions/all [get]
+func (c *LiveSessionController) ListAll(ctx *router.Context) error {
+	items, err := c.Service.GetAllForSelect()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to fetch select options: " + err.Error()})
+	}
+
+	// Convert to select options
+	var selectOptions []*models.LiveSessionSelectOption
+	for _, item := range items {
+		selectOptions = append(selectOptions, item.ToSelectOption())
+	}
+
+	return ctx.JSON(http.StatusOK, selectOptions)
+}
+
+// UpdateLiveSession godoc
+// @Summary Update a LiveSession
+// @Description Update a LiveSession by its id; rescheduling re-arms the reminder emails
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "LiveSession id"
+// @Param live_sessions body models.UpdateLiveSessionRequest true "Update LiveSession request"
+// @Success 200 {object} models.LiveSessionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /live_sessions/{id} [put]
+func (c *LiveSessionController) Update(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	var req models.UpdateLiveSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	item, err := c.Service.Update(uint(id), &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+		}
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Validation failed", Details: validationErrs})
+		}
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update item: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// DeleteLiveSession godoc
+// @Summary Delete a LiveSession
+// @Description Delete a LiveSession by its id
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "LiveSession id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /live_sessions/{id} [delete]
+func (c *LiveSessionController) Delete(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid id format"})
+	}
+
+	if err := c.Service.Delete(uint(id)); err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Item not found"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to delete item: " + err.Error()})
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// GetCalendarFeed godoc
+// @Summary Get my calendar feed
+// @Description Get the personal iCalendar subscription URL of the current user
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.CalendarFeedResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /me/calendar [get]
+func (c *LiveSessionController) GetFeed(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	feed, err := c.Service.GetFeed(userId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get calendar feed: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, c.Service.FeedResponse(feed))
+}
+
+// RotateCalendarFeed godoc
+// @Summary Rotate my calendar feed token
+// @Description Issue a new calendar feed URL; the previous URL stops working
+// @Tags App/LiveSession
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} models.CalendarFeedResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /me/calendar/rotate [post]
+func (c *LiveSessionController) RotateFeed(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+	}
+
+	feed, err := c.Service.RotateFeed(userId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to rotate calendar feed: " + err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, c.Service.FeedResponse(feed))
+}
+
+// CalendarFeed godoc
+// @Summary Calendar feed
+// @Description RFC 5545 iCalendar feed of the live sessions of the feed owner; authenticated by the token in the URL
+// @Tags App/LiveSession
+// @Produce text/calendar
+// @Param token path string true "Feed token, optionally suffixed with .ics"
+// @Success 200 {string} string "iCalendar document"
+// @Failure 404 {object} types.ErrorResponse
+// @Router /calendar/{token} [get]
+func (c *LiveSessionController) Feed(ctx *router.Context) error {
+	token := strings.TrimSuffix(ctx.Param("token"), ".ics")
+
+	data, err := c.Service.RenderFeed(token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidFeedToken) {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Calendar not found"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to render calendar"})
+	}
+
+	ctx.SetHeader("Cache-Control", "private, max-age=300")
+	ctx.SetHeader("Content-Disposition", `inline; filename="calendar.ics"`)
+	return ctx.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
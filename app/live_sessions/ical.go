@@ -0,0 +1,111 @@
+package live_sessions
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalTimeFormat is the RFC 5545 UTC date-time form (FORM #2)
+const icalTimeFormat = "20060102T150405Z"
+
+// icalMaxLineOctets is the line length limit before folding (RFC 5545 section 3.1)
+const icalMaxLineOctets = 75
+
+// calendarEvent is a single VEVENT of a feed
+type calendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Updated     time.Time
+}
+
+// calendarWriter builds an RFC 5545 iCalendar document
+type calendarWriter struct {
+	buf bytes.Buffer
+}
+
+// writeCalendar renders the events as a VCALENDAR with the given display name
+func writeCalendar(name string, events []calendarEvent) []byte {
+	w := &calendarWriter{}
+	now := time.Now().UTC()
+
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//Base//Course Platform//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", escapeText(name))
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	w.line("X-PUBLISHED-TTL", "PT1H")
+
+	for _, event := range events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", event.UID)
+		w.line("DTSTAMP", now.Format(icalTimeFormat))
+		w.line("DTSTART", event.Start.UTC().Format(icalTimeFormat))
+		if !event.End.IsZero() {
+			w.line("DTEND", event.End.UTC().Format(icalTimeFormat))
+		}
+		if !event.Updated.IsZero() {
+			w.line("LAST-MODIFIED", event.Updated.UTC().Format(icalTimeFormat))
+		}
+		w.line("SUMMARY", escapeText(event.Summary))
+		if event.Description != "" {
+			w.line("DESCRIPTION", escapeText(event.Description))
+		}
+		if event.Location != "" {
+			w.line("LOCATION", escapeText(event.Location))
+		}
+		if event.URL != "" {
+			w.line("URL", event.URL)
+		}
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes()
+}
+
+// line writes a content line, folding it at 75 octets without splitting UTF-8 sequences
+func (w *calendarWriter) line(name, value string) {
+	content := name + ":" + value
+	limit := icalMaxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.buf.WriteString(content[:cut])
+		w.buf.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space, which counts toward the limit
+		limit = icalMaxLineOctets - 1
+	}
+	w.buf.WriteString(content)
+	w.buf.WriteString("\r\n")
+}
+
+// escapeText escapes a TEXT property value (RFC 5545 section 3.3.11)
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return replacer.Replace(value)
+}
+
+// eventUID builds a globally unique, stable identifier for a feed entry
+func eventUID(kind string, id uint, host string) string {
+	return fmt.Sprintf("%s-%d@%s", kind, id, host)
+}
@@ -0,0 +1,81 @@
+package live_sessions
+
+import (
+	"time"
+
+	"base/app/models"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/router"
+	"base/core/scheduler"
+
+	"gorm.io/gorm"
+)
+
+type Module struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Service    *LiveSessionService
+	Controller *LiveSessionController
+	Logger     logger.Logger
+}
+
+// Init creates and initializes the LiveSession module with all dependencies
+func Init(deps module.Dependencies) module.Module {
+	baseURL := ""
+	if deps.Config != nil {
+		baseURL = deps.Config.BaseURL
+	}
+
+	// Initialize service and controller
+	service := NewLiveSessionService(deps.DB, deps.Emitter, deps.Storage, deps.Logger, deps.EmailSender, baseURL)
+	controller := NewLiveSessionController(service, deps.Storage)
+
+	// Create module
+	mod := &Module{
+		DB:         deps.DB,
+		Service:    service,
+		Controller: controller,
+		Logger:     deps.Logger,
+	}
+
+	return mod
+}
+
+// Routes registers the module routes
+func (m *Module) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router)
+}
+
+// Init registers the session reminder task
+func (m *Module) Init() error {
+	mod, err := module.GetModule("scheduler")
+	if err != nil {
+		m.Logger.Warn("Scheduler not available, live session reminders are disabled")
+		return nil
+	}
+
+	schedulerModule, ok := mod.(*scheduler.Module)
+	if !ok {
+		return nil
+	}
+
+	return schedulerModule.GetScheduler().RegisterTask(&scheduler.Task{
+		Name:        "live_sessions.reminders",
+		Description: "Email students 24 hours and 1 hour before live sessions",
+		Schedule:    &scheduler.IntervalSchedule{Interval: time.Minute},
+		Handler:     m.Service.SendReminders,
+		Enabled:     true,
+	})
+}
+
+func (m *Module) Migrate() error {
+	return m.DB.AutoMigrate(&models.LiveSession{}, &models.CalendarFeed{})
+}
+
+func (m *Module) GetModels() []any {
+	return []any{
+		&models.LiveSession{},
+		&models.CalendarFeed{},
+	}
+}
@@ -0,0 +1,576 @@
+package live_sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"base/app/models"
+	"base/core/app/profile"
+	"base/core/email"
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateLiveSessionEvent = "live_sessions.create"
+	UpdateLiveSessionEvent = "live_sessions.update"
+	DeleteLiveSessionEvent = "live_sessions.delete"
+	ReminderSentEvent      = "live_sessions.reminder"
+)
+
+var ErrInvalidFeedToken = errors.New("invalid calendar feed token")
+
+type LiveSessionService struct {
+	DB          *gorm.DB
+	Emitter     *emitter.Emitter
+	Storage     *storage.ActiveStorage
+	Logger      logger.Logger
+	EmailSender email.Sender
+	BaseURL     string
+}
+
+func NewLiveSessionService(db *gorm.DB, emitter *emitter.Emitter, storage *storage.ActiveStorage, logger logger.Logger, emailSender email.Sender, baseURL string) *LiveSessionService {
+	return &LiveSessionService{
+		DB:          db,
+		Logger:      logger,
+		Emitter:     emitter,
+		Storage:     storage,
+		EmailSender: emailSender,
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// applySorting applies sorting to the query based on the sort and order parameters
+func (s *LiveSessionService) applySorting(query *gorm.DB, sortBy *string, sortOrder *string) {
+	// Valid sortable fields for LiveSession
+	validSortFields := map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"title":      "title",
+		"starts_at":  "starts_at",
+		"duration":   "duration",
+	}
+
+	// Default sorting - if sort_order exists, always use it for custom ordering
+	defaultSortBy := "starts_at"
+	defaultSortOrder := "asc"
+
+	// Determine sort field
+	sortField := defaultSortBy
+	if sortBy != nil && *sortBy != "" {
+		if field, exists := validSortFields[*sortBy]; exists {
+			sortField = field
+		}
+	}
+
+	// Determine sort direction (order parameter)
+	sortDirection := defaultSortOrder
+	if sortOrder != nil && (*sortOrder == "asc" || *sortOrder == "desc") {
+		sortDirection = *sortOrder
+	}
+
+	// Apply sorting
+	query.Order(sortField + " " + sortDirection)
+}
+
+func (s *LiveSessionService) Create(req *models.CreateLiveSessionRequest) (*models.LiveSession, error) {
+	item := &models.LiveSession{
+		CourseId:    req.CourseId,
+		CourseRunId: req.CourseRunId,
+		Title:       req.Title,
+		Description: req.Description,
+		StartsAt:    req.StartsAt,
+		Duration:    req.Duration,
+		Timezone:    req.Timezone,
+		JoinUrl:     req.JoinUrl,
+	}
+	if item.Timezone == "" {
+		item.Timezone = "UTC"
+	}
+
+	if err := s.DB.Create(item).Error; err != nil {
+		s.Logger.Error("failed to create live session", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateLiveSessionEvent, item)
+
+	return s.GetById(item.Id)
+}
+
+func (s *LiveSessionService) Update(id uint, req *models.UpdateLiveSessionRequest) (*models.LiveSession, error) {
+	item := &models.LiveSession{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find live session for update",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	// Validate request
+	if err := ValidateLiveSessionUpdateRequest(req, id); err != nil {
+		return nil, err
+	}
+
+	// Update fields directly on the model
+	// For foreign key relationships
+	if req.CourseId != 0 {
+		item.CourseId = req.CourseId
+	}
+	if req.CourseRunId != nil {
+		item.CourseRunId = req.CourseRunId
+	}
+	if req.Title != "" {
+		item.Title = req.Title
+	}
+	if req.Description != "" {
+		item.Description = req.Description
+	}
+	// Rescheduling re-arms the reminders
+	if !req.StartsAt.IsZero() && !req.StartsAt.Time.Equal(item.StartsAt.Time) {
+		item.StartsAt = req.StartsAt
+		item.Reminder24hSentAt = nil
+		item.Reminder1hSentAt = nil
+	}
+	if req.Duration != 0 {
+		item.Duration = req.Duration
+	}
+	if req.Timezone != "" {
+		item.Timezone = req.Timezone
+	}
+	if req.JoinUrl != "" {
+		item.JoinUrl = req.JoinUrl
+	}
+
+	if err := s.DB.Save(item).Error; err != nil {
+		s.Logger.Error("failed to update live session",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	result, err := s.GetById(item.Id)
+	if err != nil {
+		s.Logger.Error("failed to get updated live session",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	// Emit update event
+	s.Emitter.Emit(UpdateLiveSessionEvent, result)
+
+	return result, nil
+}
+
+func (s *LiveSessionService) Delete(id uint) error {
+	item := &models.LiveSession{}
+	if err := s.DB.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to find live session for deletion",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return err
+	}
+
+	if err := s.DB.Delete(item).Error; err != nil {
+		s.Logger.Error("failed to delete live session",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return err
+	}
+
+	// Emit delete event
+	s.Emitter.Emit(DeleteLiveSessionEvent, item)
+
+	return nil
+}
+
+func (s *LiveSessionService) GetById(id uint) (*models.LiveSession, error) {
+	item := &models.LiveSession{}
+
+	query := item.Preload(s.DB)
+	if err := query.First(item, id).Error; err != nil {
+		s.Logger.Error("failed to get live session",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(id)))
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (s *LiveSessionService) GetAll(page *int, limit *int, sortBy *string, sortOrder *string, courseId *uint, courseRunId *uint) (*types.PaginatedResponse, error) {
+	var items []*models.LiveSession
+	var total int64
+
+	query := s.DB.Model(&models.LiveSession{})
+	if courseId != nil {
+		query = query.Where("course_id = ?", *courseId)
+	}
+	if courseRunId != nil {
+		query = query.Where("course_run_id = ?", *courseRunId)
+	}
+	// Set default values if nil
+	defaultPage := 1
+	defaultLimit := 10
+	if page == nil {
+		page = &defaultPage
+	}
+	if limit == nil {
+		limit = &defaultLimit
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		s.Logger.Error("failed to count live sessions",
+			logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Apply pagination if provided
+	if page != nil && limit != nil {
+		offset := (*page - 1) * *limit
+		query = query.Offset(offset).Limit(*limit)
+	}
+
+	// Apply sorting
+	s.applySorting(query, sortBy, sortOrder)
+
+	// Execute query
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("failed to get live sessions",
+			logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	// Convert to response type
+	responses := make([]*models.LiveSessionListResponse, len(items))
+	for i, item := range items {
+		responses[i] = item.ToListResponse()
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(total) / float64(*limit)))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	return &types.PaginatedResponse{
+		Data: responses,
+		Pagination: types.Pagination{
+			Total:      int(total),
+			Page:       *page,
+			PageSize:   *limit,
+			TotalPages: totalPages,
+		},
+	}, nil
+}
+
+// GetAllForSelect gets all items for select box/dropdown options (simplified response)
+func (s *LiveSessionService) GetAllForSelect() ([]*models.LiveSession, error) {
+	var items []*models.LiveSession
+
+	query := s.DB.Model(&models.LiveSession{})
+
+	// Only select the necessary fields for select options
+	query = query.Select("id, title")
+
+	// Order by name/title for better UX
+	query = query.Order("title ASC")
+
+	if err := query.Find(&items).Error; err != nil {
+		s.Logger.Error("Failed to fetch items for select", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// sessionsForStudent returns the sessions of every course and cohort the student is enrolled in.
+// Sessions attached to a cohort are only visible to that cohort's students.
+func (s *LiveSessionService) sessionsForStudent(userId uint, since time.Time) ([]*models.LiveSession, error) {
+	var enrollments []*models.Enrollment
+	if err := s.DB.Where("student_id = ?", userId).Find(&enrollments).Error; err != nil {
+		return nil, err
+	}
+	if len(enrollments) == 0 {
+		return nil, nil
+	}
+
+	var courseIds, runIds []uint
+	for _, enrollment := range enrollments {
+		courseIds = append(courseIds, enrollment.CourseId)
+		if enrollment.CourseRunId != nil {
+			runIds = append(runIds, *enrollment.CourseRunId)
+		}
+	}
+
+	query := s.DB.Where("starts_at >= ?", since)
+	if len(runIds) > 0 {
+		query = query.Where(s.DB.Where("course_run_id IS NULL AND course_id IN ?", courseIds).
+			Or("course_run_id IN ?", runIds))
+	} else {
+		query = query.Where("course_run_id IS NULL AND course_id IN ?", courseIds)
+	}
+
+	var sessions []*models.LiveSession
+	if err := query.Order("starts_at ASC").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// attendeesFor returns the students expected at a session
+func (s *LiveSessionService) attendeesFor(session *models.LiveSession) ([]*profile.User, error) {
+	query := s.DB.Model(&models.Enrollment{})
+	if session.CourseRunId != nil {
+		query = query.Where("course_run_id = ?", *session.CourseRunId)
+	} else {
+		query = query.Where("course_id = ?", session.CourseId)
+	}
+
+	var studentIds []uint
+	if err := query.Distinct().Pluck("student_id", &studentIds).Error; err != nil {
+		return nil, err
+	}
+	if len(studentIds) == 0 {
+		return nil, nil
+	}
+
+	var students []*profile.User
+	if err := s.DB.Where("id IN ?", studentIds).Find(&students).Error; err != nil {
+		return nil, err
+	}
+	return students, nil
+}
+
+// GetFeed returns the user's calendar feed, creating its token on first use
+func (s *LiveSessionService) GetFeed(userId uint) (*models.CalendarFeed, error) {
+	feed := &models.CalendarFeed{}
+	err := s.DB.Where("user_id = ?", userId).First(feed).Error
+	if err == nil {
+		return feed, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	token, err := generateFeedToken()
+	if err != nil {
+		return nil, err
+	}
+	feed = &models.CalendarFeed{UserId: userId, Token: token}
+	if err := s.DB.Create(feed).Error; err != nil {
+		s.Logger.Error("failed to create calendar feed",
+			logger.String("error", err.Error()),
+			logger.Int("user_id", int(userId)))
+		return nil, err
+	}
+	return feed, nil
+}
+
+// RotateFeed replaces the feed token, invalidating previously shared URLs
+func (s *LiveSessionService) RotateFeed(userId uint) (*models.CalendarFeed, error) {
+	feed, err := s.GetFeed(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := generateFeedToken()
+	if err != nil {
+		return nil, err
+	}
+	feed.Token = token
+	feed.LastAccessedAt = nil
+	if err := s.DB.Save(feed).Error; err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+// FeedResponse builds the subscription URLs of a feed
+func (s *LiveSessionService) FeedResponse(feed *models.CalendarFeed) *models.CalendarFeedResponse {
+	feedURL := fmt.Sprintf("%s/api/calendar/%s.ics", s.BaseURL, feed.Token)
+	webcalURL := feedURL
+	if parsed, err := url.Parse(feedURL); err == nil {
+		parsed.Scheme = "webcal"
+		webcalURL = parsed.String()
+	}
+	return &models.CalendarFeedResponse{
+		Url:            feedURL,
+		WebcalUrl:      webcalURL,
+		CreatedAt:      feed.CreatedAt,
+		LastAccessedAt: feed.LastAccessedAt,
+	}
+}
+
+// RenderFeed renders the iCalendar document for a feed token
+func (s *LiveSessionService) RenderFeed(token string) ([]byte, error) {
+	if token == "" {
+		return nil, ErrInvalidFeedToken
+	}
+
+	feed := &models.CalendarFeed{}
+	if err := s.DB.Where("token = ?", token).First(feed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidFeedToken
+		}
+		return nil, err
+	}
+
+	// Keep a month of history so recently past sessions stay visible
+	sessions, err := s.sessionsForStudent(feed.UserId, time.Now().AddDate(0, -1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	host := "base"
+	if parsed, err := url.Parse(s.BaseURL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+
+	events := make([]calendarEvent, 0, len(sessions))
+	for _, session := range sessions {
+		description := session.Description
+		if session.JoinUrl != "" {
+			description = strings.TrimSpace(description + "\n\nJoin: " + session.JoinUrl)
+		}
+		events = append(events, calendarEvent{
+			UID:         eventUID("live-session", session.Id, host),
+			Summary:     session.Title,
+			Description: description,
+			Location:    session.JoinUrl,
+			URL:         session.JoinUrl,
+			Start:       session.StartsAt.Time,
+			End:         session.EndsAt(),
+			Updated:     session.UpdatedAt,
+		})
+	}
+
+	now := time.Now()
+	s.DB.Model(feed).Update("last_accessed_at", &now)
+
+	return writeCalendar("Course schedule", events), nil
+}
+
+// SendReminders emails students 24 hours and 1 hour before their sessions start.
+// Each reminder is claimed before it is sent, so runs that overlap send it once.
+func (s *LiveSessionService) SendReminders(ctx context.Context) error {
+	now := time.Now()
+
+	// 1 hour reminders; also mark the 24 hour one so a late-created session gets a single email
+	var soon []*models.LiveSession
+	if err := s.DB.Where("starts_at > ? AND starts_at <= ? AND reminder_1h_sent_at IS NULL", now, now.Add(time.Hour)).
+		Find(&soon).Error; err != nil {
+		return fmt.Errorf("failed to find sessions starting within an hour: %w", err)
+	}
+	for _, session := range soon {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result := s.DB.Model(&models.LiveSession{}).
+			Where("id = ? AND reminder_1h_sent_at IS NULL", session.Id).
+			Updates(map[string]any{
+				"reminder_1h_sent_at":  now,
+				"reminder_24h_sent_at": gorm.Expr("COALESCE(reminder_24h_sent_at, ?)", now),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to claim session reminder: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			s.remind(session, "in 1 hour")
+		}
+	}
+
+	var upcoming []*models.LiveSession
+	if err := s.DB.Where("starts_at > ? AND starts_at <= ? AND reminder_24h_sent_at IS NULL", now.Add(time.Hour), now.Add(24*time.Hour)).
+		Find(&upcoming).Error; err != nil {
+		return fmt.Errorf("failed to find sessions starting within a day: %w", err)
+	}
+	for _, session := range upcoming {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		result := s.DB.Model(&models.LiveSession{}).
+			Where("id = ? AND reminder_24h_sent_at IS NULL", session.Id).
+			Update("reminder_24h_sent_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("failed to claim session reminder: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			s.remind(session, "tomorrow")
+		}
+	}
+
+	return nil
+}
+
+// remind emails every attendee of the session
+func (s *LiveSessionService) remind(session *models.LiveSession, when string) {
+	students, err := s.attendeesFor(session)
+	if err != nil {
+		s.Logger.Error("failed to load session attendees",
+			logger.String("error", err.Error()),
+			logger.Int("id", int(session.Id)))
+		return
+	}
+
+	for _, student := range students {
+		if err := s.sendReminderEmail(session, student, when); err != nil {
+			s.Logger.Error("failed to send session reminder",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(session.Id)),
+				logger.Int("student_id", int(student.Id)))
+		}
+	}
+
+	s.Emitter.Emit(ReminderSentEvent, session)
+}
+
+func (s *LiveSessionService) sendReminderEmail(session *models.LiveSession, student *profile.User, when string) error {
+	if s.EmailSender == nil {
+		return fmt.Errorf("email sender not configured")
+	}
+
+	start := session.StartsAt.Time.In(session.Location())
+	subject := fmt.Sprintf("Reminder: %s starts %s", session.Title, when)
+	content := fmt.Sprintf(`
+		<p>Hi %s,</p>
+		<p><strong>%s</strong> starts %s, on %s (%s).</p>
+		<p>Duration: %d minutes</p>
+	`, html.EscapeString(student.FirstName), html.EscapeString(session.Title), when, start.Format("Mon, 02 Jan 2006 15:04"), start.Location().String(), session.Duration)
+	if session.JoinUrl != "" {
+		content += fmt.Sprintf(`<p><a href="%s">Join the session</a></p>`, html.EscapeString(session.JoinUrl))
+	}
+
+	return s.EmailSender.Send(email.Message{
+		To:      []string{student.Email},
+		From:    "no-reply@base.al",
+		Subject: subject,
+		Body:    content,
+		IsHTML:  true,
+	})
+}
+
+// generateFeedToken creates a random, URL-safe feed token
+func generateFeedToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
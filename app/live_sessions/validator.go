@@ -0,0 +1,98 @@
+package live_sessions
+
+import (
+	"time"
+
+	"base/app/models"
+	"base/core/validator"
+)
+
+// Global validator instance using Base core validator wrapper
+var validate = validator.New()
+
+// ValidateLiveSessionCreateRequest validates the create request
+func ValidateLiveSessionCreateRequest(req *models.CreateLiveSessionRequest) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	if err := ValidateTimezone(req.Timezone); err != nil {
+		return err
+	}
+
+	// Use Base core validator
+	return validate.Validate(req)
+}
+
+// ValidateLiveSessionUpdateRequest validates the update request
+func ValidateLiveSessionUpdateRequest(req *models.UpdateLiveSessionRequest, id uint) error {
+	if req == nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "request",
+				Tag:     "required",
+				Value:   "nil",
+				Message: "request cannot be nil",
+			},
+		}
+	}
+
+	if id == 0 {
+		return validator.ValidationErrors{
+			{
+				Field:   "id",
+				Tag:     "required",
+				Value:   "0",
+				Message: "id cannot be zero",
+			},
+		}
+	}
+
+	// All fields are optional, only check the ones that need parsing
+	return ValidateTimezone(req.Timezone)
+}
+
+// ValidateLiveSessionDeleteRequest validates the delete request
+func ValidateLiveSessionDeleteRequest(id uint) error {
+	return ValidateID(id)
+}
+
+// ValidateID validates if the ID is valid
+func ValidateID(id uint) error {
+	if id == 0 {
+		return validator.ValidationErrors{
+			{
+				Field:   "id",
+				Tag:     "required",
+				Value:   "0",
+				Message: "id cannot be zero",
+			},
+		}
+	}
+	return nil
+}
+
+// ValidateTimezone checks that the timezone is a known IANA name
+func ValidateTimezone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return validator.ValidationErrors{
+			{
+				Field:   "timezone",
+				Tag:     "timezone",
+				Value:   tz,
+				Message: "timezone must be a valid IANA time zone name",
+			},
+		}
+	}
+	return nil
+}
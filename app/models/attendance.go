@@ -0,0 +1,178 @@
+package models
+
+import (
+	"base/core/app/profile"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Attendance states
+const (
+	AttendancePresent = "present"
+	AttendanceLate    = "late"
+	AttendanceAbsent  = "absent"
+	AttendanceExcused = "excused"
+)
+
+// Attendance records whether a student attended a live session
+type Attendance struct {
+	Id            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Status        string         `json:"status" gorm:"size:16"`
+	CheckedInAt   *time.Time     `json:"checked_in_at,omitempty"` // Set on student self check-in
+	Note          string         `json:"note"`
+	LiveSessionId uint           `json:"live_session_id" gorm:"uniqueIndex:idx_attendance_session_student"`
+	StudentId     uint           `json:"student_id" gorm:"uniqueIndex:idx_attendance_session_student"`
+	MarkedById    *uint          `json:"marked_by_id,omitempty"` // Instructor who last marked the record
+	LiveSession   *LiveSession   `json:"live_session,omitempty" gorm:"foreignKey:LiveSessionId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Student       *profile.User  `json:"student,omitempty" gorm:"foreignKey:StudentId"`
+}
+
+// TableName returns the table name for the Attendance model
+func (m *Attendance) TableName() string {
+	return "attendances"
+}
+
+// GetId returns the Id of the model
+func (m *Attendance) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *Attendance) GetModelName() string {
+	return "attendance"
+}
+
+// IsAttended reports whether the record counts towards the attendance rate
+func (m *Attendance) IsAttended() bool {
+	return m.Status == AttendancePresent || m.Status == AttendanceLate
+}
+
+// AttendanceCheckInCode is a short-lived code shown by the instructor for self check-in
+type AttendanceCheckInCode struct {
+	Id            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Code          string         `json:"code" gorm:"size:16;index"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	LiveSessionId uint           `json:"live_session_id" gorm:"index"`
+	CreatedById   uint           `json:"created_by_id"`
+}
+
+// TableName returns the table name for the AttendanceCheckInCode model
+func (m *AttendanceCheckInCode) TableName() string {
+	return "attendance_check_in_codes"
+}
+
+// GetId returns the Id of the model
+func (m *AttendanceCheckInCode) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *AttendanceCheckInCode) GetModelName() string {
+	return "attendance_check_in_code"
+}
+
+// AttendanceCheckInAttempt is a self check-in attempt of a student; only wrong
+// codes are kept, so they can be limited per session
+type AttendanceCheckInAttempt struct {
+	Id            uint      `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time `json:"created_at"`
+	LiveSessionId uint      `json:"live_session_id" gorm:"index:idx_attendance_attempt_session_student"`
+	StudentId     uint      `json:"student_id" gorm:"index:idx_attendance_attempt_session_student"`
+}
+
+// TableName returns the table name for the AttendanceCheckInAttempt model
+func (m *AttendanceCheckInAttempt) TableName() string {
+	return "attendance_check_in_attempts"
+}
+
+// MarkAttendanceRequest represents a single entry of a bulk marking request
+type MarkAttendanceRequest struct {
+	StudentId uint   `json:"student_id" validate:"required"`
+	Status    string `json:"status" validate:"required,oneof=present late absent excused"`
+	Note      string `json:"note"`
+}
+
+// BulkMarkAttendanceRequest represents the request payload for marking a whole session
+type BulkMarkAttendanceRequest struct {
+	Records []MarkAttendanceRequest `json:"records" validate:"required,min=1,dive"`
+}
+
+// CheckInRequest represents the request payload for student self check-in
+type CheckInRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// AttendanceResponse represents the API response for Attendance
+type AttendanceResponse struct {
+	Id            uint                       `json:"id"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+	Status        string                     `json:"status"`
+	CheckedInAt   *time.Time                 `json:"checked_in_at,omitempty"`
+	Note          string                     `json:"note"`
+	LiveSessionId uint                       `json:"live_session_id"`
+	MarkedById    *uint                      `json:"marked_by_id,omitempty"`
+	StudentId     uint                       `json:"student_id"`
+	Student       *profile.UserModelResponse `json:"student,omitempty"`
+	LiveSession   *LiveSessionModelResponse  `json:"live_session,omitempty"`
+}
+
+// AttendanceCheckInCodeResponse represents the API response for a generated check-in code
+type AttendanceCheckInCodeResponse struct {
+	Code          string    `json:"code"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	LiveSessionId uint      `json:"live_session_id"`
+}
+
+// ToResponse converts the model to an API response
+func (m *Attendance) ToResponse() *AttendanceResponse {
+	if m == nil {
+		return nil
+	}
+	response := &AttendanceResponse{
+		Id:            m.Id,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+		Status:        m.Status,
+		CheckedInAt:   m.CheckedInAt,
+		Note:          m.Note,
+		LiveSessionId: m.LiveSessionId,
+		MarkedById:    m.MarkedById,
+		StudentId:     m.StudentId,
+	}
+	if m.Student != nil {
+		response.Student = m.Student.ToModelResponse()
+	}
+	if m.LiveSession != nil {
+		response.LiveSession = m.LiveSession.ToModelResponse()
+	}
+
+	return response
+}
+
+// ToResponse converts the model to an API response
+func (m *AttendanceCheckInCode) ToResponse() *AttendanceCheckInCodeResponse {
+	if m == nil {
+		return nil
+	}
+	return &AttendanceCheckInCodeResponse{
+		Code:          m.Code,
+		ExpiresAt:     m.ExpiresAt,
+		LiveSessionId: m.LiveSessionId,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (m *Attendance) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Student")
+	query = query.Preload("LiveSession")
+	return query
+}
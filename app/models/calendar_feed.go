@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed holds the secret token of a user's personal iCalendar feed
+type CalendarFeed struct {
+	Id             uint       `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Token          string     `json:"-" gorm:"uniqueIndex;size:64"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	UserId         uint       `json:"user_id" gorm:"uniqueIndex"`
+}
+
+// TableName returns the table name for the CalendarFeed model
+func (m *CalendarFeed) TableName() string {
+	return "calendar_feeds"
+}
+
+// GetId returns the Id of the model
+func (m *CalendarFeed) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *CalendarFeed) GetModelName() string {
+	return "calendar_feed"
+}
+
+// CalendarFeedResponse represents the API response for a user's calendar feed
+type CalendarFeedResponse struct {
+	Url            string     `json:"url"`
+	WebcalUrl      string     `json:"webcal_url"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+}
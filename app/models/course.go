@@ -2,6 +2,7 @@ package models
 
 import (
 	"base/core/app/profile"
+	"base/core/storage"
 	"time"
 
 	"gorm.io/gorm"
@@ -26,6 +27,9 @@ type Course struct {
 	CategoryId   *uint           `json:"category_id,omitempty" gorm:"index"`
 	Instructor   *profile.User   `json:"instructor,omitempty" gorm:"foreignKey:InstructorId"`
 	Category     *CourseCategory `json:"category,omitempty" gorm:"foreignKey:CategoryId;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+
+	// Thumbnail is the uploaded thumbnail image with its resized variants
+	Thumbnail *storage.Attachment `json:"thumbnail,omitempty"`
 }
 
 // TableName returns the table name for the Course model
@@ -43,6 +47,15 @@ func (m *Course) GetModelName() string {
 	return "course"
 }
 
+// IsStaff reports whether the user teaches the course or administers the platform
+func (m *Course) IsStaff(user *profile.User) bool {
+	if m == nil || user == nil {
+		return false
+	}
+	// Administrators manage every course
+	return user.Id == m.InstructorId || user.IsAdmin()
+}
+
 // CreateCourseRequest represents the request payload for creating a Course
 type CreateCourseRequest struct {
 	Title        string `json:"title"`
@@ -90,6 +103,7 @@ type CourseResponse struct {
 	Duration     int                          `json:"duration"`
 	Instructor   *profile.UserModelResponse   `json:"instructor,omitempty"`
 	Category     *CourseCategoryModelResponse `json:"category,omitempty"`
+	Thumbnail    *storage.Attachment          `json:"thumbnail,omitempty"`
 }
 
 // CourseModelResponse represents a simplified response when this model is part of other entities
@@ -106,19 +120,20 @@ type CourseSelectOption struct {
 
 // CourseListResponse represents the response for list operations (optimized for performance)
 type CourseListResponse struct {
-	Id           uint           `json:"id"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `json:"deleted_at"`
-	Title        string         `json:"title"`
-	Slug         string         `json:"slug"`
-	Description  string         `json:"description"`
-	Price        int            `json:"price"`
-	Level        string         `json:"level"`
-	Language     string         `json:"language"`
-	ThumbnailUrl string         `json:"thumbnail_url"`
-	Status       string         `json:"status"`
-	Duration     int            `json:"duration"`
+	Id           uint                `json:"id"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt      `json:"deleted_at"`
+	Title        string              `json:"title"`
+	Slug         string              `json:"slug"`
+	Description  string              `json:"description"`
+	Price        int                 `json:"price"`
+	Level        string              `json:"level"`
+	Language     string              `json:"language"`
+	ThumbnailUrl string              `json:"thumbnail_url"`
+	Status       string              `json:"status"`
+	Duration     int                 `json:"duration"`
+	Thumbnail    *storage.Attachment `json:"thumbnail,omitempty"`
 }
 
 // ToResponse converts the model to an API response
@@ -140,6 +155,7 @@ func (m *Course) ToResponse() *CourseResponse {
 		ThumbnailUrl: m.ThumbnailUrl,
 		Status:       m.Status,
 		Duration:     m.Duration,
+		Thumbnail:    m.Thumbnail,
 	}
 	if m.InstructorId != 0 {
 		response.Instructor = m.Instructor.ToModelResponse()
@@ -194,6 +210,7 @@ func (m *Course) ToListResponse() *CourseListResponse {
 		ThumbnailUrl: m.ThumbnailUrl,
 		Status:       m.Status,
 		Duration:     m.Duration,
+		Thumbnail:    m.Thumbnail,
 	}
 }
 
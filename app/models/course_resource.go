@@ -1,6 +1,7 @@
 package models
 
 import (
+	"base/core/storage"
 	"base/core/types"
 	"time"
 
@@ -9,15 +10,16 @@ import (
 
 // CourseResource represents a courseResource entity
 type CourseResource struct {
-	Id         uint           `json:"id" gorm:"primarykey"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-	FileUrl    string         `json:"file_url"`
-	Title      string         `json:"title"`
-	UploadedAt types.DateTime `json:"uploaded_at"`
-	CourseId   uint           `json:"course_id,omitempty"`
-	Course     *Course        `json:"course,omitempty" gorm:"foreignKey:CourseId"`
+	Id         uint                `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt      `json:"deleted_at" gorm:"index"`
+	FileUrl    string              `json:"file_url"`       // External link; uploaded files go to File
+	File       *storage.Attachment `json:"file,omitempty"` // Private; downloaded through signed URLs
+	Title      string              `json:"title"`
+	UploadedAt types.DateTime      `json:"uploaded_at"`
+	CourseId   uint                `json:"course_id,omitempty"`
+	Course     *Course             `json:"course,omitempty" gorm:"foreignKey:CourseId"`
 }
 
 // TableName returns the table name for the CourseResource model
@@ -57,7 +59,8 @@ type CourseResourceResponse struct {
 	CreatedAt  time.Time            `json:"created_at"`
 	UpdatedAt  time.Time            `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt       `json:"deleted_at"`
-	FileUrl    string               `json:"file_url"`
+	FileUrl    string               `json:"file_url,omitempty"` // Left out for users neither enrolled nor course staff
+	File       *storage.Attachment  `json:"file,omitempty"`
 	Title      string               `json:"title"`
 	UploadedAt types.DateTime       `json:"uploaded_at"`
 	Course     *CourseModelResponse `json:"course,omitempty"`
@@ -77,13 +80,21 @@ type CourseResourceSelectOption struct {
 
 // CourseResourceListResponse represents the response for list operations (optimized for performance)
 type CourseResourceListResponse struct {
-	Id         uint           `json:"id"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at"`
-	FileUrl    string         `json:"file_url"`
-	Title      string         `json:"title"`
-	UploadedAt types.DateTime `json:"uploaded_at"`
+	Id         uint                `json:"id"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt      `json:"deleted_at"`
+	FileUrl    string              `json:"file_url,omitempty"` // Left out for users neither enrolled nor course staff
+	File       *storage.Attachment `json:"file,omitempty"`
+	Title      string              `json:"title"`
+	UploadedAt types.DateTime      `json:"uploaded_at"`
+}
+
+// CourseResourceDownloadResponse represents a download link for a CourseResource
+type CourseResourceDownloadResponse struct {
+	Url       string     `json:"url"`
+	Filename  string     `json:"filename,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Not set for external links
 }
 
 // ToResponse converts the model to an API response
@@ -97,6 +108,7 @@ func (m *CourseResource) ToResponse() *CourseResourceResponse {
 		UpdatedAt:  m.UpdatedAt,
 		DeletedAt:  m.DeletedAt,
 		FileUrl:    m.FileUrl,
+		File:       m.File,
 		Title:      m.Title,
 		UploadedAt: m.UploadedAt,
 	}
@@ -142,6 +154,7 @@ func (m *CourseResource) ToListResponse() *CourseResourceListResponse {
 		UpdatedAt:  m.UpdatedAt,
 		DeletedAt:  m.DeletedAt,
 		FileUrl:    m.FileUrl,
+		File:       m.File,
 		Title:      m.Title,
 		UploadedAt: m.UploadedAt,
 	}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"base/core/types"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CourseRun represents a cohort of a course with fixed dates and a seat limit
+type CourseRun struct {
+	Id                   uint           `json:"id" gorm:"primarykey"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Title                string         `json:"title"`
+	StartsAt             types.DateTime `json:"starts_at"`
+	EndsAt               types.DateTime `json:"ends_at"`
+	EnrollmentOpensAt    types.DateTime `json:"enrollment_opens_at"`
+	EnrollmentClosesAt   types.DateTime `json:"enrollment_closes_at"`
+	Capacity             int            `json:"capacity"`               // 0 means unlimited seats
+	MinAttendancePercent int            `json:"min_attendance_percent"` // 0 disables the certificate gate
+	CourseId             uint           `json:"course_id,omitempty" gorm:"index"`
+	Course               *Course        `json:"course,omitempty" gorm:"foreignKey:CourseId"`
+}
+
+// TableName returns the table name for the CourseRun model
+func (m *CourseRun) TableName() string {
+	return "course_runs"
+}
+
+// GetId returns the Id of the model
+func (m *CourseRun) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *CourseRun) GetModelName() string {
+	return "course_run"
+}
+
+// IsEnrollmentOpen reports whether sign-ups are accepted at the given time
+func (m *CourseRun) IsEnrollmentOpen(now time.Time) bool {
+	if !m.EnrollmentOpensAt.IsZero() && now.Before(m.EnrollmentOpensAt.Time) {
+		return false
+	}
+	if !m.EnrollmentClosesAt.IsZero() && now.After(m.EnrollmentClosesAt.Time) {
+		return false
+	}
+	return true
+}
+
+// CreateCourseRunRequest represents the request payload for creating a CourseRun
+type CreateCourseRunRequest struct {
+	CourseId             uint           `json:"course_id" validate:"required"`
+	Title                string         `json:"title" validate:"required"`
+	StartsAt             types.DateTime `json:"starts_at" swaggertype:"string"`
+	EndsAt               types.DateTime `json:"ends_at" swaggertype:"string"`
+	EnrollmentOpensAt    types.DateTime `json:"enrollment_opens_at" swaggertype:"string"`
+	EnrollmentClosesAt   types.DateTime `json:"enrollment_closes_at" swaggertype:"string"`
+	Capacity             int            `json:"capacity" validate:"min=0"`
+	MinAttendancePercent int            `json:"min_attendance_percent" validate:"min=0,max=100"`
+}
+
+// UpdateCourseRunRequest represents the request payload for updating a CourseRun
+type UpdateCourseRunRequest struct {
+	CourseId             uint           `json:"course_id,omitempty"`
+	Title                string         `json:"title,omitempty"`
+	StartsAt             types.DateTime `json:"starts_at,omitempty" swaggertype:"string"`
+	EndsAt               types.DateTime `json:"ends_at,omitempty" swaggertype:"string"`
+	EnrollmentOpensAt    types.DateTime `json:"enrollment_opens_at,omitempty" swaggertype:"string"`
+	EnrollmentClosesAt   types.DateTime `json:"enrollment_closes_at,omitempty" swaggertype:"string"`
+	Capacity             *int           `json:"capacity,omitempty"`
+	MinAttendancePercent *int           `json:"min_attendance_percent,omitempty" validate:"omitempty,min=0,max=100"`
+}
+
+// CourseRunResponse represents the API response for CourseRun
+type CourseRunResponse struct {
+	Id                   uint                 `json:"id"`
+	CreatedAt            time.Time            `json:"created_at"`
+	UpdatedAt            time.Time            `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt       `json:"deleted_at"`
+	Title                string               `json:"title"`
+	StartsAt             types.DateTime       `json:"starts_at"`
+	EndsAt               types.DateTime       `json:"ends_at"`
+	EnrollmentOpensAt    types.DateTime       `json:"enrollment_opens_at"`
+	EnrollmentClosesAt   types.DateTime       `json:"enrollment_closes_at"`
+	Capacity             int                  `json:"capacity"`
+	MinAttendancePercent int                  `json:"min_attendance_percent"`
+	SeatsTaken           int64                `json:"seats_taken"`
+	WaitlistCount        int64                `json:"waitlist_count"`
+	Course               *CourseModelResponse `json:"course,omitempty"`
+}
+
+// CourseRunModelResponse represents a simplified response when this model is part of other entities
+type CourseRunModelResponse struct {
+	Id    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+// CourseRunSelectOption represents a simplified response for select boxes and dropdowns
+type CourseRunSelectOption struct {
+	Id   uint   `json:"id"`
+	Name string `json:"name"` // From Title field
+}
+
+// CourseRunListResponse represents the response for list operations (optimized for performance)
+type CourseRunListResponse struct {
+	Id                   uint           `json:"id"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `json:"deleted_at"`
+	Title                string         `json:"title"`
+	StartsAt             types.DateTime `json:"starts_at"`
+	EndsAt               types.DateTime `json:"ends_at"`
+	EnrollmentOpensAt    types.DateTime `json:"enrollment_opens_at"`
+	EnrollmentClosesAt   types.DateTime `json:"enrollment_closes_at"`
+	Capacity             int            `json:"capacity"`
+	MinAttendancePercent int            `json:"min_attendance_percent"`
+	CourseId             uint           `json:"course_id"`
+}
+
+// ToResponse converts the model to an API response
+func (m *CourseRun) ToResponse() *CourseRunResponse {
+	if m == nil {
+		return nil
+	}
+	response := &CourseRunResponse{
+		Id:                   m.Id,
+		CreatedAt:            m.CreatedAt,
+		UpdatedAt:            m.UpdatedAt,
+		DeletedAt:            m.DeletedAt,
+		Title:                m.Title,
+		StartsAt:             m.StartsAt,
+		EndsAt:               m.EndsAt,
+		EnrollmentOpensAt:    m.EnrollmentOpensAt,
+		EnrollmentClosesAt:   m.EnrollmentClosesAt,
+		Capacity:             m.Capacity,
+		MinAttendancePercent: m.MinAttendancePercent,
+	}
+	if m.CourseId != 0 {
+		response.Course = m.Course.ToModelResponse()
+	}
+
+	return response
+}
+
+// ToModelResponse converts the model to a simplified response for when it's part of other entities
+func (m *CourseRun) ToModelResponse() *CourseRunModelResponse {
+	if m == nil {
+		return nil
+	}
+	return &CourseRunModelResponse{
+		Id:    m.Id,
+		Title: m.Title,
+	}
+}
+
+// ToSelectOption converts the model to a select option for dropdowns
+func (m *CourseRun) ToSelectOption() *CourseRunSelectOption {
+	if m == nil {
+		return nil
+	}
+	displayName := m.Title
+
+	return &CourseRunSelectOption{
+		Id:   m.Id,
+		Name: displayName,
+	}
+}
+
+// ToListResponse converts the model to a list response (without preloaded relationships for fast listing)
+func (m *CourseRun) ToListResponse() *CourseRunListResponse {
+	if m == nil {
+		return nil
+	}
+	return &CourseRunListResponse{
+		Id:                   m.Id,
+		CreatedAt:            m.CreatedAt,
+		UpdatedAt:            m.UpdatedAt,
+		DeletedAt:            m.DeletedAt,
+		Title:                m.Title,
+		StartsAt:             m.StartsAt,
+		EndsAt:               m.EndsAt,
+		EnrollmentOpensAt:    m.EnrollmentOpensAt,
+		EnrollmentClosesAt:   m.EnrollmentClosesAt,
+		Capacity:             m.Capacity,
+		MinAttendancePercent: m.MinAttendancePercent,
+		CourseId:             m.CourseId,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (m *CourseRun) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Course")
+	return query
+}
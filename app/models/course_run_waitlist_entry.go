@@ -0,0 +1,88 @@
+package models
+
+import (
+	"base/core/app/profile"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Waitlist entry statuses
+const (
+	WaitlistStatusWaiting   = "waiting"   // In the queue for a seat
+	WaitlistStatusOffered   = "offered"   // A seat is held until ClaimDeadline
+	WaitlistStatusClaimed   = "claimed"   // The student took the seat
+	WaitlistStatusExpired   = "expired"   // The offer was not claimed in time
+	WaitlistStatusCancelled = "cancelled" // The student left the waitlist
+)
+
+// CourseRunWaitlistEntry represents a student's place in a full cohort's waitlist
+type CourseRunWaitlistEntry struct {
+	Id            uint           `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Status        string         `json:"status" gorm:"index;default:waiting"`
+	OfferedAt     *time.Time     `json:"offered_at,omitempty"`
+	ClaimDeadline *time.Time     `json:"claim_deadline,omitempty" gorm:"index"`
+	CourseRunId   uint           `json:"course_run_id" gorm:"index"`
+	StudentId     uint           `json:"student_id" gorm:"index"`
+	CourseRun     *CourseRun     `json:"course_run,omitempty" gorm:"foreignKey:CourseRunId"`
+	Student       *profile.User  `json:"student,omitempty" gorm:"foreignKey:StudentId"`
+}
+
+// TableName returns the table name for the CourseRunWaitlistEntry model
+func (m *CourseRunWaitlistEntry) TableName() string {
+	return "course_run_waitlist_entries"
+}
+
+// GetId returns the Id of the model
+func (m *CourseRunWaitlistEntry) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *CourseRunWaitlistEntry) GetModelName() string {
+	return "course_run_waitlist_entry"
+}
+
+// CourseRunWaitlistEntryResponse represents the API response for CourseRunWaitlistEntry
+type CourseRunWaitlistEntryResponse struct {
+	Id            uint                       `json:"id"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	Status        string                     `json:"status"`
+	Position      int                        `json:"position,omitempty"`
+	OfferedAt     *time.Time                 `json:"offered_at,omitempty"`
+	ClaimDeadline *time.Time                 `json:"claim_deadline,omitempty"`
+	CourseRunId   uint                       `json:"course_run_id"`
+	StudentId     uint                       `json:"student_id"`
+	Student       *profile.UserModelResponse `json:"student,omitempty"`
+}
+
+// ToResponse converts the model to an API response
+func (m *CourseRunWaitlistEntry) ToResponse() *CourseRunWaitlistEntryResponse {
+	if m == nil {
+		return nil
+	}
+	response := &CourseRunWaitlistEntryResponse{
+		Id:            m.Id,
+		CreatedAt:     m.CreatedAt,
+		Status:        m.Status,
+		OfferedAt:     m.OfferedAt,
+		ClaimDeadline: m.ClaimDeadline,
+		CourseRunId:   m.CourseRunId,
+		StudentId:     m.StudentId,
+	}
+	if m.Student != nil {
+		response.Student = m.Student.ToModelResponse()
+	}
+
+	return response
+}
+
+// Preload preloads all the model's relationships
+func (m *CourseRunWaitlistEntry) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Student")
+	return query
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"base/core/app/profile"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Upvote targets
+const (
+	DiscussionVoteThread = "thread"
+	DiscussionVoteReply  = "reply"
+)
+
+// DiscussionReply represents a reply in a discussion thread, optionally nested under another reply
+type DiscussionReply struct {
+	Id                 uint              `json:"id" gorm:"primarykey"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt    `json:"deleted_at" gorm:"index"`
+	Body               string            `json:"body"`
+	UpvoteCount        int               `json:"upvote_count"`
+	IsInstructorAnswer bool              `json:"is_instructor_answer"` // Posted by course staff
+	ThreadId           uint              `json:"thread_id" gorm:"index"`
+	ParentId           *uint             `json:"parent_id,omitempty" gorm:"index"`
+	AuthorId           uint              `json:"author_id" gorm:"index"`
+	Thread             *DiscussionThread `json:"thread,omitempty" gorm:"foreignKey:ThreadId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Author             *profile.User     `json:"author,omitempty" gorm:"foreignKey:AuthorId"`
+}
+
+// TableName returns the table name for the DiscussionReply model
+func (m *DiscussionReply) TableName() string {
+	return "discussion_replies"
+}
+
+// GetId returns the Id of the model
+func (m *DiscussionReply) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *DiscussionReply) GetModelName() string {
+	return "discussion_reply"
+}
+
+// DiscussionVote records a user's upvote on a thread or a reply
+type DiscussionVote struct {
+	Id         uint      `json:"id" gorm:"primarykey"`
+	CreatedAt  time.Time `json:"created_at"`
+	TargetType string    `json:"target_type" gorm:"size:16;uniqueIndex:idx_discussion_vote"` // thread or reply
+	TargetId   uint      `json:"target_id" gorm:"uniqueIndex:idx_discussion_vote"`
+	UserId     uint      `json:"user_id" gorm:"uniqueIndex:idx_discussion_vote"`
+}
+
+// TableName returns the table name for the DiscussionVote model
+func (m *DiscussionVote) TableName() string {
+	return "discussion_votes"
+}
+
+// CreateDiscussionReplyRequest represents the request payload for creating a DiscussionReply
+type CreateDiscussionReplyRequest struct {
+	ParentId *uint  `json:"parent_id,omitempty"`
+	Body     string `json:"body" validate:"required"`
+}
+
+// UpdateDiscussionReplyRequest represents the request payload for updating a DiscussionReply
+type UpdateDiscussionReplyRequest struct {
+	Body string `json:"body" validate:"required"`
+}
+
+// DiscussionReplyResponse represents the API response for DiscussionReply
+type DiscussionReplyResponse struct {
+	Id                 uint                       `json:"id"`
+	CreatedAt          time.Time                  `json:"created_at"`
+	UpdatedAt          time.Time                  `json:"updated_at"`
+	Body               string                     `json:"body"`
+	UpvoteCount        int                        `json:"upvote_count"`
+	IsInstructorAnswer bool                       `json:"is_instructor_answer"`
+	IsAccepted         bool                       `json:"is_accepted"`
+	Upvoted            bool                       `json:"upvoted"` // Whether the current user upvoted
+	ThreadId           uint                       `json:"thread_id"`
+	ParentId           *uint                      `json:"parent_id,omitempty"`
+	Author             *profile.UserModelResponse `json:"author,omitempty"`
+	Replies            []*DiscussionReplyResponse `json:"replies,omitempty"`
+}
+
+// ToResponse converts the model to an API response
+func (m *DiscussionReply) ToResponse() *DiscussionReplyResponse {
+	if m == nil {
+		return nil
+	}
+	response := &DiscussionReplyResponse{
+		Id:                 m.Id,
+		CreatedAt:          m.CreatedAt,
+		UpdatedAt:          m.UpdatedAt,
+		Body:               m.Body,
+		UpvoteCount:        m.UpvoteCount,
+		IsInstructorAnswer: m.IsInstructorAnswer,
+		ThreadId:           m.ThreadId,
+		ParentId:           m.ParentId,
+	}
+	if m.Author != nil {
+		response.Author = m.Author.ToModelResponse()
+	}
+
+	return response
+}
+
+// Preload preloads all the model's relationships
+func (m *DiscussionReply) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Author")
+	return query
+}
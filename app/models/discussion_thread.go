@@ -0,0 +1,188 @@
+package models
+
+import (
+	"base/core/app/profile"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DiscussionThread represents a question or discussion attached to a course or one of its lessons
+type DiscussionThread struct {
+	Id              uint           `json:"id" gorm:"primarykey"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Title           string         `json:"title"`
+	Body            string         `json:"body"`
+	IsAnswered      bool           `json:"is_answered" gorm:"index"` // Set by staff replies, accepted answers or moderators
+	AcceptedReplyId *uint          `json:"accepted_reply_id,omitempty"`
+	IsLocked        bool           `json:"is_locked"` // Locked threads accept no new replies from students
+	IsPinned        bool           `json:"is_pinned"` // Pinned threads are listed first
+	ReplyCount      int            `json:"reply_count"`
+	UpvoteCount     int            `json:"upvote_count"`
+	LastActivityAt  time.Time      `json:"last_activity_at" gorm:"index"`
+	CourseId        uint           `json:"course_id" gorm:"index"`
+	LessonId        *uint          `json:"lesson_id,omitempty" gorm:"index"`
+	AuthorId        uint           `json:"author_id" gorm:"index"`
+	Course          *Course        `json:"course,omitempty" gorm:"foreignKey:CourseId"`
+	Lesson          *Lesson        `json:"lesson,omitempty" gorm:"foreignKey:LessonId;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Author          *profile.User  `json:"author,omitempty" gorm:"foreignKey:AuthorId"`
+}
+
+// TableName returns the table name for the DiscussionThread model
+func (m *DiscussionThread) TableName() string {
+	return "discussion_threads"
+}
+
+// GetId returns the Id of the model
+func (m *DiscussionThread) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *DiscussionThread) GetModelName() string {
+	return "discussion_thread"
+}
+
+// DiscussionFollow subscribes a user to email notifications about new replies in a thread
+type DiscussionFollow struct {
+	Id        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	ThreadId  uint      `json:"thread_id" gorm:"uniqueIndex:idx_discussion_follow"`
+	UserId    uint      `json:"user_id" gorm:"uniqueIndex:idx_discussion_follow"`
+}
+
+// TableName returns the table name for the DiscussionFollow model
+func (m *DiscussionFollow) TableName() string {
+	return "discussion_follows"
+}
+
+// CreateDiscussionThreadRequest represents the request payload for creating a DiscussionThread
+type CreateDiscussionThreadRequest struct {
+	CourseId uint   `json:"course_id" validate:"required"`
+	LessonId *uint  `json:"lesson_id,omitempty"`
+	Title    string `json:"title" validate:"required,max=255"`
+	Body     string `json:"body" validate:"required"`
+}
+
+// UpdateDiscussionThreadRequest represents the request payload for updating a DiscussionThread
+type UpdateDiscussionThreadRequest struct {
+	Title string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Body  string `json:"body,omitempty"`
+}
+
+// ModerateDiscussionThreadRequest represents the request payload for moderating a DiscussionThread
+type ModerateDiscussionThreadRequest struct {
+	IsLocked   *bool `json:"is_locked,omitempty"`
+	IsPinned   *bool `json:"is_pinned,omitempty"`
+	IsAnswered *bool `json:"is_answered,omitempty"`
+}
+
+// AcceptDiscussionReplyRequest represents the request payload for accepting an answer
+type AcceptDiscussionReplyRequest struct {
+	ReplyId uint `json:"reply_id" validate:"required"`
+}
+
+// DiscussionThreadResponse represents the API response for DiscussionThread
+type DiscussionThreadResponse struct {
+	Id              uint                       `json:"id"`
+	CreatedAt       time.Time                  `json:"created_at"`
+	UpdatedAt       time.Time                  `json:"updated_at"`
+	Title           string                     `json:"title"`
+	Body            string                     `json:"body"`
+	IsAnswered      bool                       `json:"is_answered"`
+	AcceptedReplyId *uint                      `json:"accepted_reply_id,omitempty"`
+	IsLocked        bool                       `json:"is_locked"`
+	IsPinned        bool                       `json:"is_pinned"`
+	ReplyCount      int                        `json:"reply_count"`
+	UpvoteCount     int                        `json:"upvote_count"`
+	LastActivityAt  time.Time                  `json:"last_activity_at"`
+	CourseId        uint                       `json:"course_id"`
+	LessonId        *uint                      `json:"lesson_id,omitempty"`
+	Upvoted         bool                       `json:"upvoted"`   // Whether the current user upvoted
+	Following       bool                       `json:"following"` // Whether the current user follows
+	Author          *profile.UserModelResponse `json:"author,omitempty"`
+	Lesson          *LessonModelResponse       `json:"lesson,omitempty"`
+	Replies         []*DiscussionReplyResponse `json:"replies,omitempty"` // Nested replies, only on detail
+}
+
+// DiscussionThreadListResponse represents the response for list operations (optimized for performance)
+type DiscussionThreadListResponse struct {
+	Id             uint                       `json:"id"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	Title          string                     `json:"title"`
+	IsAnswered     bool                       `json:"is_answered"`
+	IsLocked       bool                       `json:"is_locked"`
+	IsPinned       bool                       `json:"is_pinned"`
+	ReplyCount     int                        `json:"reply_count"`
+	UpvoteCount    int                        `json:"upvote_count"`
+	LastActivityAt time.Time                  `json:"last_activity_at"`
+	CourseId       uint                       `json:"course_id"`
+	LessonId       *uint                      `json:"lesson_id,omitempty"`
+	Author         *profile.UserModelResponse `json:"author,omitempty"`
+}
+
+// ToResponse converts the model to an API response
+func (m *DiscussionThread) ToResponse() *DiscussionThreadResponse {
+	if m == nil {
+		return nil
+	}
+	response := &DiscussionThreadResponse{
+		Id:              m.Id,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+		Title:           m.Title,
+		Body:            m.Body,
+		IsAnswered:      m.IsAnswered,
+		AcceptedReplyId: m.AcceptedReplyId,
+		IsLocked:        m.IsLocked,
+		IsPinned:        m.IsPinned,
+		ReplyCount:      m.ReplyCount,
+		UpvoteCount:     m.UpvoteCount,
+		LastActivityAt:  m.LastActivityAt,
+		CourseId:        m.CourseId,
+		LessonId:        m.LessonId,
+	}
+	if m.Author != nil {
+		response.Author = m.Author.ToModelResponse()
+	}
+	if m.LessonId != nil {
+		response.Lesson = m.Lesson.ToModelResponse()
+	}
+
+	return response
+}
+
+// ToListResponse converts the model to a list response
+func (m *DiscussionThread) ToListResponse() *DiscussionThreadListResponse {
+	if m == nil {
+		return nil
+	}
+	response := &DiscussionThreadListResponse{
+		Id:             m.Id,
+		CreatedAt:      m.CreatedAt,
+		Title:          m.Title,
+		IsAnswered:     m.IsAnswered,
+		IsLocked:       m.IsLocked,
+		IsPinned:       m.IsPinned,
+		ReplyCount:     m.ReplyCount,
+		UpvoteCount:    m.UpvoteCount,
+		LastActivityAt: m.LastActivityAt,
+		CourseId:       m.CourseId,
+		LessonId:       m.LessonId,
+	}
+	if m.Author != nil {
+		response.Author = m.Author.ToModelResponse()
+	}
+
+	return response
+}
+
+// Preload preloads all the model's relationships
+func (m *DiscussionThread) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Author")
+	query = query.Preload("Lesson")
+	return query
+}
@@ -11,17 +11,19 @@ import (
 
 // Enrollment represents a enrollment entity
 type Enrollment struct {
-	Id         uint           `json:"id" gorm:"primarykey"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-	EnrolledAt types.DateTime `json:"enrolled_at"`
-	Progress   int            `json:"progress"`
-	Completed  bool           `json:"completed"`
-	StudentId  uint           `json:"student_id,omitempty"`
-	CourseId   uint           `json:"course_id,omitempty"`
-	Student    *profile.User  `json:"student,omitempty" gorm:"foreignKey:StudentId"`
-	Course     *Course        `json:"course,omitempty" gorm:"foreignKey:CourseId"`
+	Id          uint           `json:"id" gorm:"primarykey"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	EnrolledAt  types.DateTime `json:"enrolled_at"`
+	Progress    int            `json:"progress"`
+	Completed   bool           `json:"completed"`
+	StudentId   uint           `json:"student_id,omitempty"`
+	CourseId    uint           `json:"course_id,omitempty"`
+	CourseRunId *uint          `json:"course_run_id,omitempty" gorm:"index"`
+	Student     *profile.User  `json:"student,omitempty" gorm:"foreignKey:StudentId"`
+	Course      *Course        `json:"course,omitempty" gorm:"foreignKey:CourseId"`
+	CourseRun   *CourseRun     `json:"course_run,omitempty" gorm:"foreignKey:CourseRunId;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 }
 
 // TableName returns the table name for the Enrollment model
@@ -41,20 +43,22 @@ func (m *Enrollment) GetModelName() string {
 
 // CreateEnrollmentRequest represents the request payload for creating a Enrollment
 type CreateEnrollmentRequest struct {
-	StudentId  uint           `json:"student_id,omitempty"`
-	CourseId   uint           `json:"course_id,omitempty"`
-	EnrolledAt types.DateTime `json:"enrolled_at" swaggertype:"string"`
-	Progress   int            `json:"progress"`
-	Completed  bool           `json:"completed"`
+	StudentId   uint           `json:"student_id,omitempty"`
+	CourseId    uint           `json:"course_id,omitempty"`
+	CourseRunId *uint          `json:"course_run_id,omitempty"`
+	EnrolledAt  types.DateTime `json:"enrolled_at" swaggertype:"string"`
+	Progress    int            `json:"progress"`
+	Completed   bool           `json:"completed"`
 }
 
 // UpdateEnrollmentRequest represents the request payload for updating a Enrollment
 type UpdateEnrollmentRequest struct {
-	StudentId  uint           `json:"student_id,omitempty"`
-	CourseId   uint           `json:"course_id,omitempty"`
-	EnrolledAt types.DateTime `json:"enrolled_at,omitempty" swaggertype:"string"`
-	Progress   int            `json:"progress,omitempty"`
-	Completed  *bool          `json:"completed,omitempty"`
+	StudentId   uint           `json:"student_id,omitempty"`
+	CourseId    uint           `json:"course_id,omitempty"`
+	CourseRunId *uint          `json:"course_run_id,omitempty"`
+	EnrolledAt  types.DateTime `json:"enrolled_at,omitempty" swaggertype:"string"`
+	Progress    int            `json:"progress,omitempty"`
+	Completed   *bool          `json:"completed,omitempty"`
 }
 
 // EnrollmentResponse represents the API response for Enrollment
@@ -68,6 +72,7 @@ type EnrollmentResponse struct {
 	Completed  bool                       `json:"completed"`
 	Student    *profile.UserModelResponse `json:"student,omitempty"`
 	Course     *CourseModelResponse       `json:"course,omitempty"`
+	CourseRun  *CourseRunModelResponse    `json:"course_run,omitempty"`
 }
 
 // EnrollmentModelResponse represents a simplified response when this model is part of other entities
@@ -84,13 +89,14 @@ type EnrollmentSelectOption struct {
 
 // EnrollmentListResponse represents the response for list operations (optimized for performance)
 type EnrollmentListResponse struct {
-	Id         uint           `json:"id"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at"`
-	EnrolledAt types.DateTime `json:"enrolled_at"`
-	Progress   int            `json:"progress"`
-	Completed  bool           `json:"completed"`
+	Id          uint           `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at"`
+	EnrolledAt  types.DateTime `json:"enrolled_at"`
+	Progress    int            `json:"progress"`
+	Completed   bool           `json:"completed"`
+	CourseRunId *uint          `json:"course_run_id,omitempty"`
 }
 
 // ToResponse converts the model to an API response
@@ -113,6 +119,9 @@ func (m *Enrollment) ToResponse() *EnrollmentResponse {
 	if m.CourseId != 0 {
 		response.Course = m.Course.ToModelResponse()
 	}
+	if m.CourseRunId != nil {
+		response.CourseRun = m.CourseRun.ToModelResponse()
+	}
 
 	return response
 }
@@ -147,13 +156,14 @@ func (m *Enrollment) ToListResponse() *EnrollmentListResponse {
 		return nil
 	}
 	return &EnrollmentListResponse{
-		Id:         m.Id,
-		CreatedAt:  m.CreatedAt,
-		UpdatedAt:  m.UpdatedAt,
-		DeletedAt:  m.DeletedAt,
-		EnrolledAt: m.EnrolledAt,
-		Progress:   m.Progress,
-		Completed:  m.Completed,
+		Id:          m.Id,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		DeletedAt:   m.DeletedAt,
+		EnrolledAt:  m.EnrolledAt,
+		Progress:    m.Progress,
+		Completed:   m.Completed,
+		CourseRunId: m.CourseRunId,
 	}
 }
 
@@ -162,5 +172,6 @@ func (m *Enrollment) Preload(db *gorm.DB) *gorm.DB {
 	query := db
 	query = query.Preload("Student")
 	query = query.Preload("Course")
+	query = query.Preload("CourseRun")
 	return query
 }
@@ -58,16 +58,17 @@ type UpdateLessonRequest struct {
 
 // LessonResponse represents the API response for Lesson
 type LessonResponse struct {
-	Id          uint                 `json:"id"`
-	CreatedAt   time.Time            `json:"created_at"`
-	UpdatedAt   time.Time            `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt       `json:"deleted_at"`
-	Title       string               `json:"title"`
-	Content     string               `json:"content"`
-	VideoUrl    string               `json:"video_url"`
-	Duration    int                  `json:"duration"`
-	OrderNumber int                  `json:"order_number"`
-	Course      *CourseModelResponse `json:"course,omitempty"`
+	Id          uint                    `json:"id"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt          `json:"deleted_at"`
+	Title       string                  `json:"title"`
+	Content     string                  `json:"content"`
+	VideoUrl    string                  `json:"video_url"`
+	Duration    int                     `json:"duration"`
+	OrderNumber int                     `json:"order_number"`
+	Course      *CourseModelResponse    `json:"course,omitempty"`
+	Playback    *LessonPlaybackResponse `json:"playback,omitempty"` // Current user's progress and resume position
 }
 
 // LessonModelResponse represents a simplified response when this model is part of other entities
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LessonBookmark marks a lesson a student wants to come back to
+type LessonBookmark struct {
+	Id        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"created_at"`
+	UserId    uint      `json:"user_id" gorm:"uniqueIndex:idx_lesson_bookmark_user_lesson"`
+	LessonId  uint      `json:"lesson_id" gorm:"uniqueIndex:idx_lesson_bookmark_user_lesson"`
+	CourseId  uint      `json:"course_id" gorm:"index"` // Copied from the lesson for per-course listing
+	Lesson    *Lesson   `json:"lesson,omitempty" gorm:"foreignKey:LessonId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TableName returns the table name for the LessonBookmark model
+func (m *LessonBookmark) TableName() string {
+	return "lesson_bookmarks"
+}
+
+// GetId returns the Id of the model
+func (m *LessonBookmark) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *LessonBookmark) GetModelName() string {
+	return "lesson_bookmark"
+}
+
+// LessonBookmarkResponse represents the API response for LessonBookmark
+type LessonBookmarkResponse struct {
+	Id        uint                 `json:"id"`
+	CreatedAt time.Time            `json:"created_at"`
+	LessonId  uint                 `json:"lesson_id"`
+	CourseId  uint                 `json:"course_id"`
+	Lesson    *LessonModelResponse `json:"lesson,omitempty"`
+}
+
+// ToResponse converts the model to an API response
+func (m *LessonBookmark) ToResponse() *LessonBookmarkResponse {
+	if m == nil {
+		return nil
+	}
+	return &LessonBookmarkResponse{
+		Id:        m.Id,
+		CreatedAt: m.CreatedAt,
+		LessonId:  m.LessonId,
+		CourseId:  m.CourseId,
+		Lesson:    m.Lesson.ToModelResponse(),
+	}
+}
+
+// Preload preloads all the model's relationships
+func (m *LessonBookmark) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Lesson")
+	return query
+}
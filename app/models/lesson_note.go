@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LessonNote represents a private note a student takes on a lesson, optionally pinned to a video position
+type LessonNote struct {
+	Id             uint           `json:"id" gorm:"primarykey"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Body           string         `json:"body" gorm:"type:text"`     // Markdown
+	VideoTimestamp *int           `json:"video_timestamp,omitempty"` // Seconds into the lesson video
+	UserId         uint           `json:"user_id" gorm:"index:idx_lesson_note_user_lesson"`
+	LessonId       uint           `json:"lesson_id" gorm:"index:idx_lesson_note_user_lesson"`
+	CourseId       uint           `json:"course_id" gorm:"index"` // Copied from the lesson for per-course review
+	Lesson         *Lesson        `json:"lesson,omitempty" gorm:"foreignKey:LessonId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TableName returns the table name for the LessonNote model
+func (m *LessonNote) TableName() string {
+	return "lesson_notes"
+}
+
+// GetId returns the Id of the model
+func (m *LessonNote) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *LessonNote) GetModelName() string {
+	return "lesson_note"
+}
+
+// CreateLessonNoteRequest represents the request payload for creating a LessonNote
+type CreateLessonNoteRequest struct {
+	Body           string `json:"body" validate:"required,max=20000"`
+	VideoTimestamp *int   `json:"video_timestamp,omitempty" validate:"omitempty,min=0"`
+}
+
+// UpdateLessonNoteRequest represents the request payload for updating a LessonNote
+type UpdateLessonNoteRequest struct {
+	Body           string `json:"body,omitempty" validate:"omitempty,max=20000"`
+	VideoTimestamp *int   `json:"video_timestamp,omitempty" validate:"omitempty,min=0"`
+}
+
+// LessonNoteResponse represents the API response for LessonNote
+type LessonNoteResponse struct {
+	Id             uint                 `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Body           string               `json:"body"`
+	VideoTimestamp *int                 `json:"video_timestamp,omitempty"`
+	LessonId       uint                 `json:"lesson_id"`
+	CourseId       uint                 `json:"course_id"`
+	Lesson         *LessonModelResponse `json:"lesson,omitempty"`
+}
+
+// ToResponse converts the model to an API response
+func (m *LessonNote) ToResponse() *LessonNoteResponse {
+	if m == nil {
+		return nil
+	}
+	return &LessonNoteResponse{
+		Id:             m.Id,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+		Body:           m.Body,
+		VideoTimestamp: m.VideoTimestamp,
+		LessonId:       m.LessonId,
+		CourseId:       m.CourseId,
+		Lesson:         m.Lesson.ToModelResponse(),
+	}
+}
+
+// Preload preloads all the model's relationships
+func (m *LessonNote) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Lesson")
+	return query
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PlaybackInterval is a watched span of a video, in seconds
+type PlaybackInterval struct {
+	Start float64 `json:"start" validate:"min=0"`
+	End   float64 `json:"end" validate:"min=0"`
+}
+
+// LessonPlayback tracks how much of a lesson video a student watched and where they stopped
+type LessonPlayback struct {
+	Id               uint               `json:"id" gorm:"primarykey"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt     `json:"deleted_at" gorm:"index"`
+	Position         float64            `json:"position"` // Last reported position in seconds
+	Duration         float64            `json:"duration"` // Video length in seconds
+	WatchedIntervals []PlaybackInterval `json:"watched_intervals" gorm:"serializer:json;type:text"`
+	WatchedSeconds   float64            `json:"watched_seconds"`
+	WatchedPercent   float64            `json:"watched_percent"`
+	CompletedAt      *time.Time         `json:"completed_at,omitempty"` // When the completion threshold was reached
+	UserId           uint               `json:"user_id" gorm:"uniqueIndex:idx_lesson_playback_user_lesson"`
+	LessonId         uint               `json:"lesson_id" gorm:"uniqueIndex:idx_lesson_playback_user_lesson"`
+	EnrollmentId     uint               `json:"enrollment_id" gorm:"index"`
+	Lesson           *Lesson            `json:"lesson,omitempty" gorm:"foreignKey:LessonId;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TableName returns the table name for the LessonPlayback model
+func (m *LessonPlayback) TableName() string {
+	return "lesson_playbacks"
+}
+
+// GetId returns the Id of the model
+func (m *LessonPlayback) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *LessonPlayback) GetModelName() string {
+	return "lesson_playback"
+}
+
+// AddIntervals merges the new spans into the watched intervals and recomputes the totals.
+// All spans, stored ones included, are clamped to the video length as it may have
+// changed; overlapping and touching spans are joined so rewatching a part never
+// counts twice.
+func (m *LessonPlayback) AddIntervals(intervals []PlaybackInterval) {
+	all := make([]PlaybackInterval, 0, len(m.WatchedIntervals)+len(intervals))
+	for _, interval := range append(append([]PlaybackInterval{}, m.WatchedIntervals...), intervals...) {
+		if interval.Start < 0 {
+			interval.Start = 0
+		}
+		if m.Duration > 0 && interval.End > m.Duration {
+			interval.End = m.Duration
+		}
+		if interval.End > interval.Start {
+			all = append(all, interval)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+
+	merged := make([]PlaybackInterval, 0, len(all))
+	for _, interval := range all {
+		last := len(merged) - 1
+		if last >= 0 && interval.Start <= merged[last].End {
+			if interval.End > merged[last].End {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+
+	m.WatchedIntervals = merged
+	m.WatchedSeconds = 0
+	for _, interval := range merged {
+		m.WatchedSeconds += interval.End - interval.Start
+	}
+	m.WatchedPercent = 0
+	if m.Duration > 0 {
+		m.WatchedPercent = min(100, m.WatchedSeconds/m.Duration*100)
+	}
+}
+
+// ResumePosition is where playback should continue; finished videos start over
+func (m *LessonPlayback) ResumePosition() float64 {
+	if m.Duration > 0 && m.Position >= m.Duration-5 {
+		return 0
+	}
+	return m.Position
+}
+
+// PlaybackHeartbeatRequest represents a periodic report from the video player
+type PlaybackHeartbeatRequest struct {
+	Position  float64            `json:"position" validate:"min=0"`
+	Duration  float64            `json:"duration,omitempty" validate:"omitempty,min=0"` // Video length reported by the player; refines Lesson.Duration, never shortens the stored length
+	Intervals []PlaybackInterval `json:"intervals" validate:"max=100,dive"`             // Spans watched since the last heartbeat
+}
+
+// LessonPlaybackResponse represents the API response for LessonPlayback
+type LessonPlaybackResponse struct {
+	LessonId         uint               `json:"lesson_id"`
+	Position         float64            `json:"position"`
+	ResumePosition   float64            `json:"resume_position"`
+	Duration         float64            `json:"duration"`
+	WatchedIntervals []PlaybackInterval `json:"watched_intervals"`
+	WatchedSeconds   float64            `json:"watched_seconds"`
+	WatchedPercent   float64            `json:"watched_percent"`
+	Completed        bool               `json:"completed"`
+	CompletedAt      *time.Time         `json:"completed_at,omitempty"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// ToResponse converts the model to an API response
+func (m *LessonPlayback) ToResponse() *LessonPlaybackResponse {
+	if m == nil {
+		return nil
+	}
+	intervals := m.WatchedIntervals
+	if intervals == nil {
+		intervals = []PlaybackInterval{}
+	}
+	return &LessonPlaybackResponse{
+		LessonId:         m.LessonId,
+		Position:         m.Position,
+		ResumePosition:   m.ResumePosition(),
+		Duration:         m.Duration,
+		WatchedIntervals: intervals,
+		WatchedSeconds:   m.WatchedSeconds,
+		WatchedPercent:   m.WatchedPercent,
+		Completed:        m.CompletedAt != nil,
+		CompletedAt:      m.CompletedAt,
+		UpdatedAt:        m.UpdatedAt,
+	}
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"base/core/types"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LiveSession represents a scheduled live workshop of a course or cohort
+type LiveSession struct {
+	Id                uint           `json:"id" gorm:"primarykey"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	Title             string         `json:"title"`
+	Description       string         `json:"description"`
+	StartsAt          types.DateTime `json:"starts_at" gorm:"index"`
+	Duration          int            `json:"duration"` // Duration in minutes
+	Timezone          string         `json:"timezone"` // IANA name, e.g. Europe/Tirane
+	JoinUrl           string         `json:"join_url"`
+	Reminder24hSentAt *time.Time     `json:"-" gorm:"column:reminder_24h_sent_at"`
+	Reminder1hSentAt  *time.Time     `json:"-" gorm:"column:reminder_1h_sent_at"`
+	CourseId          uint           `json:"course_id,omitempty" gorm:"index"`
+	CourseRunId       *uint          `json:"course_run_id,omitempty" gorm:"index"`
+	Course            *Course        `json:"course,omitempty" gorm:"foreignKey:CourseId"`
+	CourseRun         *CourseRun     `json:"course_run,omitempty" gorm:"foreignKey:CourseRunId;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+// TableName returns the table name for the LiveSession model
+func (m *LiveSession) TableName() string {
+	return "live_sessions"
+}
+
+// GetId returns the Id of the model
+func (m *LiveSession) GetId() uint {
+	return m.Id
+}
+
+// GetModelName returns the model name
+func (m *LiveSession) GetModelName() string {
+	return "live_session"
+}
+
+// EndsAt returns the end time of the session
+func (m *LiveSession) EndsAt() time.Time {
+	return m.StartsAt.Time.Add(time.Duration(m.Duration) * time.Minute)
+}
+
+// Location returns the session's time zone, falling back to UTC
+func (m *LiveSession) Location() *time.Location {
+	if m.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(m.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// CreateLiveSessionRequest represents the request payload for creating a LiveSession
+type CreateLiveSessionRequest struct {
+	CourseId    uint           `json:"course_id" validate:"required"`
+	CourseRunId *uint          `json:"course_run_id,omitempty"`
+	Title       string         `json:"title" validate:"required"`
+	Description string         `json:"description"`
+	StartsAt    types.DateTime `json:"starts_at" swaggertype:"string"`
+	Duration    int            `json:"duration" validate:"min=1"`
+	Timezone    string         `json:"timezone"`
+	JoinUrl     string         `json:"join_url" validate:"omitempty,url"`
+}
+
+// UpdateLiveSessionRequest represents the request payload for updating a LiveSession
+type UpdateLiveSessionRequest struct {
+	CourseId    uint           `json:"course_id,omitempty"`
+	CourseRunId *uint          `json:"course_run_id,omitempty"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	StartsAt    types.DateTime `json:"starts_at,omitempty" swaggertype:"string"`
+	Duration    int            `json:"duration,omitempty"`
+	Timezone    string         `json:"timezone,omitempty"`
+	JoinUrl     string         `json:"join_url,omitempty"`
+}
+
+// LiveSessionResponse represents the API response for LiveSession
+type LiveSessionResponse struct {
+	Id          uint                    `json:"id"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt          `json:"deleted_at"`
+	Title       string                  `json:"title"`
+	Description string                  `json:"description"`
+	StartsAt    types.DateTime          `json:"starts_at"`
+	Duration    int                     `json:"duration"`
+	Timezone    string                  `json:"timezone"`
+	JoinUrl     string                  `json:"join_url"`
+	Course      *CourseModelResponse    `json:"course,omitempty"`
+	CourseRun   *CourseRunModelResponse `json:"course_run,omitempty"`
+}
+
+// LiveSessionModelResponse represents a simplified response when this model is part of other entities
+type LiveSessionModelResponse struct {
+	Id    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+// LiveSessionSelectOption represents a simplified response for select boxes and dropdowns
+type LiveSessionSelectOption struct {
+	Id   uint   `json:"id"`
+	Name string `json:"name"` // From Title field
+}
+
+// LiveSessionListResponse represents the response for list operations (optimized for performance)
+type LiveSessionListResponse struct {
+	Id          uint           `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at"`
+	Title       string         `json:"title"`
+	StartsAt    types.DateTime `json:"starts_at"`
+	Duration    int            `json:"duration"`
+	Timezone    string         `json:"timezone"`
+	JoinUrl     string         `json:"join_url"`
+	CourseId    uint           `json:"course_id"`
+	CourseRunId *uint          `json:"course_run_id,omitempty"`
+}
+
+// ToResponse converts the model to an API response
+func (m *LiveSession) ToResponse() *LiveSessionResponse {
+	if m == nil {
+		return nil
+	}
+	response := &LiveSessionResponse{
+		Id:          m.Id,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		DeletedAt:   m.DeletedAt,
+		Title:       m.Title,
+		Description: m.Description,
+		StartsAt:    m.StartsAt,
+		Duration:    m.Duration,
+		Timezone:    m.Timezone,
+		JoinUrl:     m.JoinUrl,
+	}
+	if m.CourseId != 0 {
+		response.Course = m.Course.ToModelResponse()
+	}
+	if m.CourseRunId != nil {
+		response.CourseRun = m.CourseRun.ToModelResponse()
+	}
+
+	return response
+}
+
+// ToModelResponse converts the model to a simplified response for when it's part of other entities
+func (m *LiveSession) ToModelResponse() *LiveSessionModelResponse {
+	if m == nil {
+		return nil
+	}
+	return &LiveSessionModelResponse{
+		Id:    m.Id,
+		Title: m.Title,
+	}
+}
+
+// ToSelectOption converts the model to a select option for dropdowns
+func (m *LiveSession) ToSelectOption() *LiveSessionSelectOption {
+	if m == nil {
+		return nil
+	}
+	displayName := m.Title
+
+	return &LiveSessionSelectOption{
+		Id:   m.Id,
+		Name: displayName,
+	}
+}
+
+// ToListResponse converts the model to a list response (without preloaded relationships for fast listing)
+func (m *LiveSession) ToListResponse() *LiveSessionListResponse {
+	if m == nil {
+		return nil
+	}
+	return &LiveSessionListResponse{
+		Id:          m.Id,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		DeletedAt:   m.DeletedAt,
+		Title:       m.Title,
+		StartsAt:    m.StartsAt,
+		Duration:    m.Duration,
+		Timezone:    m.Timezone,
+		JoinUrl:     m.JoinUrl,
+		CourseId:    m.CourseId,
+		CourseRunId: m.CourseRunId,
+	}
+}
+
+// Preload preloads all the model's relationships
+func (m *LiveSession) Preload(db *gorm.DB) *gorm.DB {
+	query := db
+	query = query.Preload("Course")
+	query = query.Preload("CourseRun")
+	return query
+}
@@ -6,27 +6,30 @@ import (
 	"strings"
 
 	"base/app/models"
+	"base/core/app/verification"
 	"base/core/router"
 	"base/core/storage"
 	"base/core/types"
 )
 
 type PaymentController struct {
-	Service *PaymentService
-	Storage *storage.ActiveStorage
+	Service      *PaymentService
+	Storage      *storage.ActiveStorage
+	Verification *verification.VerificationService
 }
 
-func NewPaymentController(service *PaymentService, storage *storage.ActiveStorage) *PaymentController {
+func NewPaymentController(service *PaymentService, storage *storage.ActiveStorage, verification *verification.VerificationService) *PaymentController {
 	return &PaymentController{
-		Service: service,
-		Storage: storage,
+		Service:      service,
+		Storage:      storage,
+		Verification: verification,
 	}
 }
 
 func (c *PaymentController) Routes(router *router.RouterGroup) {
 	// Main CRUD endpoints - specific routes MUST come before parameterized routes
-	router.GET("/payments", c.List)          // Paginated list
-	router.POST("/payments", c.Create)       // Create
+	router.GET("/payments", c.List) // Paginated list
+	router.POST("/payments", c.Create, verification.Require(c.Verification, verification.ActionPurchase))
 	router.GET("/payments/all", c.ListAll)   // Unpaginated list - MUST be before /:id
 	router.GET("/payments/:id", c.Get)       // Get by ID - MUST be after /all
 	router.PUT("/payments/:id", c.Update)    // Update
@@ -37,7 +40,7 @@ func (c *PaymentController) Routes(router *router.RouterGroup) {
 
 // CreatePayment godoc
 // @Summary Create a new Payment
-// @Description Create a new Payment with the input payload
+// @Description Create a new Payment with the input payload. Users must have verified their email when EMAIL_VERIFICATION_REQUIRED_FOR includes "purchase".
 // @Tags App/Payment
 // @Security ApiKeyAuth
 // @Security BearerAuth
@@ -46,6 +49,7 @@ func (c *PaymentController) Routes(router *router.RouterGroup) {
 // @Param payments body models.CreatePaymentRequest true "Create Payment request"
 // @Success 201 {object} models.PaymentResponse
 // @Failure 400 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
 // @Failure 500 {object} types.ErrorResponse
 // @Router /payments [post]
 func (c *PaymentController) Create(ctx *router.Context) error {
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+
+	"base/core/storage"
+)
+
+// Command is a maintenance task run from the command line instead of the server,
+// e.g. `go run . storage:dedupe`
+type Command struct {
+	Description string
+	Run         func(ctx context.Context, app *App, args []string) error
+}
+
+// commands lists the available maintenance commands by name
+var commands = map[string]Command{
+	"storage:dedupe": {
+		Description: "Hash stored files and collapse duplicates into shared blobs",
+		Run:         runStorageDedupe,
+	},
+	"storage:gc": {
+		Description: "Remove attachments of deleted records and files without an attachment (--dry-run, --retention)",
+		Run:         runStorageGC,
+	},
+	"storage:usage": {
+		Description: "Record the owners of older files and print the top storage consumers (--limit)",
+		Run:         runStorageUsage,
+	},
+	"storage:migrate": {
+		Description: "Copy files from STORAGE_FALLBACK_PROVIDER to STORAGE_PROVIDER (--dry-run to preview)",
+		Run:         runStorageMigrate,
+	},
+	"storage:conformance": {
+		Description: "Check that the storage provider behaves as expected (--fetch, --fallback)",
+		Run:         runStorageConformance,
+	},
+}
+
+// RunCommand boots the application without the HTTP server, so modules register
+// their attachment configs, and runs a maintenance command
+func (app *App) RunCommand(name string, args []string) error {
+	command, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n\n%s", name, commandUsage())
+	}
+
+	app.
+		loadEnvironment().
+		initConfig().
+		initLogger().
+		initDatabase().
+		initInfrastructure().
+		initRouter().
+		autoDiscoverModules()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return command.Run(ctx, app, args)
+}
+
+// commandUsage lists the available commands
+func commandUsage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	usage := "Available commands:\n"
+	for _, name := range names {
+		usage += fmt.Sprintf("  %-22s %s\n", name, commands[name].Description)
+	}
+	return usage
+}
+
+// printReport writes a command report as indented JSON
+func printReport(report any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func runStorageDedupe(ctx context.Context, app *App, args []string) error {
+	report, err := app.storage.Dedupe(ctx)
+	if report != nil {
+		if printErr := printReport(report); printErr != nil {
+			return printErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d attachments could not be deduplicated", len(report.Errors))
+	}
+	return nil
+}
+
+func runStorageMigrate(ctx context.Context, app *App, args []string) error {
+	flags := flag.NewFlagSet("storage:migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report the files to copy without copying them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := app.storage.Migrate(ctx, *dryRun)
+	if report != nil {
+		if printErr := printReport(report); printErr != nil {
+			return printErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d attachments could not be migrated; run the command again to retry them", len(report.Errors))
+	}
+	return nil
+}
+
+func runStorageGC(ctx context.Context, app *App, args []string) error {
+	flags := flag.NewFlagSet("storage:gc", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be removed without removing it")
+	retention := flags.Duration("retention", storage.DefaultGCRetention, "keep files of deleted records and unreferenced files younger than this")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := app.storage.CollectGarbage(ctx, storage.GCOptions{Retention: *retention, DryRun: *dryRun})
+	if report != nil {
+		if printErr := printReport(report); printErr != nil {
+			return printErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d removals failed", len(report.Errors))
+	}
+	return nil
+}
+
+func runStorageUsage(ctx context.Context, app *App, args []string) error {
+	flags := flag.NewFlagSet("storage:usage", flag.ContinueOnError)
+	limit := flags.Int("limit", 10, "consumers to list per type")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filled, err := app.storage.BackfillOwners(ctx)
+	if err != nil {
+		return err
+	}
+
+	consumers := make(map[string][]*storage.Usage)
+	for _, subjectType := range append([]string{storage.QuotaUser}, app.storage.OwnerTypes()...) {
+		usages, err := app.storage.TopConsumers(subjectType, *limit)
+		if err != nil {
+			return err
+		}
+		consumers[subjectType] = usages
+	}
+	return printReport(map[string]any{"owners_recorded": filled, "top_consumers": consumers})
+}
+
+func runStorageConformance(ctx context.Context, app *App, args []string) error {
+	flags := flag.NewFlagSet("storage:conformance", flag.ContinueOnError)
+	fetch := flags.Bool("fetch", false, "download public and signed URLs over HTTP; local signed URLs need the server running")
+	fallback := flags.Bool("fallback", false, "check STORAGE_FALLBACK_PROVIDER instead of STORAGE_PROVIDER")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := app.storage.RunConformance(ctx, *fallback, storage.ConformanceOptions{FetchURLs: *fetch})
+	if report != nil {
+		if printErr := printReport(report); printErr != nil {
+			return printErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", report.Failed, report.Failed+report.Passed)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package apikeys
+
+import (
+	"base/core/logger"
+	"base/core/router"
+	"base/core/types"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type APIKeyController struct {
+	service *APIKeyService
+	logger  logger.Logger
+}
+
+func NewAPIKeyController(service *APIKeyService, logger logger.Logger) *APIKeyController {
+	return &APIKeyController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (c *APIKeyController) Routes(router *router.RouterGroup) {
+	router.GET("", c.List)
+	router.POST("", c.Create)
+	router.GET("/:id", c.Get)
+	router.POST("/:id/rotate", c.Rotate)
+	router.DELETE("/:id", c.Revoke)
+}
+
+// @Summary List API Keys
+// @Description List the API keys of the current user, or of another user for administrators
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/APIKeys
+// @Produce json
+// @Param user_id query int false "Owner of the keys (administrators only)"
+// @Success 200 {array} APIKey
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /api-keys [get]
+func (c *APIKeyController) List(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	var ownerId uint
+	if value := ctx.Query("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user Id"})
+		}
+		ownerId = uint(id)
+	}
+
+	keys, err := c.service.List(userId, ownerId)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, keys)
+}
+
+// @Summary Create API Key
+// @Description Issue an API key acting for the current user, limited to its scopes: permissions as resource:action, the same scopes OAuth clients use (see /oauth2/scopes). Requests with the key in X-Api-Key need no bearer token. The key is only shown once.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/APIKeys
+// @Accept json
+// @Produce json
+// @Param body body CreateRequest true "API key"
+// @Success 201 {object} CreatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /api-keys [post]
+func (c *APIKeyController) Create(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	var req CreateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	created, err := c.service.Create(userId, &req)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+// @Summary Get API Key
+// @Description Get an API key of the current user, or any key for administrators
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/APIKeys
+// @Produce json
+// @Param id path int true "API key Id"
+// @Success 200 {object} APIKey
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /api-keys/{id} [get]
+func (c *APIKeyController) Get(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid API key Id"})
+	}
+
+	key, err := c.service.Get(userId, uint(id))
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, key)
+}
+
+// @Summary Rotate API Key
+// @Description Issue a replacement for an API key with the same name, scopes and expiry. The old key keeps working for the grace period, or stops right away without one. The new key is only shown once.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/APIKeys
+// @Accept json
+// @Produce json
+// @Param id path int true "API key Id"
+// @Param body body RotateRequest false "Grace period"
+// @Success 201 {object} CreatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /api-keys/{id}/rotate [post]
+func (c *APIKeyController) Rotate(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid API key Id"})
+	}
+
+	var req RotateRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		}
+	}
+	var gracePeriod time.Duration
+	if req.GracePeriod != "" {
+		gracePeriod, err = time.ParseDuration(req.GracePeriod)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: ErrInvalidGracePeriod.Error()})
+		}
+	}
+
+	created, err := c.service.Rotate(userId, uint(id), gracePeriod)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+// @Summary Revoke API Key
+// @Description Revoke an API key so it stops working
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/APIKeys
+// @Produce json
+// @Param id path int true "API key Id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /api-keys/{id} [delete]
+func (c *APIKeyController) Revoke(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid API key Id"})
+	}
+
+	if err := c.service.Revoke(userId, uint(id)); err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "API key revoked"})
+}
+
+// currentUser returns the logged in user, writing an error response when there is
+// none. Keys are managed with a login only, so an API key cannot mint broader keys.
+func (c *APIKeyController) currentUser(ctx *router.Context) (uint, bool) {
+	if _, viaKey := ctx.Get("api_key"); viaKey && ctx.Header("Authorization") == "" {
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "API keys cannot manage API keys; log in instead"})
+		return 0, false
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+		return 0, false
+	}
+	return userId, true
+}
+
+// error maps service errors to responses
+func (c *APIKeyController) error(ctx *router.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidScope), errors.Is(err, ErrInvalidExpiry), errors.Is(err, ErrInvalidGracePeriod):
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrNotAdmin):
+		return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrKeyNotFound):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrKeyRevoked), errors.Is(err, ErrKeyRotated):
+		return ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: err.Error()})
+	default:
+		c.logger.Error("API key request failed",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Internal server error"})
+	}
+}
@@ -0,0 +1,47 @@
+package apikeys
+
+import "time"
+
+// APIKey is a key issued to a user for programmatic access, stored as the SHA-256
+// of the key. It acts for its owner, limited to its scopes.
+type APIKey struct {
+	Id           uint       `json:"id" gorm:"primaryKey"`
+	UserId       uint       `json:"user_id" gorm:"index"` // Owner the key acts for
+	Name         string     `json:"name" gorm:"size:100"`
+	Prefix       string     `json:"prefix" gorm:"size:32;uniqueIndex"` // Start of the key, to tell keys apart
+	KeyHash      string     `json:"-" gorm:"size:64"`
+	Scopes       []string   `json:"scopes" gorm:"serializer:json;type:text"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	ReplacedById *uint      `json:"replaced_by_id,omitempty"` // Key issued when this one was rotated
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Active reports whether the key is neither revoked nor expired
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreateRequest describes a new API key
+type CreateRequest struct {
+	Name      string     `json:"name" binding:"required,max=100" example:"Course sync"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1" example:"course:read,enrollment:list"`
+	ExpiresAt *time.Time `json:"expires_at"` // Never expires when empty
+}
+
+// RotateRequest sets how long a rotated key keeps working next to its replacement
+type RotateRequest struct {
+	GracePeriod string `json:"grace_period" example:"24h"` // Revoked right away when empty
+}
+
+// CreatedResponse holds a new API key; the key itself is only shown once
+type CreatedResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
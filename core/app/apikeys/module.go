@@ -0,0 +1,55 @@
+package apikeys
+
+import (
+	"base/core/logger"
+	"base/core/module"
+	"base/core/router"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyModule struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *APIKeyController
+	Service    *APIKeyService
+	Logger     logger.Logger
+}
+
+func NewAPIKeyModule(db *gorm.DB, router *router.RouterGroup, logger logger.Logger) *APIKeyModule {
+	service := NewAPIKeyService(db)
+	controller := NewAPIKeyController(service, logger)
+
+	return &APIKeyModule{
+		DB:         db,
+		Controller: controller,
+		Service:    service,
+		Logger:     logger,
+	}
+}
+
+// Init makes the API key middleware resolve issued keys through the service
+func (m *APIKeyModule) Init() error {
+	types.SetAPIKeyResolver(m.Service.Resolve)
+	return nil
+}
+
+func (m *APIKeyModule) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router.Group("/api-keys"))
+}
+
+func (m *APIKeyModule) Migrate() error {
+	err := m.DB.AutoMigrate(&APIKey{})
+	if err != nil {
+		m.Logger.Error("Migration failed", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (m *APIKeyModule) GetModels() []any {
+	return []any{
+		&APIKey{},
+	}
+}
@@ -0,0 +1,310 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"base/core/app/authorization"
+	"base/core/app/profile"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrKeyNotFound        = errors.New("API key not found")
+	ErrKeyRevoked         = errors.New("API key already revoked")
+	ErrKeyRotated         = errors.New("API key already rotated")
+	ErrNotAdmin           = errors.New("administrator role required")
+	ErrInvalidScope       = errors.New("scopes must name permissions as resource:action, as OAuth scopes do; see /oauth2/scopes")
+	ErrInvalidExpiry      = errors.New("expiry must be in the future")
+	ErrInvalidGracePeriod = errors.New("grace period must be a duration of at most 7 days, such as 24h")
+)
+
+const (
+	keyPrefix       = "base"
+	maxGracePeriod  = 7 * 24 * time.Hour
+	lastUsedEvery   = time.Minute // Last use is only recorded this often, not on every request
+	secretBytes     = 32
+	prefixRandBytes = 4
+)
+
+// APIKeyService issues API keys and resolves them to the users they act for
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// Create issues a key to a user. The key is only returned here.
+func (s *APIKeyService) Create(userId uint, req *CreateRequest) (*CreatedResponse, error) {
+	scopes := normalizeScopes(req.Scopes)
+	if err := s.validateScopes(scopes); err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	var created *CreatedResponse
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = s.issue(tx, userId, req.Name, scopes, req.ExpiresAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// List returns the keys of the owner, newest first. Only administrators may list
+// the keys of another user.
+func (s *APIKeyService) List(userId, ownerId uint) ([]APIKey, error) {
+	if ownerId == 0 {
+		ownerId = userId
+	}
+	if ownerId != userId {
+		admin, err := s.isAdmin(userId)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, ErrNotAdmin
+		}
+	}
+
+	keys := []APIKey{}
+	if err := s.db.Where("user_id = ?", ownerId).Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return keys, nil
+}
+
+// Get returns a key of a user; administrators may see any key
+func (s *APIKeyService) Get(userId, id uint) (*APIKey, error) {
+	return s.find(userId, id)
+}
+
+// Rotate issues a replacement with the same name, scopes and expiry. The old key
+// keeps working for the grace period, or is revoked right away without one.
+func (s *APIKeyService) Rotate(userId, id uint, gracePeriod time.Duration) (*CreatedResponse, error) {
+	if gracePeriod < 0 || gracePeriod > maxGracePeriod {
+		return nil, ErrInvalidGracePeriod
+	}
+	key, err := s.find(userId, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if key.ReplacedById != nil {
+		return nil, ErrKeyRotated
+	}
+	if !key.Active(now) {
+		return nil, ErrKeyRevoked
+	}
+
+	var created *CreatedResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		created, err = s.issue(tx, key.UserId, key.Name, key.Scopes, key.ExpiresAt)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]any{"replaced_by_id": created.Id}
+		if gracePeriod == 0 {
+			updates["revoked_at"] = now
+		} else if until := now.Add(gracePeriod); key.ExpiresAt == nil || until.Before(*key.ExpiresAt) {
+			updates["expires_at"] = until
+		}
+		// Only one of two concurrent rotations may replace the key
+		result := tx.Model(&APIKey{}).Where("id = ? AND replaced_by_id IS NULL AND revoked_at IS NULL", key.Id).Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate API key: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrKeyRotated
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Revoke stops a key from working
+func (s *APIKeyService) Revoke(userId, id uint) error {
+	key, err := s.find(userId, id)
+	if err != nil {
+		return err
+	}
+	result := s.db.Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", key.Id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrKeyRevoked
+	}
+	return nil
+}
+
+// Resolve returns the identity of an active key whose owner still exists; it is
+// what the API key middleware checks keys with, through types.SetAPIKeyResolver
+func (s *APIKeyService) Resolve(rawKey string) (*types.APIKeyIdentity, error) {
+	cut := strings.LastIndex(rawKey, "_")
+	if cut <= 0 {
+		return nil, types.ErrInvalidAPIKey
+	}
+
+	var key APIKey
+	if err := s.db.Where("prefix = ?", rawKey[:cut]).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, types.ErrInvalidAPIKey
+	}
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, types.ErrInvalidAPIKey
+	}
+
+	var owners int64
+	if err := s.db.Model(&profile.User{}).Where("id = ?", key.UserId).Count(&owners).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if owners == 0 {
+		return nil, types.ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedEvery {
+		if err := s.db.Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+
+	return &types.APIKeyIdentity{KeyId: key.Id, UserId: key.UserId, Scopes: key.Scopes}, nil
+}
+
+// issue stores a new random key as prefix_secret, keeping only its hash
+func (s *APIKeyService) issue(tx *gorm.DB, userId uint, name string, scopes []string, expiresAt *time.Time) (*CreatedResponse, error) {
+	prefix, err := randomHex(prefixRandBytes)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return nil, err
+	}
+	prefix = keyPrefix + "_" + prefix
+	rawKey := prefix + "_" + secret
+
+	key := APIKey{
+		UserId:    userId,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hashKey(rawKey),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := tx.Create(&key).Error; err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return &CreatedResponse{APIKey: key, Key: rawKey}, nil
+}
+
+// find loads a key owned by a user, or any key for administrators
+func (s *APIKeyService) find(userId, id uint) (*APIKey, error) {
+	var key APIKey
+	if err := s.db.First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if key.UserId == userId {
+		return &key, nil
+	}
+
+	admin, err := s.isAdmin(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		// Other users' keys are reported as missing rather than forbidden
+		return nil, ErrKeyNotFound
+	}
+	return &key, nil
+}
+
+func (s *APIKeyService) isAdmin(userId uint) (bool, error) {
+	var user profile.User
+	if err := s.db.First(&user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	return user.IsAdmin(), nil
+}
+
+// validateScopes checks that each scope names an authorization permission, the
+// same resource:action scopes OAuth clients ask for
+func (s *APIKeyService) validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		resource, action, ok := strings.Cut(scope, ":")
+		var count int64
+		if ok {
+			if err := s.db.Model(&authorization.Permission{}).
+				Where("resource_type = ? AND action = ?", resource, action).
+				Count(&count).Error; err != nil {
+				return fmt.Errorf("database error: %w", err)
+			}
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// normalizeScopes trims, lowercases and deduplicates scopes
+func normalizeScopes(scopes []string) []string {
+	normalized := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized
+}
+
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
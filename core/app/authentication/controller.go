@@ -1,11 +1,18 @@
 package authentication
 
 import (
+	"base/core/app/sessions"
+	"base/core/app/verification"
 	"base/core/email"
 	"base/core/logger"
+	"base/core/password"
 	"base/core/router"
+	"base/core/types"
 	"errors"
+	"html"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"go.uber.org/zap"
@@ -29,12 +36,26 @@ func (c *AuthController) Routes(router *router.RouterGroup) {
 	router.POST("/register", c.Register)
 	router.POST("/login", c.Login)
 	router.POST("/logout", c.Logout)
+	router.POST("/refresh", c.Refresh)
+	router.POST("/otp/send", c.SendOTP)
+	router.POST("/otp/verify", c.VerifyOTP)
+	router.POST("/2fa/setup", c.SetupTwoFactor)
+	router.POST("/2fa/enable", c.EnableTwoFactor)
+	router.POST("/2fa/disable", c.DisableTwoFactor)
+	router.POST("/2fa/recovery-codes", c.RegenerateRecoveryCodes)
+	router.POST("/2fa/challenge/verify", c.VerifyTwoFactor)
+	router.POST("/2fa/challenge/setup", c.SetupTwoFactorChallenge)
+	router.POST("/2fa/challenge/enable", c.EnableTwoFactorChallenge)
 	router.POST("/forgot-password", c.ForgotPassword)
 	router.POST("/reset-password", c.ResetPassword)
+	router.GET("/unlock", c.UnlockLink)
+	router.POST("/unlock", c.Unlock)
+	router.GET("/admin/login-attempts", c.LoginAttempts)
+	router.POST("/admin/users/:id/unlock", c.UnlockUser)
 }
 
 // @Summary Register
-// @Description Register user
+// @Description Register user. The password must follow the password policy.
 // @Security ApiKeyAuth
 // @Tags Core/Auth
 // @Accept json
@@ -54,7 +75,7 @@ func (c *AuthController) Register(ctx *router.Context) error {
 		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	user, err := c.service.Register(&req)
+	user, err := c.service.Register(&req, clientOf(ctx))
 	if err != nil {
 		// Log the underlying service error to help debug 500s
 		c.logger.Error("Failed to register user",
@@ -64,6 +85,9 @@ func (c *AuthController) Register(ctx *router.Context) error {
 		if strings.Contains(strings.ToLower(err.Error()), "user already exists") {
 			status = http.StatusConflict // 409
 		}
+		if errors.Is(err, password.ErrWeakPassword) {
+			status = http.StatusBadRequest
+		}
 		return ctx.JSON(status, ErrorResponse{Error: err.Error()})
 	}
 
@@ -90,7 +114,8 @@ func (c *AuthController) Register(ctx *router.Context) error {
 }
 
 // @Summary Login
-// @Description Login user
+// @Description Login user. With two-factor authentication the response carries a challenge token instead of tokens; complete the login at /auth/2fa/challenge/verify, or enroll at /auth/2fa/challenge/setup when the challenge is "two_factor_setup".
+// @Description After failed logins further attempts are delayed (429 with Retry-After) and accounts get locked. Suspicious logins are refused with 428 and a proof-of-work challenge: find a nonce such that SHA-256(challenge + nonce) starts with difficulty zero bits, and send both as proofOfWork.
 // @Security ApiKeyAuth
 // @Tags Core/Auth
 // @Accept json
@@ -100,6 +125,8 @@ func (c *AuthController) Register(ctx *router.Context) error {
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 428 {object} LoginBlockedResponse
+// @Failure 429 {object} LoginBlockedResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/login [post]
 func (c *AuthController) Login(ctx *router.Context) error {
@@ -108,8 +135,15 @@ func (c *AuthController) Login(ctx *router.Context) error {
 		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 	}
 
-	response, err := c.service.Login(&req)
+	response, err := c.service.Login(&req, clientOf(ctx))
 	if err != nil {
+		var blocked *LoginBlockedError
+		if errors.As(err, &blocked) {
+			return c.loginBlocked(ctx, blocked)
+		}
+		if errors.Is(err, verification.ErrEmailNotVerified) {
+			return ctx.JSON(http.StatusForbidden, ErrorResponse{Error: "Verify your email address to log in"})
+		}
 		if strings.Contains(err.Error(), "access_denied") {
 			// Return both the response and error when user is not an author
 			return ctx.JSON(http.StatusForbidden, map[string]any{
@@ -126,21 +160,177 @@ func (c *AuthController) Login(ctx *router.Context) error {
 	return ctx.JSON(http.StatusOK, response)
 }
 
+// loginBlocked responds to a login refused before its password was checked
+func (c *AuthController) loginBlocked(ctx *router.Context, blocked *LoginBlockedError) error {
+	c.logger.Warn("Login blocked",
+		logger.String("reason", blocked.Error()),
+		logger.String("ip", ctx.ClientIP()))
+
+	response := LoginBlockedResponse{Error: blocked.Error(), ProofOfWork: blocked.ProofOfWork}
+	if blocked.ProofOfWork != nil {
+		return ctx.JSON(http.StatusPreconditionRequired, response)
+	}
+	response.RetryAfter = int64(math.Ceil(blocked.RetryAfter.Seconds()))
+	ctx.SetHeader("Retry-After", strconv.FormatInt(response.RetryAfter, 10))
+	return ctx.JSON(http.StatusTooManyRequests, response)
+}
+
+// @Summary Send Login Code
+// @Description Email a 6-digit code for passwordless login. The response is the same whether or not the email is registered; a code is sent at most once a minute per account.
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body SendOTPRequest true "Send OTP Request"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/otp/send [post]
+func (c *AuthController) SendOTP(ctx *router.Context) error {
+	var req SendOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := c.service.SendOTP(req.Email); err != nil {
+		c.logger.Error("Failed to send login code",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+	}
+
+	return ctx.JSON(http.StatusOK, SuccessResponse{Message: "If the email is registered, a login code has been sent"})
+}
+
+// @Summary Verify Login Code
+// @Description Login user with the code sent to their email
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body VerifyOTPRequest true "Verify OTP Request"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/otp/verify [post]
+func (c *AuthController) VerifyOTP(ctx *router.Context) error {
+	var req VerifyOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := c.service.VerifyOTP(&req, clientOf(ctx))
+	if err != nil {
+		var blocked *LoginBlockedError
+		switch {
+		case errors.As(err, &blocked):
+			return c.loginBlocked(ctx, blocked)
+		case errors.Is(err, ErrInvalidOTP), errors.Is(err, ErrOTPExpired):
+			return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrOTPAttempts):
+			return ctx.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, verification.ErrEmailNotVerified):
+			return ctx.JSON(http.StatusForbidden, ErrorResponse{Error: "Verify your email address to log in"})
+		case strings.Contains(err.Error(), "access_denied"):
+			return ctx.JSON(http.StatusForbidden, map[string]any{
+				"error": err.Error(),
+				"data":  response,
+			})
+		default:
+			c.logger.Error("Failed to verify login code",
+				logger.String("error", err.Error()))
+			return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// Refresh exchanges a refresh token for new tokens
+// @Summary Refresh
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token works once; reusing one revokes its session.
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body RefreshRequest true "Refresh Request"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (c *AuthController) Refresh(ctx *router.Context) error {
+	var req RefreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := c.service.Refresh(req.RefreshToken, clientOf(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, sessions.ErrRefreshTokenReused):
+			c.logger.Warn("Refresh token reused, session revoked",
+				logger.String("ip", ctx.ClientIP()))
+			return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, sessions.ErrInvalidRefreshToken), errors.Is(err, sessions.ErrSessionRevoked):
+			return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		default:
+			c.logger.Error("Failed to refresh token",
+				logger.String("error", err.Error()))
+			return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, response)
+}
+
 // Logout handles user logout
 // @Summary Logout
-// @Description Logout user
+// @Description Logout user by revoking the session of the access token, or of the refresh token in the body when the access token expired
 // @Security ApiKeyAuth
 // @Tags Core/Auth
 // @Accept json
 // @Produce json
+// @Param body body LogoutRequest false "Logout Request"
 // @Success 200 {object} SuccessResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Router /auth/logout [post]
 func (c *AuthController) Logout(ctx *router.Context) error {
+	var req LogoutRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+	}
+
+	var claims *types.Claims
+	if parts := strings.SplitN(ctx.Header("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "Bearer" {
+		claims, _ = types.ParseJWT(parts[1])
+	}
+	if claims == nil && req.RefreshToken == "" {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	if err := c.service.Logout(claims, req.RefreshToken); err != nil {
+		if errors.Is(err, sessions.ErrInvalidRefreshToken) {
+			return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		}
+		c.logger.Error("Failed to logout",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+	}
+
 	return ctx.JSON(http.StatusOK, SuccessResponse{Message: "Logout successful"})
 }
 
+// clientOf describes the device a request comes from
+func clientOf(ctx *router.Context) sessions.Client {
+	return sessions.Client{UserAgent: ctx.Request.UserAgent(), IP: ctx.ClientIP()}
+}
+
 // @Summary Forgot Password
 // @Description Request to reset password
 // @Security ApiKeyAuth
@@ -176,7 +366,7 @@ func (c *AuthController) ForgotPassword(ctx *router.Context) error {
 
 // ResetPassword handles password reset requests
 // @Summary Reset Password
-// @Description Reset user password using token
+// @Description Reset user password using token. The new password must follow the password policy and differ from the last ones.
 // @Security ApiKeyAuth
 // @Tags Core/Auth
 // @Accept json
@@ -199,6 +389,8 @@ func (c *AuthController) ResetPassword(ctx *router.Context) error {
 		switch {
 		case errors.Is(err, ErrInvalidToken):
 			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid or expired token"})
+		case errors.Is(err, password.ErrWeakPassword), errors.Is(err, password.ErrPasswordReused):
+			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		case errors.Is(err, ErrUserNotFound):
 			return ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
 		default:
@@ -209,9 +401,417 @@ func (c *AuthController) ResetPassword(ctx *router.Context) error {
 	return ctx.JSON(http.StatusOK, SuccessResponse{Message: "Password reset successful"})
 }
 
+// @Summary Set Up Two-Factor
+// @Description Generate a TOTP secret and otpauth URI to add to an authenticator app. Two-factor authentication is enabled once a first code is confirmed at /auth/2fa/enable.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Auth
+// @Produce json
+// @Success 200 {object} TwoFactorSetupResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/2fa/setup [post]
+func (c *AuthController) SetupTwoFactor(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	response, err := c.service.SetupTwoFactor(userId)
+	if err != nil {
+		return c.twoFactorError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Enable Two-Factor
+// @Description Confirm the authenticator with a first TOTP code and enable two-factor authentication. The recovery codes in the response are only shown once.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/2fa/enable [post]
+func (c *AuthController) EnableTwoFactor(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := c.service.EnableTwoFactor(userId, req.Code)
+	if err != nil {
+		return c.twoFactorError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Disable Two-Factor
+// @Description Disable two-factor authentication with a current TOTP or recovery code. Not allowed when the user's role requires it.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} LoginBlockedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/2fa/disable [post]
+func (c *AuthController) DisableTwoFactor(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	if err := c.service.DisableTwoFactor(userId, &req, clientOf(ctx)); err != nil {
+		return c.twoFactorError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, SuccessResponse{Message: "Two-factor authentication disabled"})
+}
+
+// @Summary Regenerate Recovery Codes
+// @Description Replace all recovery codes after checking a current TOTP code. The old codes stop working.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} LoginBlockedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/2fa/recovery-codes [post]
+func (c *AuthController) RegenerateRecoveryCodes(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	var req TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := c.service.RegenerateRecoveryCodes(userId, req.Code, clientOf(ctx))
+	if err != nil {
+		return c.twoFactorError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Verify Two-Factor Login
+// @Description Complete a login that returned a "two_factor" challenge with a TOTP code or a recovery code
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorChallengeRequest true "Challenge token and code"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/2fa/challenge/verify [post]
+func (c *AuthController) VerifyTwoFactor(ctx *router.Context) error {
+	var req TwoFactorChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := c.service.VerifyTwoFactor(&req, clientOf(ctx))
+	if err != nil {
+		return c.twoFactorLoginError(ctx, err, response)
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Set Up Two-Factor During Login
+// @Description Generate a TOTP secret for a user whose role requires two-factor authentication, using the challenge token of a "two_factor_setup" login
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorChallengeRequest true "Challenge token"
+// @Success 200 {object} TwoFactorSetupResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/2fa/challenge/setup [post]
+func (c *AuthController) SetupTwoFactorChallenge(ctx *router.Context) error {
+	var req TwoFactorChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := c.service.SetupTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		return c.twoFactorError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Enable Two-Factor During Login
+// @Description Confirm the authenticator with a first TOTP code and complete a "two_factor_setup" login. The recovery codes in the response are only shown once.
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorChallengeRequest true "Challenge token and TOTP code"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} LoginBlockedResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/2fa/challenge/enable [post]
+func (c *AuthController) EnableTwoFactorChallenge(ctx *router.Context) error {
+	var req TwoFactorChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	response, err := c.service.EnableTwoFactorChallenge(&req, clientOf(ctx))
+	if err != nil {
+		return c.twoFactorLoginError(ctx, err, response)
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// twoFactorError maps two-factor errors to responses. Wrong codes count toward
+// the account lockout, which refuses further ones as a *LoginBlockedError.
+func (c *AuthController) twoFactorError(ctx *router.Context, err error) error {
+	var blocked *LoginBlockedError
+	if errors.As(err, &blocked) {
+		return c.loginBlocked(ctx, blocked)
+	}
+	switch {
+	case errors.Is(err, ErrInvalidChallenge), errors.Is(err, ErrInvalidTwoFactorCode):
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrTwoFactorAttempts):
+		return ctx.JSON(http.StatusTooManyRequests, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrTwoFactorRoleRequired):
+		return ctx.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrTwoFactorEnabled), errors.Is(err, ErrTwoFactorNotEnabled), errors.Is(err, ErrTwoFactorNotSetUp):
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrUserNotFound):
+		return ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	default:
+		c.logger.Error("Two-factor request failed",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+	}
+}
+
+// twoFactorLoginError maps the errors of a two-factor step that completes a login,
+// including logins denied by user.login_attempt listeners
+func (c *AuthController) twoFactorLoginError(ctx *router.Context, err error, response *AuthResponse) error {
+	if strings.Contains(err.Error(), "access_denied") {
+		return ctx.JSON(http.StatusForbidden, map[string]any{
+			"error": err.Error(),
+			"data":  response,
+		})
+	}
+	return c.twoFactorError(ctx, err)
+}
+
+// @Summary Unlock Account From Link
+// @Description Open the link emailed when an account was locked. The page only asks to confirm; the account is unlocked when the form is submitted, so link scanners and prefetching cannot unlock it.
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Produce html
+// @Param token query string true "Unlock token"
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Error page"
+// @Router /auth/unlock [get]
+func (c *AuthController) UnlockLink(ctx *router.Context) error {
+	token := ctx.Query("token")
+	if token == "" {
+		return ctx.HTML(http.StatusBadRequest, unlockPage(ErrInvalidUnlockToken.Error(), ""))
+	}
+	return ctx.HTML(http.StatusOK, unlockPage("Your account was locked after too many failed login attempts.", token))
+}
+
+// @Summary Unlock Account
+// @Description Lift the lockout of an account with the token of an unlock link. Takes JSON from pages set as LOGIN_UNLOCK_URL, or the form of the GET /auth/unlock page, which gets a page back.
+// @Security ApiKeyAuth
+// @Tags Core/Auth
+// @Accept json
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Produce html
+// @Param body body UnlockRequest true "Unlock token"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/unlock [post]
+func (c *AuthController) Unlock(ctx *router.Context) error {
+	if strings.Contains(ctx.ContentType(), "application/x-www-form-urlencoded") {
+		switch err := c.unlock(ctx.FormValue("token")); {
+		case err == nil:
+			return ctx.HTML(http.StatusOK, unlockPage("Your account is unlocked. You can log in again.", ""))
+		case errors.Is(err, ErrInvalidUnlockToken):
+			return ctx.HTML(http.StatusBadRequest, unlockPage(err.Error(), ""))
+		default:
+			return ctx.HTML(http.StatusInternalServerError, unlockPage("Something went wrong. Please try again later.", ""))
+		}
+	}
+
+	var req UnlockRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	if err := c.unlock(req.Token); err != nil {
+		if errors.Is(err, ErrInvalidUnlockToken) {
+			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		}
+		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+	}
+	return ctx.JSON(http.StatusOK, SuccessResponse{Message: "Account unlocked"})
+}
+
+func (c *AuthController) unlock(token string) error {
+	if token == "" {
+		return ErrInvalidUnlockToken
+	}
+	err := c.service.Unlock(token)
+	if err != nil && !errors.Is(err, ErrInvalidUnlockToken) {
+		c.logger.Error("Failed to unlock account",
+			logger.String("error", err.Error()))
+	}
+	return err
+}
+
+// @Summary List Failed Logins
+// @Description List the latest refused logins, newest first. Administrators only.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Auth
+// @Produce json
+// @Param user_id query int false "Filter by user Id"
+// @Param email query string false "Filter by email"
+// @Param ip query string false "Filter by IP address"
+// @Param limit query int false "Maximum number of attempts (default 50, max 500)"
+// @Success 200 {array} LoginAttempt
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/admin/login-attempts [get]
+func (c *AuthController) LoginAttempts(ctx *router.Context) error {
+	adminId := ctx.GetUint("user_id")
+	if adminId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	filter := LoginAttemptFilter{Email: ctx.Query("email"), IP: ctx.Query("ip")}
+	if value := ctx.Query("user_id"); value != "" {
+		userId, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user Id"})
+		}
+		filter.UserId = uint(userId)
+	}
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit"})
+		}
+		filter.Limit = limit
+	}
+
+	attempts, err := c.service.LoginAttempts(adminId, filter)
+	if err != nil {
+		return c.adminError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, attempts)
+}
+
+// @Summary Unlock User
+// @Description Lift the lockout of an account and clear its failed logins. Administrators only.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Auth
+// @Produce json
+// @Param id path int true "User Id"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/admin/users/{id}/unlock [post]
+func (c *AuthController) UnlockUser(ctx *router.Context) error {
+	adminId := ctx.GetUint("user_id")
+	if adminId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	userId, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user Id"})
+	}
+
+	if err := c.service.UnlockUser(adminId, uint(userId)); err != nil {
+		return c.adminError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, SuccessResponse{Message: "Account unlocked"})
+}
+
+// adminError maps the errors of administrator endpoints to responses
+func (c *AuthController) adminError(ctx *router.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrNotAdmin):
+		return ctx.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrUserNotFound):
+		return ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+	default:
+		c.logger.Error("Admin request failed",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+	}
+}
+
 func (c *AuthController) getWelcomeEmailBody(name string) string {
 	return "<h1>Welcome to Base!</h1>" +
 		"<p>Hi " + name + ",</p>" +
 		"<p>Thank you for registering with our application.</p>" +
 		"<p>Best regards,<br>Team</p>"
 }
+
+// unlockPage renders the page of an unlock link: a message, and a button that
+// posts the token back when there is one
+func unlockPage(message, token string) string {
+	form := ""
+	if token != "" {
+		form = `<form method="post">` +
+			`<input type="hidden" name="token" value="` + html.EscapeString(token) + `">` +
+			`<button type="submit">Unlock my account</button>` +
+			`</form>`
+	}
+	return `<!DOCTYPE html><html lang="en"><head><meta charset="utf-8">` +
+		`<meta name="viewport" content="width=device-width, initial-scale=1">` +
+		`<meta name="robots" content="noindex"><title>Unlock your account</title></head>` +
+		`<body><h1>Unlock your account</h1><p>` + html.EscapeString(message) + `</p>` + form + `</body></html>`
+}
@@ -10,4 +10,22 @@ var (
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrEmailExists     = errors.New("email already exists")
 	ErrInvalidEmail    = errors.New("invalid email")
+	ErrInvalidOTP      = errors.New("invalid login code")
+	ErrOTPExpired      = errors.New("login code expired")
+	ErrOTPAttempts     = errors.New("too many attempts; request a new login code")
+
+	ErrInvalidChallenge      = errors.New("invalid or expired challenge token")
+	ErrInvalidTwoFactorCode  = errors.New("invalid two-factor code")
+	ErrTwoFactorAttempts     = errors.New("too many invalid two-factor codes; try again later")
+	ErrTwoFactorEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled   = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotSetUp     = errors.New("two-factor authentication has not been set up")
+	ErrTwoFactorRoleRequired = errors.New("two-factor authentication is required for your role")
+
+	ErrAccountLocked       = errors.New("account temporarily locked after too many failed logins; check your email to unlock it")
+	ErrLoginBackoff        = errors.New("too many failed logins; try again later")
+	ErrProofOfWorkRequired = errors.New("proof of work required")
+	ErrInvalidProofOfWork  = errors.New("invalid or expired proof of work")
+	ErrInvalidUnlockToken  = errors.New("invalid or expired unlock link")
+	ErrNotAdmin            = errors.New("administrator role required")
 )
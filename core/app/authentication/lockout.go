@@ -0,0 +1,456 @@
+package authentication
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/url"
+	"strings"
+	"time"
+
+	"base/core/app/sessions"
+	"base/core/config"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+// Reasons a login was refused, as recorded in LoginAttempt
+const (
+	AttemptInvalidCredentials = "invalid_credentials"
+	AttemptLocked             = "locked"
+	AttemptBackoff            = "backoff"
+	AttemptProofOfWork        = "proof_of_work"
+)
+
+// Challenge token purposes of unlock links and proof-of-work challenges
+const (
+	challengeUnlock      = "unlock"
+	challengeProofOfWork = "proof_of_work"
+)
+
+const (
+	loginBackoffBase      = time.Second     // Delay after the first failure, doubled by each further one
+	loginBackoffMax       = 5 * time.Minute // Longest delay between two attempts
+	lockoutMax            = 24 * time.Hour  // Longest lockout, however many came before
+	proofOfWorkTTL        = 2 * time.Minute
+	loginAttemptRetention = 30 * 24 * time.Hour
+	loginAttemptsLimit    = 50
+	loginAttemptsLimitMax = 500
+)
+
+// loginPolicy holds the brute-force protection settings of password logins
+type loginPolicy struct {
+	maxFailures   int
+	lockout       time.Duration
+	window        time.Duration
+	ipMaxFailures int
+	powAfter      int
+	ipPowAfter    int
+	powDifficulty int
+	unlockLink    string
+}
+
+func newLoginPolicy(cfg *config.Config) loginPolicy {
+	link := cfg.LoginUnlockURL
+	if link == "" {
+		link = strings.TrimSuffix(cfg.BaseURL, "/") + "/api/auth/unlock"
+	}
+	return loginPolicy{
+		maxFailures:   cfg.LoginMaxFailures,
+		lockout:       cfg.LoginLockoutDuration,
+		window:        cfg.LoginFailureWindow,
+		ipMaxFailures: cfg.LoginIPMaxFailures,
+		powAfter:      cfg.LoginPoWAfter,
+		ipPowAfter:    cfg.LoginIPPoWAfter,
+		powDifficulty: cfg.LoginPoWDifficulty,
+		unlockLink:    link,
+	}
+}
+
+// LoginBlockedError refuses a login before its password is checked. It wraps
+// ErrAccountLocked, ErrLoginBackoff, ErrProofOfWorkRequired or ErrInvalidProofOfWork.
+type LoginBlockedError struct {
+	Err         error
+	RetryAfter  time.Duration
+	ProofOfWork *ProofOfWorkChallenge // A new challenge when a proof of work is needed
+}
+
+func (e *LoginBlockedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
+
+// LoginAttemptFilter narrows the login attempts administrators list
+type LoginAttemptFilter struct {
+	UserId uint
+	Email  string
+	IP     string
+	Limit  int
+}
+
+// checkLogin refuses a login to a locked account, one tried again too soon after a
+// failure from the same account or IP, or one missing a needed proof of work.
+// user is nil when no account has the email.
+func (s *AuthService) checkLogin(user *AuthUser, req *LoginRequest, client sessions.Client) error {
+	now := time.Now()
+	if user != nil {
+		if err := s.checkAccount(user, req.Email, client); err != nil {
+			return err
+		}
+	}
+
+	ipFailures, lastFailure, err := s.ipFailures(client.IP, now)
+	if err != nil {
+		return err
+	}
+	if ipFailures >= int64(s.policy.ipMaxFailures) && lastFailure != nil {
+		if wait := lastFailure.Add(backoffDelay(int(ipFailures) - s.policy.ipMaxFailures)).Sub(now); wait > 0 {
+			return s.refuseLogin(user, req.Email, client, AttemptBackoff,
+				&LoginBlockedError{Err: ErrLoginBackoff, RetryAfter: wait})
+		}
+	}
+
+	suspicious := ipFailures >= int64(s.policy.ipPowAfter) || (user != nil && user.FailedLogins >= s.policy.powAfter)
+	if !suspicious {
+		return nil
+	}
+	if err := s.checkProofOfWork(req.ProofOfWork); err != nil {
+		if !errors.Is(err, ErrProofOfWorkRequired) && !errors.Is(err, ErrInvalidProofOfWork) {
+			return err
+		}
+		challenge, err2 := s.newProofOfWork()
+		if err2 != nil {
+			return err2
+		}
+		return s.refuseLogin(user, req.Email, client, AttemptProofOfWork,
+			&LoginBlockedError{Err: err, ProofOfWork: challenge})
+	}
+	return nil
+}
+
+// checkAccount refuses a login to a locked account, or one tried again too soon
+// after a failure
+func (s *AuthService) checkAccount(user *AuthUser, email string, client sessions.Client) error {
+	if err := s.checkLocked(user, email, client); err != nil {
+		return err
+	}
+	if user.FailedLogins > 0 && user.LastFailedLogin != nil {
+		if wait := time.Until(user.LastFailedLogin.Add(backoffDelay(user.FailedLogins - 1))); wait > 0 {
+			return s.refuseLogin(user, email, client, AttemptBackoff,
+				&LoginBlockedError{Err: ErrLoginBackoff, RetryAfter: wait})
+		}
+	}
+	return nil
+}
+
+// checkLocked refuses a login to a locked account. Every way of logging in checks
+// it, so a lockout cannot be sidestepped with a login code or an identity provider.
+func (s *AuthService) checkLocked(user *AuthUser, email string, client sessions.Client) error {
+	now := time.Now()
+	if user.LockedUntil == nil || !now.Before(*user.LockedUntil) {
+		return nil
+	}
+	return s.refuseLogin(user, email, client, AttemptLocked,
+		&LoginBlockedError{Err: ErrAccountLocked, RetryAfter: user.LockedUntil.Sub(now)})
+}
+
+// refuseLogin records a login refused by checkLogin and returns its error
+func (s *AuthService) refuseLogin(user *AuthUser, email string, client sessions.Client, reason string, blocked *LoginBlockedError) error {
+	if err := s.recordAttempt(user, email, client, reason); err != nil {
+		return err
+	}
+	return blocked
+}
+
+// loginFailed records a wrong password or code. The account is locked, and its owner
+// emailed an unlock link, once it reaches the failure limit.
+func (s *AuthService) loginFailed(user *AuthUser, email string, client sessions.Client) error {
+	if err := s.recordAttempt(user, email, client, AttemptInvalidCredentials); err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	// The counters are NULL for users created before them or outside of Register
+	now := time.Now()
+	if err := s.db.Model(&AuthUser{}).Where("id = ?", user.Id).UpdateColumns(map[string]any{
+		"failed_logins":     gorm.Expr("COALESCE(failed_logins, 0) + 1"),
+		"last_failed_login": now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	var current AuthUser
+	if err := s.db.Select("id", "failed_logins", "lockouts").First(&current, user.Id).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if current.FailedLogins < s.policy.maxFailures {
+		return nil
+	}
+
+	// Resetting the failures as the lock is set lets only one of two concurrent
+	// failures lock the account and send the email
+	until := now.Add(lockoutDuration(s.policy.lockout, current.Lockouts))
+	result := s.db.Model(&AuthUser{}).
+		Where("id = ? AND failed_logins >= ?", user.Id, s.policy.maxFailures).
+		UpdateColumns(map[string]any{
+			"locked_until":        until,
+			"lockouts":            gorm.Expr("COALESCE(lockouts, 0) + 1"),
+			"failed_logins":       0,
+			"two_factor_failures": 0,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to lock account: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	go func() {
+		if err := s.sendLockoutEmail(user, until); err != nil {
+			fmt.Printf("Failed to send account locked email: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// loginSucceeded clears the failures of an account once every login step passed.
+// A right password alone does not, so wrong two-factor codes keep adding up.
+func (s *AuthService) loginSucceeded(user *AuthUser) error {
+	if user.FailedLogins == 0 && user.Lockouts == 0 && user.LockedUntil == nil && user.TwoFactorFailures == 0 {
+		return nil
+	}
+	if err := s.db.Model(&AuthUser{}).Where("id = ?", user.Id).Updates(map[string]any{
+		"failed_logins":       0,
+		"lockouts":            0,
+		"locked_until":        nil,
+		"two_factor_failures": 0,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to clear failed logins: %w", err)
+	}
+	user.FailedLogins, user.Lockouts, user.LockedUntil, user.TwoFactorFailures = 0, 0, nil, 0
+	return nil
+}
+
+// recordAttempt stores a refused login
+func (s *AuthService) recordAttempt(user *AuthUser, email string, client sessions.Client, reason string) error {
+	attempt := LoginAttempt{
+		Email:     email,
+		IP:        client.IP,
+		UserAgent: truncate(client.UserAgent, 255),
+		Reason:    reason,
+	}
+	if user != nil {
+		attempt.UserId = user.Id
+	}
+	if err := s.db.Create(&attempt).Error; err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	return nil
+}
+
+// PruneLoginAttempts removes the login attempts past retention. The scheduler runs
+// it, so failed logins do not each pay for a delete.
+func (s *AuthService) PruneLoginAttempts(ctx context.Context) error {
+	if err := s.db.WithContext(ctx).Where("created_at < ?", time.Now().Add(-loginAttemptRetention)).Delete(&LoginAttempt{}).Error; err != nil {
+		return fmt.Errorf("failed to prune login attempts: %w", err)
+	}
+	return nil
+}
+
+// ipFailures counts the wrong passwords sent from an IP within the failure window,
+// and returns the time of the last one once there are enough to slow the IP down
+func (s *AuthService) ipFailures(ip string, now time.Time) (int64, *time.Time, error) {
+	query := s.db.Model(&LoginAttempt{}).
+		Where("ip = ? AND reason = ? AND created_at > ?", ip, AttemptInvalidCredentials, now.Add(-s.policy.window))
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, nil, fmt.Errorf("database error: %w", err)
+	}
+	if count < int64(s.policy.ipMaxFailures) {
+		return count, nil, nil
+	}
+
+	var last LoginAttempt
+	if err := query.Order("created_at DESC").First(&last).Error; err != nil {
+		return 0, nil, fmt.Errorf("database error: %w", err)
+	}
+	return count, &last.CreatedAt, nil
+}
+
+// backoffDelay is the wait before the next attempt after n failures past the free ones
+func backoffDelay(n int) time.Duration {
+	if n < 0 {
+		return 0
+	}
+	if n >= 16 {
+		return loginBackoffMax
+	}
+	return min(loginBackoffBase<<n, loginBackoffMax)
+}
+
+// lockoutDuration doubles the base lockout for each lockout since the last login
+func lockoutDuration(base time.Duration, lockouts int) time.Duration {
+	if lockouts >= 16 {
+		return lockoutMax
+	}
+	return min(base<<lockouts, lockoutMax)
+}
+
+// newProofOfWork issues a single-use challenge, signed so the server keeps no state
+func (s *AuthService) newProofOfWork() (*ProofOfWorkChallenge, error) {
+	token, claims, err := types.GenerateChallengeToken(0, challengeProofOfWork, proofOfWorkTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proof of work challenge: %w", err)
+	}
+	return &ProofOfWorkChallenge{
+		Challenge:  token,
+		Difficulty: s.policy.powDifficulty,
+		ExpiresAt:  claims.ExpiresAt.Unix(),
+	}, nil
+}
+
+// checkProofOfWork accepts a solved challenge once
+func (s *AuthService) checkProofOfWork(proof *ProofOfWork) error {
+	if proof == nil || proof.Challenge == "" {
+		return ErrProofOfWorkRequired
+	}
+	claims, err := types.ParseChallengeToken(proof.Challenge, challengeProofOfWork)
+	if err != nil {
+		return ErrInvalidProofOfWork
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(proof.Challenge+proof.Nonce))) < s.policy.powDifficulty {
+		return ErrInvalidProofOfWork
+	}
+	return s.consumeChallenge(claims)
+}
+
+// leadingZeroBits counts the zero bits a hash starts with
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Unlock lifts the lockout an unlock link was emailed for. Each link works once.
+func (s *AuthService) Unlock(token string) error {
+	claims, user, err := s.challengeUser(token, challengeUnlock)
+	if err != nil {
+		if errors.Is(err, ErrInvalidChallenge) {
+			return ErrInvalidUnlockToken
+		}
+		return err
+	}
+	if err := s.consumeChallenge(claims); err != nil {
+		return err
+	}
+	return s.unlock(user.Id)
+}
+
+// UnlockUser lets an administrator lift the lockout of an account
+func (s *AuthService) UnlockUser(adminId, userId uint) error {
+	if err := s.requireAdmin(adminId); err != nil {
+		return err
+	}
+	if _, err := s.findUser(userId); err != nil {
+		return err
+	}
+	return s.unlock(userId)
+}
+
+// LoginAttempts lists the latest refused logins for an administrator
+func (s *AuthService) LoginAttempts(adminId uint, filter LoginAttemptFilter) ([]LoginAttempt, error) {
+	if err := s.requireAdmin(adminId); err != nil {
+		return nil, err
+	}
+
+	query := s.db.Model(&LoginAttempt{})
+	if filter.UserId != 0 {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.Email != "" {
+		query = query.Where("email = ?", filter.Email)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = loginAttemptsLimit
+	}
+	limit = min(limit, loginAttemptsLimitMax)
+
+	attempts := []LoginAttempt{}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&attempts).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return attempts, nil
+}
+
+// unlock clears the lockout and failures of an account; past lockouts still count
+// toward the length of the next one until the owner logs in
+func (s *AuthService) unlock(userId uint) error {
+	if err := s.db.Model(&AuthUser{}).Where("id = ?", userId).Updates(map[string]any{
+		"locked_until":        nil,
+		"failed_logins":       0,
+		"two_factor_failures": 0,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	return nil
+}
+
+func (s *AuthService) requireAdmin(userId uint) error {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.IsAdmin() {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+func (s *AuthService) sendLockoutEmail(user *AuthUser, until time.Time) error {
+	token, _, err := types.GenerateChallengeToken(user.Id, challengeUnlock, time.Until(until))
+	if err != nil {
+		return fmt.Errorf("failed to create unlock token: %w", err)
+	}
+	link := s.policy.unlockLink
+	if strings.Contains(link, "?") {
+		link += "&token=" + url.QueryEscape(token)
+	} else {
+		link += "?token=" + url.QueryEscape(token)
+	}
+
+	title := "Your Base Account Has Been Locked"
+	content := fmt.Sprintf(`
+		<p>Hi %s,</p>
+		<p>Your account was locked after too many failed login attempts. It unlocks automatically at %s.</p>
+		<p>If these attempts were yours, you can unlock it now:</p>
+		<p><a href="%s">Unlock my account</a></p>
+		<p>If they were not, someone may be trying to guess your password. Consider resetting it.</p>
+	`, user.FirstName, until.UTC().Format("2006-01-02 15:04 MST"), link)
+	return s.sendEmail(user.Email, title, title, content)
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -10,6 +10,44 @@ type AuthUser struct {
 	LastLogin        *time.Time `gorm:"column:last_login"`
 	ResetToken       string     `gorm:"column:reset_token"`
 	ResetTokenExpiry *time.Time `gorm:"column:reset_token_expiry"`
+	OTPHash          string     `gorm:"column:otp_hash"` // bcrypt hash of the pending login code
+	OTPExpiry        *time.Time `gorm:"column:otp_expiry"`
+	OTPAttempts      int        `gorm:"column:otp_attempts"`
+	OTPSentAt        *time.Time `gorm:"column:otp_sent_at"`
+
+	// Two-factor authentication; the secret is pending until TwoFactorEnabled
+	TwoFactorSecret   string `gorm:"column:two_factor_secret;size:64"`
+	TwoFactorEnabled  bool   `gorm:"column:two_factor_enabled;default:false"`
+	TwoFactorLastStep int64  `gorm:"column:two_factor_last_step"` // Last accepted TOTP time step, so codes work once
+	TwoFactorFailures int    `gorm:"column:two_factor_failures"`  // Wrong codes since the last login or lockout
+
+	// Brute-force protection of password logins
+	FailedLogins    int        `gorm:"column:failed_logins"` // Wrong passwords and codes since the last login or lockout
+	LastFailedLogin *time.Time `gorm:"column:last_failed_login"`
+	LockedUntil     *time.Time `gorm:"column:locked_until"`
+	Lockouts        int        `gorm:"column:lockouts"` // Lockouts since the last login; each doubles the next
+}
+
+// LoginAttempt records a refused password login, for per-IP limits and for
+// administrators to review
+type LoginAttempt struct {
+	Id        uint      `gorm:"primaryKey" json:"id"`
+	UserId    uint      `gorm:"index" json:"user_id,omitempty"` // 0 when no account has the email
+	Email     string    `gorm:"size:255;index" json:"email"`
+	IP        string    `gorm:"size:64;index" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	Reason    string    `gorm:"size:32" json:"reason"` // invalid_credentials, locked, backoff or proof_of_work
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the
+// authenticator is lost, stored as its SHA-256 hash
+type RecoveryCode struct {
+	Id        uint   `gorm:"primaryKey"`
+	UserId    uint   `gorm:"index"`
+	CodeHash  string `gorm:"size:64;index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
 }
 
 func (AuthUser) TableName() string {
@@ -37,8 +75,8 @@ type RegisterRequest struct {
 	Phone string `json:"phone" example:"+1234567890" gorm:"column:phone"`
 	// @Description User's email address
 	Email string `json:"email" binding:"required,email" example:"john@example.com"`
-	// @Description Password for the account (minimum 8 characters)
-	Password string `json:"password" binding:"required,min=8" example:"password123"`
+	// @Description Password for the account, following the password policy
+	Password string `json:"password" binding:"required" example:"Correct-Horse-42"`
 }
 
 // LoginRequest represents the payload for user login
@@ -47,6 +85,35 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"john@example.com"`
 	Password string `json:"password" binding:"required" example:"password123"`
+	// Required after repeated failures; solves the challenge of the last refused login
+	ProofOfWork *ProofOfWork `json:"proofOfWork,omitempty"`
+}
+
+// ProofOfWork solves a challenge: the SHA-256 of challenge + nonce must start with
+// the number of zero bits the challenge asked for
+type ProofOfWork struct {
+	Challenge string `json:"challenge"`
+	Nonce     string `json:"nonce"`
+}
+
+// ProofOfWorkChallenge asks the client to find a nonce for a proof of work
+type ProofOfWorkChallenge struct {
+	Challenge  string `json:"challenge"`
+	Difficulty int    `json:"difficulty"` // Leading zero bits of SHA-256(challenge + nonce)
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+// LoginBlockedResponse is returned when a login is refused before the password is
+// checked: the account is locked, attempts are too frequent, or a proof of work is needed
+type LoginBlockedResponse struct {
+	Error       string                `json:"error"`
+	RetryAfter  int64                 `json:"retryAfter,omitempty"` // Seconds before trying again
+	ProofOfWork *ProofOfWorkChallenge `json:"proofOfWork,omitempty"`
+}
+
+// UnlockRequest carries the token of an unlock link
+type UnlockRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 type ForgotPasswordRequest struct {
@@ -56,14 +123,45 @@ type ForgotPasswordRequest struct {
 type ResetPasswordRequest struct {
 	Email       string `json:"email" binding:"required,email" example:"john@example.com"`
 	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6" example:"newpassword123"`
+	NewPassword string `json:"new_password" binding:"required" example:"Battery-Staple-7"`
 }
 
 type AuthResponse struct {
 	profile.UserResponse
-	AccessToken string `json:"accessToken"`
-	Exp         int64  `json:"exp"`
-	Extend      any    `json:"extend,omitempty"`
+	AccessToken  string `json:"accessToken"`
+	Exp          int64  `json:"exp"`
+	RefreshToken string `json:"refreshToken"`
+	RefreshExp   int64  `json:"refreshExp"`
+	Extend       any    `json:"extend,omitempty"`
+
+	// Set instead of the tokens when the login needs a second step: "two_factor"
+	// to verify a TOTP code, or "two_factor_setup" to enroll first
+	Challenge      string `json:"challenge,omitempty"`
+	ChallengeToken string `json:"challengeToken,omitempty"`
+	ChallengeExp   int64  `json:"challengeExp,omitempty"`
+
+	// Returned once, when two-factor authentication is enabled during login
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+// RefreshRequest represents the payload to exchange a refresh token for new tokens
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// LogoutRequest optionally names the refresh token of the session to end, for
+// clients whose access token already expired
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// TokenResponse holds the tokens issued by a refresh; the refresh token replaces
+// the one sent, which stops working
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	Exp          int64  `json:"exp"`
+	RefreshToken string `json:"refreshToken"`
+	RefreshExp   int64  `json:"refreshExp"`
 }
 
 type ErrorResponse struct {
@@ -76,11 +174,35 @@ type SuccessResponse struct {
 
 // VerifyOTPRequest represents the payload to verify an OTP for login
 type VerifyOTPRequest struct {
-	Email string `json:"email" binding:"required,email"`
-	OTP   string `json:"otp" binding:"required"`
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+	OTP   string `json:"otp" binding:"required,len=6,numeric" example:"123456"`
 }
 
 // SendOTPRequest represents the payload to request sending an OTP
 type SendOTPRequest struct {
-	Email string `json:"email" binding:"required,email"`
+	Email string `json:"email" binding:"required,email" example:"john@example.com"`
+}
+
+// TwoFactorCodeRequest carries a TOTP code, or a recovery code where accepted
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code" example:"123456"`
+	RecoveryCode string `json:"recoveryCode" example:"abcde-fghij"`
+}
+
+// TwoFactorChallengeRequest completes a login that returned a challenge
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" example:"123456"`
+	RecoveryCode   string `json:"recoveryCode" example:"abcde-fghij"`
+}
+
+// TwoFactorSetupResponse holds a new TOTP secret to add to an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // otpauth:// URI to render as a QR code
+}
+
+// RecoveryCodesResponse lists new recovery codes; they are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
 }
@@ -1,11 +1,16 @@
 package authentication
 
 import (
+	"base/core/app/profile"
+	"base/core/app/sessions"
+	"base/core/app/verification"
 	"base/core/email"
 	"base/core/emitter"
 	"base/core/logger"
 	"base/core/module"
 	"base/core/router"
+	"base/core/scheduler"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -18,10 +23,11 @@ type AuthenticationModule struct {
 	Logger      logger.Logger
 	EmailSender email.Sender
 	Emitter     *emitter.Emitter
+	Scheduler   *scheduler.Scheduler
 }
 
-func NewAuthenticationModule(db *gorm.DB, router *router.RouterGroup, emailSender email.Sender, logger logger.Logger, emitter *emitter.Emitter) module.Module {
-	service := NewAuthService(db, emailSender, emitter)
+func NewAuthenticationModule(db *gorm.DB, router *router.RouterGroup, emailSender email.Sender, logger logger.Logger, emitter *emitter.Emitter, sessions *sessions.SessionService, verification *verification.VerificationService, scheduler *scheduler.Scheduler) *AuthenticationModule {
+	service := NewAuthService(db, emailSender, emitter, sessions, verification)
 	controller := NewAuthController(service, emailSender, logger)
 
 	authModule := &AuthenticationModule{
@@ -31,11 +37,27 @@ func NewAuthenticationModule(db *gorm.DB, router *router.RouterGroup, emailSende
 		Logger:      logger,
 		EmailSender: emailSender,
 		Emitter:     emitter,
+		Scheduler:   scheduler,
 	}
 
 	return authModule
 }
 
+// Init registers the task that removes login attempts past retention
+func (m *AuthenticationModule) Init() error {
+	if m.Scheduler == nil {
+		m.Logger.Warn("Scheduler not available, old login attempts will not be removed")
+		return nil
+	}
+	return m.Scheduler.RegisterTask(&scheduler.Task{
+		Name:        "authentication.login_attempts",
+		Description: "Remove refused login attempts past their retention",
+		Schedule:    &scheduler.IntervalSchedule{Interval: time.Hour},
+		Handler:     m.Service.PruneLoginAttempts,
+		Enabled:     true,
+	})
+}
+
 func (m *AuthenticationModule) Routes(router *router.RouterGroup) {
 	// Create /auth group under /api (router is already /api from main.go)
 	authGroup := router.Group("/auth")
@@ -44,11 +66,16 @@ func (m *AuthenticationModule) Routes(router *router.RouterGroup) {
 }
 
 func (m *AuthenticationModule) Migrate() error {
-	return m.DB.AutoMigrate(&AuthUser{})
+	if err := profile.MigrateEmailVerification(m.DB); err != nil {
+		return err
+	}
+	return m.DB.AutoMigrate(&AuthUser{}, &RecoveryCode{}, &LoginAttempt{})
 }
 
 func (m *AuthenticationModule) GetModels() []any {
 	return []any{
 		&AuthUser{},
+		&RecoveryCode{},
+		&LoginAttempt{},
 	}
 }
@@ -6,20 +6,32 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math/big"
 	"sync"
 	"text/template"
 	"time"
 
 	"base/app"
 	"base/core/app/profile"
+	"base/core/app/sessions"
+	"base/core/app/verification"
+	"base/core/config"
 	"base/core/email"
 	"base/core/emitter"
+	"base/core/password"
 	"base/core/types"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// Login codes sent by email for passwordless login
+const (
+	otpTTL            = 10 * time.Minute
+	otpMaxAttempts    = 5
+	otpResendInterval = time.Minute
+)
+
 var (
 	emailTemplateMutex sync.RWMutex
 	emailTemplateCache *template.Template
@@ -27,24 +39,29 @@ var (
 
 // AuthService handles authentication related operations
 type AuthService struct {
-	db          *gorm.DB
-	emailSender email.Sender
-	emitter     *emitter.Emitter
+	db           *gorm.DB
+	emailSender  email.Sender
+	emitter      *emitter.Emitter
+	sessions     *sessions.SessionService
+	verification *verification.VerificationService
+	policy       loginPolicy
+	passwords    *password.Policy
 }
 
 // NewAuthService creates a new authentication service
-func NewAuthService(db *gorm.DB, emailSender email.Sender, emitter *emitter.Emitter) *AuthService {
+func NewAuthService(db *gorm.DB, emailSender email.Sender, emitter *emitter.Emitter, sessions *sessions.SessionService, verification *verification.VerificationService) *AuthService {
+	cfg := config.NewConfig()
 	return &AuthService{
-		db:          db,
-		emailSender: emailSender,
-		emitter:     emitter,
+		db:           db,
+		emailSender:  emailSender,
+		emitter:      emitter,
+		sessions:     sessions,
+		verification: verification,
+		policy:       newLoginPolicy(cfg),
+		passwords:    password.NewPolicy(cfg),
 	}
 }
 
-func (s *AuthService) ValidateKey(key string) (any, error) {
-	return nil, nil
-}
-
 // validateUser checks if username or email already exists
 func (s *AuthService) validateUser(email, username string) error {
 	var count int64
@@ -60,11 +77,14 @@ func (s *AuthService) validateUser(email, username string) error {
 	return nil
 }
 
-func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
+func (s *AuthService) Register(req *RegisterRequest, client sessions.Client) (*AuthResponse, error) {
 	// Validate unique constraints first
 	if err := s.validateUser(req.Email, req.Username); err != nil {
 		return nil, err
 	}
+	if err := s.passwords.Validate(req.Password, req.Email, req.Username, req.FirstName, req.LastName); err != nil {
+		return nil, err
+	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -104,6 +124,11 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	if err := s.passwords.Remember(tx, user.Id, user.Password); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -111,10 +136,10 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 	// Get extended data for JWT token
 	extendData := app.Extend(user.User.Id)
 
-	// Generate JWT token
-	token, err := types.GenerateJWT(user.User.Id, extendData)
+	// Start a session with an access and a refresh token
+	tokens, err := s.sessions.Start(user.User.Id, client, extendData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
 	userData := types.UserData{
@@ -132,6 +157,13 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		fmt.Printf("Emitter is nil in AuthService.Register; cannot emit 'user.registered' event")
 	}
 
+	// Email a link to verify the address asynchronously
+	go func() {
+		if err := s.verification.Send(user.Id); err != nil {
+			fmt.Printf("Failed to send verification email: %v\n", err)
+		}
+	}()
+
 	// Send welcome email asynchronously
 	// go func() {
 	// 	if err := s.sendWelcomeEmail(&user); err != nil {
@@ -142,35 +174,96 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 	userResponse := profile.ToResponse(&user.User)
 	userResponse.LastLogin = now.Format(time.RFC3339)
 
-	return &AuthResponse{
-		UserResponse: *userResponse,
-		AccessToken:  token,
-		Exp:          now.Add(24 * time.Hour).Unix(),
-		Extend:       extendData,
-	}, nil
+	return newAuthResponse(userResponse, tokens, extendData), nil
 }
 
-func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
+// Login checks the password of a user. Wrong passwords slow down further attempts
+// for the account and the IP, then lock the account or ask for a proof of work;
+// such refusals are returned as a *LoginBlockedError.
+func (s *AuthService) Login(req *LoginRequest, client sessions.Client) (*AuthResponse, error) {
 	var user AuthUser
 	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid credentials")
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("database error: %w", err)
 		}
-		return nil, fmt.Errorf("database error: %w", err)
+		// Unknown emails still count against the IP
+		if err := s.checkLogin(nil, req, client); err != nil {
+			return nil, err
+		}
+		if err := s.loginFailed(nil, req.Email, client); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid credentials")
+	}
+
+	if err := s.checkLogin(&user, req, client); err != nil {
+		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		if err := s.loginFailed(&user, req.Email, client); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("invalid credentials")
 	}
 
+	return s.completeLogin(&user, client)
+}
+
+// completeLogin finishes the login of a user whose credentials were verified, or
+// returns a two-factor challenge instead of tokens when a second step is needed.
+// Locked accounts are refused whichever credentials were used.
+func (s *AuthService) completeLogin(user *AuthUser, client sessions.Client) (*AuthResponse, error) {
+	if err := s.checkLocked(user, user.Email, client); err != nil {
+		return nil, err
+	}
+	if err := s.verification.Check(user.Id, verification.ActionLogin); err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.twoFactorChallenge(user)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+	return s.startLogin(user, client)
+}
+
+// ExternalLogin logs in a user an external identity provider authenticated, with
+// the same email verification and two-factor steps as a password login
+func (s *AuthService) ExternalLogin(userId uint, client sessions.Client) (*AuthResponse, error) {
+	var user AuthUser
+	if err := s.db.First(&user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return s.completeLogin(&user, client)
+}
+
+// startLogin starts a session for a user who passed every login step and lets
+// listeners of user.login_attempt deny the login. The lockout is checked again
+// since the account may have been locked while a two-factor challenge was open;
+// past it, the failures of the account are cleared.
+func (s *AuthService) startLogin(user *AuthUser, client sessions.Client) (*AuthResponse, error) {
+	if err := s.checkLocked(user, user.Email, client); err != nil {
+		return nil, err
+	}
+	if err := s.loginSucceeded(user); err != nil {
+		return nil, err
+	}
+
 	// Get extended data for JWT token
 	extendData := app.Extend(user.User.Id)
 
-	// Proceed with generating token and response
+	// Proceed with starting a session and the response
 	now := time.Now()
-	token, err := types.GenerateJWT(user.User.Id, extendData)
+	tokens, err := s.sessions.Start(user.User.Id, client, extendData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
 	// Create the response
@@ -179,17 +272,12 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 		userResponse.LastLogin = user.LastLogin.Format(time.RFC3339)
 	}
 
-	response := &AuthResponse{
-		UserResponse: *userResponse,
-		AccessToken:  token,
-		Exp:          now.Add(24 * time.Hour).Unix(),
-		Extend:       extendData,
-	}
+	response := newAuthResponse(userResponse, tokens, extendData)
 
 	// Prepare the login event
 	loginAllowed := true
 	event := LoginEvent{
-		User:         &user,
+		User:         user,
 		LoginAllowed: &loginAllowed,
 		Response:     response,
 	}
@@ -206,7 +294,7 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 	}
 
 	// Update last login with proper time handling
-	if err := s.db.Model(&user).Update("last_login", sql.NullTime{
+	if err := s.db.Model(user).Update("last_login", sql.NullTime{
 		Time:  now,
 		Valid: true,
 	}).Error; err != nil {
@@ -216,6 +304,138 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 	return response, nil
 }
 
+// SendOTP emails a 6-digit login code to a user, replacing any pending one.
+// Unknown emails, and requests within otpResendInterval of the last code, are
+// ignored without an error, so the endpoint does not reveal who has an account.
+func (s *AuthService) SendOTP(email string) error {
+	var user AuthUser
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	now := time.Now()
+	if user.OTPSentAt != nil && now.Sub(*user.OTPSentAt) < otpResendInterval {
+		return nil
+	}
+
+	code, err := generateOTP()
+	if err != nil {
+		return err
+	}
+	hashedCode, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash login code: %w", err)
+	}
+
+	updates := map[string]any{
+		"otp_hash":     string(hashedCode),
+		"otp_expiry":   sql.NullTime{Time: now.Add(otpTTL), Valid: true},
+		"otp_attempts": 0,
+		"otp_sent_at":  sql.NullTime{Time: now, Valid: true},
+	}
+	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to save login code: %w", err)
+	}
+
+	if err := s.sendOTPEmail(&user, code); err != nil {
+		return fmt.Errorf("failed to send login code email: %w", err)
+	}
+	return nil
+}
+
+// VerifyOTP logs a user in with the code sent by SendOTP. A code works once, and
+// only otpMaxAttempts guesses are allowed per code. Wrong codes also count as
+// failed logins of the account, which resends do not reset, so guessing across
+// many codes ends in the same lockout as guessing passwords.
+func (s *AuthService) VerifyOTP(req *VerifyOTPRequest, client sessions.Client) (*AuthResponse, error) {
+	var user AuthUser
+	if err := s.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidOTP
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if err := s.checkLocked(&user, req.Email, client); err != nil {
+		return nil, err
+	}
+	if user.OTPHash == "" {
+		return nil, ErrInvalidOTP
+	}
+	if user.OTPExpiry == nil || time.Now().After(*user.OTPExpiry) {
+		return nil, ErrOTPExpired
+	}
+
+	// Count the attempt before checking it, so concurrent guesses cannot exceed the limit
+	result := s.db.Model(&AuthUser{}).
+		Where("id = ? AND otp_hash = ? AND otp_attempts < ?", user.Id, user.OTPHash, otpMaxAttempts).
+		UpdateColumn("otp_attempts", gorm.Expr("otp_attempts + 1"))
+	if result.Error != nil {
+		return nil, fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOTPAttempts
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.OTPHash), []byte(req.OTP)); err != nil {
+		if err := s.loginFailed(&user, req.Email, client); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidOTP
+	}
+
+	// Consume the code; only one of two concurrent verifications may win
+	result = s.db.Model(&AuthUser{}).
+		Where("id = ? AND otp_hash = ?", user.Id, user.OTPHash).
+		Updates(map[string]any{"otp_hash": "", "otp_expiry": nil, "otp_attempts": 0})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to consume login code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidOTP
+	}
+
+	return s.completeLogin(&user, client)
+}
+
+// Refresh rotates a refresh token and issues a new access token for its session
+func (s *AuthService) Refresh(refreshToken string, client sessions.Client) (*TokenResponse, error) {
+	tokens, err := s.sessions.Refresh(refreshToken, client, app.Extend)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		Exp:          tokens.AccessExpiresAt.Unix(),
+		RefreshToken: tokens.RefreshToken,
+		RefreshExp:   tokens.RefreshExpiresAt.Unix(),
+	}, nil
+}
+
+// Logout revokes the session of an access token, or of a refresh token when the
+// access token is missing or already expired
+func (s *AuthService) Logout(claims *types.Claims, refreshToken string) error {
+	if claims != nil {
+		return s.sessions.Logout(claims)
+	}
+	return s.sessions.LogoutRefreshToken(refreshToken)
+}
+
+// newAuthResponse builds the response to a login or registration
+func newAuthResponse(user *profile.UserResponse, tokens *sessions.Tokens, extend any) *AuthResponse {
+	return &AuthResponse{
+		UserResponse: *user,
+		AccessToken:  tokens.AccessToken,
+		Exp:          tokens.AccessExpiresAt.Unix(),
+		RefreshToken: tokens.RefreshToken,
+		RefreshExp:   tokens.RefreshExpiresAt.Unix(),
+		Extend:       extend,
+	}
+}
+
 func (s *AuthService) ForgotPassword(email string) error {
 	var user AuthUser
 	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
@@ -275,6 +495,13 @@ func (s *AuthService) ResetPassword(email, token, newPassword string) error {
 		return errors.New("token expired")
 	}
 
+	if err := s.passwords.Validate(newPassword, user.Email, user.Username, user.FirstName, user.LastName); err != nil {
+		return err
+	}
+	if err := s.passwords.CheckHistory(s.db, user.Id, user.Password, newPassword); err != nil {
+		return err
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
@@ -290,6 +517,9 @@ func (s *AuthService) ResetPassword(email, token, newPassword string) error {
 		"password":           string(hashedPassword),
 		"reset_token":        "",
 		"reset_token_expiry": nil,
+		// The reset proves the user owns the email, as an unlock link would
+		"locked_until":  nil,
+		"failed_logins": 0,
 	}
 
 	if err := tx.Model(&user).Updates(updates).Error; err != nil {
@@ -297,10 +527,20 @@ func (s *AuthService) ResetPassword(email, token, newPassword string) error {
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
+	if err := s.passwords.Remember(tx, user.Id, string(hashedPassword)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Sessions opened with the old password must not outlive it
+	if _, err := s.sessions.RevokeAll(user.Id, "", sessions.ReasonPasswordChanged); err != nil {
+		return err
+	}
+
 	// Send confirmation email asynchronously
 	go func() {
 		if err := s.sendPasswordChangedEmail(&user); err != nil {
@@ -319,6 +559,15 @@ func generateToken() (string, error) {
 	return fmt.Sprintf("%x", b), nil
 }
 
+// generateOTP returns a random 6-digit login code
+func generateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate login code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
 // Email sending functions
 func (s *AuthService) sendEmail(to, subject, title, content string) error {
 	var cachedTemplate *template.Template
@@ -371,6 +620,18 @@ func (s *AuthService) sendPasswordResetEmail(user *AuthUser, token string) error
 	return s.sendEmail(user.Email, title, title, content)
 }
 
+func (s *AuthService) sendOTPEmail(user *AuthUser, code string) error {
+	title := "Your Base Login Code"
+	content := fmt.Sprintf(`
+		<p>Hi %s,</p>
+		<p>Use the following code to log in:</p>
+		<h2>%s</h2>
+		<p>This code will expire in %d minutes.</p>
+		<p>If you didn't request a login code, you can ignore this email.</p>
+	`, user.FirstName, code, int(otpTTL.Minutes()))
+	return s.sendEmail(user.Email, title, title, content)
+}
+
 func (s *AuthService) sendPasswordChangedEmail(user *AuthUser) error {
 	title := "Your Base Password Has Been Changed"
 	content := fmt.Sprintf("<p>Hi %s,</p><p>Your password has been successfully changed. If you did not make this change, please contact support immediately.</p>", user.FirstName)
@@ -0,0 +1,380 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"base/core/app/authorization"
+	"base/core/app/profile"
+	"base/core/app/sessions"
+	"base/core/config"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+// Login challenges returned instead of tokens when a second step is needed
+const (
+	ChallengeTwoFactor      = "two_factor"       // Verify a TOTP or recovery code
+	ChallengeTwoFactorSetup = "two_factor_setup" // The role requires two-factor; enroll first
+)
+
+const (
+	challengeTTL      = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// twoFactorChallenge returns the challenge a user must pass before a session is
+// started, or nil when the password (or email code) is enough
+func (s *AuthService) twoFactorChallenge(user *AuthUser) (*AuthResponse, error) {
+	purpose := ""
+	if user.TwoFactorEnabled {
+		purpose = ChallengeTwoFactor
+	} else {
+		required, err := s.roleRequiresTwoFactor(user.RoleId)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			purpose = ChallengeTwoFactorSetup
+		}
+	}
+	if purpose == "" {
+		return nil, nil
+	}
+
+	token, claims, err := types.GenerateChallengeToken(user.Id, purpose, challengeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge token: %w", err)
+	}
+	return &AuthResponse{
+		UserResponse:   *profile.ToResponse(&user.User),
+		Challenge:      purpose,
+		ChallengeToken: token,
+		ChallengeExp:   claims.ExpiresAt.Unix(),
+	}, nil
+}
+
+// roleRequiresTwoFactor reports whether members of a role must use two-factor authentication
+func (s *AuthService) roleRequiresTwoFactor(roleId uint) (bool, error) {
+	var role authorization.Role
+	if err := s.db.Select("id", "require_two_factor").First(&role, roleId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	return role.RequireTwoFactor, nil
+}
+
+// SetupTwoFactor generates a new TOTP secret for a user. It stays pending, and
+// logins keep working without it, until EnableTwoFactor confirms a first code.
+func (s *AuthService) SetupTwoFactor(userId uint) (*TwoFactorSetupResponse, error) {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).Update("two_factor_secret", secret).Error; err != nil {
+		return nil, fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+
+	issuer := config.NewConfig().TwoFactorIssuer
+	return &TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    totpURI(issuer, user.Email, secret),
+	}, nil
+}
+
+// EnableTwoFactor turns two-factor authentication on once the user proves their
+// authenticator works, and returns their recovery codes
+func (s *AuthService) EnableTwoFactor(userId uint, code string) (*RecoveryCodesResponse, error) {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TwoFactorSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	step, ok := verifyTOTP(user.TwoFactorSecret, code, time.Now(), user.TwoFactorLastStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{
+			"two_factor_enabled":   true,
+			"two_factor_last_step": step,
+			"two_factor_failures":  0,
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return err
+		}
+		codes, err = s.replaceRecoveryCodes(tx, user.Id)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a current
+// TOTP or recovery code. Members of roles that require it cannot turn it off.
+func (s *AuthService) DisableTwoFactor(userId uint, req *TwoFactorCodeRequest, client sessions.Client) error {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	required, err := s.roleRequiresTwoFactor(user.RoleId)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRoleRequired
+	}
+	if err := s.checkTwoFactor(user, req.Code, req.RecoveryCode, client); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{
+			"two_factor_secret":    "",
+			"two_factor_enabled":   false,
+			"two_factor_last_step": 0,
+			"two_factor_failures":  0,
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+		if err := tx.Where("user_id = ?", user.Id).Delete(&RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of a user after checking a
+// current TOTP code
+func (s *AuthService) RegenerateRecoveryCodes(userId uint, code string, client sessions.Client) (*RecoveryCodesResponse, error) {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.checkTwoFactor(user, code, "", client); err != nil {
+		return nil, err
+	}
+	if err := s.loginSucceeded(user); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		codes, err = s.replaceRecoveryCodes(tx, user.Id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyTwoFactor completes a login that returned a "two_factor" challenge
+func (s *AuthService) VerifyTwoFactor(req *TwoFactorChallengeRequest, client sessions.Client) (*AuthResponse, error) {
+	claims, user, err := s.challengeUser(req.ChallengeToken, ChallengeTwoFactor)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.checkTwoFactor(user, req.Code, req.RecoveryCode, client); err != nil {
+		return nil, err
+	}
+	if err := s.consumeChallenge(claims); err != nil {
+		return nil, err
+	}
+
+	return s.startLogin(user, client)
+}
+
+// SetupTwoFactorChallenge generates a TOTP secret for a user whose login returned a
+// "two_factor_setup" challenge
+func (s *AuthService) SetupTwoFactorChallenge(challengeToken string) (*TwoFactorSetupResponse, error) {
+	_, user, err := s.challengeUser(challengeToken, ChallengeTwoFactorSetup)
+	if err != nil {
+		return nil, err
+	}
+	return s.SetupTwoFactor(user.Id)
+}
+
+// EnableTwoFactorChallenge enables two-factor authentication for a user whose login
+// returned a "two_factor_setup" challenge, and completes the login. The response
+// carries the new recovery codes.
+func (s *AuthService) EnableTwoFactorChallenge(req *TwoFactorChallengeRequest, client sessions.Client) (*AuthResponse, error) {
+	claims, user, err := s.challengeUser(req.ChallengeToken, ChallengeTwoFactorSetup)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := s.EnableTwoFactor(user.Id, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.consumeChallenge(claims); err != nil {
+		return nil, err
+	}
+
+	response, err := s.startLogin(user, client)
+	if response != nil {
+		response.RecoveryCodes = codes.RecoveryCodes
+	}
+	return response, err
+}
+
+// challengeUser validates a challenge token and loads its user
+func (s *AuthService) challengeUser(challengeToken, purpose string) (*types.Claims, *AuthUser, error) {
+	claims, err := types.ParseChallengeToken(challengeToken, purpose)
+	if err != nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+	user, err := s.findUser(claims.UserId)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, nil, ErrInvalidChallenge
+		}
+		return nil, nil, err
+	}
+	return claims, user, nil
+}
+
+// consumeChallenge revokes a challenge token so it cannot complete a second login
+func (s *AuthService) consumeChallenge(claims *types.Claims) error {
+	return s.sessions.RevokeToken(claims.TokenId, claims.ExpiresAt)
+}
+
+// checkTwoFactor checks a second factor as Login checks a password: a locked or
+// backing-off account is refused, and wrong codes count as failed logins toward
+// its lockout. New challenges do not reset the count; only a successful login or
+// a lockout does. The attempt is counted before the code is checked, so
+// concurrent guesses cannot get past the limit.
+func (s *AuthService) checkTwoFactor(user *AuthUser, code, recoveryCode string, client sessions.Client) error {
+	if err := s.checkAccount(user, user.Email, client); err != nil {
+		return err
+	}
+
+	// The counter is NULL for users created before it
+	result := s.db.Model(&AuthUser{}).
+		Where("id = ? AND COALESCE(two_factor_failures, 0) < ?", user.Id, s.policy.maxFailures).
+		UpdateColumn("two_factor_failures", gorm.Expr("COALESCE(two_factor_failures, 0) + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Guesses past the limit still fail logins, so they end in a lockout which clears it
+		if err := s.loginFailed(user, user.Email, client); err != nil {
+			return err
+		}
+		return ErrTwoFactorAttempts
+	}
+
+	err := s.checkSecondFactor(user, code, recoveryCode)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		if err := s.loginFailed(user, user.Email, client); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// checkSecondFactor accepts a TOTP code not used before, or an unused recovery code
+// which it marks as used
+func (s *AuthService) checkSecondFactor(user *AuthUser, code, recoveryCode string) error {
+	if code != "" {
+		step, ok := verifyTOTP(user.TwoFactorSecret, code, time.Now(), user.TwoFactorLastStep)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		// Only one of two concurrent uses of the same code may win
+		result := s.db.Model(&AuthUser{}).
+			Where("id = ? AND two_factor_last_step < ?", user.Id, step).
+			UpdateColumn("two_factor_last_step", step)
+		if result.Error != nil {
+			return fmt.Errorf("database error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	if recoveryCode != "" {
+		result := s.db.Model(&RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.Id, hashRecoveryCode(recoveryCode)).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("database error: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and stores new ones,
+// returning them in plain text for the user to save
+func (s *AuthService) replaceRecoveryCodes(tx *gorm.DB, userId uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userId).Delete(&RecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		records[i] = RecoveryCode{UserId: userId, CodeHash: hashRecoveryCode(code)}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// findUser loads a user by id
+func (s *AuthService) findUser(userId uint) (*AuthUser, error) {
+	var user AuthUser
+	if err := s.db.First(&user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &user, nil
+}
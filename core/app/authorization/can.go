@@ -42,6 +42,14 @@ func Can(action, resourceType string) router.MiddlewareFunc {
 			normalizedResourceType := strings.ToLower(resourceType)
 			normalizedAction := strings.ToLower(action)
 
+			// OAuth tokens only carry the permissions granted to them as scopes
+			if !ScopeAllows(c, normalizedResourceType, normalizedAction) {
+				c.AbortWithStatusJSON(http.StatusForbidden, map[string]any{
+					"error": fmt.Sprintf("permission denied: token lacks the %s:%s scope", normalizedResourceType, normalizedAction),
+				})
+				return nil
+			}
+
 			// Check if the user has permission to perform the action on the resource type
 			hasPermission, err := authorizationService.HasPermission(userId, normalizedResourceType, normalizedAction)
 			if err != nil {
@@ -219,6 +227,9 @@ func CanAny(permissions []string) router.MiddlewareFunc {
 
 				action := strings.ToLower(strings.TrimSpace(parts[0]))
 				resourceType := strings.ToLower(strings.TrimSpace(parts[1]))
+				if !ScopeAllows(c, resourceType, action) {
+					continue
+				}
 
 				hasPermission, err := authorizationService.HasPermission(userId, resourceType, action)
 				if err != nil {
@@ -282,6 +293,12 @@ func CanAll(permissions []string) router.MiddlewareFunc {
 
 				action := strings.ToLower(strings.TrimSpace(parts[0]))
 				resourceType := strings.ToLower(strings.TrimSpace(parts[1]))
+				if !ScopeAllows(c, resourceType, action) {
+					c.AbortWithStatusJSON(http.StatusForbidden, map[string]any{
+						"error": fmt.Sprintf("missing required scope: %s:%s", resourceType, action),
+					})
+					return nil
+				}
 
 				hasPermission, err := authorizationService.HasPermission(userId, resourceType, action)
 				if err != nil {
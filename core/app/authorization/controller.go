@@ -35,6 +35,7 @@ func (c *AuthorizationController) Routes(router *router.RouterGroup) {
 		authzRoutes.POST("/roles", c.CreateRole)
 		authzRoutes.PUT("/roles/:id", c.UpdateRole)
 		authzRoutes.DELETE("/roles/:id", c.DeleteRole)
+		authzRoutes.PUT("/roles/:id/two-factor", c.SetRoleTwoFactor)
 
 		// Permission management
 		authzRoutes.GET("/permissions", c.GetPermissions)
@@ -224,6 +225,59 @@ func (c *AuthorizationController) UpdateRole(ctx *router.Context) error {
 	})
 }
 
+// SetRoleTwoFactor sets whether a role requires two-factor authentication
+// @Summary Require two-factor authentication for a role
+// @Description Members of a role that requires it must enroll in and log in with TOTP two-factor authentication. Applies to system roles too.
+// @Tags Core/Authorization
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Role Id"
+// @Param body body RoleTwoFactorRequest true "Two-factor requirement"
+// @Success 200 {object} object{data=Role} "Role updated successfully"
+// @Failure 400 {object} types.ErrorResponse "Invalid request"
+// @Failure 404 {object} types.ErrorResponse "Role not found"
+// @Failure 500 {object} types.ErrorResponse "Internal server error"
+// @Router /authorization/roles/{id}/two-factor [put]
+func (c *AuthorizationController) SetRoleTwoFactor(ctx *router.Context) error {
+	roleId := ctx.Param("id")
+	roleIdUint, err := strconv.ParseUint(roleId, 10, 64)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error: "Invalid role Id: " + err.Error(),
+		})
+	}
+
+	var req RoleTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{
+			Error: "Invalid request: " + err.Error(),
+		})
+	}
+
+	role, err := c.Service.SetRoleTwoFactor(roleIdUint, *req.Required)
+	if err != nil {
+		if err == ErrRoleNotFound {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{
+				Error: "Role not found",
+			})
+		}
+
+		c.Logger.Error("Error updating role two-factor requirement",
+			logger.String("error", err.Error()),
+			logger.String("role_id", roleId))
+
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{
+			Error: "Failed to update role",
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]any{
+		"data": role,
+	})
+}
+
 // DeleteRole deletes a role
 // @Summary Delete a role
 // @Description Deletes a role by its Id
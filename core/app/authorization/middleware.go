@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 )
 
@@ -75,6 +76,18 @@ func GetOrganizationIdFromContext(c *router.Context) (uint64, error) {
 	return 0, ErrMissingOrganization
 }
 
+// ScopeAllows reports whether a request may use a permission as far as its OAuth
+// token goes: tokens issued to OAuth clients carry the permissions granted to them
+// as "resource:action" scopes, other requests are not limited by scopes
+func ScopeAllows(c *router.Context, resourceType, action string) bool {
+	value, ok := c.Get("oauth_scopes")
+	if !ok {
+		return true
+	}
+	scopes, _ := value.([]string)
+	return slices.Contains(scopes, resourceType+":"+action)
+}
+
 // AuthMiddleware creates a middleware function that checks if the user has permission to access a resource
 func AuthMiddleware(resourceType string, action string) router.MiddlewareFunc {
 	return func(next router.HandlerFunc) router.HandlerFunc {
@@ -105,6 +118,13 @@ func AuthMiddleware(resourceType string, action string) router.MiddlewareFunc {
 				return nil
 			}
 
+			if !ScopeAllows(c, resourceType, action) {
+				c.AbortWithStatusJSON(http.StatusForbidden, map[string]any{
+					"error": ErrPermissionDenied.Error(),
+				})
+				return nil
+			}
+
 			// Check if the user has permission to perform the action on the resource type
 			hasPermission, err := authorizationService.HasPermission(userId, resourceType, action)
 			if err != nil {
@@ -20,13 +20,14 @@ var (
 
 // Role represents a set of permissions assigned to users within an organization
 type Role struct {
-	Id              uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
-	Name            string    `gorm:"not null" json:"name"`
-	Description     string    `json:"description"`
-	IsSystem        bool      `gorm:"default:false" json:"is_system"`
-	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updated_at"`
-	PermissionCount int       `json:"permission_count"` // New field
+	Id               uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Name             string    `gorm:"not null" json:"name"`
+	Description      string    `json:"description"`
+	IsSystem         bool      `gorm:"default:false" json:"is_system"`
+	RequireTwoFactor bool      `gorm:"default:false" json:"require_two_factor"` // Members must log in with a TOTP code
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	PermissionCount  int       `json:"permission_count"` // New field
 }
 
 // ToResponse converts the role to a response object
@@ -35,25 +36,27 @@ func (r *Role) ToResponse() *RoleResponse {
 		return nil
 	}
 	return &RoleResponse{
-		Id:              r.Id,
-		Name:            r.Name,
-		Description:     r.Description,
-		IsSystem:        r.IsSystem,
-		CreatedAt:       r.CreatedAt,
-		UpdatedAt:       r.UpdatedAt,
-		PermissionCount: r.PermissionCount,
+		Id:               r.Id,
+		Name:             r.Name,
+		Description:      r.Description,
+		IsSystem:         r.IsSystem,
+		RequireTwoFactor: r.RequireTwoFactor,
+		CreatedAt:        r.CreatedAt,
+		UpdatedAt:        r.UpdatedAt,
+		PermissionCount:  r.PermissionCount,
 	}
 }
 
 // RoleResponse represents the response structure for a role
 type RoleResponse struct {
-	Id              uint      `json:"id"`
-	Name            string    `json:"name"`
-	Description     string    `json:"description"`
-	IsSystem        bool      `json:"is_system"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	PermissionCount int       `json:"permission_count"` // New field
+	Id               uint      `json:"id"`
+	Name             string    `json:"name"`
+	Description      string    `json:"description"`
+	IsSystem         bool      `json:"is_system"`
+	RequireTwoFactor bool      `json:"require_two_factor"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	PermissionCount  int       `json:"permission_count"` // New field
 }
 
 // CreateRoleRequest represents the payload for creating a role
@@ -69,6 +72,11 @@ type UpdateRoleRequest struct {
 	Description string `json:"description,omitempty"`
 }
 
+// RoleTwoFactorRequest sets whether members of a role must use two-factor authentication
+type RoleTwoFactorRequest struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
 // Permission defines an action that can be performed on a resource
 type Permission struct {
 	Id           uint      `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
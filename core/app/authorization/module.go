@@ -100,6 +100,12 @@ func (m *AuthorizationModule) seedDefaultData() error {
 		"authorization",
 		"media",
 		"profile",
+		"course",
+		"course_run",
+		"course_resource",
+		"lesson",
+		"enrollment",
+		"course_progress_log",
 	}
 
 	// Define actions
@@ -208,6 +214,12 @@ func (m *AuthorizationModule) seedDefaultData() error {
 			"role:create", "role:read", "role:update", "role:delete", "role:list",
 			"permission:create", "permission:read", "permission:update", "permission:delete", "permission:list",
 			"resource_permission:create", "resource_permission:read", "resource_permission:update", "resource_permission:delete", "resource_permission:list",
+			"course:create", "course:read", "course:update", "course:delete", "course:list",
+			"course_run:create", "course_run:read", "course_run:update", "course_run:delete", "course_run:list",
+			"course_resource:create", "course_resource:read", "course_resource:update", "course_resource:delete", "course_resource:list",
+			"lesson:create", "lesson:read", "lesson:update", "lesson:delete", "lesson:list",
+			"enrollment:create", "enrollment:read", "enrollment:update", "enrollment:delete", "enrollment:list",
+			"course_progress_log:create", "course_progress_log:read", "course_progress_log:update", "course_progress_log:delete", "course_progress_log:list",
 		}
 
 		for _, permName := range adminPermissions {
@@ -251,6 +263,12 @@ func (m *AuthorizationModule) seedDefaultData() error {
 			"role:read", "role:list",
 			"permission:read", "permission:list",
 			"resource_permission:read", "resource_permission:list",
+			"course:read", "course:list",
+			"course_run:read", "course_run:list",
+			"course_resource:read", "course_resource:list",
+			"lesson:read", "lesson:list",
+			"enrollment:read", "enrollment:list",
+			"course_progress_log:read", "course_progress_log:list",
 		}
 
 		for _, permName := range memberPermissions {
@@ -294,6 +312,12 @@ func (m *AuthorizationModule) seedDefaultData() error {
 			"role:read", "role:list",
 			"permission:read", "permission:list",
 			"resource_permission:read", "resource_permission:list",
+			"course:read", "course:list",
+			"course_run:read", "course_run:list",
+			"course_resource:read", "course_resource:list",
+			"lesson:read", "lesson:list",
+			"enrollment:read", "enrollment:list",
+			"course_progress_log:read", "course_progress_log:list",
 		}
 
 		for _, permName := range viewerPermissions {
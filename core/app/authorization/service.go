@@ -117,6 +117,24 @@ func (s *AuthorizationService) UpdateRole(role *Role) error {
 	return nil
 }
 
+// SetRoleTwoFactor sets whether members of a role must log in with two-factor
+// authentication. Unlike other role changes it applies to system roles too.
+func (s *AuthorizationService) SetRoleTwoFactor(id uint64, required bool) (*Role, error) {
+	var role Role
+	if err := s.DB.First(&role, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+
+	role.RequireTwoFactor = required
+	if err := s.DB.Model(&role).Update("require_two_factor", required).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
 // DeleteRole deletes a role
 func (s *AuthorizationService) DeleteRole(id uint64) error {
 	var existingRole Role
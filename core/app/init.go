@@ -1,11 +1,16 @@
 package app
 
 import (
+	"base/core/app/apikeys"
 	"base/core/app/authentication"
 	"base/core/app/authorization"
 	"base/core/app/media"
 	"base/core/app/oauth"
+	"base/core/app/oauthserver"
 	"base/core/app/profile"
+	"base/core/app/sessions"
+	"base/core/app/uploads"
+	"base/core/app/verification"
 	"base/core/module"
 	"base/core/scheduler"
 	"base/core/translation"
@@ -20,11 +25,30 @@ func (cm *CoreModules) GetCoreModules(deps module.Dependencies) map[string]modul
 	modules := make(map[string]module.Module)
 
 	// Core modules - essential system functionality
+	sessionsModule := sessions.NewSessionModule(
+		deps.DB,
+		deps.Router,
+		deps.Logger,
+		deps.Config.RefreshTokenTTL,
+	)
+	modules["sessions"] = sessionsModule
+
+	verificationModule := verification.NewVerificationModule(
+		deps.DB,
+		deps.Router,
+		deps.EmailSender,
+		deps.Logger,
+		deps.Config,
+	)
+	modules["verification"] = verificationModule
+
 	modules["users"] = profile.NewUserModule(
 		deps.DB,
 		deps.Router,
 		deps.Logger,
 		deps.Storage,
+		sessionsModule.Service,
+		verificationModule.Service,
 	)
 
 	modules["media"] = media.NewMediaModule(
@@ -35,40 +59,70 @@ func (cm *CoreModules) GetCoreModules(deps module.Dependencies) map[string]modul
 		deps.Logger,
 	)
 
-	modules["authentication"] = authentication.NewAuthenticationModule(
+	modules["uploads"] = uploads.NewUploadModule(
+		deps.DB,
+		deps.Router,
+		deps.Storage,
+		deps.Emitter,
+		deps.Logger,
+		deps.Config,
+	)
+
+	schedulerModule := scheduler.NewSchedulerModule(
+		deps.DB,
+		deps.Router,
+		deps.Logger,
+		deps.Emitter,
+		deps.Storage,
+	)
+	modules["scheduler"] = schedulerModule
+
+	authenticationModule := authentication.NewAuthenticationModule(
 		deps.DB,
 		deps.Router, // Will be handled by orchestrator to use AuthRouter
 		deps.EmailSender,
 		deps.Logger,
 		deps.Emitter,
+		sessionsModule.Service,
+		verificationModule.Service,
+		schedulerModule.GetScheduler(),
 	)
+	modules["authentication"] = authenticationModule
 
 	modules["oauth"] = oauth.NewOAuthModule(
 		deps.DB,
 		deps.Router,
 		deps.Logger,
 		deps.Storage,
+		authenticationModule.Service,
 	)
 
-	modules["authorization"] = authorization.NewAuthorizationModule(
+	modules["apikeys"] = apikeys.NewAPIKeyModule(
 		deps.DB,
-		deps.Router, // Will be handled by orchestrator to use AuthRouter
+		deps.Router,
 		deps.Logger,
 	)
 
-	modules["translation"] = translation.NewTranslationModule(
+	modules["oauthserver"] = oauthserver.NewOAuthServerModule(
 		deps.DB,
 		deps.Router,
 		deps.Logger,
-		deps.Emitter,
-		deps.Storage,
+		sessionsModule.Service,
+		deps.Config,
 	)
 
-	modules["scheduler"] = scheduler.NewSchedulerModule(
+	modules["authorization"] = authorization.NewAuthorizationModule(
+		deps.DB,
+		deps.Router, // Will be handled by orchestrator to use AuthRouter
+		deps.Logger,
+	)
+
+	modules["translation"] = translation.NewTranslationModule(
 		deps.DB,
 		deps.Router,
 		deps.Logger,
 		deps.Emitter,
+		deps.Storage,
 	)
 
 	return modules
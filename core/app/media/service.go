@@ -30,6 +30,13 @@ func NewMediaService(db *gorm.DB, emitter *emitter.Emitter, activeStorage *stora
 		AllowedExtensions: []string{".jpg", ".jpeg", ".png", ".mp3", ".webp", ".webv", ".wav", ".ogg"},
 		MaxFileSize:       100 << 20, // 100MB
 		Multiple:          false,
+		// Most media files are never shown resized, so variants are made on first request
+		Variants: []storage.Variant{
+			{Name: "thumb", Width: 320, Height: 180, Fit: storage.FitCover},
+			{Name: "medium", Width: 800},
+			{Name: "webp", Width: 800, Format: storage.FormatWebP},
+		},
+		LazyVariants: true,
 	})
 
 	return &MediaService{
@@ -164,7 +171,7 @@ func (s *MediaService) Create(req *CreateMediaRequest) (*Media, error) {
 	// Handle file upload if provided
 	if req.File != nil {
 		// Upload the file using storage system
-		attachment, err := s.ActiveStorage.Attach(item, "file", req.File)
+		attachment, err := s.ActiveStorage.Attach(item, "file", req.File, 0)
 		if err != nil {
 			tx.Rollback()
 			s.Logger.Error("failed to upload file", logger.String("error", err.Error()))
@@ -234,7 +241,7 @@ func (s *MediaService) Update(id uint, req *UpdateMediaRequest) (*Media, error)
 		}
 
 		// Upload new file
-		attachment, err := s.ActiveStorage.Attach(item, "file", req.File)
+		attachment, err := s.ActiveStorage.Attach(item, "file", req.File, 0)
 		if err != nil {
 			tx.Rollback()
 			s.Logger.Error("failed to upload file", logger.String("error", err.Error()))
@@ -337,7 +344,7 @@ func (s *MediaService) UpdateFile(ctx context.Context, id uint, file *multipart.
 	}
 
 	// Upload new file
-	attachment, err := s.ActiveStorage.Attach(item, "file", file)
+	attachment, err := s.ActiveStorage.Attach(item, "file", file, 0)
 	if err != nil {
 		tx.Rollback()
 		s.Logger.Error("failed to upload file", logger.String("error", err.Error()))
@@ -3,12 +3,14 @@ package oauth
 import (
 	"log"
 	"os"
+	"strings"
 )
 
 type OAuthConfig struct {
 	Google    ProviderConfig
 	Facebook  ProviderConfig
 	Apple     ProviderConfig
+	OIDC      []OIDCProviderConfig
 	JWTSecret string
 }
 
@@ -18,6 +20,30 @@ type ProviderConfig struct {
 	RedirectURL  string
 }
 
+// OIDCProviderConfig configures a generic OpenID Connect provider such as Okta,
+// Keycloak or Azure AD; its endpoints and keys come from the issuer's discovery
+// document
+type OIDCProviderConfig struct {
+	Name         string // Used in the login URLs, e.g. okta
+	DisplayName  string
+	Issuer       string
+	ClientId     string
+	ClientSecret string // Empty for public clients
+	RedirectURL  string
+	Scopes       []string
+	Claims       map[string]string // profile.User field to ID token claim
+	TrustEmail   bool              // Link accounts by email even without email_verified
+}
+
+// defaultOIDCClaims maps profile.User fields to standard OIDC claims
+var defaultOIDCClaims = map[string]string{
+	"email":      "email",
+	"first_name": "given_name",
+	"last_name":  "family_name",
+	"username":   "preferred_username",
+	"phone":      "phone_number",
+}
+
 func LoadConfig() *OAuthConfig {
 	log.Println("Loading OAuth configuration")
 	config := &OAuthConfig{
@@ -36,6 +62,7 @@ func LoadConfig() *OAuthConfig {
 			ClientSecret: os.Getenv("APPLE_CLIENT_SECRET"),
 			RedirectURL:  os.Getenv("APPLE_REDIRECT_URL"),
 		},
+		OIDC:      loadOIDCConfig(),
 		JWTSecret: os.Getenv("JWT_SECRET"),
 	}
 	log.Println("OAuth configuration loaded successfully")
@@ -59,6 +86,14 @@ func ValidateConfig(config *OAuthConfig) {
 		hasProvider = true
 		log.Println("Apple OAuth provider configured")
 	}
+	for _, provider := range config.OIDC {
+		if provider.Issuer == "" || provider.ClientId == "" || provider.RedirectURL == "" {
+			log.Printf("Warning: OIDC provider %s needs an issuer, client Id and redirect URL; it is disabled.", provider.Name)
+			continue
+		}
+		hasProvider = true
+		log.Printf("OIDC provider %s configured (%s)", provider.Name, provider.Issuer)
+	}
 
 	if !hasProvider {
 		log.Println("Warning: No OAuth providers configured. OAuth functionality will be disabled.")
@@ -71,3 +106,48 @@ func ValidateConfig(config *OAuthConfig) {
 
 	log.Println("OAuth configuration validated successfully")
 }
+
+// loadOIDCConfig reads the providers named in OIDC_PROVIDERS, each configured by
+// OIDC_<NAME>_* variables. OIDC_<NAME>_CLAIMS overrides the claim of a field as
+// comma-separated field=claim pairs, e.g. email=upn.
+func loadOIDCConfig() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		provider := OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  os.Getenv(prefix + "DISPLAY_NAME"),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientId:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+			Claims:       make(map[string]string, len(defaultOIDCClaims)),
+			TrustEmail:   os.Getenv(prefix+"TRUST_EMAIL") == "true",
+		}
+		if provider.DisplayName == "" {
+			provider.DisplayName = name
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		for field, claim := range defaultOIDCClaims {
+			provider.Claims[field] = claim
+		}
+		for _, pair := range strings.Split(os.Getenv(prefix+"CLAIMS"), ",") {
+			field, claim, ok := strings.Cut(pair, "=")
+			field = strings.TrimSpace(field)
+			if _, known := defaultOIDCClaims[field]; !ok || !known {
+				continue
+			}
+			provider.Claims[field] = strings.TrimSpace(claim)
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
@@ -1,9 +1,16 @@
 package oauth
 
 import (
+	"base/core/app/authentication"
+	"base/core/app/sessions"
+	"base/core/app/verification"
 	"base/core/logger"
 	"base/core/router"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type OAuthController struct {
@@ -24,6 +31,9 @@ func (c *OAuthController) Routes(router *router.RouterGroup) {
 	router.POST("/google/callback", c.GoogleCallback)
 	router.POST("/facebook/callback", c.FacebookCallback)
 	router.POST("/apple/callback", c.AppleCallback)
+	router.GET("/oidc/providers", c.OIDCProviders)
+	router.GET("/oidc/:provider/authorize", c.OIDCAuthorize)
+	router.POST("/oidc/:provider/callback", c.OIDCCallback)
 }
 
 // GoogleCallback godoc
@@ -128,6 +138,106 @@ func (c *OAuthController) AppleCallback(ctx *router.Context) error {
 	return nil
 }
 
+// OIDCProviders godoc
+// @Summary List OIDC providers
+// @Description List the configured OpenID Connect providers users can log in with
+// @Security ApiKeyAuth
+// @Tags Core/OAuth
+// @Produce json
+// @Success 200 {array} OIDCProviderResponse
+// @Router /oauth/oidc/providers [get]
+func (c *OAuthController) OIDCProviders(ctx *router.Context) error {
+	return ctx.JSON(http.StatusOK, c.Service.OIDCProviders())
+}
+
+// OIDCAuthorize godoc
+// @Summary Start OIDC login
+// @Description Start a login with an OpenID Connect provider. Send the user to the returned URL; the provider redirects them back to the configured redirect URL with a code and the state, which go to the callback.
+// @Security ApiKeyAuth
+// @Tags Core/OAuth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} OIDCAuthorizeResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /oauth/oidc/{provider}/authorize [get]
+func (c *OAuthController) OIDCAuthorize(ctx *router.Context) error {
+	response, err := c.Service.StartOIDCLogin(ctx.Request.Context(), ctx.Param("provider"))
+	if err != nil {
+		if errors.Is(err, ErrOIDCProviderNotFound) {
+			return ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		}
+		c.Logger.Error("Failed to start OIDC login",
+			logger.String("provider", ctx.Param("provider")),
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusBadGateway, ErrorResponse{Error: "Identity provider unavailable"})
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// OIDCCallback godoc
+// @Summary Finish OIDC login
+// @Description Finish a login with the code and state the OpenID Connect provider returned. Responds like a password login: tokens, or a two-factor challenge.
+// @Security ApiKeyAuth
+// @Tags Core/OAuth
+// @Accept json
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param body body OIDCCallbackRequest true "Code and state"
+// @Success 200 {object} authentication.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} authentication.LoginBlockedResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /oauth/oidc/{provider}/callback [post]
+func (c *OAuthController) OIDCCallback(ctx *router.Context) error {
+	var req OIDCCallbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload"})
+	}
+
+	client := sessions.Client{UserAgent: ctx.Request.UserAgent(), IP: ctx.ClientIP()}
+	response, err := c.Service.FinishOIDCLogin(ctx.Request.Context(), ctx.Param("provider"), &req, client)
+	if err != nil {
+		var blocked *authentication.LoginBlockedError
+		switch {
+		case errors.As(err, &blocked):
+			retryAfter := int64(math.Ceil(blocked.RetryAfter.Seconds()))
+			ctx.SetHeader("Retry-After", strconv.FormatInt(retryAfter, 10))
+			return ctx.JSON(http.StatusTooManyRequests, authentication.LoginBlockedResponse{Error: blocked.Error(), RetryAfter: retryAfter})
+		case errors.Is(err, ErrOIDCProviderNotFound):
+			return ctx.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrOIDCInvalidState):
+			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrOIDCInvalidIdToken), errors.Is(err, ErrOIDCNoEmail):
+			c.Logger.Warn("OIDC login rejected",
+				logger.String("provider", ctx.Param("provider")),
+				logger.String("error", err.Error()))
+			return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrOIDCAccountExists):
+			return ctx.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, verification.ErrEmailNotVerified):
+			return ctx.JSON(http.StatusForbidden, ErrorResponse{Error: "Verify your email address to log in"})
+		case errors.Is(err, authentication.ErrUserNotFound):
+			return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		case strings.Contains(err.Error(), "access_denied"):
+			return ctx.JSON(http.StatusForbidden, map[string]any{
+				"error": err.Error(),
+				"data":  response,
+			})
+		default:
+			c.Logger.Error("OIDC login failed",
+				logger.String("provider", ctx.Param("provider")),
+				logger.String("error", err.Error()))
+			return ctx.JSON(http.StatusBadGateway, ErrorResponse{Error: "OIDC login failed"})
+		}
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"base/core/app/authentication"
 	"base/core/logger"
 	"base/core/module"
 	"base/core/router"
@@ -18,11 +19,11 @@ type OAuthModule struct {
 	ActiveStorage *storage.ActiveStorage
 }
 
-func NewOAuthModule(db *gorm.DB, router *router.RouterGroup, logger logger.Logger, activeStorage *storage.ActiveStorage) module.Module {
+func NewOAuthModule(db *gorm.DB, router *router.RouterGroup, logger logger.Logger, activeStorage *storage.ActiveStorage, auth *authentication.AuthService) module.Module {
 	config := LoadConfig()
 	ValidateConfig(config)
 
-	service := NewOAuthService(db, config, activeStorage)
+	service := NewOAuthService(db, config, activeStorage, auth)
 	controller := NewOAuthController(service, logger, config)
 
 	oauthModule := &OAuthModule{
@@ -42,11 +43,12 @@ func (m *OAuthModule) Routes(router *router.RouterGroup) {
 }
 
 func (m *OAuthModule) Migrate() error {
-	return m.DB.AutoMigrate(&AuthProvider{})
+	return m.DB.AutoMigrate(&AuthProvider{}, &OIDCLoginState{})
 }
 
 func (m *OAuthModule) GetModels() []any {
 	return []any{
 		&AuthProvider{},
+		&OIDCLoginState{},
 	}
 }
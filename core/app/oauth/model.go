@@ -32,6 +32,38 @@ func (AuthProvider) TableName() string {
 	return "auth_providers"
 }
 
+// OIDCLoginState is a login started with an OIDC provider, kept until its callback.
+// The state is stored as its SHA-256; the nonce and PKCE verifier bind the code
+// the provider returns to this login.
+type OIDCLoginState struct {
+	Id           uint      `gorm:"primaryKey"`
+	StateHash    string    `gorm:"size:64;uniqueIndex"`
+	Provider     string    `gorm:"size:50"`
+	Nonce        string    `gorm:"size:64"`
+	CodeVerifier string    `gorm:"size:128"`
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
+
+func (OIDCLoginState) TableName() string {
+	return "oidc_login_states"
+}
+
+type OIDCProviderResponse struct {
+	Name        string `json:"name" example:"okta"`
+	DisplayName string `json:"display_name" example:"Okta"`
+}
+
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"` // Also in the URL; the provider sends it back with the code
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
 // You might want to add OAuth-specific request/response structs here
 type OAuthLoginRequest struct {
 	Provider    string `json:"provider" binding:"required"`
@@ -0,0 +1,368 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	discoveryTTL     = time.Hour
+	jwksRefreshAfter = time.Minute // Unknown key ids refetch the JWKS at most this often
+	idTokenLeeway    = time.Minute
+	maxResponseBytes = 1 << 20
+)
+
+var (
+	ErrOIDCProviderNotFound = errors.New("unknown OIDC provider")
+	ErrOIDCInvalidState     = errors.New("invalid or expired login state")
+	ErrOIDCInvalidIdToken   = errors.New("invalid ID token")
+)
+
+// idTokenAlgorithms are the signing algorithms accepted on ID tokens; none and
+// the HMAC ones are never accepted
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// OIDCDiscovery is the part of an OpenID provider's discovery document the login uses
+type OIDCDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// OIDCProvider runs the authorization code flow against one OpenID provider. The
+// discovery document and the signing keys are fetched on first use and cached.
+type OIDCProvider struct {
+	Config OIDCProviderConfig
+	client *http.Client
+
+	mu           sync.Mutex
+	discovery    *OIDCDiscovery
+	discoveredAt time.Time
+	keys         map[string]any // Public keys by key id
+	keysFetched  time.Time
+}
+
+// oidcTokens is the token endpoint's response to an authorization code
+type oidcTokens struct {
+	AccessToken      string `json:"access_token"`
+	IdToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func NewOIDCProvider(config OIDCProviderConfig, client *http.Client) *OIDCProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if !slices.Contains(config.Scopes, "openid") {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+	return &OIDCProvider{Config: config, client: client}
+}
+
+// AuthorizationURL is where the user signs in with the provider; the code it
+// returns is bound to the state, nonce and PKCE verifier of this login
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	target, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	query := target.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.Config.ClientId)
+	query.Set("redirect_uri", p.Config.RedirectURL)
+	query.Set("scope", strings.Join(p.Config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", pkceChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	target.RawQuery = query.Encode()
+	return target.String(), nil
+}
+
+// Exchange trades an authorization code for the provider's tokens and returns
+// the claims of the validated ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (jwt.MapClaims, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.Config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	// client_secret_basic is the default; client_secret_post only when it is all the provider takes
+	basic := p.Config.ClientSecret != "" && (len(discovery.TokenEndpointAuthMethodsSupported) == 0 ||
+		slices.Contains(discovery.TokenEndpointAuthMethodsSupported, "client_secret_basic"))
+	if !basic {
+		form.Set("client_id", p.Config.ClientId)
+		if p.Config.ClientSecret != "" {
+			form.Set("client_secret", p.Config.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(p.Config.ClientId), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens oidcTokens
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if tokens.Error != "" {
+			return nil, fmt.Errorf("token request failed: %s %s", tokens.Error, tokens.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+	if tokens.IdToken == "" {
+		return nil, fmt.Errorf("%w: the token response has none", ErrOIDCInvalidIdToken)
+	}
+
+	return p.VerifyIdToken(ctx, tokens.IdToken, nonce)
+}
+
+// VerifyIdToken checks the signature of an ID token against the provider's keys,
+// its issuer, audience, expiry and nonce, and returns its claims
+func (p *OIDCProvider) VerifyIdToken(ctx context.Context, raw, nonce string) (jwt.MapClaims, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid, token.Method.Alg())
+	},
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.Config.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidIdToken, err)
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrOIDCInvalidIdToken)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCInvalidIdToken)
+	}
+	// With several audiences the token must have been issued to us
+	audience, _ := claims.GetAudience()
+	if azp, _ := claims["azp"].(string); (len(audience) > 1 || azp != "") && azp != p.Config.ClientId {
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrOIDCInvalidIdToken)
+	}
+	return claims, nil
+}
+
+// Discover returns the provider's discovery document, fetching it when it is not
+// cached; its issuer must be the configured one
+func (p *OIDCProvider) Discover(ctx context.Context) (*OIDCDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	var discovery OIDCDiscovery
+	endpoint := strings.TrimSuffix(p.Config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, endpoint, &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if discovery.Issuer != p.Config.Issuer {
+		return nil, fmt.Errorf("OIDC discovery failed: issuer %q does not match %q", discovery.Issuer, p.Config.Issuer)
+	}
+	for _, endpoint := range []string{discovery.AuthorizationEndpoint, discovery.TokenEndpoint, discovery.JWKSURI} {
+		if !secureURL(endpoint) {
+			return nil, fmt.Errorf("OIDC discovery failed: endpoint %q is not an https URL", endpoint)
+		}
+	}
+
+	if p.discovery != nil && p.discovery.JWKSURI != discovery.JWKSURI {
+		p.keys = nil
+	}
+	p.discovery = &discovery
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+// key returns the public key with a key id for an algorithm. Unknown ids refetch
+// the JWKS, so keys the provider rotated in are picked up.
+func (p *OIDCProvider) key(ctx context.Context, kid, alg string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.findKey(kid)
+	if !ok && time.Since(p.keysFetched) >= jwksRefreshAfter {
+		if err := p.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+		key, ok = p.findKey(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS") {
+			return key, nil
+		}
+	case *ecdsa.PublicKey:
+		if strings.HasPrefix(alg, "ES") {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("signing key %q does not fit %s", kid, alg)
+}
+
+// findKey looks a key up by id; tokens without one may use the only key there is
+func (p *OIDCProvider) findKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// fetchKeys loads the signing keys of the JWKS; keys of other uses or unsupported
+// types are skipped. Callers hold p.mu.
+func (p *OIDCProvider) fetchKeys(ctx context.Context) error {
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	p.keysFetched = time.Now()
+	if err := p.getJSON(ctx, p.discovery.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			curve := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}[jwk.Crv]
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if curve == nil || errX != nil || errY != nil {
+				continue
+			}
+			key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+			if !curve.IsOnCurve(key.X, key.Y) {
+				continue
+			}
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	return nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, endpoint string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(target)
+}
+
+// secureURL accepts https URLs, and http URLs on loopback hosts for local issuers
+func secureURL(raw string) bool {
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" {
+		return false
+	}
+	switch target.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := target.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
+
+// pkceChallenge is the S256 code challenge of a PKCE verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,271 @@
+package oauth
+
+import (
+	"base/core/app/authentication"
+	"base/core/app/profile"
+	"base/core/app/sessions"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const oidcLoginTTL = 10 * time.Minute
+
+var (
+	ErrOIDCNoEmail       = errors.New("the identity provider did not share an email address")
+	ErrOIDCAccountExists = errors.New("an account with this email already exists; the identity provider has not verified the address, so it cannot be linked")
+)
+
+// oidcIdentity is a user as an OIDC provider describes them, mapped to profile.User fields
+type oidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Username      string
+	Phone         string
+}
+
+// OIDCProviders lists the configured OIDC providers for login buttons
+func (s *OAuthService) OIDCProviders() []OIDCProviderResponse {
+	providers := make([]OIDCProviderResponse, 0, len(s.OIDC))
+	for _, config := range s.Config.OIDC {
+		if _, ok := s.OIDC[config.Name]; ok {
+			providers = append(providers, OIDCProviderResponse{Name: config.Name, DisplayName: config.DisplayName})
+		}
+	}
+	return providers
+}
+
+// StartOIDCLogin begins a login with an OIDC provider and returns where to send
+// the user to sign in. The state, nonce and PKCE verifier stay on the server
+// until the callback.
+func (s *OAuthService) StartOIDCLogin(ctx context.Context, name string) (*OIDCAuthorizeResponse, error) {
+	provider, ok := s.OIDC[name]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := randomHex(32)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authorizationURL, err := provider.AuthorizationURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Abandoned logins are cleaned up as new ones start
+	if err := s.DB.Where("expires_at < ?", time.Now()).Delete(&OIDCLoginState{}).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if err := s.DB.Create(&OIDCLoginState{
+		StateHash:    hashState(state),
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to store login state: %w", err)
+	}
+
+	return &OIDCAuthorizeResponse{AuthorizationURL: authorizationURL, State: state}, nil
+}
+
+// FinishOIDCLogin completes a login with the code the provider returned: the
+// state is consumed, the code exchanged and the ID token validated. The user is
+// found by their provider identity, linked by a verified email or created, then
+// logged in like a password login, so two-factor challenges still apply.
+func (s *OAuthService) FinishOIDCLogin(ctx context.Context, name string, req *OIDCCallbackRequest, client sessions.Client) (*authentication.AuthResponse, error) {
+	provider, ok := s.OIDC[name]
+	if !ok {
+		return nil, ErrOIDCProviderNotFound
+	}
+	if req.Code == "" || req.State == "" {
+		return nil, ErrOIDCInvalidState
+	}
+
+	var login OIDCLoginState
+	if err := s.DB.Where("state_hash = ? AND provider = ?", hashState(req.State), name).First(&login).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOIDCInvalidState
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	// A state works once; only one of two concurrent callbacks may win
+	result := s.DB.Delete(&OIDCLoginState{}, login.Id)
+	if result.Error != nil {
+		return nil, fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 || time.Now().After(login.ExpiresAt) {
+		return nil, ErrOIDCInvalidState
+	}
+
+	claims, err := provider.Exchange(ctx, req.Code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := provider.identity(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := s.oidcUser("oidc:"+name, identity, provider.Config.TrustEmail)
+	if err != nil {
+		return nil, err
+	}
+	return s.Auth.ExternalLogin(userId, client)
+}
+
+// oidcUser returns the user of a provider identity, linking or creating one on
+// the first login. Names follow the provider on every login.
+func (s *OAuthService) oidcUser(provider string, identity *oidcIdentity, trustEmail bool) (uint, error) {
+	var userId uint
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var user profile.User
+		var link AuthProvider
+		err := tx.Where("provider = ? AND provider_id = ?", provider, identity.Subject).First(&link).Error
+		switch {
+		case err == nil:
+			// When the linked user was deleted the identity gets linked anew
+			if err := tx.First(&user, link.UserId).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("database error: %w", err)
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return fmt.Errorf("database error: %w", err)
+		}
+
+		emailTrusted := identity.EmailVerified || trustEmail
+		if user.Id == 0 {
+			err := tx.Where("email = ?", identity.Email).First(&user).Error
+			switch {
+			case err == nil:
+				// Only an address the provider vouches for may take over an existing account
+				if !emailTrusted {
+					return ErrOIDCAccountExists
+				}
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				// Phone numbers are unique; one taken by another account is left out
+				var taken int64
+				if identity.Phone != "" {
+					if err := tx.Model(&profile.User{}).Where("phone = ?", identity.Phone).Count(&taken).Error; err != nil {
+						return fmt.Errorf("database error: %w", err)
+					}
+				}
+				if taken > 0 {
+					identity.Phone = ""
+				}
+				user = profile.User{
+					Email:     identity.Email,
+					FirstName: identity.FirstName,
+					LastName:  identity.LastName,
+					Username:  s.generateUniqueUsername(identity.Username),
+					Phone:     identity.Phone,
+				}
+				if emailTrusted {
+					now := time.Now()
+					user.VerifiedAt = &now
+				}
+				if err := tx.Create(&user).Error; err != nil {
+					return fmt.Errorf("failed to create user: %w", err)
+				}
+			default:
+				return fmt.Errorf("database error: %w", err)
+			}
+		}
+
+		updates := map[string]any{}
+		if identity.FirstName != "" && identity.FirstName != user.FirstName {
+			updates["first_name"] = identity.FirstName
+		}
+		if identity.LastName != "" && identity.LastName != user.LastName {
+			updates["last_name"] = identity.LastName
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update user: %w", err)
+			}
+		}
+
+		link.UserId = user.Id
+		link.Provider = provider
+		link.ProviderId = identity.Subject
+		link.LastLogin = time.Now()
+		if err := tx.Save(&link).Error; err != nil {
+			return fmt.Errorf("failed to link identity provider: %w", err)
+		}
+		userId = user.Id
+		return nil
+	})
+	return userId, err
+}
+
+// identity maps the claims of an ID token to profile.User fields through the
+// provider's claim mapping
+func (p *OIDCProvider) identity(claims jwt.MapClaims) (*oidcIdentity, error) {
+	claim := func(field string) string {
+		value, _ := claims[p.Config.Claims[field]].(string)
+		return strings.TrimSpace(value)
+	}
+
+	identity := &oidcIdentity{
+		Email:     strings.ToLower(claim("email")),
+		FirstName: claim("first_name"),
+		LastName:  claim("last_name"),
+		Username:  claim("username"),
+		Phone:     claim("phone"),
+	}
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Email == "" || !strings.Contains(identity.Email, "@") {
+		return nil, ErrOIDCNoEmail
+	}
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	// Only the email claim itself is vouched for
+	if p.Config.Claims["email"] != "email" {
+		identity.EmailVerified = false
+	}
+
+	if identity.FirstName == "" && identity.LastName == "" {
+		name, _ := claims["name"].(string)
+		identity.FirstName, identity.LastName, _ = strings.Cut(strings.TrimSpace(name), " ")
+	}
+	if identity.Username == "" || strings.Contains(identity.Username, "@") {
+		identity.Username, _, _ = strings.Cut(identity.Email, "@")
+	}
+	identity.Username = strings.ToLower(strings.Join(strings.Fields(identity.Username), ""))
+	return identity, nil
+}
+
+func hashState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,261 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"base/core/app/oauth/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientId     = "base-app"
+	testClientSecret = "base-secret"
+	testRedirectURL  = "http://localhost:8080/login/callback"
+)
+
+func newTestProvider(t *testing.T) (*oidctest.Issuer, *OIDCProvider) {
+	t.Helper()
+	issuer := oidctest.NewIssuer(testClientId, testClientSecret)
+	t.Cleanup(issuer.Close)
+	provider := NewOIDCProvider(OIDCProviderConfig{
+		Name:         "test",
+		Issuer:       issuer.URL,
+		ClientId:     testClientId,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"email", "profile"},
+		Claims:       defaultOIDCClaims,
+	}, nil)
+	return issuer, provider
+}
+
+// idTokenClaims are the claims of a valid ID token from the issuer
+func idTokenClaims(issuer *oidctest.Issuer, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   issuer.URL,
+		"aud":   testClientId,
+		"sub":   "oidctest-user",
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func TestOIDCDiscover(t *testing.T) {
+	issuer, provider := newTestProvider(t)
+
+	discovery, err := provider.Discover(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discovery.Issuer != issuer.URL ||
+		discovery.AuthorizationEndpoint != issuer.URL+"/authorize" ||
+		discovery.TokenEndpoint != issuer.URL+"/token" ||
+		discovery.JWKSURI != issuer.URL+"/jwks" {
+		t.Errorf("unexpected discovery document %+v", discovery)
+	}
+
+	// The issuer of the document must be the configured one
+	other := NewOIDCProvider(OIDCProviderConfig{Issuer: issuer.URL + "/", ClientId: testClientId}, nil)
+	if _, err := other.Discover(context.Background()); err == nil {
+		t.Error("discovery accepted a document of another issuer")
+	}
+}
+
+func TestOIDCAuthorizationURL(t *testing.T) {
+	issuer, provider := newTestProvider(t)
+
+	target, err := provider.AuthorizationURL(context.Background(), "state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != issuer.URL+"/authorize" {
+		t.Errorf("authorization endpoint is %s", got)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientId,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        pkceChallenge("verifier-1"),
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if query.Get(name) != value {
+			t.Errorf("%s is %q, want %q", name, query.Get(name), value)
+		}
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	_, provider := newTestProvider(t)
+	ctx := context.Background()
+
+	// authorize signs the user in at the issuer and returns the code it redirects with
+	authorize := func(state, nonce, verifier string) string {
+		t.Helper()
+		target, err := provider.AuthorizationURL(ctx, state, nonce, verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		response, err := client.Get(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		location, err := url.Parse(response.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(location.String(), testRedirectURL) {
+			t.Fatalf("redirected to %s", location)
+		}
+		if location.Query().Get("state") != state {
+			t.Fatalf("state is %q, want %q", location.Query().Get("state"), state)
+		}
+		return location.Query().Get("code")
+	}
+
+	code := authorize("state-1", "nonce-1", "verifier-1")
+	claims, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "oidctest-user" || claims["email"] != "jane.doe@example.com" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	identity, err := provider.identity(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "jane.doe@example.com" || !identity.EmailVerified ||
+		identity.FirstName != "Jane" || identity.LastName != "Doe" || identity.Username != "jane.doe" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+
+	// A code works once
+	if _, err := provider.Exchange(ctx, code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("a code was exchanged twice")
+	}
+
+	// The code is bound to the PKCE verifier of its login
+	code = authorize("state-2", "nonce-2", "verifier-2")
+	if _, err := provider.Exchange(ctx, code, "another-verifier", "nonce-2"); err == nil {
+		t.Error("a code was exchanged with the wrong PKCE verifier")
+	}
+
+	// and to the nonce
+	code = authorize("state-3", "nonce-3", "verifier-3")
+	if _, err := provider.Exchange(ctx, code, "verifier-3", "another-nonce"); !errors.Is(err, ErrOIDCInvalidIdToken) {
+		t.Errorf("exchange with the wrong nonce returned %v, want ErrOIDCInvalidIdToken", err)
+	}
+}
+
+func TestOIDCVerifyIdToken(t *testing.T) {
+	issuer, provider := newTestProvider(t)
+	ctx := context.Background()
+
+	with := func(change func(claims jwt.MapClaims)) string {
+		claims := idTokenClaims(issuer, "nonce-1")
+		change(claims)
+		return issuer.IdToken(claims)
+	}
+	signed := func(method jwt.SigningMethod, key any) string {
+		token := jwt.NewWithClaims(method, idTokenClaims(issuer, "nonce-1"))
+		token.Header["kid"] = "oidctest-1"
+		raw, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	valid := with(func(jwt.MapClaims) {})
+	if _, err := provider.VerifyIdToken(ctx, valid, "nonce-1"); err != nil {
+		t.Fatalf("a valid ID token was rejected: %v", err)
+	}
+
+	// Another user's claims carrying the signature of the valid token
+	other := with(func(claims jwt.MapClaims) { claims["sub"] = "another-user" })
+	tampered := other[:strings.LastIndex(other, ".")] + valid[strings.LastIndex(valid, "."):]
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"bad nonce", valid, "nonce-2"},
+		{"missing nonce", with(func(claims jwt.MapClaims) { delete(claims, "nonce") }), "nonce-1"},
+		{"other audience", with(func(claims jwt.MapClaims) { claims["aud"] = "another-app" }), "nonce-1"},
+		{"other authorized party", with(func(claims jwt.MapClaims) {
+			claims["aud"] = []string{testClientId, "another-app"}
+			claims["azp"] = "another-app"
+		}), "nonce-1"},
+		{"other issuer", with(func(claims jwt.MapClaims) { claims["iss"] = "https://issuer.example.com" }), "nonce-1"},
+		{"expired", with(func(claims jwt.MapClaims) {
+			claims["iat"] = time.Now().Add(-time.Hour).Unix()
+			claims["exp"] = time.Now().Add(-10 * time.Minute).Unix()
+		}), "nonce-1"},
+		{"no expiry", with(func(claims jwt.MapClaims) { delete(claims, "exp") }), "nonce-1"},
+		{"no subject", with(func(claims jwt.MapClaims) { delete(claims, "sub") }), "nonce-1"},
+		{"HS256 with the client secret", signed(jwt.SigningMethodHS256, []byte(testClientSecret)), "nonce-1"},
+		{"alg none", signed(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType), "nonce-1"},
+		{"tampered", tampered, "nonce-1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := provider.VerifyIdToken(ctx, test.token, test.nonce); !errors.Is(err, ErrOIDCInvalidIdToken) {
+				t.Errorf("got %v, want ErrOIDCInvalidIdToken", err)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	issuer, provider := newTestProvider(t)
+	ctx := context.Background()
+
+	before := issuer.IdToken(idTokenClaims(issuer, "nonce-1"))
+	if _, err := provider.VerifyIdToken(ctx, before, "nonce-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	issuer.RotateKey()
+	after := issuer.IdToken(idTokenClaims(issuer, "nonce-1"))
+
+	// Unknown key ids refetch the JWKS at most once a minute
+	if _, err := provider.VerifyIdToken(ctx, after, "nonce-1"); !errors.Is(err, ErrOIDCInvalidIdToken) {
+		t.Fatalf("a key rotated in within a minute of the last fetch was accepted: %v", err)
+	}
+
+	provider.mu.Lock()
+	provider.keysFetched = time.Now().Add(-jwksRefreshAfter)
+	provider.mu.Unlock()
+	if _, err := provider.VerifyIdToken(ctx, after, "nonce-1"); err != nil {
+		t.Fatalf("the rotated key was not picked up: %v", err)
+	}
+
+	// Tokens signed with the previous key stay valid while the JWKS lists it
+	if _, err := provider.VerifyIdToken(ctx, before, "nonce-1"); err != nil {
+		t.Errorf("a token of the previous key was rejected: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+// Package oidctest runs a local OpenID provider for trying and testing the
+// generic OIDC login without an Okta, Keycloak or Azure AD tenant.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer is a mock OpenID provider on a local httptest server. Its authorization
+// endpoint signs in Claims at once and redirects back with a code; its token
+// endpoint checks the client, the redirect URI and the PKCE verifier, and returns
+// an RS256 ID token carrying Claims.
+type Issuer struct {
+	URL          string
+	ClientId     string
+	ClientSecret string
+	Claims       map[string]any // Claims of the user who signs in; sub is required
+
+	server *httptest.Server
+
+	mu    sync.Mutex
+	keys  []signingKey // Published in the JWKS; the last one signs
+	codes map[string]authorization
+}
+
+// signingKey is a key of the issuer with its key id
+type signingKey struct {
+	id  string
+	key *rsa.PrivateKey
+}
+
+// authorization is a code handed out by the authorization endpoint
+type authorization struct {
+	redirectURI string
+	nonce       string
+	challenge   string
+	claims      map[string]any
+}
+
+// NewIssuer starts an issuer for a client; Close stops it
+func NewIssuer(clientId, clientSecret string) *Issuer {
+	issuer := &Issuer{
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Claims: map[string]any{
+			"sub":                "oidctest-user",
+			"email":              "jane.doe@example.com",
+			"email_verified":     true,
+			"given_name":         "Jane",
+			"family_name":        "Doe",
+			"preferred_username": "jane.doe",
+		},
+		codes: make(map[string]authorization),
+	}
+	issuer.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("GET /jwks", issuer.jwks)
+	mux.HandleFunc("GET /authorize", issuer.authorize)
+	mux.HandleFunc("POST /token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	issuer.URL = issuer.server.URL
+	return issuer
+}
+
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// RotateKey signs further ID tokens with a new key, published in the JWKS next to
+// the earlier ones, and returns its key id
+func (i *Issuer) RotateKey() string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("oidctest: failed to generate key: " + err.Error())
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	id := fmt.Sprintf("oidctest-%d", len(i.keys)+1)
+	i.keys = append(i.keys, signingKey{id: id, key: key})
+	return id
+}
+
+// IdToken signs an ID token with the issuer's current key, for tests of token validation
+func (i *Issuer) IdToken(claims jwt.MapClaims) string {
+	i.mu.Lock()
+	current := i.keys[len(i.keys)-1]
+	i.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = current.id
+	signed, err := token.SignedString(current.key)
+	if err != nil {
+		panic("oidctest: failed to sign ID token: " + err.Error())
+	}
+	return signed
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	keys := make([]map[string]string, 0, len(i.keys))
+	for _, key := range i.keys {
+		keys = append(keys, map[string]string{
+			"kid": key.id,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.key.E)).Bytes()),
+		})
+	}
+	i.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if query.Get("client_id") != i.ClientId || err != nil || redirectURI.Host == "" {
+		http.Error(w, "unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	params := redirectURI.Query()
+	params.Set("state", query.Get("state"))
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		params.Set("error", "invalid_request")
+	} else {
+		code := randomHex()
+		i.mu.Lock()
+		claims := make(map[string]any, len(i.Claims))
+		for name, value := range i.Claims {
+			claims[name] = value
+		}
+		i.codes[code] = authorization{
+			redirectURI: redirectURI.String(),
+			nonce:       query.Get("nonce"),
+			challenge:   query.Get("code_challenge"),
+			claims:      claims,
+		}
+		i.mu.Unlock()
+		params.Set("code", code)
+	}
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientId, _ = url.QueryUnescape(clientId)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientId, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientId != i.ClientId || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(i.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	i.mu.Lock()
+	code, ok := i.codes[r.PostFormValue("code")]
+	delete(i.codes, r.PostFormValue("code"))
+	i.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || code.redirectURI != r.PostFormValue("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": i.URL,
+		"aud": i.ClientId,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if code.nonce != "" {
+		claims["nonce"] = code.nonce
+	}
+	for name, value := range code.claims {
+		claims[name] = value
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomHex(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     i.IdToken(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func randomHex() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
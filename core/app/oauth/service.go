@@ -1,6 +1,7 @@
 package oauth
 
 import (
+	"base/core/app/authentication"
 	"base/core/app/profile"
 	"base/core/storage"
 	"bytes"
@@ -22,13 +23,25 @@ type OAuthService struct {
 	DB            *gorm.DB
 	Config        *OAuthConfig
 	ActiveStorage *storage.ActiveStorage
+	Auth          *authentication.AuthService
+	OIDC          map[string]*OIDCProvider // Generic OIDC providers by name
 }
 
-func NewOAuthService(db *gorm.DB, config *OAuthConfig, activeStorage *storage.ActiveStorage) *OAuthService {
+func NewOAuthService(db *gorm.DB, config *OAuthConfig, activeStorage *storage.ActiveStorage, auth *authentication.AuthService) *OAuthService {
+	providers := make(map[string]*OIDCProvider, len(config.OIDC))
+	for _, provider := range config.OIDC {
+		if provider.Issuer == "" || provider.ClientId == "" || provider.RedirectURL == "" {
+			continue
+		}
+		providers[provider.Name] = NewOIDCProvider(provider, nil)
+	}
+
 	return &OAuthService{
 		DB:            db,
 		Config:        config,
 		ActiveStorage: activeStorage,
+		Auth:          auth,
+		OIDC:          providers,
 	}
 }
 
@@ -129,12 +142,15 @@ func (s *OAuthService) processUser(email, name, username, pictureURL, provider,
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			// Create new user
+			now := time.Now()
 			user = OAuthUser{
 				User: profile.User{
 					Email:     email,
 					FirstName: name[:strings.Index(name, " ")],
 					LastName:  name[strings.Index(name, " ")+1:],
 					Username:  s.generateUniqueUsername(username),
+					// The provider vouches for the email address
+					VerifiedAt: &now,
 				},
 				Provider:       provider,
 				ProviderId:     providerId,
@@ -250,7 +266,7 @@ func (s *OAuthService) fetchAndAttachAvatar(user *OAuthUser, avatarURL string) (
 	}
 
 	// Attach the avatar to ActiveStorage
-	attachment, err := s.ActiveStorage.Attach(&user.User, "avatar", fileHeader)
+	attachment, err := s.ActiveStorage.Attach(&user.User, "avatar", fileHeader, user.Id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to attach avatar: %w", err)
 	}
@@ -0,0 +1,214 @@
+package oauthserver
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	codeTTL   = 10 * time.Minute
+	codeBytes = 32
+)
+
+var (
+	codeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)       // base64url SHA-256, unpadded
+	codeVerifierPattern  = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`) // RFC 7636 section 4.1
+)
+
+// authorizationRequest is a checked authorization request
+type authorizationRequest struct {
+	client      *Client
+	redirectURI string
+	state       string
+	challenge   string
+	scopes      []string // Scopes to grant
+	dropped     []string // Requested scopes the user has no permission for
+}
+
+// Consent checks an authorization request of a client and describes it for the
+// consent screen the user approves or denies it on
+func (s *OAuthServerService) Consent(userId uint, req *AuthorizeRequest) (*ConsentResponse, error) {
+	authz, err := s.authorizationRequest(userId, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var consent Consent
+	granted := false
+	err = s.db.Where("user_id = ? AND client_id = ?", userId, authz.client.ClientId).First(&consent).Error
+	switch {
+	case err == nil:
+		granted = !slices.ContainsFunc(authz.scopes, func(scope string) bool {
+			return !slices.Contains(consent.Scopes, scope)
+		})
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	known, err := s.Scopes()
+	if err != nil {
+		return nil, err
+	}
+	scopes := make([]ScopeInfo, 0, len(authz.scopes))
+	for _, info := range known {
+		if slices.Contains(authz.scopes, info.Scope) {
+			scopes = append(scopes, info)
+		}
+	}
+
+	return &ConsentResponse{
+		ClientId:    authz.client.ClientId,
+		ClientName:  authz.client.Name,
+		Website:     authz.client.Website,
+		RedirectURI: authz.redirectURI,
+		Scopes:      scopes,
+		Granted:     granted,
+		Dropped:     authz.dropped,
+	}, nil
+}
+
+// Decide records the user's decision on an authorization request and returns
+// where to send them back to the client: with a code when they approved, with
+// an access_denied error otherwise
+func (s *OAuthServerService) Decide(userId uint, req *ConsentRequest) (string, error) {
+	authz, err := s.authorizationRequest(userId, &req.AuthorizeRequest)
+	if err != nil {
+		return "", err
+	}
+	if !req.Approve {
+		return redirectWith(authz.redirectURI, authz.state, url.Values{
+			"error":             {ErrorAccessDenied},
+			"error_description": {"the user denied the request"},
+		}), nil
+	}
+
+	code, err := randomHex(codeBytes)
+	if err != nil {
+		return "", err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var consent Consent
+		err := tx.Where("user_id = ? AND client_id = ?", userId, authz.client.ClientId).First(&consent).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			consent = Consent{UserId: userId, ClientId: authz.client.ClientId, Scopes: authz.scopes}
+			if err := tx.Create(&consent).Error; err != nil {
+				return fmt.Errorf("failed to record consent: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("database error: %w", err)
+		default:
+			// Consent covers every scope the user granted the client so far
+			consent.Scopes = normalizeScopes(append(consent.Scopes, authz.scopes...))
+			if err := tx.Model(&consent).Update("scopes", consent.Scopes).Error; err != nil {
+				return fmt.Errorf("failed to record consent: %w", err)
+			}
+		}
+
+		return tx.Create(&AuthorizationCode{
+			CodeHash:      hashToken(code),
+			ClientId:      authz.client.ClientId,
+			UserId:        userId,
+			RedirectURI:   authz.redirectURI,
+			Scopes:        authz.scopes,
+			CodeChallenge: authz.challenge,
+			ExpiresAt:     time.Now().Add(codeTTL),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return redirectWith(authz.redirectURI, authz.state, url.Values{"code": {code}}), nil
+}
+
+// authorizationRequest checks an authorization request. Until the client and its
+// redirect URI are known the error goes to the user alone; after that it carries
+// the redirect reporting it to the client.
+func (s *OAuthServerService) authorizationRequest(userId uint, req *AuthorizeRequest) (*authorizationRequest, error) {
+	if req.ClientId == "" {
+		return nil, &Error{Code: ErrorInvalidRequest, Description: "client_id is required"}
+	}
+	var client Client
+	if err := s.db.Where("client_id = ?", req.ClientId).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &Error{Code: ErrorInvalidClient, Description: "unknown client"}
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return nil, &Error{Code: ErrorInvalidRequest, Description: "redirect_uri is not registered for the client"}
+	}
+
+	fail := func(code, description string) error {
+		return &Error{
+			Code:        code,
+			Description: description,
+			RedirectTo: redirectWith(redirectURI, req.State, url.Values{
+				"error":             {code},
+				"error_description": {description},
+			}),
+		}
+	}
+	if req.ResponseType != "code" {
+		return nil, fail(ErrorUnsupportedResponseType, "only the code response type is supported")
+	}
+	if req.CodeChallengeMethod != "S256" || !codeChallengePattern.MatchString(req.CodeChallenge) {
+		return nil, fail(ErrorInvalidRequest, "a PKCE code_challenge with code_challenge_method S256 is required")
+	}
+
+	requested := client.Scopes
+	if req.Scope != "" {
+		requested = normalizeScopes(strings.Fields(req.Scope))
+	}
+	for _, scope := range requested {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, fail(ErrorInvalidScope, fmt.Sprintf("the client may not ask for %s", scope))
+		}
+	}
+	scopes, dropped, err := s.grantable(&client, userId, requested)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, fail(ErrorInvalidScope, "the user has none of the requested permissions")
+	}
+
+	return &authorizationRequest{
+		client:      &client,
+		redirectURI: redirectURI,
+		state:       req.State,
+		challenge:   req.CodeChallenge,
+		scopes:      scopes,
+		dropped:     dropped,
+	}, nil
+}
+
+// redirectWith adds params and the client's state to a redirect URI
+func redirectWith(redirectURI, state string, params url.Values) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
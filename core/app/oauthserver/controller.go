@@ -0,0 +1,543 @@
+package oauthserver
+
+import (
+	"base/core/logger"
+	"base/core/router"
+	"base/core/types"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type OAuthServerController struct {
+	service *OAuthServerService
+	logger  logger.Logger
+}
+
+func NewOAuthServerController(service *OAuthServerService, logger logger.Logger) *OAuthServerController {
+	return &OAuthServerController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (c *OAuthServerController) Routes(router *router.RouterGroup) {
+	router.GET("/scopes", c.Scopes)
+	router.GET("/clients", c.ListClients)
+	router.POST("/clients", c.CreateClient)
+	router.GET("/clients/:client_id", c.GetClient)
+	router.PUT("/clients/:client_id", c.UpdateClient)
+	router.POST("/clients/:client_id/secret", c.RotateSecret)
+	router.DELETE("/clients/:client_id", c.DeleteClient)
+	router.GET("/authorize", c.Consent)
+	router.POST("/authorize", c.Decide)
+	router.GET("/authorizations", c.Authorizations)
+	router.DELETE("/authorizations/:client_id", c.RevokeAuthorization)
+	router.POST("/token", c.Token)
+	router.POST("/introspect", c.Introspect)
+	router.POST("/revoke", c.Revoke)
+}
+
+// @Summary List Scopes
+// @Description List the scopes OAuth clients may ask for: one per authorization permission, named resource:action
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Success 200 {array} ScopeInfo
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/scopes [get]
+func (c *OAuthServerController) Scopes(ctx *router.Context) error {
+	scopes, err := c.service.Scopes()
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, scopes)
+}
+
+// @Summary List OAuth Clients
+// @Description List the OAuth clients the current user registered, or those of another user for administrators
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Param user_id query int false "Owner of the clients (administrators only)"
+// @Success 200 {array} Client
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/clients [get]
+func (c *OAuthServerController) ListClients(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	var ownerId uint
+	if value := ctx.Query("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user Id"})
+		}
+		ownerId = uint(id)
+	}
+
+	clients, err := c.service.ListClients(userId, ownerId)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, clients)
+}
+
+// @Summary Register OAuth Client
+// @Description Register a third-party application. Confidential clients (server apps) get a secret, shown only once, and may use client credentials, acting for the current user; public clients (browser and mobile apps) have none. Every client must use PKCE.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Accept json
+// @Produce json
+// @Param body body ClientRequest true "Client"
+// @Success 201 {object} ClientCreatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/clients [post]
+func (c *OAuthServerController) CreateClient(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	var req ClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	created, err := c.service.CreateClient(userId, &req)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+// @Summary Get OAuth Client
+// @Description Get an OAuth client of the current user, or any client for administrators
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Param client_id path string true "Client Id"
+// @Success 200 {object} Client
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/clients/{client_id} [get]
+func (c *OAuthServerController) GetClient(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	client, err := c.service.GetClient(userId, ctx.Param("client_id"))
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, client)
+}
+
+// @Summary Update OAuth Client
+// @Description Change the name, website, redirect URIs and scopes of an OAuth client. Whether it is confidential cannot change.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Accept json
+// @Produce json
+// @Param client_id path string true "Client Id"
+// @Param body body ClientRequest true "Client"
+// @Success 200 {object} Client
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/clients/{client_id} [put]
+func (c *OAuthServerController) UpdateClient(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	var req ClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+
+	client, err := c.service.UpdateClient(userId, ctx.Param("client_id"), &req)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, client)
+}
+
+// @Summary Rotate OAuth Client Secret
+// @Description Replace the secret of a confidential client. The old secret stops working right away; the new one is only shown once.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Param client_id path string true "Client Id"
+// @Success 200 {object} ClientCreatedResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/clients/{client_id}/secret [post]
+func (c *OAuthServerController) RotateSecret(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	rotated, err := c.service.RotateSecret(userId, ctx.Param("client_id"))
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, rotated)
+}
+
+// @Summary Delete OAuth Client
+// @Description Delete an OAuth client; its tokens stop working
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Param client_id path string true "Client Id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/clients/{client_id} [delete]
+func (c *OAuthServerController) DeleteClient(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	if err := c.service.DeleteClient(userId, ctx.Param("client_id")); err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "OAuth client deleted"})
+}
+
+// @Summary Review Authorization Request
+// @Description For the consent screen: check the authorization request a client sent the user with, and describe the client and the scopes it would get. Scopes the user lacks the permission for are dropped. On errors past the client check, send the user to redirect_to.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client Id"
+// @Param redirect_uri query string false "Registered redirect URI; optional when the client has only one"
+// @Param scope query string false "Space-separated scopes; all of the client's by default"
+// @Param state query string false "Opaque value returned to the client"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200 {object} ConsentResponse
+// @Failure 400 {object} AuthorizeErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/authorize [get]
+func (c *OAuthServerController) Consent(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	consent, err := c.service.Consent(userId, &AuthorizeRequest{
+		ResponseType:        ctx.Query("response_type"),
+		ClientId:            ctx.Query("client_id"),
+		RedirectURI:         ctx.Query("redirect_uri"),
+		Scope:               ctx.Query("scope"),
+		State:               ctx.Query("state"),
+		CodeChallenge:       ctx.Query("code_challenge"),
+		CodeChallengeMethod: ctx.Query("code_challenge_method"),
+	})
+	if err != nil {
+		return c.authorizeError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, consent)
+}
+
+// @Summary Decide Authorization Request
+// @Description Approve or deny the authorization request shown on the consent screen, then send the user to redirect_to: with an authorization code for the client when approved, with an access_denied error otherwise.
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Accept json
+// @Produce json
+// @Param body body ConsentRequest true "Authorization request and decision"
+// @Success 200 {object} RedirectResponse
+// @Failure 400 {object} AuthorizeErrorResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/authorize [post]
+func (c *OAuthServerController) Decide(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	var req ConsentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, AuthorizeErrorResponse{Error: ErrorInvalidRequest, ErrorDescription: err.Error()})
+	}
+
+	redirectTo, err := c.service.Decide(userId, &req)
+	if err != nil {
+		return c.authorizeError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, RedirectResponse{RedirectTo: redirectTo})
+}
+
+// @Summary List Authorized Apps
+// @Description List the OAuth clients the current user authorized, with the scopes granted
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Success 200 {array} AuthorizationResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/authorizations [get]
+func (c *OAuthServerController) Authorizations(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	authorizations, err := c.service.Authorizations(userId)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, authorizations)
+}
+
+// @Summary Revoke Authorized App
+// @Description Withdraw the current user's consent to an OAuth client and revoke the tokens it holds for them
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/OAuth2
+// @Produce json
+// @Param client_id path string true "Client Id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 401 {object} types.ErrorResponse
+// @Failure 403 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /oauth2/authorizations/{client_id} [delete]
+func (c *OAuthServerController) RevokeAuthorization(ctx *router.Context) error {
+	userId, ok := c.currentUser(ctx)
+	if !ok {
+		return nil
+	}
+
+	if err := c.service.RevokeAuthorization(userId, ctx.Param("client_id")); err != nil {
+		return c.error(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Authorization revoked"})
+}
+
+// @Summary Token
+// @Description OAuth 2.0 token endpoint for clients (RFC 6749). Clients authenticate with HTTP Basic or client_id and client_secret fields; public clients send client_id alone. Grants: authorization_code (with code, code_verifier and redirect_uri), refresh_token (refresh tokens rotate on every use) and client_credentials (confidential clients only, acting for the user who registered the client). Access tokens are bearer tokens for the API, limited to their scopes.
+// @Tags Core/OAuth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param code formData string false "Authorization code"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param redirect_uri formData string false "Redirect URI the code was sent to; required for authorization_code"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Space-separated scopes, to narrow a refresh or client credentials"
+// @Param client_id formData string false "Client Id, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} TokenErrorResponse
+// @Failure 401 {object} TokenErrorResponse
+// @Failure 500 {object} TokenErrorResponse
+// @Router /oauth2/token [post]
+func (c *OAuthServerController) Token(ctx *router.Context) error {
+	credentials, params, err := clientRequest(ctx)
+	if err != nil {
+		return c.tokenError(ctx, err)
+	}
+
+	response, err := c.service.Token(credentials, params)
+	if err != nil {
+		return c.tokenError(ctx, err)
+	}
+	noStore(ctx)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Introspect Token
+// @Description Describe an access or refresh token to the client it was issued to (RFC 7662). Tokens of other clients, and invalid ones, are reported inactive.
+// @Tags Core/OAuth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param client_id formData string false "Client Id, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Success 200 {object} IntrospectionResponse
+// @Failure 400 {object} TokenErrorResponse
+// @Failure 401 {object} TokenErrorResponse
+// @Failure 500 {object} TokenErrorResponse
+// @Router /oauth2/introspect [post]
+func (c *OAuthServerController) Introspect(ctx *router.Context) error {
+	credentials, params, err := clientRequest(ctx)
+	if err != nil {
+		return c.tokenError(ctx, err)
+	}
+	if params.Get("token") == "" {
+		return c.tokenError(ctx, &Error{Code: ErrorInvalidRequest, Description: "token is required"})
+	}
+
+	response, err := c.service.Introspect(credentials, params.Get("token"))
+	if err != nil {
+		return c.tokenError(ctx, err)
+	}
+	noStore(ctx)
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// @Summary Revoke Token
+// @Description Revoke an access or refresh token of the calling client (RFC 7009); revoking a refresh token also revokes the access token issued with it. Unknown tokens are ignored.
+// @Tags Core/OAuth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param client_id formData string false "Client Id, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} TokenErrorResponse
+// @Failure 401 {object} TokenErrorResponse
+// @Failure 500 {object} TokenErrorResponse
+// @Router /oauth2/revoke [post]
+func (c *OAuthServerController) Revoke(ctx *router.Context) error {
+	credentials, params, err := clientRequest(ctx)
+	if err != nil {
+		return c.tokenError(ctx, err)
+	}
+	if params.Get("token") == "" {
+		return c.tokenError(ctx, &Error{Code: ErrorInvalidRequest, Description: "token is required"})
+	}
+
+	if err := c.service.Revoke(credentials, params.Get("token")); err != nil {
+		return c.tokenError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Token revoked"})
+}
+
+// currentUser returns the logged in user, writing an error response when there is
+// none. Clients and consents are managed with a login only, not with API keys.
+func (c *OAuthServerController) currentUser(ctx *router.Context) (uint, bool) {
+	if _, viaKey := ctx.Get("api_key"); viaKey && ctx.Header("Authorization") == "" {
+		ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: "API keys cannot manage OAuth clients or authorizations; log in instead"})
+		return 0, false
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Authentication required"})
+		return 0, false
+	}
+	return userId, true
+}
+
+// clientRequest reads the form of a token, introspection or revocation request and
+// the client credentials, sent with HTTP Basic or as form fields (RFC 6749 section 2.3.1)
+func clientRequest(ctx *router.Context) (ClientCredentials, url.Values, error) {
+	if !strings.HasPrefix(ctx.ContentType(), "application/x-www-form-urlencoded") {
+		return ClientCredentials{}, nil, &Error{Code: ErrorInvalidRequest, Description: "requests must be form-encoded"}
+	}
+	if err := ctx.Request.ParseForm(); err != nil {
+		return ClientCredentials{}, nil, &Error{Code: ErrorInvalidRequest, Description: "invalid form"}
+	}
+	params := ctx.Request.PostForm
+
+	if clientId, secret, ok := ctx.Request.BasicAuth(); ok {
+		if params.Get("client_secret") != "" {
+			return ClientCredentials{}, nil, &Error{Code: ErrorInvalidRequest, Description: "send the client credentials one way only"}
+		}
+		// Both parts are form-encoded before they are joined
+		clientId, errId := url.QueryUnescape(clientId)
+		secret, errSecret := url.QueryUnescape(secret)
+		if errId != nil || errSecret != nil {
+			return ClientCredentials{}, nil, &Error{Code: ErrorInvalidClient, Description: "invalid client credentials"}
+		}
+		return ClientCredentials{ClientId: clientId, ClientSecret: secret}, params, nil
+	}
+	return ClientCredentials{ClientId: params.Get("client_id"), ClientSecret: params.Get("client_secret")}, params, nil
+}
+
+// noStore keeps token responses out of caches (RFC 6749 section 5.1)
+func noStore(ctx *router.Context) {
+	ctx.SetHeader("Cache-Control", "no-store")
+	ctx.SetHeader("Pragma", "no-cache")
+}
+
+// tokenError answers a token, introspection or revocation request with an OAuth error
+func (c *OAuthServerController) tokenError(ctx *router.Context, err error) error {
+	noStore(ctx)
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) {
+		c.logger.Error("OAuth token request failed",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, TokenErrorResponse{Error: "server_error"})
+	}
+	if oauthErr.Code == ErrorInvalidClient {
+		ctx.SetHeader("WWW-Authenticate", `Basic realm="oauth2"`)
+		return ctx.JSON(http.StatusUnauthorized, TokenErrorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description})
+	}
+	return ctx.JSON(http.StatusBadRequest, TokenErrorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description})
+}
+
+// authorizeError answers the consent screen with an authorization request error
+func (c *OAuthServerController) authorizeError(ctx *router.Context, err error) error {
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) {
+		c.logger.Error("OAuth authorization request failed",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Internal server error"})
+	}
+	return ctx.JSON(http.StatusBadRequest, AuthorizeErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+		RedirectTo:       oauthErr.RedirectTo,
+	})
+}
+
+// error maps service errors to responses
+func (c *OAuthServerController) error(ctx *router.Context, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidName), errors.Is(err, ErrInvalidWebsite), errors.Is(err, ErrInvalidRedirectURI),
+		errors.Is(err, ErrInvalidScope), errors.Is(err, ErrPublicClient):
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrNotAdmin):
+		return ctx.JSON(http.StatusForbidden, types.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrClientNotFound), errors.Is(err, ErrAuthorizationNotFound):
+		return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: err.Error()})
+	default:
+		c.logger.Error("OAuth request failed",
+			logger.String("error", err.Error()))
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Internal server error"})
+	}
+}
@@ -0,0 +1,176 @@
+package oauthserver
+
+import "time"
+
+// Client is a third-party application registered to act for users. Confidential
+// clients run on a server and hold a secret, stored as its SHA-256; public clients
+// (browser and mobile apps) have none and rely on PKCE alone.
+type Client struct {
+	Id           uint      `json:"id" gorm:"primaryKey"`
+	ClientId     string    `json:"client_id" gorm:"size:64;uniqueIndex"`
+	SecretHash   string    `json:"-" gorm:"size:64"`
+	Name         string    `json:"name" gorm:"size:100"`
+	Website      string    `json:"website" gorm:"size:255"`
+	OwnerId      uint      `json:"owner_id" gorm:"index"`                          // User who registered the client; client credentials act for them
+	RedirectURIs []string  `json:"redirect_uris" gorm:"serializer:json;type:text"` // Exact URIs codes may be sent to
+	Scopes       []string  `json:"scopes" gorm:"serializer:json;type:text"`        // Scopes the client may ask for
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (Client) TableName() string {
+	return "oauth_clients"
+}
+
+// AuthorizationCode is a single-use code handed to a client through its redirect
+// URI once a user approved it, stored as its SHA-256
+type AuthorizationCode struct {
+	Id            uint     `gorm:"primaryKey"`
+	CodeHash      string   `gorm:"size:64;uniqueIndex"`
+	ClientId      string   `gorm:"size:64;index"`
+	UserId        uint     `gorm:"index"`
+	RedirectURI   string   `gorm:"type:text"`
+	Scopes        []string `gorm:"serializer:json;type:text"`
+	CodeChallenge string   `gorm:"size:128"` // PKCE S256 challenge the code verifier must match
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+}
+
+func (AuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// Consent records the scopes a user granted a client
+type Consent struct {
+	Id        uint      `json:"id" gorm:"primaryKey"`
+	UserId    uint      `json:"user_id" gorm:"uniqueIndex:idx_oauth_consent"`
+	ClientId  string    `json:"client_id" gorm:"size:64;uniqueIndex:idx_oauth_consent"`
+	Scopes    []string  `json:"scopes" gorm:"serializer:json;type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Consent) TableName() string {
+	return "oauth_consents"
+}
+
+// RefreshToken is a single-use token of a user's grant to a client, stored as
+// its SHA-256. Each one is exchanged for the next; replaying a used one revokes
+// the grant's tokens.
+type RefreshToken struct {
+	Id              uint     `gorm:"primaryKey"`
+	TokenHash       string   `gorm:"size:64;uniqueIndex"`
+	ClientId        string   `gorm:"size:64;index"`
+	UserId          uint     `gorm:"index"`
+	Scopes          []string `gorm:"serializer:json;type:text"`
+	AccessTokenId   string   `gorm:"size:32"` // jti of the access token issued alongside, revoked with it
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+func (RefreshToken) TableName() string {
+	return "oauth_refresh_tokens"
+}
+
+// ClientRequest registers or updates a client
+type ClientRequest struct {
+	Name         string   `json:"name" example:"HR Sync"`
+	Website      string   `json:"website" example:"https://partner.example.com"`
+	RedirectURIs []string `json:"redirect_uris" example:"https://partner.example.com/oauth/callback"`
+	Scopes       []string `json:"scopes" example:"profile:read"`
+	Confidential bool     `json:"confidential"` // Ignored on updates
+}
+
+// ClientCreatedResponse holds a client with its secret, which is only shown once
+type ClientCreatedResponse struct {
+	Client
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// ScopeInfo describes a scope, as shown on consent screens
+type ScopeInfo struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// AuthorizeRequest is an authorization request of a client, as received by the
+// consent screen (RFC 6749 section 4.1.1 with PKCE, RFC 7636)
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientId            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// ConsentRequest is the user's decision on an authorization request
+type ConsentRequest struct {
+	AuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// ConsentResponse describes an authorization request for the consent screen
+type ConsentResponse struct {
+	ClientId    string      `json:"client_id"`
+	ClientName  string      `json:"client_name"`
+	Website     string      `json:"website"`
+	RedirectURI string      `json:"redirect_uri"`
+	Scopes      []ScopeInfo `json:"scopes"`            // Scopes that will be granted
+	Granted     bool        `json:"granted"`           // The user already granted all of them
+	Dropped     []string    `json:"dropped,omitempty"` // Requested scopes the user has no permission for
+}
+
+// RedirectResponse is where the consent screen sends the user back to the client
+type RedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AuthorizeErrorResponse is an authorization request error. RedirectTo is set
+// once the client and redirect URI are trusted, to report the error to the client.
+type AuthorizeErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	RedirectTo       string `json:"redirect_to,omitempty"`
+}
+
+// TokenResponse is the token endpoint response (RFC 6749 section 5.1)
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// TokenErrorResponse is an error of the token, introspection and revocation
+// endpoints (RFC 6749 section 5.2)
+type TokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse describes a token to the client it was issued to (RFC 7662)
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"` // Id of the user the token acts for
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// AuthorizationResponse describes a client a user authorized
+type AuthorizationResponse struct {
+	ClientId   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Website    string    `json:"website"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
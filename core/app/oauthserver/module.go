@@ -0,0 +1,60 @@
+package oauthserver
+
+import (
+	"base/core/app/sessions"
+	"base/core/config"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/router"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+type OAuthServerModule struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *OAuthServerController
+	Service    *OAuthServerService
+	Logger     logger.Logger
+}
+
+func NewOAuthServerModule(db *gorm.DB, router *router.RouterGroup, logger logger.Logger, sessions *sessions.SessionService, cfg *config.Config) *OAuthServerModule {
+	service := NewOAuthServerService(db, sessions, cfg)
+	controller := NewOAuthServerController(service, logger)
+
+	return &OAuthServerModule{
+		DB:         db,
+		Controller: controller,
+		Service:    service,
+		Logger:     logger,
+	}
+}
+
+// Init makes tokens of deleted clients stop working
+func (m *OAuthServerModule) Init() error {
+	types.SetClientCheck(m.Service.CheckClient)
+	return nil
+}
+
+func (m *OAuthServerModule) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router.Group("/oauth2"))
+}
+
+func (m *OAuthServerModule) Migrate() error {
+	err := m.DB.AutoMigrate(&Client{}, &AuthorizationCode{}, &Consent{}, &RefreshToken{})
+	if err != nil {
+		m.Logger.Error("Migration failed", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (m *OAuthServerModule) GetModels() []any {
+	return []any{
+		&Client{},
+		&AuthorizationCode{},
+		&Consent{},
+		&RefreshToken{},
+	}
+}
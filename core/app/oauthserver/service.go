@@ -0,0 +1,480 @@
+package oauthserver
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"base/core/app/authorization"
+	"base/core/app/profile"
+	"base/core/app/sessions"
+	"base/core/config"
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrClientNotFound        = errors.New("OAuth client not found")
+	ErrAuthorizationNotFound = errors.New("authorization not found")
+	ErrNotAdmin              = errors.New("administrator role required")
+	ErrPublicClient          = errors.New("public clients have no secret")
+	ErrInvalidName           = errors.New("name is required and may have at most 100 characters")
+	ErrInvalidWebsite        = errors.New("website must be an http or https URL")
+	ErrInvalidRedirectURI    = errors.New("redirect URIs must be https URLs, http URLs on localhost, or private-use schemes such as com.example.app:/callback, without fragments")
+	ErrInvalidScope          = errors.New("scopes must name permissions as resource:action, see /oauth2/scopes")
+)
+
+const (
+	maxRedirectURIs = 10
+	clientIdBytes   = 16
+	secretBytes     = 32
+)
+
+// Error is an OAuth protocol error, reported to clients by its code (RFC 6749
+// sections 4.1.2.1 and 5.2)
+type Error struct {
+	Code        string
+	Description string
+	RedirectTo  string // Where the user goes back to the client with the error, once the client is trusted
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// OAuth error codes
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorAccessDenied            = "access_denied"
+)
+
+// OAuthServerService lets third-party clients act for users: it registers clients,
+// records the scopes users grant them and issues their tokens
+type OAuthServerService struct {
+	db         *gorm.DB
+	sessions   *sessions.SessionService
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewOAuthServerService(db *gorm.DB, sessions *sessions.SessionService, cfg *config.Config) *OAuthServerService {
+	return &OAuthServerService{
+		db:         db,
+		sessions:   sessions,
+		accessTTL:  cfg.OAuthServerAccessTTL,
+		refreshTTL: cfg.OAuthServerRefreshTTL,
+	}
+}
+
+// Scopes lists the scopes clients may ask for: one per authorization permission
+func (s *OAuthServerService) Scopes() ([]ScopeInfo, error) {
+	var permissions []authorization.Permission
+	if err := s.db.Order("resource_type, action").Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	scopes := make([]ScopeInfo, 0, len(permissions))
+	for _, permission := range permissions {
+		scopes = append(scopes, ScopeInfo{
+			Scope:       permission.ResourceType + ":" + permission.Action,
+			Description: permission.Description,
+		})
+	}
+	return scopes, nil
+}
+
+// CreateClient registers a client for a user; the secret of a confidential
+// client is only returned here
+func (s *OAuthServerService) CreateClient(userId uint, req *ClientRequest) (*ClientCreatedResponse, error) {
+	if err := s.validateClient(req); err != nil {
+		return nil, err
+	}
+	clientId, err := randomHex(clientIdBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	client := Client{
+		ClientId:     clientId,
+		Name:         strings.TrimSpace(req.Name),
+		Website:      req.Website,
+		OwnerId:      userId,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       normalizeScopes(req.Scopes),
+		Confidential: req.Confidential,
+	}
+	var secret string
+	if client.Confidential {
+		if secret, err = randomHex(secretBytes); err != nil {
+			return nil, err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := s.db.Create(&client).Error; err != nil {
+		return nil, fmt.Errorf("failed to create OAuth client: %w", err)
+	}
+	return &ClientCreatedResponse{Client: client, ClientSecret: secret}, nil
+}
+
+// ListClients returns the clients of the owner, newest first. Only administrators
+// may list the clients of another user.
+func (s *OAuthServerService) ListClients(userId, ownerId uint) ([]Client, error) {
+	if ownerId == 0 {
+		ownerId = userId
+	}
+	if ownerId != userId {
+		admin, err := s.isAdmin(userId)
+		if err != nil {
+			return nil, err
+		}
+		if !admin {
+			return nil, ErrNotAdmin
+		}
+	}
+
+	clients := []Client{}
+	if err := s.db.Where("owner_id = ?", ownerId).Order("created_at DESC, id DESC").Find(&clients).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return clients, nil
+}
+
+// GetClient returns a client of a user; administrators may see any client
+func (s *OAuthServerService) GetClient(userId uint, clientId string) (*Client, error) {
+	return s.findClient(userId, clientId)
+}
+
+// UpdateClient changes the name, website, redirect URIs and scopes of a client.
+// Tokens issued before keep their scopes until they are refreshed.
+func (s *OAuthServerService) UpdateClient(userId uint, clientId string, req *ClientRequest) (*Client, error) {
+	client, err := s.findClient(userId, clientId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateClient(req); err != nil {
+		return nil, err
+	}
+
+	client.Name = strings.TrimSpace(req.Name)
+	client.Website = req.Website
+	client.RedirectURIs = req.RedirectURIs
+	client.Scopes = normalizeScopes(req.Scopes)
+	if err := s.db.Select("name", "website", "redirect_uris", "scopes").Updates(client).Error; err != nil {
+		return nil, fmt.Errorf("failed to update OAuth client: %w", err)
+	}
+	return client, nil
+}
+
+// RotateSecret replaces the secret of a confidential client; the old one stops
+// working right away
+func (s *OAuthServerService) RotateSecret(userId uint, clientId string) (*ClientCreatedResponse, error) {
+	client, err := s.findClient(userId, clientId)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential {
+		return nil, ErrPublicClient
+	}
+
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return nil, err
+	}
+	client.SecretHash = hashToken(secret)
+	if err := s.db.Model(client).Update("secret_hash", client.SecretHash).Error; err != nil {
+		return nil, fmt.Errorf("failed to rotate client secret: %w", err)
+	}
+	return &ClientCreatedResponse{Client: *client, ClientSecret: secret}, nil
+}
+
+// DeleteClient removes a client with its codes, consents and refresh tokens; its
+// access tokens stop working as CheckClient no longer finds it
+func (s *OAuthServerService) DeleteClient(userId uint, clientId string) error {
+	client, err := s.findClient(userId, clientId)
+	if err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&AuthorizationCode{}, &Consent{}, &RefreshToken{}} {
+			if err := tx.Where("client_id = ?", client.ClientId).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to delete OAuth client: %w", err)
+			}
+		}
+		if err := tx.Delete(client).Error; err != nil {
+			return fmt.Errorf("failed to delete OAuth client: %w", err)
+		}
+		return nil
+	})
+}
+
+// Authorizations lists the clients a user authorized
+func (s *OAuthServerService) Authorizations(userId uint) ([]AuthorizationResponse, error) {
+	var consents []Consent
+	if err := s.db.Where("user_id = ?", userId).Order("updated_at DESC").Find(&consents).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	authorizations := make([]AuthorizationResponse, 0, len(consents))
+	for _, consent := range consents {
+		var client Client
+		if err := s.db.Where("client_id = ?", consent.ClientId).First(&client).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		authorizations = append(authorizations, AuthorizationResponse{
+			ClientId:   client.ClientId,
+			ClientName: client.Name,
+			Website:    client.Website,
+			Scopes:     consent.Scopes,
+			GrantedAt:  consent.UpdatedAt,
+		})
+	}
+	return authorizations, nil
+}
+
+// RevokeAuthorization withdraws the consent a user gave a client and revokes the
+// tokens the client holds for them
+func (s *OAuthServerService) RevokeAuthorization(userId uint, clientId string) error {
+	var revoked []RefreshToken
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND client_id = ?", userId, clientId).Delete(&Consent{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke authorization: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrAuthorizationNotFound
+		}
+		if err := tx.Where("user_id = ? AND client_id = ? AND used_at IS NULL", userId, clientId).Delete(&AuthorizationCode{}).Error; err != nil {
+			return fmt.Errorf("failed to revoke authorization: %w", err)
+		}
+		var err error
+		revoked, err = revokeRefreshTokens(tx, tx.Where("user_id = ? AND client_id = ?", userId, clientId))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return s.revokeAccessTokens(revoked)
+}
+
+// CheckClient rejects tokens of clients that were deleted; it runs on every
+// request with an OAuth token through types.SetClientCheck
+func (s *OAuthServerService) CheckClient(clientId string) error {
+	var count int64
+	if err := s.db.Model(&Client{}).Where("client_id = ?", clientId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return types.ErrTokenRevoked
+	}
+	return nil
+}
+
+// revokeRefreshTokens revokes the refresh tokens matched by query and returns them,
+// so the access tokens issued with them can be revoked too
+func revokeRefreshTokens(tx *gorm.DB, query *gorm.DB) ([]RefreshToken, error) {
+	var tokens []RefreshToken
+	if err := query.Where("revoked_at IS NULL").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(tokens))
+	for _, token := range tokens {
+		ids = append(ids, token.Id)
+	}
+	if err := tx.Model(&RefreshToken{}).Where("id IN ?", ids).Update("revoked_at", time.Now()).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// revokeAccessTokens revokes the access tokens last issued with refresh tokens,
+// unless they already expired
+func (s *OAuthServerService) revokeAccessTokens(tokens []RefreshToken) error {
+	now := time.Now()
+	for _, token := range tokens {
+		if token.AccessTokenId != "" && token.AccessExpiresAt.After(now) {
+			if err := s.sessions.RevokeToken(token.AccessTokenId, token.AccessExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateClient checks a client registration or update
+func (s *OAuthServerService) validateClient(req *ClientRequest) error {
+	if name := strings.TrimSpace(req.Name); name == "" || len(name) > 100 {
+		return ErrInvalidName
+	}
+	if req.Website != "" {
+		website, err := url.Parse(req.Website)
+		if err != nil || (website.Scheme != "http" && website.Scheme != "https") || website.Host == "" || len(req.Website) > 255 {
+			return ErrInvalidWebsite
+		}
+	}
+	if len(req.RedirectURIs) == 0 || len(req.RedirectURIs) > maxRedirectURIs {
+		return fmt.Errorf("%w; register between 1 and %d", ErrInvalidRedirectURI, maxRedirectURIs)
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			return fmt.Errorf("%w: %q", ErrInvalidRedirectURI, redirectURI)
+		}
+	}
+
+	scopes := normalizeScopes(req.Scopes)
+	if len(scopes) == 0 {
+		return ErrInvalidScope
+	}
+	known, err := s.Scopes()
+	if err != nil {
+		return err
+	}
+	for _, scope := range scopes {
+		if !slices.ContainsFunc(known, func(info ScopeInfo) bool { return info.Scope == scope }) {
+			return fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// userScopes returns the permissions of a user as scopes, from their role and the
+// permissions granted to them directly
+func (s *OAuthServerService) userScopes(userId uint) (map[string]bool, error) {
+	var permissions []authorization.Permission
+	err := s.db.Raw(`
+		SELECT DISTINCT p.* FROM permissions p
+		JOIN role_permissions rp ON p.id = rp.permission_id
+		JOIN users u ON u.role_id = rp.role_id
+		WHERE u.id = ? AND u.deleted_at IS NULL
+		UNION
+		SELECT DISTINCT p.* FROM permissions p
+		JOIN resource_permissions rp ON p.id = rp.permission_id
+		WHERE rp.user_id = ?
+	`, userId, userId).Scan(&permissions).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	scopes := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		scopes[permission.ResourceType+":"+permission.Action] = true
+	}
+	return scopes, nil
+}
+
+// grantable narrows scopes to those of the client that the user holds, returning
+// the ones dropped
+func (s *OAuthServerService) grantable(client *Client, userId uint, scopes []string) (granted, dropped []string, err error) {
+	held, err := s.userScopes(userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, scope := range scopes {
+		if held[scope] && slices.Contains(client.Scopes, scope) {
+			granted = append(granted, scope)
+		} else {
+			dropped = append(dropped, scope)
+		}
+	}
+	return granted, dropped, nil
+}
+
+// findClient loads a client owned by a user, or any client for administrators
+func (s *OAuthServerService) findClient(userId uint, clientId string) (*Client, error) {
+	var client Client
+	if err := s.db.Where("client_id = ?", clientId).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClientNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if client.OwnerId == userId {
+		return &client, nil
+	}
+
+	admin, err := s.isAdmin(userId)
+	if err != nil {
+		return nil, err
+	}
+	if !admin {
+		// Other users' clients are reported as missing rather than forbidden
+		return nil, ErrClientNotFound
+	}
+	return &client, nil
+}
+
+func (s *OAuthServerService) isAdmin(userId uint) (bool, error) {
+	var user profile.User
+	if err := s.db.First(&user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("database error: %w", err)
+	}
+	return user.IsAdmin(), nil
+}
+
+// validRedirectURI accepts https URLs, http URLs on loopback hosts and private-use
+// schemes of native apps in reverse domain notation (RFC 8252), without fragments
+func validRedirectURI(raw string) bool {
+	if strings.Contains(raw, "#") || len(raw) > 2000 {
+		return false
+	}
+	redirectURI, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch redirectURI.Scheme {
+	case "https":
+		return redirectURI.Host != ""
+	case "http":
+		host := redirectURI.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		return strings.Contains(redirectURI.Scheme, ".")
+	}
+}
+
+// normalizeScopes trims, lowercases and deduplicates scopes
+func normalizeScopes(scopes []string) []string {
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope != "" && !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,370 @@
+package oauthserver
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+const refreshTokenBytes = 32
+
+// errRefreshRaced reports a refresh token used by a concurrent refresh
+var errRefreshRaced = errors.New("refresh token already used")
+
+// ClientCredentials are what a client authenticates with at the token,
+// introspection and revocation endpoints
+type ClientCredentials struct {
+	ClientId     string
+	ClientSecret string
+}
+
+// Token answers a token request: an authorization code or refresh token exchange,
+// or client credentials (RFC 6749 sections 4.1.3, 4.4 and 6)
+func (s *OAuthServerService) Token(credentials ClientCredentials, params url.Values) (*TokenResponse, error) {
+	client, err := s.authenticate(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	switch params.Get("grant_type") {
+	case "authorization_code":
+		return s.exchangeCode(client, params)
+	case "refresh_token":
+		return s.refresh(client, params)
+	case "client_credentials":
+		return s.clientCredentials(client, params)
+	case "":
+		return nil, &Error{Code: ErrorInvalidRequest, Description: "grant_type is required"}
+	default:
+		return nil, &Error{Code: ErrorUnsupportedGrantType, Description: "supported grant types are authorization_code, refresh_token and client_credentials"}
+	}
+}
+
+// Introspect describes a token to the client it was issued to; tokens of other
+// clients, and invalid ones, are reported inactive (RFC 7662)
+func (s *OAuthServerService) Introspect(credentials ClientCredentials, token string) (*IntrospectionResponse, error) {
+	client, err := s.authenticate(credentials)
+	if err != nil {
+		return nil, err
+	}
+	inactive := &IntrospectionResponse{Active: false}
+
+	if isAccessToken(token) {
+		claims, err := types.ParseJWT(token)
+		if err != nil || claims.ClientId != client.ClientId {
+			return inactive, nil
+		}
+		return &IntrospectionResponse{
+			Active:    true,
+			Scope:     strings.Join(claims.Scopes, " "),
+			ClientId:  claims.ClientId,
+			Subject:   strconv.FormatUint(uint64(claims.UserId), 10),
+			TokenType: "access_token",
+			ExpiresAt: claims.ExpiresAt.Unix(),
+		}, nil
+	}
+
+	refreshToken, err := s.findRefreshToken(client, token)
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken == nil || refreshToken.UsedAt != nil || refreshToken.RevokedAt != nil || time.Now().After(refreshToken.ExpiresAt) {
+		return inactive, nil
+	}
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(refreshToken.Scopes, " "),
+		ClientId:  refreshToken.ClientId,
+		Subject:   strconv.FormatUint(uint64(refreshToken.UserId), 10),
+		TokenType: "refresh_token",
+		ExpiresAt: refreshToken.ExpiresAt.Unix(),
+	}, nil
+}
+
+// Revoke revokes a token of the calling client; a refresh token takes the access
+// token issued with it along. Unknown tokens are ignored (RFC 7009).
+func (s *OAuthServerService) Revoke(credentials ClientCredentials, token string) error {
+	client, err := s.authenticate(credentials)
+	if err != nil {
+		return err
+	}
+
+	if isAccessToken(token) {
+		claims, err := types.ParseJWT(token)
+		if err != nil || claims.ClientId != client.ClientId {
+			return nil
+		}
+		return s.sessions.RevokeToken(claims.TokenId, claims.ExpiresAt)
+	}
+
+	refreshToken, err := s.findRefreshToken(client, token)
+	if err != nil || refreshToken == nil {
+		return err
+	}
+	revoked, err := revokeRefreshTokens(s.db, s.db.Where("id = ?", refreshToken.Id))
+	if err != nil {
+		return err
+	}
+	return s.revokeAccessTokens(revoked)
+}
+
+// exchangeCode exchanges an authorization code and its PKCE verifier for tokens
+func (s *OAuthServerService) exchangeCode(client *Client, params url.Values) (*TokenResponse, error) {
+	code, verifier := params.Get("code"), params.Get("code_verifier")
+	if code == "" || verifier == "" {
+		return nil, &Error{Code: ErrorInvalidRequest, Description: "code and code_verifier are required"}
+	}
+
+	var stored AuthorizationCode
+	if err := s.db.Where("code_hash = ?", hashToken(code)).First(&stored).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &Error{Code: ErrorInvalidGrant, Description: "invalid authorization code"}
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if stored.ClientId != client.ClientId {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "invalid authorization code"}
+	}
+	if stored.UsedAt != nil {
+		return nil, s.replayed(stored.UserId, stored.ClientId, "authorization code already used")
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "authorization code expired"}
+	}
+	// The code is bound to the redirect URI it was sent to (RFC 6749 section 4.1.3)
+	if stored.RedirectURI != "" && params.Get("redirect_uri") != stored.RedirectURI {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "redirect_uri is missing or does not match the authorization request"}
+	}
+	if !codeVerifierPattern.MatchString(verifier) ||
+		subtle.ConstantTimeCompare([]byte(pkceChallenge(verifier)), []byte(stored.CodeChallenge)) != 1 {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "code_verifier does not match the code challenge"}
+	}
+
+	scopes, _, err := s.grantable(client, stored.UserId, stored.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "the user no longer has the granted permissions"}
+	}
+
+	var response *TokenResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Only one of two concurrent exchanges of the same code may win
+		result := tx.Model(&AuthorizationCode{}).Where("id = ? AND used_at IS NULL", stored.Id).Update("used_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to use authorization code: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return &Error{Code: ErrorInvalidGrant, Description: "authorization code already used"}
+		}
+		response, err = s.issue(tx, client, stored.UserId, scopes, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// refresh exchanges a refresh token for new tokens, optionally with fewer scopes.
+// Each refresh token works once; a replayed one revokes the tokens of the grant.
+func (s *OAuthServerService) refresh(client *Client, params url.Values) (*TokenResponse, error) {
+	raw := params.Get("refresh_token")
+	if raw == "" {
+		return nil, &Error{Code: ErrorInvalidRequest, Description: "refresh_token is required"}
+	}
+	token, err := s.findRefreshToken(client, raw)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.RevokedAt != nil {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "invalid refresh token"}
+	}
+	if token.UsedAt != nil {
+		return nil, s.replayed(token.UserId, token.ClientId, "refresh token already used")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "refresh token expired"}
+	}
+
+	var consents int64
+	if err := s.db.Model(&Consent{}).Where("user_id = ? AND client_id = ?", token.UserId, token.ClientId).Count(&consents).Error; err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if consents == 0 {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "the user revoked the authorization"}
+	}
+
+	requested := token.Scopes
+	if scope := params.Get("scope"); scope != "" {
+		requested = normalizeScopes(strings.Fields(scope))
+		for _, scope := range requested {
+			if !slices.Contains(token.Scopes, scope) {
+				return nil, &Error{Code: ErrorInvalidScope, Description: fmt.Sprintf("%s was not granted", scope)}
+			}
+		}
+	}
+	scopes, _, err := s.grantable(client, token.UserId, requested)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, &Error{Code: ErrorInvalidGrant, Description: "the user no longer has the granted permissions"}
+	}
+
+	var response *TokenResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", token.Id).Update("used_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshRaced
+		}
+		response, err = s.issue(tx, client, token.UserId, scopes, true)
+		return err
+	})
+	if errors.Is(err, errRefreshRaced) {
+		return nil, s.replayed(token.UserId, token.ClientId, "refresh token already used")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// clientCredentials issues a confidential client an access token acting for the
+// user who registered it, limited to the client's scopes that user holds
+func (s *OAuthServerService) clientCredentials(client *Client, params url.Values) (*TokenResponse, error) {
+	if !client.Confidential {
+		return nil, &Error{Code: ErrorUnauthorizedClient, Description: "only confidential clients may use client credentials"}
+	}
+
+	requested := client.Scopes
+	if scope := params.Get("scope"); scope != "" {
+		requested = normalizeScopes(strings.Fields(scope))
+		for _, scope := range requested {
+			if !slices.Contains(client.Scopes, scope) {
+				return nil, &Error{Code: ErrorInvalidScope, Description: fmt.Sprintf("the client may not ask for %s", scope)}
+			}
+		}
+	}
+	scopes, _, err := s.grantable(client, client.OwnerId, requested)
+	if err != nil {
+		return nil, err
+	}
+	if len(scopes) == 0 {
+		return nil, &Error{Code: ErrorInvalidScope, Description: "the client's owner has none of the requested permissions"}
+	}
+
+	return s.issue(s.db, client, client.OwnerId, scopes, false)
+}
+
+// issue creates an access token, and a refresh token when asked
+func (s *OAuthServerService) issue(tx *gorm.DB, client *Client, userId uint, scopes []string, withRefresh bool) (*TokenResponse, error) {
+	accessToken, claims, err := types.GenerateOAuthToken(userId, client.ClientId, scopes, s.accessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	response := &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.accessTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}
+	if !withRefresh {
+		return response, nil
+	}
+
+	raw, err := randomHex(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := RefreshToken{
+		TokenHash:       hashToken(raw),
+		ClientId:        client.ClientId,
+		UserId:          userId,
+		Scopes:          scopes,
+		AccessTokenId:   claims.TokenId,
+		AccessExpiresAt: claims.ExpiresAt,
+		ExpiresAt:       time.Now().Add(s.refreshTTL),
+	}
+	if err := tx.Create(&refreshToken).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	response.RefreshToken = raw
+	return response, nil
+}
+
+// replayed revokes the tokens a client holds for a user after one of its codes or
+// refresh tokens was presented twice, as it may have leaked
+func (s *OAuthServerService) replayed(userId uint, clientId, description string) error {
+	revoked, err := revokeRefreshTokens(s.db, s.db.Where("user_id = ? AND client_id = ?", userId, clientId))
+	if err != nil {
+		return err
+	}
+	if err := s.revokeAccessTokens(revoked); err != nil {
+		return err
+	}
+	return &Error{Code: ErrorInvalidGrant, Description: description + "; the client's tokens for the user have been revoked"}
+}
+
+// authenticate identifies the calling client: confidential clients by their
+// secret, public clients by their id alone
+func (s *OAuthServerService) authenticate(credentials ClientCredentials) (*Client, error) {
+	if credentials.ClientId == "" {
+		return nil, &Error{Code: ErrorInvalidClient, Description: "client authentication is required"}
+	}
+	var client Client
+	if err := s.db.Where("client_id = ?", credentials.ClientId).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &Error{Code: ErrorInvalidClient, Description: "invalid client credentials"}
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if client.Confidential {
+		if credentials.ClientSecret == "" ||
+			subtle.ConstantTimeCompare([]byte(hashToken(credentials.ClientSecret)), []byte(client.SecretHash)) != 1 {
+			return nil, &Error{Code: ErrorInvalidClient, Description: "invalid client credentials"}
+		}
+	} else if credentials.ClientSecret != "" {
+		return nil, &Error{Code: ErrorInvalidClient, Description: "public clients have no secret"}
+	}
+	return &client, nil
+}
+
+// findRefreshToken loads a refresh token of a client, or nil when there is none
+func (s *OAuthServerService) findRefreshToken(client *Client, raw string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := s.db.Where("token_hash = ? AND client_id = ?", hashToken(raw), client.ClientId).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &token, nil
+}
+
+// isAccessToken tells access tokens, which are JWTs, from refresh tokens
+func isAccessToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// pkceChallenge is the S256 code challenge of a code verifier (RFC 7636 section 4.2)
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -1,11 +1,17 @@
 package profile
 
 import (
+	"base/core/app/sessions"
+	"base/core/app/verification"
 	"base/core/logger"
+	"base/core/password"
 	"base/core/router"
+	"base/core/storage"
 	"base/core/types"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -28,6 +34,9 @@ func (c *ProfileController) Routes(router *router.RouterGroup) {
 	router.PUT("/profile", c.Update)
 	router.PUT("/profile/avatar", c.UpdateAvatar)
 	router.PUT("/profile/password", c.UpdatePassword)
+	router.GET("/profile/sessions", c.ListSessions)
+	router.DELETE("/profile/sessions", c.RevokeOtherSessions)
+	router.DELETE("/profile/sessions/:id", c.RevokeSession)
 }
 
 // @Summary Get profile from Authenticated User Token
@@ -63,7 +72,7 @@ func (c *ProfileController) Get(ctx *router.Context) error {
 }
 
 // @Summary Update profile from Authenticated User Token
-// @Description Update profile by Bearer Token
+// @Description Update profile by Bearer Token. A new email is kept as pending_email and a verification link is sent to it; it replaces the current email once verified.
 // @Security ApiKeyAuth
 // @Security BearerAuth
 // @Tags Core/Profile
@@ -73,6 +82,7 @@ func (c *ProfileController) Get(ctx *router.Context) error {
 // @Success 200 {object} User
 // @Failure 400 {object} types.ErrorResponse
 // @Failure 404 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
 // @Failure 500 {object} types.ErrorResponse
 // @Router /profile [put]
 func (c *ProfileController) Update(ctx *router.Context) error {
@@ -88,6 +98,9 @@ func (c *ProfileController) Update(ctx *router.Context) error {
 
 	item, err := c.service.Update(uint(id), &req)
 	if err != nil {
+		if errors.Is(err, verification.ErrEmailTaken) {
+			return ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: err.Error()})
+		}
 		c.logger.Error("Failed to update user",
 			logger.Uint("user_id", id))
 
@@ -128,6 +141,8 @@ func (c *ProfileController) UpdateAvatar(ctx *router.Context) error {
 
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
+		} else if status, ok := storage.QuotaStatus(err); ok {
+			return ctx.JSON(status, types.ErrorResponse{Error: err.Error()})
 		} else {
 			return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update avatar: " + err.Error()})
 		}
@@ -137,7 +152,7 @@ func (c *ProfileController) UpdateAvatar(ctx *router.Context) error {
 }
 
 // @Summary Update profile password from Authenticated User Token
-// @Description Update profile password by Bearer Token
+// @Description Update profile password by Bearer Token. The new password must follow the password policy and differ from the last ones. All sessions, including the current one, are logged out.
 // @Security ApiKeyAuth
 // @Security BearerAuth
 // @Tags Core/Profile
@@ -161,10 +176,6 @@ func (c *ProfileController) UpdatePassword(ctx *router.Context) error {
 		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid input: " + err.Error()})
 	}
 
-	if len(req.NewPassword) < 6 {
-		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "New password must be at least 6 characters long"})
-	}
-
 	err := c.service.UpdatePassword(uint(id), &req)
 	if err != nil {
 		c.logger.Error("Failed to update password",
@@ -175,6 +186,8 @@ func (c *ProfileController) UpdatePassword(ctx *router.Context) error {
 			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "User not found"})
 		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
 			return ctx.JSON(http.StatusUnauthorized, types.ErrorResponse{Error: "Current password is incorrect"})
+		case errors.Is(err, password.ErrWeakPassword), errors.Is(err, password.ErrPasswordReused):
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
 		default:
 			return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to update password"})
 		}
@@ -182,3 +195,96 @@ func (c *ProfileController) UpdatePassword(ctx *router.Context) error {
 
 	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Password updated successfully"})
 }
+
+// @Summary List active sessions of Authenticated User
+// @Description List the devices the user is logged in on, with user agent, IP and last-seen time
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Profile
+// @Produce json
+// @Success 200 {array} sessions.SessionResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /profile/sessions [get]
+func (c *ProfileController) ListSessions(ctx *router.Context) error {
+	id := ctx.GetUint("user_id")
+	if id == 0 {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user Id"})
+	}
+
+	items, err := c.service.Sessions(id, ctx.GetString("session_id"))
+	if err != nil {
+		c.logger.Error("Failed to list sessions",
+			logger.Uint("user_id", id))
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to list sessions"})
+	}
+
+	return ctx.JSON(http.StatusOK, items)
+}
+
+// @Summary Revoke a session of Authenticated User
+// @Description Log the user out of one of their sessions
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Profile
+// @Produce json
+// @Param id path int true "Session Id"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 404 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /profile/sessions/{id} [delete]
+func (c *ProfileController) RevokeSession(ctx *router.Context) error {
+	id := ctx.GetUint("user_id")
+	if id == 0 {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user Id"})
+	}
+
+	sessionId, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid session Id"})
+	}
+
+	if err := c.service.RevokeSession(id, uint(sessionId)); err != nil {
+		if errors.Is(err, sessions.ErrSessionNotFound) {
+			return ctx.JSON(http.StatusNotFound, types.ErrorResponse{Error: "Session not found"})
+		}
+		c.logger.Error("Failed to revoke session",
+			logger.Uint("user_id", id))
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to revoke session"})
+	}
+
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Session revoked"})
+}
+
+// @Summary Log out all other sessions of Authenticated User
+// @Description Revoke every session of the user except the one making the request
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Profile
+// @Produce json
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /profile/sessions [delete]
+func (c *ProfileController) RevokeOtherSessions(ctx *router.Context) error {
+	id := ctx.GetUint("user_id")
+	if id == 0 {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user Id"})
+	}
+
+	// Without a session on the token every session would be revoked
+	current := ctx.GetString("session_id")
+	if current == "" {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "The access token does not belong to a session"})
+	}
+
+	revoked, err := c.service.RevokeOtherSessions(id, current)
+	if err != nil {
+		c.logger.Error("Failed to revoke other sessions",
+			logger.Uint("user_id", id))
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to revoke sessions"})
+	}
+
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: fmt.Sprintf("%d other sessions revoked", revoked)})
+}
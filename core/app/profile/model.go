@@ -23,6 +23,11 @@ type User struct {
 	CreatedAt time.Time           `gorm:"column:created_at"`
 	UpdatedAt time.Time           `gorm:"column:updated_at"`
 	DeletedAt gorm.DeletedAt      `gorm:"column:deleted_at"`
+
+	// Email verification; an email change waits in PendingEmail until verified
+	VerifiedAt         *time.Time `gorm:"column:verified_at"`
+	PendingEmail       string     `gorm:"column:pending_email;size:255"`
+	VerificationSentAt *time.Time `gorm:"column:verification_sent_at"`
 }
 
 func (User) TableName() string {
@@ -35,7 +40,7 @@ type CreateRequest struct {
 	Username  string `json:"username" binding:"required,max=255"`
 	Phone     string `json:"phone" binding:"max=255"`
 	Email     string `json:"email" binding:"required,email,max=255"`
-	Password  string `json:"password" binding:"required,min=8,max=255"`
+	Password  string `json:"password" binding:"required,max=255"`
 }
 
 type UpdateRequest struct {
@@ -48,7 +53,7 @@ type UpdateRequest struct {
 
 type UpdatePasswordRequest struct {
 	OldPassword string `form:"OldPassword" binding:"required,max=255"`
-	NewPassword string `form:"NewPassword" binding:"required,min=6,max=255"`
+	NewPassword string `form:"NewPassword" binding:"required,max=255"`
 }
 
 // Implement the Attachable interface
@@ -60,6 +65,11 @@ func (u *User) GetModelName() string {
 	return "users"
 }
 
+// IsAdmin reports whether the user has the Owner (1) or Administrator (2) role
+func (u *User) IsAdmin() bool {
+	return u.RoleId == 1 || u.RoleId == 2
+}
+
 // UserResponse represents the API response structure
 type UserResponse struct {
 	Id        uint   `json:"id"`
@@ -72,6 +82,11 @@ type UserResponse struct {
 	RoleName  string `json:"role_name"`
 	AvatarURL string `json:"avatar_url"`
 	LastLogin string `json:"last_login"`
+
+	EmailVerified bool   `json:"email_verified"`
+	PendingEmail  string `json:"pending_email,omitempty"` // New email awaiting verification
+
+	AvatarVariants map[string]string `json:"avatar_variants,omitempty"`
 }
 
 // AvatarResponse represents the avatar in API responses
@@ -94,6 +109,9 @@ func (u *User) ToResponse() *UserResponse {
 		Phone:     u.Phone,
 		Email:     u.Email,
 		RoleId:    u.RoleId,
+
+		EmailVerified: u.VerifiedAt != nil,
+		PendingEmail:  u.PendingEmail,
 	}
 
 	// Include role name if role relationship is loaded
@@ -103,6 +121,7 @@ func (u *User) ToResponse() *UserResponse {
 
 	if u.Avatar != nil {
 		response.AvatarURL = u.Avatar.URL
+		response.AvatarVariants = u.Avatar.Variants
 	}
 
 	if u.LastLogin != nil {
@@ -142,3 +161,24 @@ func ToResponse(user *User) *UserResponse {
 	}
 	return user.ToResponse()
 }
+
+// MigrateEmailVerification adds the email verification columns to an existing
+// users table and marks the accounts already there as verified, so they keep
+// working under the verification policy. It runs before any module migrates, as
+// migrating any model that references users adds the columns too. The users and
+// authentication modules run it as well.
+func MigrateEmailVerification(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&User{}) || migrator.HasColumn(&User{}, "VerifiedAt") {
+		return nil
+	}
+	for _, field := range []string{"VerifiedAt", "PendingEmail", "VerificationSentAt"} {
+		if !migrator.HasColumn(&User{}, field) {
+			if err := migrator.AddColumn(&User{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	return db.Model(&User{}).Unscoped().Where("verified_at IS NULL").
+		UpdateColumn("verified_at", gorm.Expr("COALESCE(created_at, CURRENT_TIMESTAMP)")).Error
+}
@@ -1,8 +1,11 @@
 package profile
 
 import (
+	"base/core/app/sessions"
+	"base/core/app/verification"
 	"base/core/logger"
 	"base/core/module"
+	"base/core/password"
 	"base/core/router"
 	"base/core/storage"
 
@@ -23,9 +26,11 @@ func NewUserModule(
 	router *router.RouterGroup,
 	logger logger.Logger,
 	activeStorage *storage.ActiveStorage,
+	sessions *sessions.SessionService,
+	verification *verification.VerificationService,
 ) module.Module {
 	// Initialize service with active storage
-	service := NewProfileService(db, logger, activeStorage)
+	service := NewProfileService(db, logger, activeStorage, sessions, verification)
 	controller := NewProfileController(service, logger)
 
 	usersModule := &UserModule{
@@ -44,7 +49,11 @@ func (m *UserModule) Routes(router *router.RouterGroup) {
 }
 
 func (m *UserModule) Migrate() error {
-	err := m.DB.AutoMigrate(&User{})
+	if err := MigrateEmailVerification(m.DB); err != nil {
+		m.Logger.Error("Migration failed", logger.String("error", err.Error()))
+		return err
+	}
+	err := m.DB.AutoMigrate(&User{}, &password.History{})
 	if err != nil {
 		m.Logger.Error("Migration failed", logger.String("error", err.Error()))
 		return err
@@ -55,6 +64,7 @@ func (m *UserModule) Migrate() error {
 func (m *UserModule) GetModels() []any {
 	return []any{
 		&User{},
+		&password.History{},
 	}
 }
 
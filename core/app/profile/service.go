@@ -1,7 +1,11 @@
 package profile
 
 import (
+	"base/core/app/sessions"
+	"base/core/app/verification"
+	"base/core/config"
 	"base/core/logger"
+	"base/core/password"
 	"base/core/storage"
 	"context"
 	"errors"
@@ -17,9 +21,12 @@ type ProfileService struct {
 	db            *gorm.DB
 	logger        logger.Logger
 	activeStorage *storage.ActiveStorage
+	sessions      *sessions.SessionService
+	verification  *verification.VerificationService
+	passwords     *password.Policy
 }
 
-func NewProfileService(db *gorm.DB, logger logger.Logger, activeStorage *storage.ActiveStorage) *ProfileService {
+func NewProfileService(db *gorm.DB, logger logger.Logger, activeStorage *storage.ActiveStorage, sessions *sessions.SessionService, verification *verification.VerificationService) *ProfileService {
 	if db == nil {
 		panic("db is required")
 	}
@@ -29,6 +36,21 @@ func NewProfileService(db *gorm.DB, logger logger.Logger, activeStorage *storage
 	if activeStorage == nil {
 		panic("activeStorage is required")
 	}
+	if sessions == nil {
+		panic("sessions is required")
+	}
+	if verification == nil {
+		panic("verification is required")
+	}
+
+	service := &ProfileService{
+		db:            db,
+		logger:        logger,
+		activeStorage: activeStorage,
+		sessions:      sessions,
+		verification:  verification,
+		passwords:     password.NewPolicy(config.NewConfig()),
+	}
 
 	// Register avatar attachment configuration
 	activeStorage.RegisterAttachment("users", storage.AttachmentConfig{
@@ -37,13 +59,19 @@ func NewProfileService(db *gorm.DB, logger logger.Logger, activeStorage *storage
 		AllowedExtensions: []string{".jpg", ".jpeg", ".png", ".gif"},
 		MaxFileSize:       5 << 20, // 5MB
 		Multiple:          false,
+		Variants: []storage.Variant{
+			{Name: "thumb", Width: 64, Height: 64, Fit: storage.FitCover},
+			{Name: "medium", Width: 256, Height: 256, Fit: storage.FitCover},
+		},
+		OnUpdate: service.refreshAvatar,
 	})
 
-	return &ProfileService{
-		db:            db,
-		logger:        logger,
-		activeStorage: activeStorage,
-	}
+	return service
+}
+
+// refreshAvatar updates the copy of the avatar kept on the user when its file moves
+func (s *ProfileService) refreshAvatar(attachment *storage.Attachment) error {
+	return s.db.Model(&User{}).Where("id = ?", attachment.ModelId).Update("avatar", attachment).Error
 }
 
 // Helper method to convert user to response
@@ -86,11 +114,23 @@ func (s *ProfileService) Update(id uint, req *UpdateRequest) (*UserResponse, err
 	if req.Username != "" {
 		user.Username = req.Username
 	}
-	if req.Email != "" {
-		user.Email = req.Email
+
+	// A new email only replaces the current one once it is verified
+	if req.Email != "" && req.Email != user.Email && req.Email != user.PendingEmail {
+		if err := s.verification.RequestEmailChange(id, req.Email); err != nil {
+			s.logger.Error("Failed to request email change",
+				zap.Error(err),
+				zap.Uint("user_id", id))
+			return nil, err
+		}
+		user.PendingEmail = req.Email
+	} else if req.Email != "" && req.Email == user.Email {
+		// Asking for the current email again cancels a pending change
+		user.PendingEmail = ""
 	}
 
-	if err := s.db.Save(&user).Error; err != nil {
+	// The verification service owns when the verification email was sent
+	if err := s.db.Omit("verified_at", "verification_sent_at").Save(&user).Error; err != nil {
 		s.logger.Error("Failed to save user updates",
 			zap.Error(err),
 			zap.Uint("user_id", id))
@@ -107,7 +147,7 @@ func (s *ProfileService) UpdateAvatar(ctx context.Context, id uint, avatarFile *
 	}
 
 	// Just attach the new file - cleanup is handled inside Attach
-	attachment, err := s.activeStorage.Attach(&user, "avatar", avatarFile)
+	attachment, err := s.activeStorage.Attach(&user, "avatar", avatarFile, user.Id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload avatar: %w", err)
 	}
@@ -172,6 +212,13 @@ func (s *ProfileService) UpdatePassword(id uint, req *UpdatePasswordRequest) err
 		return bcrypt.ErrMismatchedHashAndPassword
 	}
 
+	if err := s.passwords.Validate(req.NewPassword, user.Email, user.Username, user.FirstName, user.LastName); err != nil {
+		return err
+	}
+	if err := s.passwords.CheckHistory(s.db, user.Id, user.Password, req.NewPassword); err != nil {
+		return err
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
 		s.logger.Error("Failed to hash new password",
@@ -181,12 +228,40 @@ func (s *ProfileService) UpdatePassword(id uint, req *UpdatePasswordRequest) err
 	}
 
 	user.Password = string(hashedPassword)
-	if err := s.db.Save(&user).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return fmt.Errorf("failed to update user password: %w", err)
+		}
+		return s.passwords.Remember(tx, user.Id, user.Password)
+	}); err != nil {
 		s.logger.Error("Failed to save new password",
 			zap.Error(err),
 			zap.Uint("user_id", id))
-		return fmt.Errorf("failed to update user password: %w", err)
+		return err
+	}
+
+	// Whoever knew the old password may still be logged in somewhere
+	if _, err := s.sessions.RevokeAll(id, "", sessions.ReasonPasswordChanged); err != nil {
+		s.logger.Error("Failed to revoke sessions after password change",
+			zap.Error(err),
+			zap.Uint("user_id", id))
+		return err
 	}
 
 	return nil
 }
+
+// Sessions lists the active sessions of a user, marking the one with currentSid
+func (s *ProfileService) Sessions(id uint, currentSid string) ([]*sessions.SessionResponse, error) {
+	return s.sessions.List(id, currentSid)
+}
+
+// RevokeSession logs a user out of one of their sessions
+func (s *ProfileService) RevokeSession(id, sessionId uint) error {
+	return s.sessions.Revoke(id, sessionId, sessions.ReasonRevoked)
+}
+
+// RevokeOtherSessions logs a user out of every session but the one with currentSid
+func (s *ProfileService) RevokeOtherSessions(id uint, currentSid string) (int64, error) {
+	return s.sessions.RevokeAll(id, currentSid, sessions.ReasonOthersRevoked)
+}
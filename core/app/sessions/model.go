@@ -0,0 +1,73 @@
+package sessions
+
+import "time"
+
+// Session is a login of a user on one device. Its refresh tokens form a family:
+// each one is exchanged for the next, and replaying a used one revokes the session.
+type Session struct {
+	Id            uint       `json:"id" gorm:"primaryKey"`
+	Sid           string     `json:"-" gorm:"size:32;uniqueIndex"` // Random id carried by access tokens as "sid"
+	UserId        uint       `json:"user_id" gorm:"index"`
+	UserAgent     string     `json:"user_agent"`
+	IP            string     `json:"ip" gorm:"column:ip;size:64"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	ExpiresAt     time.Time  `json:"expires_at"` // Pushed back on every refresh
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty" gorm:"size:64"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// RefreshToken is a single-use token of a session, stored as its SHA-256 hash
+type RefreshToken struct {
+	Id        uint       `gorm:"primaryKey"`
+	SessionId uint       `gorm:"index"`
+	TokenHash string     `gorm:"size:64;uniqueIndex"`
+	UsedAt    *time.Time // Set when exchanged for the next token of the family
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// RevokedToken is an access token revoked before it expires, by its jti
+type RevokedToken struct {
+	Id        uint      `gorm:"primaryKey"`
+	TokenId   string    `gorm:"size:32;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"` // The entry can go once the token has expired
+	CreatedAt time.Time
+}
+
+// Client describes the device a session is used from
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Tokens are the access and refresh tokens issued for a session
+type Tokens struct {
+	UserId           uint
+	SessionId        string
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// SessionResponse describes an active session to its user
+type SessionResponse struct {
+	Id         uint      `json:"id"`
+	Device     string    `json:"device"` // e.g. "Chrome on macOS"
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"` // The session of the request
+}
+
+// Revocation reasons recorded on sessions
+const (
+	ReasonLogout          = "logout"
+	ReasonTokenReuse      = "refresh_token_reuse"
+	ReasonRevoked         = "revoked_by_user"
+	ReasonOthersRevoked   = "other_sessions_revoked"
+	ReasonPasswordChanged = "password_changed"
+)
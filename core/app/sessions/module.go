@@ -0,0 +1,51 @@
+package sessions
+
+import (
+	"base/core/logger"
+	"base/core/module"
+	"base/core/router"
+	"base/core/types"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionModule struct {
+	module.DefaultModule
+	DB      *gorm.DB
+	Service *SessionService
+	Logger  logger.Logger
+}
+
+func NewSessionModule(db *gorm.DB, router *router.RouterGroup, logger logger.Logger, refreshTTL time.Duration) *SessionModule {
+	service := NewSessionService(db, refreshTTL)
+
+	return &SessionModule{
+		DB:      db,
+		Service: service,
+		Logger:  logger,
+	}
+}
+
+// Init makes every access token check go through the session revocation lookup
+func (m *SessionModule) Init() error {
+	types.SetRevocationCheck(m.Service.Check)
+	return nil
+}
+
+func (m *SessionModule) Migrate() error {
+	err := m.DB.AutoMigrate(&Session{}, &RefreshToken{}, &RevokedToken{})
+	if err != nil {
+		m.Logger.Error("Migration failed", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (m *SessionModule) GetModels() []any {
+	return []any{
+		&Session{},
+		&RefreshToken{},
+		&RevokedToken{},
+	}
+}
@@ -0,0 +1,332 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"base/core/types"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token already used; the session has been revoked")
+	ErrSessionRevoked      = errors.New("session has been revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// lastSeenInterval is how often requests with a session's access token update
+// when it was last seen; refreshes always do
+const lastSeenInterval = time.Minute
+
+// SessionService issues, rotates and revokes the tokens of user sessions
+type SessionService struct {
+	db         *gorm.DB
+	refreshTTL time.Duration
+}
+
+// NewSessionService creates a session service; sessions not refreshed within
+// refreshTTL expire
+func NewSessionService(db *gorm.DB, refreshTTL time.Duration) *SessionService {
+	return &SessionService{
+		db:         db,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Start opens a session for a user who just logged in and issues its first tokens
+func (s *SessionService) Start(userId uint, client Client, extend any) (*Tokens, error) {
+	sid, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	session := &Session{
+		Sid:        sid,
+		UserId:     userId,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+
+	var refreshToken *issuedToken
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		refreshToken, err = s.issueRefreshToken(tx, session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.tokens(session, refreshToken, extend)
+}
+
+// Refresh exchanges a refresh token for new access and refresh tokens. Each refresh
+// token works once; presenting one that was already exchanged means it was stolen
+// or replayed, so the whole session is revoked.
+func (s *SessionService) Refresh(refreshToken string, client Client, extend func(userId uint) any) (*Tokens, error) {
+	var token RefreshToken
+	if err := s.db.Where("token_hash = ?", hashToken(refreshToken)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	var session Session
+	if err := s.db.First(&session, token.SessionId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+	if token.UsedAt != nil {
+		return nil, s.revokeReused(&session)
+	}
+	now := time.Now()
+	if now.After(token.ExpiresAt) || now.After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var next *issuedToken
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Only one of two concurrent refreshes with the same token may win
+		result := tx.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", token.Id).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		session.LastSeenAt = now
+		session.ExpiresAt = now.Add(s.refreshTTL)
+		session.UserAgent = client.UserAgent
+		session.IP = client.IP
+		if err := tx.Model(&session).Select("last_seen_at", "expires_at", "user_agent", "ip").Updates(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		next, err = s.issueRefreshToken(tx, &session)
+		return err
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		return nil, s.revokeReused(&session)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	return s.tokens(&session, next, extend(session.UserId))
+}
+
+// Logout revokes the session of an access token, and the token itself so it stops
+// working even if it was issued without a session
+func (s *SessionService) Logout(claims *types.Claims) error {
+	if claims.SessionId != "" {
+		if err := s.RevokeSession(claims.SessionId, ReasonLogout); err != nil {
+			return err
+		}
+	}
+	if claims.TokenId != "" {
+		return s.RevokeToken(claims.TokenId, claims.ExpiresAt)
+	}
+	return nil
+}
+
+// LogoutRefreshToken revokes the session a refresh token belongs to, for clients
+// whose access token already expired
+func (s *SessionService) LogoutRefreshToken(refreshToken string) error {
+	var token RefreshToken
+	if err := s.db.Where("token_hash = ?", hashToken(refreshToken)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+	return s.revoke(s.db.Where("id = ?", token.SessionId), ReasonLogout)
+}
+
+// List returns the active sessions of a user, most recently seen first. The session
+// with sid currentSid is marked as the current one.
+func (s *SessionService) List(userId uint, currentSid string) ([]*SessionResponse, error) {
+	var sessions []*Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	responses := make([]*SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = &SessionResponse{
+			Id:         session.Id,
+			Device:     deviceName(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    currentSid != "" && session.Sid == currentSid,
+		}
+	}
+	return responses, nil
+}
+
+// Revoke revokes one active session of a user
+func (s *SessionService) Revoke(userId, id uint, reason string) error {
+	result := s.db.Model(&Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		Updates(map[string]any{"revoked_at": time.Now(), "revoked_reason": reason})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAll revokes every active session of a user except the one with sid
+// exceptSid, which may be empty, and returns how many were revoked
+func (s *SessionService) RevokeAll(userId uint, exceptSid, reason string) (int64, error) {
+	query := s.db.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userId)
+	if exceptSid != "" {
+		query = query.Where("sid <> ?", exceptSid)
+	}
+	result := query.Updates(map[string]any{"revoked_at": time.Now(), "revoked_reason": reason})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// RevokeSession revokes a session by its sid; its access and refresh tokens stop working
+func (s *SessionService) RevokeSession(sid, reason string) error {
+	return s.revoke(s.db.Where("sid = ?", sid), reason)
+}
+
+// RevokeToken revokes a single access token by its jti until it expires
+func (s *SessionService) RevokeToken(tokenId string, expiresAt time.Time) error {
+	if err := s.db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{}).Error; err != nil {
+		return fmt.Errorf("failed to prune revoked tokens: %w", err)
+	}
+	revoked := RevokedToken{TokenId: tokenId, ExpiresAt: expiresAt}
+	if err := s.db.Where(RevokedToken{TokenId: tokenId}).FirstOrCreate(&revoked).Error; err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// Check rejects access tokens that were revoked, or whose session was; it runs on
+// every authenticated request through types.SetRevocationCheck
+func (s *SessionService) Check(claims *types.Claims) error {
+	if claims.TokenId != "" {
+		var count int64
+		if err := s.db.Model(&RevokedToken{}).Where("token_id = ?", claims.TokenId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return types.ErrTokenRevoked
+		}
+	}
+	if claims.SessionId != "" {
+		var session Session
+		if err := s.db.Select("id", "revoked_at", "last_seen_at").Where("sid = ?", claims.SessionId).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return types.ErrTokenRevoked
+			}
+			return err
+		}
+		if session.RevokedAt != nil {
+			return types.ErrTokenRevoked
+		}
+		if now := time.Now(); now.Sub(session.LastSeenAt) > lastSeenInterval {
+			s.db.Model(&Session{}).Where("id = ?", session.Id).UpdateColumn("last_seen_at", now)
+		}
+	}
+	return nil
+}
+
+// revoke marks the sessions matched by query as revoked
+func (s *SessionService) revoke(query *gorm.DB, reason string) error {
+	err := query.Model(&Session{}).Where("revoked_at IS NULL").
+		Updates(map[string]any{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// revokeReused revokes a session whose refresh token was replayed
+func (s *SessionService) revokeReused(session *Session) error {
+	if err := s.revoke(s.db.Where("id = ?", session.Id), ReasonTokenReuse); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issuedToken is a refresh token as handed to the client
+type issuedToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// issueRefreshToken stores the hash of a new refresh token for a session
+func (s *SessionService) issueRefreshToken(tx *gorm.DB, session *Session) (*issuedToken, error) {
+	value, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	token := RefreshToken{
+		SessionId: session.Id,
+		TokenHash: hashToken(value),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return &issuedToken{value: value, expiresAt: token.ExpiresAt}, nil
+}
+
+// tokens issues an access token for a session alongside its refresh token
+func (s *SessionService) tokens(session *Session, refreshToken *issuedToken, extend any) (*Tokens, error) {
+	accessToken, claims, err := types.GenerateAccessToken(session.UserId, session.Sid, extend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return &Tokens{
+		UserId:           session.UserId,
+		SessionId:        session.Sid,
+		AccessToken:      accessToken,
+		AccessExpiresAt:  claims.ExpiresAt,
+		RefreshToken:     refreshToken.value,
+		RefreshExpiresAt: refreshToken.expiresAt,
+	}, nil
+}
+
+// randomToken returns n random bytes as hex
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 of a refresh token, as stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,435 @@
+package uploads
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"base/core/logger"
+	"base/core/router"
+	"base/core/storage"
+)
+
+// TusVersion is the supported version of the tus resumable upload protocol
+const TusVersion = "1.0.0"
+
+// TusExtensions lists the supported tus protocol extensions
+const TusExtensions = "creation,termination,expiration"
+
+type UploadController struct {
+	Service *UploadService
+	Logger  logger.Logger
+}
+
+func NewUploadController(service *UploadService, logger logger.Logger) *UploadController {
+	return &UploadController{
+		Service: service,
+		Logger:  logger,
+	}
+}
+
+func (c *UploadController) Routes(router *router.RouterGroup) {
+	// tus 1.0 resumable upload endpoints
+	router.POST("/uploads", c.Create)          // Creation
+	router.HEAD("/uploads/:id", c.Head)        // Current offset
+	router.PATCH("/uploads/:id", c.Patch)      // Append a chunk
+	router.DELETE("/uploads/:id", c.Terminate) // Termination
+
+	// Upload status with the resulting attachment
+	router.GET("/uploads/:id", c.Get)
+
+	// Private files behind local signed URLs
+	router.GET("/files/*path", c.Download)
+
+	// Image variants generated on their first request
+	router.GET("/variants/:id/:name", c.Variant)
+
+	// Storage usage against quotas
+	router.GET("/storage/usage", c.Usage)
+	router.GET("/storage/consumers", c.Consumers) // Administrators only
+}
+
+// Create godoc
+// @Summary Create a resumable upload
+// @Description Start a tus 1.0 upload. Upload-Metadata must include filename, model, model_id and field; the file is attached to that record when the last chunk arrives
+// @Tags Core/Uploads
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Param Upload-Length header int true "Total file size in bytes"
+// @Param Upload-Metadata header string true "Comma-separated key and base64 value pairs"
+// @Success 201 "Created; the upload URL is in the Location header"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 412 "Unsupported protocol version"
+// @Failure 413 {object} ErrorResponse
+// @Router /uploads [post]
+// @Security ApiKeyAuth
+// @Security BearerAuth
+func (c *UploadController) Create(ctx *router.Context) error {
+	if !c.checkVersion(ctx) {
+		return nil
+	}
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	length, err := strconv.ParseInt(ctx.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Upload-Length header is required"})
+	}
+	metadata, err := parseMetadata(ctx.GetHeader("Upload-Metadata"))
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid Upload-Metadata header"})
+	}
+
+	item, err := c.Service.Create(userId, length, metadata)
+	if item == nil && err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	location := strings.TrimSuffix(ctx.Request.URL.Path, "/") + "/" + item.Id
+	ctx.SetHeader("Location", location)
+	c.setStatusHeaders(ctx, item)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+	ctx.Status(http.StatusCreated)
+	return nil
+}
+
+// Head godoc
+// @Summary Get the offset of a resumable upload
+// @Description Return the number of bytes received in the Upload-Offset header so the client can resume
+// @Tags Core/Uploads
+// @Param id path string true "Upload Id"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Success 200 "Upload-Offset and Upload-Length headers"
+// @Failure 404 "Upload not found"
+// @Failure 410 "Upload expired"
+// @Router /uploads/{id} [head]
+// @Security ApiKeyAuth
+// @Security BearerAuth
+func (c *UploadController) Head(ctx *router.Context) error {
+	if !c.checkVersion(ctx) {
+		return nil
+	}
+
+	item, err := c.Service.Get(ctx.Param("id"), ctx.GetUint("user_id"))
+	if err != nil {
+		ctx.Status(errorStatus(err))
+		return nil
+	}
+
+	c.setStatusHeaders(ctx, item)
+	ctx.SetHeader("Upload-Length", strconv.FormatInt(item.Length, 10))
+	if len(item.Metadata) > 0 {
+		ctx.SetHeader("Upload-Metadata", formatMetadata(item.Metadata))
+	}
+	ctx.Status(http.StatusOK)
+	return nil
+}
+
+// Patch godoc
+// @Summary Append a chunk to a resumable upload
+// @Description Write the request body at Upload-Offset. The response Upload-Offset is the new offset; after the last chunk the file is attached to its record
+// @Tags Core/Uploads
+// @Accept application/offset+octet-stream
+// @Param id path string true "Upload Id"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Param Upload-Offset header int true "Offset the chunk starts at"
+// @Success 204 "Chunk stored"
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 423 {object} ErrorResponse
+// @Router /uploads/{id} [patch]
+// @Security ApiKeyAuth
+// @Security BearerAuth
+func (c *UploadController) Patch(ctx *router.Context) error {
+	if !c.checkVersion(ctx) {
+		return nil
+	}
+	if ctx.GetHeader("Content-Type") != "application/offset+octet-stream" {
+		return ctx.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Error: "Content-Type must be application/offset+octet-stream"})
+	}
+	offset, err := strconv.ParseInt(ctx.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Upload-Offset header is required"})
+	}
+
+	item, err := c.Service.Write(ctx.Param("id"), ctx.GetUint("user_id"), offset, ctx.Request.Body)
+	if item != nil {
+		c.setStatusHeaders(ctx, item)
+	}
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// Terminate godoc
+// @Summary Terminate a resumable upload
+// @Description Cancel an upload and discard the bytes received so far
+// @Tags Core/Uploads
+// @Param id path string true "Upload Id"
+// @Param Tus-Resumable header string true "Protocol version (1.0.0)"
+// @Success 204 "Upload terminated"
+// @Failure 404 {object} ErrorResponse
+// @Router /uploads/{id} [delete]
+// @Security ApiKeyAuth
+// @Security BearerAuth
+func (c *UploadController) Terminate(ctx *router.Context) error {
+	if !c.checkVersion(ctx) {
+		return nil
+	}
+
+	if err := c.Service.Terminate(ctx.Param("id"), ctx.GetUint("user_id")); err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	ctx.SetHeader("Tus-Resumable", TusVersion)
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// Get godoc
+// @Summary Get a resumable upload
+// @Description Get the progress of an upload and, once complete, the resulting attachment
+// @Tags Core/Uploads
+// @Produce json
+// @Param id path string true "Upload Id"
+// @Success 200 {object} UploadResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
+// @Router /uploads/{id} [get]
+// @Security ApiKeyAuth
+// @Security BearerAuth
+func (c *UploadController) Get(ctx *router.Context) error {
+	item, err := c.Service.Get(ctx.Param("id"), ctx.GetUint("user_id"))
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, item.ToResponse())
+}
+
+// Download godoc
+// @Summary Download a private file
+// @Description Serve a private file from local storage. The URL is issued by the endpoint of the resource it belongs to and stops working once it expires
+// @Tags Core/Uploads
+// @Param path path string true "Storage key"
+// @Param expires query int true "Expiry as a Unix timestamp"
+// @Param signature query string true "HMAC signature"
+// @Success 200 "File contents"
+// @Failure 403 {object} ErrorResponse
+// @Router /files/{path} [get]
+func (c *UploadController) Download(ctx *router.Context) error {
+	key := strings.TrimPrefix(ctx.Param("path"), "/")
+	file, err := c.Service.Storage.SignedFile(key, ctx.Query("expires"), ctx.Query("signature"))
+	if err != nil {
+		return ctx.JSON(http.StatusForbidden, ErrorResponse{Error: "Invalid or expired link"})
+	}
+
+	ctx.SetHeader("Content-Type", c.Service.Storage.ContentType(key))
+	ctx.SetHeader("X-Content-Type-Options", "nosniff")
+	ctx.SetHeader("Content-Disposition", "attachment; filename=\""+path.Base(key)+"\"")
+	ctx.SetHeader("Cache-Control", "private, no-store")
+	ctx.File(file)
+	return nil
+}
+
+// Variant godoc
+// @Summary Get an image variant
+// @Description Redirect to a resized variant of an image attachment, generating and storing it on the first request
+// @Tags Core/Uploads
+// @Param id path int true "Attachment Id"
+// @Param name path string true "Variant name"
+// @Success 302 "Redirect to the variant"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /variants/{id}/{name} [get]
+func (c *UploadController) Variant(ctx *router.Context) error {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid id format"})
+	}
+
+	url, err := c.Service.Storage.VariantURL(uint(id), ctx.Param("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrVariantNotFound), errors.Is(err, storage.ErrNotImage),
+			strings.Contains(err.Error(), "record not found"):
+			return ctx.JSON(http.StatusNotFound, ErrorResponse{Error: "Variant not found"})
+		default:
+			c.Logger.Error("failed to generate variant",
+				logger.String("error", err.Error()),
+				logger.Int("id", int(id)))
+			return ctx.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to generate variant"})
+		}
+	}
+
+	// The variant never changes once it is stored
+	ctx.SetHeader("Cache-Control", "public, max-age=86400")
+	return ctx.Redirect(http.StatusFound, url)
+}
+
+// Usage godoc
+// @Summary Get my storage usage
+// @Description Get the storage taken by the files the authenticated user uploaded and the user quota
+// @Tags Core/Uploads
+// @Produce json
+// @Success 200 {object} storage.Usage
+// @Failure 401 {object} ErrorResponse
+// @Router /storage/usage [get]
+// @Security ApiKeyAuth
+// @Security BearerAuth
+func (c *UploadController) Usage(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	usage, err := c.Service.Usage(userId)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, usage)
+}
+
+// Consumers godoc
+// @Summary List top storage consumers
+// @Description List the users, or the owner records such as courses, taking the most storage. Administrators only
+// @Tags Core/Uploads
+// @Produce json
+// @Param type query string false "user (default) or an owner type such as course"
+// @Param limit query int false "Number of consumers (default 20, max 100)"
+// @Success 200 {array} StorageConsumer
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /storage/consumers [get]
+// @Security ApiKeyAuth
+// @Security BearerAuth
+func (c *UploadController) Consumers(ctx *router.Context) error {
+	userId := ctx.GetUint("user_id")
+	if userId == 0 {
+		return ctx.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Authentication required"})
+	}
+
+	subjectType := ctx.DefaultQuery("type", storage.QuotaUser)
+	limit := 20
+	if value := ctx.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid limit"})
+		}
+		limit = min(parsed, 100)
+	}
+
+	consumers, err := c.Service.TopConsumers(userId, subjectType, limit)
+	if err != nil {
+		return c.handleError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, consumers)
+}
+
+// checkVersion rejects requests for another protocol version with 412
+func (c *UploadController) checkVersion(ctx *router.Context) bool {
+	ctx.SetHeader("Tus-Resumable", TusVersion)
+	ctx.SetHeader("Tus-Version", TusVersion)
+	ctx.SetHeader("Tus-Extension", TusExtensions)
+	ctx.SetHeader("Tus-Max-Size", strconv.FormatInt(c.Service.MaxSize, 10))
+	if ctx.GetHeader("Tus-Resumable") != TusVersion {
+		ctx.Status(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// setStatusHeaders sets the offset and expiration of an upload
+func (c *UploadController) setStatusHeaders(ctx *router.Context, item *Upload) {
+	ctx.SetHeader("Upload-Offset", strconv.FormatInt(item.Offset, 10))
+	ctx.SetHeader("Cache-Control", "no-store")
+	if item.CompletedAt == nil {
+		ctx.SetHeader("Upload-Expires", item.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (c *UploadController) handleError(ctx *router.Context, err error) error {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		c.Logger.Error("upload failed", logger.String("error", err.Error()))
+	}
+	return ctx.JSON(status, ErrorResponse{Error: err.Error()})
+}
+
+// errorStatus maps service errors to tus response codes
+func errorStatus(err error) int {
+	if status, ok := storage.QuotaStatus(err); ok {
+		return status
+	}
+	switch {
+	case errors.Is(err, ErrInvalidUpload):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotAllowed), errors.Is(err, ErrNotAdmin):
+		return http.StatusForbidden
+	case errors.Is(err, ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrOffsetMismatch):
+		return http.StatusConflict
+	case errors.Is(err, ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, ErrUploadLocked):
+		return http.StatusLocked
+	case strings.Contains(err.Error(), "record not found"):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseMetadata decodes an Upload-Metadata header: comma-separated pairs of a key
+// and a base64 encoded value, where the value may be omitted
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		switch len(parts) {
+		case 0:
+			continue
+		case 1:
+			metadata[parts[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			metadata[parts[0]] = string(value)
+		default:
+			return nil, errors.New("invalid metadata pair")
+		}
+	}
+	return metadata, nil
+}
+
+// formatMetadata encodes metadata for the Upload-Metadata header
+func formatMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + " " + base64.StdEncoding.EncodeToString([]byte(metadata[key]))
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,97 @@
+package uploads
+
+import (
+	"time"
+
+	"base/core/storage"
+)
+
+// Upload is a resumable tus upload; received bytes are staged on local disk
+// until the upload is complete and then attached to the target model field
+type Upload struct {
+	Id           string              `json:"id" gorm:"primaryKey;size:32"`
+	Length       int64               `json:"length"` // Total size declared by the client
+	Offset       int64               `json:"offset"` // Bytes received so far
+	Metadata     map[string]string   `json:"metadata" gorm:"serializer:json;type:text"`
+	Filename     string              `json:"filename"`
+	ModelType    string              `json:"model_type" gorm:"index"`
+	ModelId      uint                `json:"model_id" gorm:"index"`
+	Field        string              `json:"field"`
+	UserId       uint                `json:"user_id" gorm:"index"`
+	AttachmentId *uint               `json:"attachment_id,omitempty"`
+	Attachment   *storage.Attachment `json:"attachment,omitempty" gorm:"-"`
+	CompletedAt  *time.Time          `json:"completed_at,omitempty"`
+	ExpiresAt    time.Time           `json:"expires_at" gorm:"index"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// TableName returns the table name for the Upload model
+func (item *Upload) TableName() string {
+	return "tus_uploads"
+}
+
+// IsComplete reports whether all declared bytes were received
+func (item *Upload) IsComplete() bool {
+	return item.Offset >= item.Length
+}
+
+// UploadResponse represents the status of an upload
+type UploadResponse struct {
+	Id          string              `json:"id"`
+	Filename    string              `json:"filename"`
+	ModelType   string              `json:"model_type"`
+	ModelId     uint                `json:"model_id"`
+	Field       string              `json:"field"`
+	Length      int64               `json:"length"`
+	Offset      int64               `json:"offset"`
+	Completed   bool                `json:"completed"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time           `json:"expires_at"`
+	Attachment  *storage.Attachment `json:"attachment,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// ToResponse converts the model to a response
+func (item *Upload) ToResponse() *UploadResponse {
+	return &UploadResponse{
+		Id:          item.Id,
+		Filename:    item.Filename,
+		ModelType:   item.ModelType,
+		ModelId:     item.ModelId,
+		Field:       item.Field,
+		Length:      item.Length,
+		Offset:      item.Offset,
+		Completed:   item.CompletedAt != nil,
+		CompletedAt: item.CompletedAt,
+		ExpiresAt:   item.ExpiresAt,
+		Attachment:  item.Attachment,
+		CreatedAt:   item.CreatedAt,
+	}
+}
+
+// StorageConsumer is the storage usage of a user or owner record in the admin view
+type StorageConsumer struct {
+	*storage.Usage
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// ErrorResponse represents an error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// target identifies the record an upload is attached to
+type target struct {
+	modelName string
+	id        uint
+}
+
+func (t target) GetId() uint {
+	return t.id
+}
+
+func (t target) GetModelName() string {
+	return t.modelName
+}
@@ -0,0 +1,78 @@
+package uploads
+
+import (
+	"context"
+	"time"
+
+	"base/core/config"
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/router"
+	"base/core/storage"
+
+	"gorm.io/gorm"
+)
+
+type UploadModule struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *UploadController
+	Service    *UploadService
+	Logger     logger.Logger
+}
+
+func NewUploadModule(
+	db *gorm.DB,
+	router *router.RouterGroup,
+	activeStorage *storage.ActiveStorage,
+	emitter *emitter.Emitter,
+	logger logger.Logger,
+	cfg *config.Config,
+) module.Module {
+	// Chunks are assembled locally before they are sent to the storage provider
+	service := NewUploadService(db, emitter, activeStorage, logger, cfg.TUSUploadDir)
+	service.Expiration = cfg.TUSUploadExpiration
+	if cfg.TUSMaxSize > 0 {
+		service.MaxSize = cfg.TUSMaxSize
+	}
+	controller := NewUploadController(service, logger)
+
+	uploadModule := &UploadModule{
+		DB:         db,
+		Controller: controller,
+		Service:    service,
+		Logger:     logger,
+	}
+
+	return uploadModule
+}
+
+// Init starts removing expired uploads. Core modules are initialized in no particular
+// order, so the scheduler may not be registered yet and the sweep runs on its own ticker.
+func (m *UploadModule) Init() error {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := m.Service.ExpireUploads(context.Background()); err != nil {
+				m.Logger.Error("failed to expire uploads", logger.String("error", err.Error()))
+			}
+		}
+	}()
+	return nil
+}
+
+func (m *UploadModule) Routes(router *router.RouterGroup) {
+	m.Logger.Info("Registering upload module routes")
+	m.Controller.Routes(router)
+	m.Logger.Info("Upload module routes registered")
+}
+
+func (m *UploadModule) Migrate() error {
+	return m.DB.AutoMigrate(&Upload{})
+}
+
+func (m *UploadModule) GetModels() []any {
+	return []any{&Upload{}}
+}
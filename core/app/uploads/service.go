@@ -0,0 +1,394 @@
+package uploads
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"base/core/app/profile"
+	"base/core/emitter"
+	"base/core/logger"
+	"base/core/storage"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CreateUploadEvent    = "uploads.create"
+	CompleteUploadEvent  = "uploads.complete"
+	TerminateUploadEvent = "uploads.terminate"
+)
+
+const (
+	// DefaultExpiration is how long an unfinished upload is kept after its last chunk
+	DefaultExpiration = 24 * time.Hour
+	// DefaultMaxSize is the largest upload accepted, whatever the field allows
+	DefaultMaxSize int64 = 10 << 30 // 10GB
+)
+
+var (
+	ErrInvalidUpload  = errors.New("invalid upload")
+	ErrNotAllowed     = errors.New("not allowed to upload to this record")
+	ErrUploadTooLarge = errors.New("upload exceeds the maximum size")
+	ErrUploadExpired  = errors.New("upload has expired")
+	ErrOffsetMismatch = errors.New("upload offset does not match")
+	ErrUploadLocked   = errors.New("upload is being written by another request")
+	ErrNotAdmin       = errors.New("storage usage of others is only available to administrators")
+)
+
+type UploadService struct {
+	DB         *gorm.DB
+	Emitter    *emitter.Emitter
+	Storage    *storage.ActiveStorage
+	Logger     logger.Logger
+	Dir        string // Local directory where chunks are assembled
+	Expiration time.Duration
+	MaxSize    int64
+	locks      sync.Map
+}
+
+func NewUploadService(db *gorm.DB, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage, logger logger.Logger, dir string) *UploadService {
+	return &UploadService{
+		DB:         db,
+		Emitter:    emitter,
+		Storage:    activeStorage,
+		Logger:     logger,
+		Dir:        dir,
+		Expiration: DefaultExpiration,
+		MaxSize:    DefaultMaxSize,
+	}
+}
+
+// Create starts an upload of length bytes. The metadata must name the file and the
+// model, record and field to attach it to, and the field's attachment config must
+// accept the file and authorize the user.
+func (s *UploadService) Create(userId uint, length int64, metadata map[string]string) (*Upload, error) {
+	if length > s.MaxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	filename := filepath.Base(metadata["filename"])
+	modelType, field := metadata["model"], metadata["field"]
+	modelId, err := strconv.ParseUint(metadata["model_id"], 10, 32)
+	if filename == "." || filename == "/" || modelType == "" || field == "" || err != nil {
+		return nil, fmt.Errorf("%w: metadata must include filename, model, model_id and field", ErrInvalidUpload)
+	}
+
+	config, err := s.Storage.ValidateUpload(modelType, field, filename, length)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUpload, err.Error())
+	}
+	if config.Authorize == nil {
+		return nil, fmt.Errorf("%w: %s.%s does not accept resumable uploads", ErrInvalidUpload, modelType, field)
+	}
+	if err := config.Authorize(userId, uint(modelId)); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotAllowed, err.Error())
+	}
+	if err := s.Storage.CheckQuota(modelType, field, uint(modelId), userId, length); err != nil {
+		return nil, err
+	}
+
+	id, err := newUploadId()
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Upload{
+		Id:        id,
+		Length:    length,
+		Metadata:  metadata,
+		Filename:  filename,
+		ModelType: modelType,
+		ModelId:   uint(modelId),
+		Field:     field,
+		UserId:    userId,
+		ExpiresAt: time.Now().Add(s.Expiration),
+	}
+
+	if err := os.MkdirAll(s.Dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	file, err := os.Create(s.chunkPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	file.Close()
+
+	if err := s.DB.Create(item).Error; err != nil {
+		s.Logger.Error("failed to create upload", logger.String("error", err.Error()))
+		_ = os.Remove(s.chunkPath(id))
+		return nil, err
+	}
+
+	// Emit create event
+	s.Emitter.Emit(CreateUploadEvent, item)
+
+	// An empty file is complete as soon as it is created
+	if item.IsComplete() {
+		if err := s.finish(item); err != nil {
+			return item, err
+		}
+	}
+
+	return item, nil
+}
+
+// Get returns an upload of the user; other users' uploads are reported as not found
+func (s *UploadService) Get(id string, userId uint) (*Upload, error) {
+	item := &Upload{}
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userId).First(item).Error; err != nil {
+		return nil, err
+	}
+	if item.AttachmentId != nil {
+		// storage.Attachment implements driver.Valuer, so gorm cannot load it as a relation
+		attachment := &storage.Attachment{}
+		if err := s.DB.First(attachment, *item.AttachmentId).Error; err == nil {
+			item.Attachment = attachment
+		}
+	}
+	if item.CompletedAt == nil && time.Now().After(item.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	return item, nil
+}
+
+// Write appends the body to the upload at the given offset. Bytes received before a
+// dropped connection are kept, so the client can resume from the new offset. Once
+// the last byte arrives the file is attached to its record.
+func (s *UploadService) Write(id string, userId uint, offset int64, body io.Reader) (*Upload, error) {
+	lock, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, ErrUploadLocked
+	}
+	defer mu.Unlock()
+
+	item, err := s.Get(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	if offset != item.Offset {
+		return item, ErrOffsetMismatch
+	}
+	if item.CompletedAt != nil {
+		return item, nil
+	}
+
+	// A previous attempt may have failed while attaching; retry it without new bytes
+	if item.IsComplete() {
+		return item, s.finish(item)
+	}
+
+	file, err := os.OpenFile(s.chunkPath(id), os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	// Drop bytes written after the last recorded offset, e.g. before a crash
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate upload file: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek upload file: %w", err)
+	}
+
+	written, copyErr := io.Copy(file, io.LimitReader(body, item.Length-item.Offset))
+	if err := file.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	item.Offset += written
+	item.ExpiresAt = time.Now().Add(s.Expiration)
+	if err := s.DB.Model(item).Updates(map[string]any{"offset": item.Offset, "expires_at": item.ExpiresAt}).Error; err != nil {
+		s.Logger.Error("failed to update upload offset",
+			logger.String("error", err.Error()),
+			logger.String("id", id))
+		return nil, err
+	}
+	if copyErr != nil {
+		return item, copyErr
+	}
+
+	if item.IsComplete() {
+		if err := s.finish(item); err != nil {
+			return item, err
+		}
+	}
+
+	return item, nil
+}
+
+// Terminate cancels an upload and removes its received bytes
+func (s *UploadService) Terminate(id string, userId uint) error {
+	item := &Upload{}
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userId).First(item).Error; err != nil {
+		return err
+	}
+
+	if err := s.remove(item); err != nil {
+		return err
+	}
+
+	// Emit terminate event
+	s.Emitter.Emit(TerminateUploadEvent, item)
+
+	return nil
+}
+
+// ExpireUploads removes unfinished uploads past their expiration and the records of
+// finished ones. It matches the scheduler task handler signature.
+func (s *UploadService) ExpireUploads(ctx context.Context) error {
+	var items []*Upload
+	if err := s.DB.WithContext(ctx).Where("expires_at < ?", time.Now()).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := s.remove(item); err != nil {
+			s.Logger.Error("failed to expire upload",
+				logger.String("error", err.Error()),
+				logger.String("id", item.Id))
+		}
+	}
+
+	if len(items) > 0 {
+		s.Logger.Info("Expired uploads removed", logger.Int("count", len(items)))
+	}
+	return nil
+}
+
+// finish streams the assembled file to the storage provider and attaches it
+func (s *UploadService) finish(item *Upload) error {
+	file, err := os.Open(s.chunkPath(item.Id))
+	if err != nil {
+		return fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	attachment, err := s.Storage.AttachReader(target{modelName: item.ModelType, id: item.ModelId}, item.Field, item.Filename, file, item.Length, item.UserId)
+	_, overQuota := storage.QuotaStatus(err)
+	if errors.Is(err, storage.ErrFileRejected) || overQuota {
+		// Retrying cannot change the content, so the upload is discarded
+		file.Close()
+		if removeErr := s.remove(item); removeErr != nil {
+			s.Logger.Error("failed to remove rejected upload",
+				logger.String("error", removeErr.Error()),
+				logger.String("id", item.Id))
+		}
+		if overQuota {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidUpload, err.Error())
+	}
+	if attachment == nil && err != nil {
+		s.Logger.Error("failed to attach upload",
+			logger.String("error", err.Error()),
+			logger.String("id", item.Id))
+		return err
+	}
+	if err != nil {
+		// The file is stored; only the follow-up hook failed
+		s.Logger.Error("failed to run attachment hook",
+			logger.String("error", err.Error()),
+			logger.String("id", item.Id))
+	}
+
+	now := time.Now()
+	item.AttachmentId = &attachment.Id
+	item.Attachment = attachment
+	item.CompletedAt = &now
+	if err := s.DB.Model(item).Updates(map[string]any{"attachment_id": attachment.Id, "completed_at": now}).Error; err != nil {
+		return err
+	}
+
+	file.Close()
+	_ = os.Remove(s.chunkPath(item.Id))
+
+	// Emit complete event
+	s.Emitter.Emit(CompleteUploadEvent, item)
+
+	return nil
+}
+
+// remove deletes an upload record and its staged bytes
+func (s *UploadService) remove(item *Upload) error {
+	if err := os.Remove(s.chunkPath(item.Id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.locks.Delete(item.Id)
+	return s.DB.Delete(item).Error
+}
+
+func (s *UploadService) chunkPath(id string) string {
+	return filepath.Join(s.Dir, id)
+}
+
+// newUploadId returns a random 128-bit hex id
+func newUploadId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Usage returns the storage taken by the files a user uploaded
+func (s *UploadService) Usage(userId uint) (*storage.Usage, error) {
+	return s.Storage.UserUsage(userId)
+}
+
+// TopConsumers lists the users, or the owners of a type such as "course", taking the
+// most storage. Only administrators may see it.
+func (s *UploadService) TopConsumers(userId uint, subjectType string, limit int) ([]*StorageConsumer, error) {
+	user := &profile.User{}
+	if err := s.DB.First(user, userId).Error; err != nil {
+		return nil, err
+	}
+	if !user.IsAdmin() {
+		return nil, ErrNotAdmin
+	}
+	if subjectType != storage.QuotaUser && !slices.Contains(s.Storage.OwnerTypes(), subjectType) {
+		return nil, fmt.Errorf("%w: unknown usage type %s", ErrInvalidUpload, subjectType)
+	}
+
+	usages, err := s.Storage.TopConsumers(subjectType, limit)
+	if err != nil {
+		return nil, err
+	}
+	consumers := make([]*StorageConsumer, len(usages))
+	ids := make([]uint, len(usages))
+	for i, usage := range usages {
+		consumers[i] = &StorageConsumer{Usage: usage}
+		ids[i] = usage.SubjectId
+	}
+
+	// Name the users so the list can be read without looking them up
+	if subjectType == storage.QuotaUser && len(ids) > 0 {
+		var users []*profile.User
+		if err := s.DB.Select("id", "first_name", "last_name", "email").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		byId := make(map[uint]*profile.User, len(users))
+		for _, u := range users {
+			byId[u.Id] = u
+		}
+		for _, consumer := range consumers {
+			if u, ok := byId[consumer.SubjectId]; ok {
+				consumer.Name = strings.TrimSpace(u.FirstName + " " + u.LastName)
+				consumer.Email = u.Email
+			}
+		}
+	}
+	return consumers, nil
+}
@@ -0,0 +1,144 @@
+package verification
+
+import (
+	"errors"
+	"net/http"
+
+	"base/core/logger"
+	"base/core/router"
+	"base/core/types"
+)
+
+type VerificationController struct {
+	service *VerificationService
+	logger  logger.Logger
+}
+
+func NewVerificationController(service *VerificationService, logger logger.Logger) *VerificationController {
+	return &VerificationController{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (c *VerificationController) Routes(router *router.RouterGroup) {
+	router.GET("/verify", c.VerifyLink)
+	router.POST("/verify", c.Verify)
+	router.POST("/resend", c.Resend)
+	router.GET("/status", c.Status)
+}
+
+// @Summary Verify Email From Link
+// @Description Verify an email address with the token of the link emailed on registration or email change
+// @Security ApiKeyAuth
+// @Tags Core/Verification
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /verification/verify [get]
+func (c *VerificationController) VerifyLink(ctx *router.Context) error {
+	return c.verify(ctx, ctx.Query("token"))
+}
+
+// @Summary Verify Email
+// @Description Verify an email address with the token of a verification link, for pages set as EMAIL_VERIFICATION_URL
+// @Security ApiKeyAuth
+// @Tags Core/Verification
+// @Accept json
+// @Produce json
+// @Param body body VerifyRequest true "Verification token"
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 409 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /verification/verify [post]
+func (c *VerificationController) Verify(ctx *router.Context) error {
+	var req VerifyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+	}
+	return c.verify(ctx, req.Token)
+}
+
+func (c *VerificationController) verify(ctx *router.Context, token string) error {
+	if token == "" {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: ErrInvalidToken.Error()})
+	}
+
+	if _, err := c.service.Verify(token); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrEmailTaken):
+			return ctx.JSON(http.StatusConflict, types.ErrorResponse{Error: err.Error()})
+		default:
+			c.logger.Error("Failed to verify email",
+				logger.String("error", err.Error()))
+			return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to verify email"})
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Email address verified"})
+}
+
+// @Summary Resend Verification Email
+// @Description Email a new verification link for the pending or unverified email of the current user, at most once a minute
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Verification
+// @Produce json
+// @Success 200 {object} types.SuccessResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 429 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /verification/resend [post]
+func (c *VerificationController) Resend(ctx *router.Context) error {
+	id := ctx.GetUint("user_id")
+	if id == 0 {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user Id"})
+	}
+
+	if err := c.service.Send(id); err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyVerified):
+			return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: err.Error()})
+		case errors.Is(err, ErrResendThrottled):
+			return ctx.JSON(http.StatusTooManyRequests, types.ErrorResponse{Error: err.Error()})
+		default:
+			c.logger.Error("Failed to resend verification email",
+				logger.Uint("user_id", id),
+				logger.String("error", err.Error()))
+			return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to send verification email"})
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, types.SuccessResponse{Message: "Verification email sent"})
+}
+
+// @Summary Email Verification Status
+// @Description Get whether the current user's email is verified, any pending email change, and which actions need a verified email
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Tags Core/Verification
+// @Produce json
+// @Success 200 {object} StatusResponse
+// @Failure 400 {object} types.ErrorResponse
+// @Failure 500 {object} types.ErrorResponse
+// @Router /verification/status [get]
+func (c *VerificationController) Status(ctx *router.Context) error {
+	id := ctx.GetUint("user_id")
+	if id == 0 {
+		return ctx.JSON(http.StatusBadRequest, types.ErrorResponse{Error: "Invalid user Id"})
+	}
+
+	status, err := c.service.Status(id)
+	if err != nil {
+		c.logger.Error("Failed to get verification status",
+			logger.Uint("user_id", id))
+		return ctx.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to get verification status"})
+	}
+	return ctx.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,31 @@
+package verification
+
+import (
+	"errors"
+	"net/http"
+
+	"base/core/router"
+	"base/core/types"
+)
+
+// Require returns a middleware rejecting users who have not verified their email
+// when the verification policy of service covers action, e.g. on the routes that
+// enroll or purchase. Requests without a user, such as API key calls, pass through.
+func Require(service *VerificationService, action string) router.MiddlewareFunc {
+	return func(next router.HandlerFunc) router.HandlerFunc {
+		return func(c *router.Context) error {
+			userId := c.GetUint("user_id")
+			if service == nil || userId == 0 {
+				return next(c)
+			}
+
+			if err := service.Check(userId, action); err != nil {
+				if errors.Is(err, ErrEmailNotVerified) {
+					return c.JSON(http.StatusForbidden, types.ErrorResponse{Error: "Verify your email address to " + action})
+				}
+				return c.JSON(http.StatusInternalServerError, types.ErrorResponse{Error: "Failed to check email verification"})
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package verification
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User is the verification state of a user. The columns belong to profile.User,
+// which this package cannot import; the users module migrates them.
+type User struct {
+	Id                 uint `gorm:"primaryKey"`
+	FirstName          string
+	Email              string
+	VerifiedAt         *time.Time
+	PendingEmail       string
+	VerificationSentAt *time.Time
+	DeletedAt          gorm.DeletedAt
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+// Actions the verification policy can restrict to verified users
+const (
+	ActionLogin    = "login"
+	ActionEnroll   = "enroll"
+	ActionPurchase = "purchase"
+)
+
+// VerifyRequest carries the token of a verification link
+type VerifyRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// StatusResponse describes the verification state of the current user
+type StatusResponse struct {
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	VerifiedAt    *time.Time `json:"verified_at,omitempty"`
+	PendingEmail  string     `json:"pending_email,omitempty"`
+	RequiredFor   []string   `json:"required_for"` // Actions that need a verified email
+}
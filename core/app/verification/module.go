@@ -0,0 +1,35 @@
+package verification
+
+import (
+	"base/core/config"
+	"base/core/email"
+	"base/core/logger"
+	"base/core/module"
+	"base/core/router"
+
+	"gorm.io/gorm"
+)
+
+type VerificationModule struct {
+	module.DefaultModule
+	DB         *gorm.DB
+	Controller *VerificationController
+	Service    *VerificationService
+	Logger     logger.Logger
+}
+
+func NewVerificationModule(db *gorm.DB, router *router.RouterGroup, emailSender email.Sender, logger logger.Logger, cfg *config.Config) *VerificationModule {
+	service := NewVerificationService(db, emailSender, logger, cfg)
+	controller := NewVerificationController(service, logger)
+
+	return &VerificationModule{
+		DB:         db,
+		Controller: controller,
+		Service:    service,
+		Logger:     logger,
+	}
+}
+
+func (m *VerificationModule) Routes(router *router.RouterGroup) {
+	m.Controller.Routes(router.Group("/verification"))
+}
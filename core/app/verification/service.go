@@ -0,0 +1,299 @@
+package verification
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"base/core/config"
+	"base/core/email"
+	"base/core/logger"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidToken     = errors.New("invalid or expired verification link")
+	ErrAlreadyVerified  = errors.New("email address already verified")
+	ErrResendThrottled  = errors.New("a verification email was sent recently; try again in a minute")
+	ErrEmailTaken       = errors.New("email address already in use")
+	ErrEmailNotVerified = errors.New("email address not verified")
+	ErrUserNotFound     = errors.New("user not found")
+)
+
+// resendInterval is the minimum time between two verification emails to a user
+const resendInterval = time.Minute
+
+// VerificationService emails signed verification links and enforces the policy
+// on what unverified users may do
+type VerificationService struct {
+	db          *gorm.DB
+	emailSender email.Sender
+	logger      logger.Logger
+	secret      []byte
+	link        string
+	ttl         time.Duration
+	requiredFor []string
+}
+
+// NewVerificationService creates a verification service; links are signed with
+// the JWT secret and point to cfg.EmailVerificationURL, or to the verify endpoint
+func NewVerificationService(db *gorm.DB, emailSender email.Sender, logger logger.Logger, cfg *config.Config) *VerificationService {
+	link := cfg.EmailVerificationURL
+	if link == "" {
+		link = strings.TrimSuffix(cfg.BaseURL, "/") + "/api/verification/verify"
+	}
+	return &VerificationService{
+		db:          db,
+		emailSender: emailSender,
+		logger:      logger,
+		secret:      []byte(cfg.JWTSecret),
+		link:        link,
+		ttl:         cfg.EmailVerificationTTL,
+		requiredFor: cfg.EmailVerificationRequiredFor,
+	}
+}
+
+// Send emails a verification link for the pending email of a user, or else for
+// their unverified email. It is throttled to one email per resendInterval.
+func (s *VerificationService) Send(userId uint) error {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+	address := user.PendingEmail
+	if address == "" {
+		if user.VerifiedAt != nil {
+			return ErrAlreadyVerified
+		}
+		address = user.Email
+	}
+
+	// Claim the send first, so concurrent requests cannot both send
+	now := time.Now()
+	result := s.db.Model(&User{}).
+		Where("id = ? AND (verification_sent_at IS NULL OR verification_sent_at < ?)", user.Id, now.Add(-resendInterval)).
+		UpdateColumn("verification_sent_at", now)
+	if result.Error != nil {
+		return fmt.Errorf("database error: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrResendThrottled
+	}
+
+	token, err := s.sign(user.Id, address, now.Add(s.ttl))
+	if err != nil {
+		return err
+	}
+	return s.sendEmail(user, address, token)
+}
+
+// RequestEmailChange records a new email for a user and emails it a verification
+// link; the user keeps their current email until the new one is verified. When the
+// email cannot be sent the previous pending email is restored.
+func (s *VerificationService) RequestEmailChange(userId uint, address string) error {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := s.db.Model(&User{}).Where("email = ? AND id <> ?", address, userId).Count(&count).Error; err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if count > 0 {
+		return ErrEmailTaken
+	}
+
+	// A new address is sent its link right away, whatever was sent before
+	updates := map[string]any{"pending_email": address, "verification_sent_at": nil}
+	if err := s.db.Model(&User{}).Where("id = ?", userId).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to save pending email: %w", err)
+	}
+	if err := s.Send(userId); err != nil {
+		restore := map[string]any{"pending_email": user.PendingEmail, "verification_sent_at": user.VerificationSentAt}
+		if err := s.db.Model(&User{}).Where("id = ?", userId).Updates(restore).Error; err != nil {
+			return fmt.Errorf("failed to restore pending email: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Verify checks the token of a verification link. A link for the current email
+// marks it verified; a link for the pending email makes it the user's email.
+func (s *VerificationService) Verify(token string) (*User, error) {
+	claims, err := s.parse(token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.findUser(claims.UserId)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case user.PendingEmail != "" && claims.Email == user.PendingEmail:
+		var count int64
+		if err := s.db.Model(&User{}).Where("email = ? AND id <> ?", claims.Email, user.Id).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if count > 0 {
+			return nil, ErrEmailTaken
+		}
+		updates := map[string]any{"email": claims.Email, "pending_email": "", "verified_at": now}
+		if err := s.db.Model(user).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to change email: %w", err)
+		}
+		user.Email, user.PendingEmail = claims.Email, ""
+	case claims.Email == user.Email:
+		if user.VerifiedAt != nil {
+			return user, nil
+		}
+		if err := s.db.Model(user).Update("verified_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to verify email: %w", err)
+		}
+	default:
+		// The link is for an address the user no longer has or wants
+		return nil, ErrInvalidToken
+	}
+
+	user.VerifiedAt = &now
+	return user, nil
+}
+
+// Status returns the verification state of a user
+func (s *VerificationService) Status(userId uint) (*StatusResponse, error) {
+	user, err := s.findUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	return &StatusResponse{
+		Email:         user.Email,
+		EmailVerified: user.VerifiedAt != nil,
+		VerifiedAt:    user.VerifiedAt,
+		PendingEmail:  user.PendingEmail,
+		RequiredFor:   s.requiredFor,
+	}, nil
+}
+
+// Check returns ErrEmailNotVerified when the policy requires a verified email for
+// action and the user has not verified theirs
+func (s *VerificationService) Check(userId uint, action string) error {
+	if !s.Requires(action) {
+		return nil
+	}
+	user, err := s.findUser(userId)
+	if err != nil {
+		return err
+	}
+	if user.VerifiedAt == nil {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// Requires reports whether the policy requires a verified email for action
+func (s *VerificationService) Requires(action string) bool {
+	for _, required := range s.requiredFor {
+		if required == action {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenClaims is the signed payload of a verification link
+type tokenClaims struct {
+	UserId    uint   `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// sign returns a token binding a user to the address it verifies, as
+// base64url(payload) "." base64url(HMAC-SHA256)
+func (s *VerificationService) sign(userId uint, address string, expiresAt time.Time) (string, error) {
+	payload, err := json.Marshal(tokenClaims{UserId: userId, Email: address, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded)), nil
+}
+
+// parse checks the signature and expiry of a token and returns its claims
+func (s *VerificationService) parse(token string) (*tokenClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.mac(encoded)) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// mac signs a payload; the label keeps the MAC from matching other uses of the secret
+func (s *VerificationService) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte("email-verification." + payload))
+	return mac.Sum(nil)
+}
+
+// findUser loads the verification state of a user
+func (s *VerificationService) findUser(userId uint) (*User, error) {
+	var user User
+	if err := s.db.First(&user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *VerificationService) sendEmail(user *User, address, token string) error {
+	link := s.link
+	if strings.Contains(link, "?") {
+		link += "&token=" + url.QueryEscape(token)
+	} else {
+		link += "?token=" + url.QueryEscape(token)
+	}
+
+	body := "<h1>Verify your email address</h1>" +
+		"<p>Hi " + html.EscapeString(user.FirstName) + ",</p>" +
+		"<p>Please confirm that " + html.EscapeString(address) + " is your email address:</p>" +
+		"<p><a href=\"" + html.EscapeString(link) + "\">Verify email address</a></p>" +
+		fmt.Sprintf("<p>This link expires in %d hours. If you didn't request it, you can ignore this email.</p>", int(s.ttl.Hours())) +
+		"<p>Best regards,<br>Team</p>"
+
+	return s.emailSender.Send(email.Message{
+		To:      []string{address},
+		From:    "no-reply@base.al",
+		Subject: "Verify your email address",
+		Body:    body,
+		IsHTML:  true,
+	})
+}
@@ -31,6 +31,28 @@ const (
 	DefaultJWTSecret = "secret"
 	DefaultAPIKey    = "test_api_key"
 
+	// Session defaults
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+	DefaultTwoFactorIssuer = "Base"
+
+	// Login and password policy defaults
+	DefaultEmailVerificationTTL = 48 * time.Hour
+	DefaultLoginMaxFailures     = 5
+	DefaultLoginLockoutDuration = 15 * time.Minute
+	DefaultLoginFailureWindow   = 15 * time.Minute
+	DefaultLoginIPMaxFailures   = 50
+	DefaultLoginPoWAfter        = 3
+	DefaultLoginIPPoWAfter      = 10
+	DefaultLoginPoWDifficulty   = 20
+	DefaultPasswordMinLength    = 8
+	DefaultPasswordMaxLength    = 72 // bcrypt ignores anything longer
+	DefaultPasswordHistory      = 5
+
+	// OAuth server defaults
+	DefaultOAuthServerAccessTTL  = time.Hour
+	DefaultOAuthServerRefreshTTL = 90 * 24 * time.Hour
+
 	// Email defaults
 	DefaultEmailProvider    = "default"
 	DefaultEmailFromAddress = "no-reply@localhost"
@@ -39,11 +61,30 @@ const (
 	// Storage defaults
 	DefaultStorageProvider   = "local"
 	DefaultStoragePath       = "storage/uploads"
-	DefaultStorageMaxSize    = 10485760 // 10MB
+	DefaultStoragePrivate    = "private_storage" // Outside ./storage, which is served statically
+	DefaultStorageMaxSize    = 10485760          // 10MB
 	DefaultStorageRegion     = "eu-central-1"
 	DefaultStorageBucket     = "default"
 	DefaultStorageExtensions = ".jpg,.jpeg,.png,.gif,.pdf,.doc,.docx"
 
+	// Waitlist defaults
+	DefaultCourseRunClaimWindow = 48 * time.Hour
+
+	// Attendance defaults
+	DefaultAttendanceCodeTTL   = 5 * time.Minute
+	DefaultAttendanceLateAfter = 10 * time.Minute
+
+	// Lesson playback defaults
+	DefaultPlaybackCompletionThreshold = 90.0
+
+	// Resumable upload defaults
+	DefaultTUSUploadDir        = "private_storage/tus"
+	DefaultTUSUploadExpiration = 24 * time.Hour
+	DefaultTUSMaxSize          = 10 << 30 // 10GB
+
+	// Course resource defaults
+	DefaultCourseResourceDownloadTTL = 15 * time.Minute
+
 	// Feature toggles defaults
 	DefaultWebSocketEnabled = true
 	DefaultSwaggerEnabled   = true
@@ -88,8 +129,80 @@ type Config struct {
 	StorageRegion        string   `json:"storage_region"`
 	StorageBucket        string   `json:"storage_bucket"`
 	StoragePublicURL     string   `json:"storage_public_url"`
+	StoragePrivatePath   string   `json:"storage_private_path"`
+	StorageSigningKey    string   `json:"-"`
 	StorageMaxSize       int64    `json:"storage_max_size"`
 	StorageAllowedExt    []string `json:"storage_allowed_ext"`
+	StorageQuotaUser     int64    `json:"storage_quota_user"`   // Bytes per uploading user, 0 for no limit
+	StorageQuotaCourse   int64    `json:"storage_quota_course"` // Bytes per course, 0 for no limit
+
+	// Google Cloud Storage
+	StorageCredentialsFile string `json:"storage_credentials_file"` // Service account key of the gcs provider
+	StorageUniformAccess   bool   `json:"storage_uniform_access"`   // Bucket policy, not object ACLs, makes files public
+
+	// Previous storage provider, read from while files are migrated off it
+	StorageFallbackProvider        string `json:"storage_fallback_provider"`
+	StorageFallbackPath            string `json:"storage_fallback_path"`
+	StorageFallbackBaseURL         string `json:"storage_fallback_base_url"`
+	StorageFallbackAPIKey          string `json:"-"`
+	StorageFallbackAPISecret       string `json:"-"`
+	StorageFallbackAccountID       string `json:"storage_fallback_account_id"`
+	StorageFallbackEndpoint        string `json:"storage_fallback_endpoint"`
+	StorageFallbackRegion          string `json:"storage_fallback_region"`
+	StorageFallbackBucket          string `json:"storage_fallback_bucket"`
+	StorageFallbackCredentialsFile string `json:"storage_fallback_credentials_file"`
+
+	// Sessions and two-factor authentication
+	AccessTokenTTL  time.Duration // Lifetime of access tokens
+	RefreshTokenTTL time.Duration // Lifetime of a session without refreshing
+	TwoFactorIssuer string        // Account issuer shown in authenticator apps
+
+	// Email verification
+	EmailVerificationURL         string        // Page verification links open, with ?token= appended
+	EmailVerificationTTL         time.Duration // Lifetime of verification links
+	EmailVerificationRequiredFor []string      // Actions unverified users cannot take: login, enroll, purchase
+
+	// Login brute-force protection
+	LoginMaxFailures     int           // Wrong passwords that lock an account
+	LoginLockoutDuration time.Duration // First lockout of an account; each further one doubles it
+	LoginFailureWindow   time.Duration // Period failed logins from an IP are counted over
+	LoginIPMaxFailures   int           // Failed logins from an IP in the window before it is slowed down
+	LoginPoWAfter        int           // Wrong passwords for an account before logins need a proof of work
+	LoginIPPoWAfter      int           // Failed logins from an IP in the window before logins need a proof of work
+	LoginPoWDifficulty   int           // Leading zero bits a proof of work must have
+	LoginUnlockURL       string        // Page unlock links open, with ?token= appended
+
+	// Password policy
+	PasswordMinLength       int      // Characters a password needs at least
+	PasswordMaxLength       int      // Bytes a password may have at most
+	PasswordRequiredClasses []string // Character classes a password needs: lower, upper, digit, symbol
+	PasswordRejectPersonal  bool     // Reject passwords containing the user's name, username or email
+	PasswordRejectBreached  bool     // Reject passwords found in the breached password list
+	PasswordBreachedPath    string   // Directory of SHA-1 range files extending the bundled breached list
+	PasswordHistory         int      // Previous passwords a new one may not repeat
+
+	// Tokens issued to OAuth clients
+	OAuthServerAccessTTL  time.Duration // Lifetime of access tokens issued to OAuth clients
+	OAuthServerRefreshTTL time.Duration // Lifetime of refresh tokens issued to OAuth clients
+
+	// Waitlist
+	CourseRunClaimWindow time.Duration // Time to claim a seat offered from the waitlist
+
+	// Attendance check-ins
+	AttendanceCodeTTL   time.Duration // Lifetime of check-in codes
+	AttendanceLateAfter time.Duration // Grace period after the session start before check-ins count as late
+
+	// Lesson playback
+	PlaybackCompletionThreshold float64 // Watched percentage that marks a lesson complete
+
+	// Resumable (tus) uploads
+	TUSUploadDir        string        // Directory chunks are assembled in before they are sent to storage
+	TUSUploadExpiration time.Duration // How long an unfinished upload is kept after its last chunk
+	TUSMaxSize          int64         // Largest upload accepted, in bytes
+
+	// Course resources
+	CourseResourceDownloadTTL time.Duration // How long signed download URLs stay valid
+
 	WebSocketEnabled     bool     `json:"websocket_enabled"`
 	SwaggerEnabled       bool     `json:"swagger_enabled"`
 	
@@ -292,6 +405,20 @@ func NewConfig() *Config {
 		ApiKey:    getEnvWithLog("API_KEY", DefaultAPIKey),
 		JWTSecret: getEnvWithLog("JWT_SECRET", DefaultJWTSecret),
 
+		// Two-factor authentication
+		TwoFactorIssuer: getEnvWithLog("TWO_FACTOR_ISSUER", DefaultTwoFactorIssuer),
+
+		// Email verification
+		EmailVerificationURL:         getEnvWithLog("EMAIL_VERIFICATION_URL", ""),
+		EmailVerificationRequiredFor: parsePathList("EMAIL_VERIFICATION_REQUIRED_FOR", "enroll,purchase"),
+
+		// Login brute-force protection
+		LoginUnlockURL: getEnvWithLog("LOGIN_UNLOCK_URL", ""),
+
+		// Password policy
+		PasswordRequiredClasses: parsePathList("PASSWORD_REQUIRED_CLASSES", "lower,upper,digit"),
+		PasswordBreachedPath:    getEnvWithLog("PASSWORD_BREACHED_PATH", ""),
+
 		// Email settings
 		EmailProvider:        getEnvWithLog("EMAIL_PROVIDER", DefaultEmailProvider),
 		EmailFromAddress:     getEnvWithLog("EMAIL_FROM_ADDRESS", DefaultEmailFromAddress),
@@ -313,13 +440,37 @@ func NewConfig() *Config {
 		StorageRegion:    getEnvWithLog("STORAGE_REGION", DefaultStorageRegion),
 		StorageBucket:    getEnvWithLog("STORAGE_BUCKET", DefaultStorageBucket),
 		StoragePublicURL: getEnvWithLog("STORAGE_PUBLIC_URL", ""),
+
+		// Private files, served through signed URLs
+		StoragePrivatePath: getEnvWithLog("STORAGE_PRIVATE_PATH", DefaultStoragePrivate),
+		StorageSigningKey:  os.Getenv("STORAGE_SIGNING_KEY"),
+
+		// Service account key of the gcs provider
+		StorageCredentialsFile: getEnvWithLog("STORAGE_CREDENTIALS_FILE", ""),
+
+		// Previous storage provider during a migration
+		StorageFallbackProvider:        getEnvWithLog("STORAGE_FALLBACK_PROVIDER", ""),
+		StorageFallbackPath:            getEnvWithLog("STORAGE_FALLBACK_PATH", DefaultStoragePath),
+		StorageFallbackBaseURL:         getEnvWithLog("STORAGE_FALLBACK_BASE_URL", ""),
+		StorageFallbackAPIKey:          os.Getenv("STORAGE_FALLBACK_API_KEY"),
+		StorageFallbackAPISecret:       os.Getenv("STORAGE_FALLBACK_API_SECRET"),
+		StorageFallbackAccountID:       getEnvWithLog("STORAGE_FALLBACK_ACCOUNT_ID", ""),
+		StorageFallbackEndpoint:        getEnvWithLog("STORAGE_FALLBACK_ENDPOINT", ""),
+		StorageFallbackRegion:          getEnvWithLog("STORAGE_FALLBACK_REGION", DefaultStorageRegion),
+		StorageFallbackBucket:          getEnvWithLog("STORAGE_FALLBACK_BUCKET", ""),
+		StorageFallbackCredentialsFile: getEnvWithLog("STORAGE_FALLBACK_CREDENTIALS_FILE", ""),
+
+		// Resumable uploads
+		TUSUploadDir: getEnvWithLog("TUS_UPLOAD_DIR", DefaultTUSUploadDir),
 	}
 
 	// Parse complex values with proper error handling
 	parseCORSOrigins(config)
 	parseStorageExtensions(config)
 	parseIntegerValues(config)
+	parseFloatValues(config)
 	parseBooleanValues(config)
+	parseDurationValues(config)
 	parseMiddlewareConfig(config)
 
 	return config
@@ -358,6 +509,36 @@ func parseIntegerValues(config *Config) {
 
 	// Storage Max Size
 	config.StorageMaxSize = parseInt64WithDefault("STORAGE_MAX_SIZE", DefaultStorageMaxSize)
+
+	// Storage quotas
+	config.StorageQuotaUser = parseInt64WithDefault("STORAGE_QUOTA_USER", 0)
+	config.StorageQuotaCourse = parseInt64WithDefault("STORAGE_QUOTA_COURSE", 0)
+
+	// Resumable upload size
+	config.TUSMaxSize = parseInt64WithDefault("TUS_MAX_SIZE", DefaultTUSMaxSize)
+
+	// Login brute-force protection
+	config.LoginMaxFailures = parseIntWithDefault("LOGIN_MAX_FAILURES", DefaultLoginMaxFailures)
+	config.LoginIPMaxFailures = parseIntWithDefault("LOGIN_IP_MAX_FAILURES", DefaultLoginIPMaxFailures)
+	config.LoginPoWAfter = parseIntWithDefault("LOGIN_POW_AFTER", DefaultLoginPoWAfter)
+	config.LoginIPPoWAfter = parseIntWithDefault("LOGIN_IP_POW_AFTER", DefaultLoginIPPoWAfter)
+	config.LoginPoWDifficulty = parseIntWithDefault("LOGIN_POW_DIFFICULTY", DefaultLoginPoWDifficulty)
+
+	// Password policy
+	config.PasswordMinLength = parseIntWithDefault("PASSWORD_MIN_LENGTH", DefaultPasswordMinLength)
+	config.PasswordMaxLength = parseIntWithDefault("PASSWORD_MAX_LENGTH", DefaultPasswordMaxLength)
+	config.PasswordHistory = parseIntWithDefault("PASSWORD_HISTORY", DefaultPasswordHistory)
+}
+
+// parseFloatValues parses all floating point configuration values
+func parseFloatValues(config *Config) {
+	// Watched percentage that completes a lesson, above 0 and at most 100
+	threshold := parseFloatWithDefault("PLAYBACK_COMPLETION_THRESHOLD", DefaultPlaybackCompletionThreshold)
+	if threshold <= 0 || threshold > 100 {
+		logConfigError("Invalid PLAYBACK_COMPLETION_THRESHOLD value: %g. Using default: %g", threshold, DefaultPlaybackCompletionThreshold)
+		threshold = DefaultPlaybackCompletionThreshold
+	}
+	config.PlaybackCompletionThreshold = threshold
 }
 
 // parseBooleanValues parses all boolean configuration values
@@ -367,6 +548,50 @@ func parseBooleanValues(config *Config) {
 
 	// Swagger enabled
 	config.SwaggerEnabled = parseBoolWithDefault("SWAGGER_ENABLED", DefaultSwaggerEnabled)
+
+	// Public files readable through the bucket policy instead of object ACLs
+	config.StorageUniformAccess = parseBoolWithDefault("STORAGE_UNIFORM_ACCESS", false)
+
+	// Password policy
+	config.PasswordRejectPersonal = parseBoolWithDefault("PASSWORD_REJECT_PERSONAL", true)
+	config.PasswordRejectBreached = parseBoolWithDefault("PASSWORD_REJECT_BREACHED", true)
+}
+
+// parseDurationValues parses all duration configuration values
+func parseDurationValues(config *Config) {
+	// Token lifetimes
+	config.AccessTokenTTL = parseDurationWithDefault("JWT_ACCESS_TTL", DefaultAccessTokenTTL)
+	config.RefreshTokenTTL = parseDurationWithDefault("JWT_REFRESH_TTL", DefaultRefreshTokenTTL)
+
+	// Email verification links
+	config.EmailVerificationTTL = parseDurationWithDefault("EMAIL_VERIFICATION_TTL", DefaultEmailVerificationTTL)
+
+	// Login lockouts
+	config.LoginLockoutDuration = parseDurationWithDefault("LOGIN_LOCKOUT_DURATION", DefaultLoginLockoutDuration)
+	config.LoginFailureWindow = parseDurationWithDefault("LOGIN_FAILURE_WINDOW", DefaultLoginFailureWindow)
+
+	// Tokens issued to OAuth clients
+	config.OAuthServerAccessTTL = parseDurationWithDefault("OAUTH_SERVER_ACCESS_TTL", DefaultOAuthServerAccessTTL)
+	config.OAuthServerRefreshTTL = parseDurationWithDefault("OAUTH_SERVER_REFRESH_TTL", DefaultOAuthServerRefreshTTL)
+
+	// Unfinished resumable uploads
+	config.TUSUploadExpiration = parseDurationWithDefault("TUS_UPLOAD_EXPIRATION", DefaultTUSUploadExpiration)
+
+	// Signed course resource downloads
+	config.CourseResourceDownloadTTL = parseDurationWithDefault("COURSE_RESOURCE_DOWNLOAD_TTL", DefaultCourseResourceDownloadTTL)
+
+	// Claim window for seats offered from the waitlist
+	config.CourseRunClaimWindow = parseDurationWithDefault("COURSE_RUN_CLAIM_WINDOW", DefaultCourseRunClaimWindow)
+
+	// Attendance check-ins; a grace period of 0 marks every check-in after the start late
+	config.AttendanceCodeTTL = parseDurationWithDefault("ATTENDANCE_CODE_TTL", DefaultAttendanceCodeTTL)
+	config.AttendanceLateAfter = DefaultAttendanceLateAfter
+	lateAfter := getEnvWithLog("ATTENDANCE_LATE_AFTER", DefaultAttendanceLateAfter.String())
+	if value, err := time.ParseDuration(lateAfter); err == nil && value >= 0 {
+		config.AttendanceLateAfter = value
+	} else {
+		logConfigError("Invalid ATTENDANCE_LATE_AFTER value: %s. Using default: %s", lateAfter, DefaultAttendanceLateAfter)
+	}
 }
 
 // parseMiddlewareConfig parses middleware configuration from environment variables
@@ -392,9 +617,9 @@ func parseMiddlewareConfig(config *Config) {
 	config.Middleware = MiddlewareConfig{
 		// Global middleware settings
 		APIKeyEnabled:     parseBoolWithDefault("MIDDLEWARE_API_KEY_ENABLED", true),
-		APIKeySkipPaths:   parsePathList("MIDDLEWARE_API_KEY_SKIP_PATHS", "/health,/,/docs,/swagger"),
+		APIKeySkipPaths:   parsePathList("MIDDLEWARE_API_KEY_SKIP_PATHS", "/health,/,/docs,/swagger,/api/files/*,/api/variants/*,/api/oauth2/token,/api/oauth2/introspect,/api/oauth2/revoke"),
 		AuthEnabled:       parseBoolWithDefault("MIDDLEWARE_AUTH_ENABLED", false),
-		AuthSkipPaths:     parsePathList("MIDDLEWARE_AUTH_SKIP_PATHS", "/api/auth/login,/api/auth/register,/api/auth/forgot-password"),
+		AuthSkipPaths:     parsePathList("MIDDLEWARE_AUTH_SKIP_PATHS", "/api/auth/login,/api/auth/register,/api/auth/forgot-password,/api/auth/refresh,/api/auth/otp/*,/api/auth/2fa/challenge/*,/api/auth/unlock,/api/verification/verify,/api/oauth/oidc/*,/api/oauth2/token,/api/oauth2/introspect,/api/oauth2/revoke,/api/calendar/*,/api/files/*,/api/variants/*"),
 		RateLimitEnabled:  parseBoolWithDefault("MIDDLEWARE_RATE_LIMIT_ENABLED", true),
 		RateLimitRequests: parseIntWithDefault("MIDDLEWARE_RATE_LIMIT_REQUESTS", 60),
 		RateLimitWindow:   getEnvWithLog("MIDDLEWARE_RATE_LIMIT_WINDOW", "1m"),
@@ -459,6 +684,17 @@ func parseInt64WithDefault(key string, defaultValue int64) int64 {
 	return value
 }
 
+// parseFloatWithDefault parses a float64 environment variable with default fallback
+func parseFloatWithDefault(key string, defaultValue float64) float64 {
+	valueStr := getEnvWithLog(key, strconv.FormatFloat(defaultValue, 'g', -1, 64))
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		logConfigError("Invalid %s value: %s. Using default: %g", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
 // parseBoolWithDefault parses a boolean environment variable with default fallback
 func parseBoolWithDefault(key string, defaultValue bool) bool {
 	valueStr := getEnvWithLog(key, fmt.Sprintf("%t", defaultValue))
@@ -470,6 +706,17 @@ func parseBoolWithDefault(key string, defaultValue bool) bool {
 	return value
 }
 
+// parseDurationWithDefault parses a duration environment variable with default fallback
+func parseDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnvWithLog(key, defaultValue.String())
+	value, err := time.ParseDuration(valueStr)
+	if err != nil || value <= 0 {
+		logConfigError("Invalid %s value: %s. Using default: %s", key, valueStr, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
 // normalizePort ensures port starts with ":"
 func normalizePort(port string) string {
 	if port != "" && port[0] != ':' {
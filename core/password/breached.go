@@ -0,0 +1,92 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// breachedList holds the uppercase SHA-1 hashes of common breached passwords, one
+// per line and sorted. Add a password with: printf '%s' "$pw" | sha1sum
+//
+//go:embed breached.txt
+var breachedList string
+
+// prefixLength is the length of the SHA-1 prefix a lookup is narrowed to, as in
+// the k-anonymity range files of Have I Been Pwned
+const prefixLength = 5
+
+var (
+	bundledOnce   sync.Once
+	bundledRanges map[string][]string
+)
+
+// bundled returns the bundled list grouped into sorted suffixes by SHA-1 prefix
+func bundled() map[string][]string {
+	bundledOnce.Do(func() {
+		bundledRanges = make(map[string][]string)
+		for _, line := range strings.Split(breachedList, "\n") {
+			hash := strings.ToUpper(strings.TrimSpace(line))
+			if len(hash) != sha1.Size*2 {
+				continue
+			}
+			prefix := hash[:prefixLength]
+			bundledRanges[prefix] = append(bundledRanges[prefix], hash[prefixLength:])
+		}
+		for _, suffixes := range bundledRanges {
+			sort.Strings(suffixes)
+		}
+	})
+	return bundledRanges
+}
+
+// breached reports whether a password is in the bundled list or in the range file
+// of its SHA-1 prefix under BreachedPath. Only the range of the prefix is read.
+func (p *Policy) breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	suffixes := bundled()[prefix]
+	if i := sort.SearchStrings(suffixes, suffix); i < len(suffixes) && suffixes[i] == suffix {
+		return true, nil
+	}
+
+	if p.BreachedPath == "" {
+		return false, nil
+	}
+	return inRangeFile(filepath.Join(p.BreachedPath, prefix+".txt"), suffix)
+}
+
+// inRangeFile looks for a suffix in a range file of SUFFIX:COUNT lines. A missing
+// file is an empty range.
+func inRangeFile(path, suffix string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open breached password range: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(candidate), suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached password range: %w", err)
+	}
+	return false, nil
+}
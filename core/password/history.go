@@ -0,0 +1,65 @@
+package password
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// History is a password a user had, stored as its bcrypt hash
+type History struct {
+	Id        uint   `gorm:"primaryKey"`
+	UserId    uint   `gorm:"index"`
+	Hash      string `gorm:"size:255"`
+	CreatedAt time.Time
+}
+
+func (History) TableName() string {
+	return "password_histories"
+}
+
+// CheckHistory returns ErrPasswordReused when a new password matches one of the
+// last History passwords of a user. The current one is also given by its hash, for
+// users whose password was set before the history was kept.
+func (p *Policy) CheckHistory(db *gorm.DB, userId uint, currentHash, password string) error {
+	if p.History <= 0 {
+		return nil
+	}
+	if currentHash != "" && bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(password)) == nil {
+		return ErrPasswordReused
+	}
+
+	var previous []History
+	if err := db.Where("user_id = ?", userId).Order("created_at DESC, id DESC").Limit(p.History).Find(&previous).Error; err != nil {
+		return fmt.Errorf("failed to load password history: %w", err)
+	}
+	for _, entry := range previous {
+		if bcrypt.CompareHashAndPassword([]byte(entry.Hash), []byte(password)) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// Remember adds the hash of a user's new password to their history and forgets
+// the passwords too old to matter
+func (p *Policy) Remember(db *gorm.DB, userId uint, hash string) error {
+	if p.History <= 0 {
+		return nil
+	}
+	if err := db.Create(&History{UserId: userId, Hash: hash}).Error; err != nil {
+		return fmt.Errorf("failed to save password history: %w", err)
+	}
+
+	var keep []uint
+	if err := db.Model(&History{}).Where("user_id = ?", userId).
+		Order("created_at DESC, id DESC").Limit(p.History).Pluck("id", &keep).Error; err != nil {
+		return fmt.Errorf("failed to load password history: %w", err)
+	}
+	if err := db.Where("user_id = ? AND id NOT IN ?", userId, keep).Delete(&History{}).Error; err != nil {
+		return fmt.Errorf("failed to prune password history: %w", err)
+	}
+	return nil
+}
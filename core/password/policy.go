@@ -0,0 +1,173 @@
+// Package password holds the policy passwords must follow: length, character
+// classes, no personal details, not breached, and not recently used.
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"base/core/config"
+)
+
+var (
+	ErrWeakPassword   = errors.New("password does not meet the password policy")
+	ErrPasswordReused = errors.New("password was used recently; choose a different one")
+)
+
+// Character classes a policy can require
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// minPersonalLength is the length below which personal details are too short to
+// be looked for in a password
+const minPersonalLength = 3
+
+// PolicyError lists the rules a password broke
+type PolicyError struct {
+	Problems []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password " + strings.Join(e.Problems, "; ")
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+// Policy is the set of rules new passwords must follow
+type Policy struct {
+	MinLength       int      // Characters
+	MaxLength       int      // Bytes, as bcrypt counts them
+	RequiredClasses []string // Any of ClassLower, ClassUpper, ClassDigit, ClassSymbol
+	RejectPersonal  bool
+	RejectBreached  bool
+	BreachedPath    string // Directory of SHA-1 range files checked besides the bundled list
+	History         int    // Previous passwords a new one may not repeat
+}
+
+// NewPolicy creates the password policy from the configuration
+func NewPolicy(cfg *config.Config) *Policy {
+	return &Policy{
+		MinLength:       cfg.PasswordMinLength,
+		MaxLength:       cfg.PasswordMaxLength,
+		RequiredClasses: cfg.PasswordRequiredClasses,
+		RejectPersonal:  cfg.PasswordRejectPersonal,
+		RejectBreached:  cfg.PasswordRejectBreached,
+		BreachedPath:    cfg.PasswordBreachedPath,
+		History:         cfg.PasswordHistory,
+	}
+}
+
+// Validate checks a new password against the policy. personal holds details of the
+// user, such as their email, username and names, the password must not contain.
+// A broken rule is returned as a *PolicyError, which wraps ErrWeakPassword.
+func (p *Policy) Validate(password string, personal ...string) error {
+	var problems []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+	for _, class := range p.RequiredClasses {
+		if !hasClass(password, class) {
+			problems = append(problems, "must contain "+describeClass(class))
+		}
+	}
+	if p.RejectPersonal && containsPersonal(password, personal) {
+		problems = append(problems, "must not contain your name, username or email")
+	}
+
+	// Only check the breached list for passwords that are otherwise fine
+	if len(problems) == 0 && p.RejectBreached {
+		breached, err := p.breached(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			problems = append(problems, "has appeared in a data breach and can't be used")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PolicyError{Problems: problems}
+	}
+	return nil
+}
+
+func hasClass(password, class string) bool {
+	for _, r := range password {
+		switch class {
+		case ClassLower:
+			if unicode.IsLower(r) {
+				return true
+			}
+		case ClassUpper:
+			if unicode.IsUpper(r) {
+				return true
+			}
+		case ClassDigit:
+			if unicode.IsDigit(r) {
+				return true
+			}
+		case ClassSymbol:
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return true
+			}
+		default:
+			// Unknown classes in the configuration are ignored
+			return true
+		}
+	}
+	return false
+}
+
+func describeClass(class string) string {
+	switch class {
+	case ClassLower:
+		return "a lowercase letter"
+	case ClassUpper:
+		return "an uppercase letter"
+	case ClassDigit:
+		return "a digit"
+	default:
+		return "a symbol"
+	}
+}
+
+// containsPersonal reports whether a password contains one of the personal
+// details, or an email's local part, ignoring case and anything but letters and digits
+func containsPersonal(password string, personal []string) bool {
+	normalized := normalize(password)
+	for _, value := range personal {
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			value = local
+		}
+		value = normalize(value)
+		if len(value) < minPersonalLength {
+			continue
+		}
+		if strings.Contains(normalized, value) || (len(normalized) >= minPersonalLength && strings.Contains(value, normalized)) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
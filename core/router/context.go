@@ -392,6 +392,13 @@ func (c *Context) GetUint(key string) uint {
 	}
 }
 
+// GetString returns a string value from context
+func (c *Context) GetString(key string) string {
+	value, _ := c.Get(key)
+	s, _ := value.(string)
+	return s
+}
+
 // GetHeader returns request header value (alias for Header for compatibility)
 func (c *Context) GetHeader(key string) string {
 	return c.Header(key)
@@ -2,16 +2,26 @@ package middleware
 
 import (
 	"base/core/router"
+	"base/core/types"
+	"crypto/subtle"
+	"errors"
 	"net/http"
 	"os"
 	"strings"
 )
 
+// Api checks the X-Api-Key header. The shared API_KEY, when set, is accepted as
+// is; any other key must be one issued to a user, whose id is then set as user_id
+// and whose scopes must cover the request as OAuth scopes do (see
+// types.RequestPermission). Third-party apps have no key: a valid OAuth access
+// token identifies them instead.
 func Api() router.MiddlewareFunc {
 	return func(next router.HandlerFunc) router.HandlerFunc {
 		return func(c *router.Context) error {
-			// Skip middleware for non-JSON requests
-			if !isJSONRequest(c) {
+			apiKey := c.GetHeader("X-Api-Key")
+
+			// Skip middleware for non-JSON requests, unless they carry a key
+			if !isJSONRequest(c) && apiKey == "" {
 				return next(c)
 			}
 
@@ -20,23 +30,62 @@ func Api() router.MiddlewareFunc {
 				return next(c)
 			}
 
-			apiKey := c.GetHeader("X-Api-Key")
-			expectedAPIKey := os.Getenv("API_KEY")
+			if apiKey == "" && isOAuthRequest(c) {
+				return next(c)
+			}
+
 			if apiKey == "" {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized: API key is required"})
 				return nil
 			}
 
-			if apiKey != expectedAPIKey {
+			expectedAPIKey := os.Getenv("API_KEY")
+			if expectedAPIKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(expectedAPIKey)) == 1 {
+				return next(c)
+			}
+
+			identity, err := types.ResolveAPIKey(apiKey)
+			if err != nil {
+				if !errors.Is(err, types.ErrInvalidAPIKey) {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"error": "Failed to check API key"})
+					return nil
+				}
 				c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized: Invalid API key"})
 				return nil
 			}
 
+			resource, actions := types.RequestPermission(c.Request.Method, c.Request.URL.Path)
+			if resource != "" && !identity.HasScope(resource, actions...) {
+				c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{
+					"error": "Forbidden: API key has no " + strings.Join(actions, " or ") + " scope for " + resource,
+				})
+				return nil
+			}
+
+			// The key acts for its owner, for authorization checks and handlers alike
+			c.Set("api_key", identity)
+			c.Set("user_id", identity.UserId)
+
 			return next(c)
 		}
 	}
 }
 
+// isOAuthRequest reports whether the request carries a valid access token issued
+// to an OAuth client whose scopes cover the request
+func isOAuthRequest(c *router.Context) bool {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	claims, err := types.ParseJWT(token)
+	if err != nil || claims.ClientId == "" {
+		return false
+	}
+	resource, actions := types.RequestPermission(c.Request.Method, c.Request.URL.Path)
+	return claims.HasScope(resource, actions...)
+}
+
 // Helper function to determine if the request is a JSON request
 func isJSONRequest(c *router.Context) bool {
 	// Check Accept header
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"base/core/router"
+	"base/core/types"
 )
 
 // contextKey is an empty struct with a descriptive name tag. Using a
@@ -115,7 +116,25 @@ func Auth(config *AuthConfig) router.MiddlewareFunc {
 
 			// Store user ID with "user_id" key for authorization middleware
 			// This is the essential information needed for permission checks
-			if userID, ok := user.(uint); ok {
+			if claims, ok := user.(*types.Claims); ok {
+				// Tokens issued to OAuth clients only reach what their scopes allow
+				if claims.ClientId != "" {
+					resource, actions := types.RequestPermission(c.Request.Method, c.Request.URL.Path)
+					if !claims.HasScope(resource, actions...) {
+						c.SetHeader("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+						return c.JSON(http.StatusForbidden, map[string]string{
+							"error": "Forbidden: token lacks a scope for this request",
+						})
+					}
+					c.Set("oauth_client_id", claims.ClientId)
+					c.Set("oauth_scopes", claims.Scopes)
+				}
+				c.Set("user_id", claims.UserId)
+				c.Set(config.Key, claims.UserId)
+				// The session and token id let handlers revoke them, e.g. on logout
+				c.Set("session_id", claims.SessionId)
+				c.Set("token_id", claims.TokenId)
+			} else if userID, ok := user.(uint); ok {
 				c.Set("user_id", userID)
 				c.Set(config.Key, userID) // Also store with configured key for backward compatibility
 			} else if userID, ok := user.(uint64); ok {
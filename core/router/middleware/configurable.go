@@ -48,12 +48,17 @@ func (cm *ConfigurableMiddleware) ConditionalAuth() router.MiddlewareFunc {
 
 			path := c.Request.URL.Path
 			
+			// An issued API key already identified the user, unless a token is sent too
+			if _, ok := c.Get("api_key"); ok && c.Header("Authorization") == "" {
+				return next(c)
+			}
+
 			if cm.config.IsAuthRequired(path) {
 				// Apply auth middleware
 				authConfig := DefaultAuthConfig()
 				authConfig.TokenValidator = func(token string) (any, error) {
-					_, userID, err := helper.ValidateJWT(token)
-					return userID, err
+					claims, _, err := helper.ValidateJWT(token)
+					return claims, err
 				}
 				authMiddleware := Auth(authConfig)
 				return authMiddleware(next)(c)
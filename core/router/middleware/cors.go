@@ -26,8 +26,10 @@ func CORSMiddleware(allowedOrigins []string) router.MiddlewareFunc {
 			if allowOrigin != "" {
 				c.SetHeader("Access-Control-Allow-Origin", allowOrigin)
 				c.SetHeader("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH, HEAD")
-				c.SetHeader("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Api-Key, Base-Orgid")
-				c.SetHeader("Access-Control-Expose-Headers", "Content-Length, Content-Type")
+				c.SetHeader("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-Api-Key, Base-Orgid, "+
+					"Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+				c.SetHeader("Access-Control-Expose-Headers", "Content-Length, Content-Type, Location, "+
+					"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Expires")
 				c.SetHeader("Access-Control-Allow-Credentials", "true")
 				c.SetHeader("Access-Control-Max-Age", "43200") // 12 hours
 			}
@@ -250,6 +250,11 @@ func (g *RouterGroup) PATCH(path string, handler HandlerFunc, middleware ...Midd
 	g.Handle(http.MethodPatch, path, handler, middleware...)
 }
 
+// HEAD registers a HEAD route in the group
+func (g *RouterGroup) HEAD(path string, handler HandlerFunc, middleware ...MiddlewareFunc) {
+	g.Handle(http.MethodHead, path, handler, middleware...)
+}
+
 // OPTIONS registers an OPTIONS route in the group
 func (g *RouterGroup) OPTIONS(path string, handler HandlerFunc, middleware ...MiddlewareFunc) {
 	g.Handle(http.MethodOptions, path, handler, middleware...)
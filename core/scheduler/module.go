@@ -5,6 +5,7 @@ import (
 	"base/core/logger"
 	"base/core/module"
 	"base/core/router"
+	"base/core/storage"
 
 	"gorm.io/gorm"
 )
@@ -16,11 +17,12 @@ type Module struct {
 	Scheduler     *Scheduler
 	CronScheduler *CronScheduler // Simple cron scheduler
 	Controller    *SchedulerController
+	Storage       *storage.ActiveStorage
 	Logger        logger.Logger
 }
 
 // NewSchedulerModule creates a new scheduler module
-func NewSchedulerModule(db *gorm.DB, routerGroup *router.RouterGroup, log logger.Logger, emitter *emitter.Emitter) module.Module {
+func NewSchedulerModule(db *gorm.DB, routerGroup *router.RouterGroup, log logger.Logger, emitter *emitter.Emitter, activeStorage *storage.ActiveStorage) *Module {
 	scheduler := NewScheduler(log)
 	cronScheduler := NewCronScheduler(log)
 	controller := NewSchedulerController(scheduler)
@@ -30,12 +32,24 @@ func NewSchedulerModule(db *gorm.DB, routerGroup *router.RouterGroup, log logger
 		Scheduler:     scheduler,
 		CronScheduler: cronScheduler,
 		Controller:    controller,
+		Storage:       activeStorage,
 		Logger:        log,
 	}
 
 	return m
 }
 
+// Init registers the core tasks and starts the schedulers so tasks registered by
+// other modules run
+func (m *Module) Init() error {
+	if m.Storage != nil {
+		if err := m.Scheduler.RegisterTask(storageGCTask(m.Storage, m.Logger)); err != nil {
+			return err
+		}
+	}
+	return m.Start()
+}
+
 // Routes registers the scheduler routes
 func (m *Module) Routes(router *router.RouterGroup) {
 	schedulerGroup := router.Group("/scheduler")
@@ -0,0 +1,45 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"base/core/logger"
+	"base/core/storage"
+)
+
+// storageGCTask removes attachments of deleted records and stored files without an
+// attachment every night. STORAGE_GC_RETENTION (e.g. "720h") sets how long they are
+// kept first.
+func storageGCTask(activeStorage *storage.ActiveStorage, log logger.Logger) *Task {
+	options := storage.GCOptions{Retention: storage.DefaultGCRetention}
+	if retention, err := time.ParseDuration(os.Getenv("STORAGE_GC_RETENTION")); err == nil && retention > 0 {
+		options.Retention = retention
+	}
+
+	return &Task{
+		Name:        "storage.gc",
+		Description: "Remove attachments of deleted records and files without an attachment",
+		Schedule:    &DailySchedule{Hour: 3, Minute: 30},
+		Handler: func(ctx context.Context) error {
+			report, err := activeStorage.CollectGarbage(ctx, options)
+			if report != nil {
+				log.Info("Storage garbage collected",
+					logger.Int("attachments", report.Attachments),
+					logger.Int("files", report.Files),
+					logger.Int64("bytes", report.Bytes),
+					logger.Int("errors", len(report.Errors)))
+			}
+			if err != nil {
+				return err
+			}
+			if len(report.Errors) > 0 {
+				return fmt.Errorf("storage gc: %d removals failed, first: %s", len(report.Errors), report.Errors[0])
+			}
+			return nil
+		},
+		Enabled: true,
+	}
+}
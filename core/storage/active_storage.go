@@ -2,24 +2,88 @@ package storage
 
 import (
 	"fmt"
+	"io"
+	"mime"
 	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 func NewActiveStorage(db *gorm.DB, config Config) (*ActiveStorage, error) {
-	var provider Provider
-	var err error
-
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get working directory: %w", err)
 	}
 
+	provider, err := newProvider(config, cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage provider: %w", err)
+	}
+
+	// If path is relative, make it absolute using cwd
+	storagePath := config.Path
+	if !filepath.IsAbs(storagePath) {
+		storagePath = filepath.Join(cwd, storagePath)
+	}
+
+	as := &ActiveStorage{
+		db:          db,
+		provider:    provider,
+		name:        ProviderName(config),
+		defaultPath: storagePath,
+		variantURL:  strings.TrimRight(config.VariantURLBase, "/"),
+		configs:     make(map[string]map[string]AttachmentConfig),
+		userQuota:   config.UserQuota,
+		ownerQuotas: config.OwnerQuotas,
+	}
+
+	if config.Fallback != nil {
+		as.fallbackName = ProviderName(*config.Fallback)
+		if as.fallbackName == as.name {
+			return nil, fmt.Errorf("fallback storage provider %s is the primary provider", as.fallbackName)
+		}
+		if as.fallback, err = newProvider(*config.Fallback, cwd); err != nil {
+			return nil, fmt.Errorf("failed to initialize fallback storage provider: %w", err)
+		}
+	}
+
+	// Auto-migrate the Attachment model
+	if err := db.AutoMigrate(&Attachment{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate attachments table: %w", err)
+	}
+
+	// Files stored before providers were recorded are on the previous provider when
+	// one is configured, and on the current one otherwise
+	legacy := as.name
+	if as.fallback != nil {
+		legacy = as.fallbackName
+	}
+	if err := db.Model(&Attachment{}).Where("provider = ? OR provider IS NULL", "").Update("provider", legacy).Error; err != nil {
+		return nil, fmt.Errorf("failed to record attachment providers: %w", err)
+	}
+
+	return as, nil
+}
+
+// ProviderName identifies the storage a config points to, such as "local" or
+// "s3:bucket", and is recorded on the attachments stored there
+func ProviderName(config Config) string {
+	name := strings.ToLower(config.Provider)
+	if name != "local" && config.Bucket != "" {
+		name += ":" + config.Bucket
+	}
+	return name
+}
+
+// newProvider creates the storage provider of a config
+func newProvider(config Config, cwd string) (Provider, error) {
 	// If path is relative, make it absolute using cwd
 	storagePath := config.Path
 	if !filepath.IsAbs(storagePath) {
@@ -28,12 +92,19 @@ func NewActiveStorage(db *gorm.DB, config Config) (*ActiveStorage, error) {
 
 	switch strings.ToLower(config.Provider) {
 	case "local":
-		provider, err = NewLocalProvider(LocalConfig{
-			BasePath: storagePath,
-			BaseURL:  config.BaseURL,
+		privatePath := config.PrivatePath
+		if privatePath != "" && !filepath.IsAbs(privatePath) {
+			privatePath = filepath.Join(cwd, privatePath)
+		}
+		return NewLocalProvider(LocalConfig{
+			BasePath:      storagePath,
+			BaseURL:       config.BaseURL,
+			PrivatePath:   privatePath,
+			SigningKey:    config.SigningKey,
+			SignedURLBase: config.SignedURLBase,
 		})
 	case "s3":
-		provider, err = NewS3Provider(S3Config{
+		return NewS3Provider(S3Config{
 			APIKey:          config.APIKey,
 			APISecret:       config.APISecret,
 			AccessKeyID:     config.APIKey,
@@ -45,7 +116,7 @@ func NewActiveStorage(db *gorm.DB, config Config) (*ActiveStorage, error) {
 			Region:          config.Region,
 		})
 	case "r2":
-		provider, err = NewR2Provider(R2Config{
+		return NewR2Provider(R2Config{
 			AccessKeyID:     config.APIKey,
 			AccessKeySecret: config.APISecret,
 			AccountID:       config.AccountID,
@@ -53,27 +124,27 @@ func NewActiveStorage(db *gorm.DB, config Config) (*ActiveStorage, error) {
 			BaseURL:         config.BaseURL,
 			CDN:             config.CDN,
 		})
+	case "gcs":
+		return NewGCSProvider(GCSConfig{
+			CredentialsFile: config.CredentialsFile,
+			Endpoint:        config.Endpoint,
+			Bucket:          config.Bucket,
+			BaseURL:         config.BaseURL,
+			UniformAccess:   config.UniformAccess,
+		})
+	case "s3compat":
+		return NewS3CompatProvider(S3CompatConfig{
+			AccessKeyID:     config.APIKey,
+			AccessKeySecret: config.APISecret,
+			Endpoint:        config.Endpoint,
+			Region:          config.Region,
+			Bucket:          config.Bucket,
+			BaseURL:         config.BaseURL,
+			UniformAccess:   config.UniformAccess,
+		})
 	default:
 		return nil, fmt.Errorf("unsupported storage provider: %s", config.Provider)
 	}
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize storage provider: %w", err)
-	}
-
-	as := &ActiveStorage{
-		db:          db,
-		provider:    provider,
-		defaultPath: storagePath,
-		configs:     make(map[string]map[string]AttachmentConfig),
-	}
-
-	// Auto-migrate the Attachment model
-	if err := db.AutoMigrate(&Attachment{}); err != nil {
-		return nil, fmt.Errorf("failed to migrate attachments table: %w", err)
-	}
-
-	return as, nil
 }
 
 func (as *ActiveStorage) RegisterAttachment(modelName string, config AttachmentConfig) {
@@ -83,7 +154,9 @@ func (as *ActiveStorage) RegisterAttachment(modelName string, config AttachmentC
 	as.configs[modelName][config.Field] = config
 }
 
-func (as *ActiveStorage) Attach(model Attachable, field string, file *multipart.FileHeader) (*Attachment, error) {
+// Attach stores an uploaded file and attaches it to the model field. The file counts
+// against the quota of uploadedBy, unless it is 0, and of the owner of the model.
+func (as *ActiveStorage) Attach(model Attachable, field string, file *multipart.FileHeader, uploadedBy uint) (*Attachment, error) {
 	// Get config for model
 	config, err := as.getConfig(model.GetModelName(), field)
 	if err != nil {
@@ -91,48 +164,207 @@ func (as *ActiveStorage) Attach(model Attachable, field string, file *multipart.
 	}
 
 	// Validate file
-	if err := as.validateFile(file, config); err != nil {
+	if err := as.validateFile(file.Filename, file.Size, config); err != nil {
+		return nil, err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer src.Close()
+
+	// Check the content and detect its type
+	content, contentType, err := as.inspect(config, file.Filename, src, file.Size)
+	if err != nil {
 		return nil, err
 	}
 
 	// Create attachment record
 	attachment := &Attachment{
-		ModelType: model.GetModelName(),
-		ModelId:   model.GetId(),
-		Field:     field,
-		Filename:  file.Filename,
-		Size:      file.Size,
-	}
-
-	// Upload file using provider
-	result, err := as.provider.Upload(file, UploadConfig{
-		AllowedExtensions: config.AllowedExtensions,
-		MaxFileSize:       config.MaxFileSize,
-		UploadPath:        filepath.Join(config.Path, model.GetModelName(), field),
-	})
+		ModelType:   model.GetModelName(),
+		ModelId:     model.GetId(),
+		Field:       field,
+		Filename:    file.Filename,
+		Size:        file.Size,
+		Private:     config.Private,
+		ContentType: contentType,
+	}
+
+	// Check quotas; a file replacing the one on a single file field frees its space
+	if err := as.assignOwner(attachment, config, uploadedBy); err != nil {
+		return nil, err
+	}
+	if err := as.checkQuota(attachment, as.replaced(attachment, config)); err != nil {
+		return nil, err
+	}
+
+	// Upload file using provider and save attachment record
+	if err := as.store(attachment, config, content); err != nil {
+		return nil, err
+	}
+
+	if err := as.attachVariants(attachment, config); err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// AttachReader streams a file that is not a multipart upload, such as an assembled
+// resumable upload, to the provider and attaches it to the model field. Unless the
+// field allows multiple files, attachments already on the field are replaced.
+func (as *ActiveStorage) AttachReader(model Attachable, field, filename string, reader io.Reader, size int64, uploadedBy uint) (*Attachment, error) {
+	config, err := as.ValidateUpload(model.GetModelName(), field, filename, size)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update attachment with upload result
-	attachment.Path = result.Path
-	attachment.URL = as.provider.GetURL(result.Path)
+	file, cleanup, err := readerAt(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
 
-	// Save attachment record
-	if err := as.db.Create(attachment).Error; err != nil {
-		// Try to delete uploaded file if record creation fails
-		_ = as.provider.Delete(result.Path)
+	content, contentType, err := as.inspect(config, filename, file, size)
+	if err != nil {
 		return nil, err
 	}
 
+	attachment := &Attachment{
+		ModelType:   model.GetModelName(),
+		ModelId:     model.GetId(),
+		Field:       field,
+		Filename:    filename,
+		Size:        size,
+		Private:     config.Private,
+		ContentType: contentType,
+	}
+
+	if err := as.assignOwner(attachment, config, uploadedBy); err != nil {
+		return nil, err
+	}
+	previous := as.replaced(attachment, config)
+	if err := as.checkQuota(attachment, previous); err != nil {
+		return nil, err
+	}
+
+	if err := as.store(attachment, config, content); err != nil {
+		return nil, err
+	}
+
+	for _, old := range previous {
+		_ = as.Delete(old)
+	}
+
+	if err := as.attachVariants(attachment, config); err != nil {
+		return attachment, err
+	}
+
+	if config.OnAttach != nil {
+		if err := config.OnAttach(attachment); err != nil {
+			return attachment, err
+		}
+	}
+
 	return attachment, nil
 }
 
+// ValidateUpload checks a file against the attachment config of a model field
+// before it is uploaded, and returns that config
+func (as *ActiveStorage) ValidateUpload(modelName, field, filename string, size int64) (AttachmentConfig, error) {
+	config, err := as.getConfig(modelName, field)
+	if err != nil {
+		return AttachmentConfig{}, err
+	}
+	if err := as.validateFile(filename, size, config); err != nil {
+		return AttachmentConfig{}, err
+	}
+	return config, nil
+}
+
+// Delete removes an attachment. Its blob and variants are removed with the last
+// attachment that shares them.
 func (as *ActiveStorage) Delete(attachment *Attachment) error {
-	if err := as.provider.Delete(attachment.Path); err != nil {
+	name := as.locate(attachment)
+	if err := as.db.Delete(attachment).Error; err != nil {
+		return err
+	}
+	if as.references(name, attachment.Path) > 0 {
+		return nil
+	}
+	if err := as.providerFor(name).Delete(attachment.Path); err != nil {
 		return err
 	}
-	return as.db.Delete(attachment).Error
+	as.deleteVariants(attachment, name)
+	return nil
+}
+
+// SignedURL returns a URL for downloading an attachment that stops working after
+// expires. Public attachments are returned with their permanent URL.
+func (as *ActiveStorage) SignedURL(attachment *Attachment, expires time.Duration) (string, error) {
+	if attachment == nil {
+		return "", fmt.Errorf("attachment is required")
+	}
+	if !attachment.Private {
+		return attachment.URL, nil
+	}
+	return as.providerFor(as.locate(attachment)).SignedURL(attachment.Path, expires)
+}
+
+// SignedFile verifies a local signed URL and returns the path of the private file it
+// points to. Cloud providers verify their own presigned URLs, so it fails for them.
+// During a migration off local storage, the fallback provider serves the files.
+func (as *ActiveStorage) SignedFile(path, expires, signature string) (string, error) {
+	local, ok := as.provider.(*localProvider)
+	if !ok {
+		local, ok = as.fallback.(*localProvider)
+	}
+	if !ok {
+		return "", ErrNotPrivate
+	}
+	return local.signedFile(path, expires, signature)
+}
+
+// ContentType returns the detected content type of a stored file, falling back to
+// the type of its extension for files stored before content types were recorded
+func (as *ActiveStorage) ContentType(path string) string {
+	var attachment Attachment
+	if err := as.db.Select("content_type").Where("path = ?", path).First(&attachment).Error; err == nil && attachment.ContentType != "" {
+		return attachment.ContentType
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// replaced returns the attachments a new attachment replaces: those already on its
+// field, unless the field allows multiple files
+func (as *ActiveStorage) replaced(attachment *Attachment, config AttachmentConfig) []*Attachment {
+	var previous []*Attachment
+	if !config.Multiple {
+		as.db.Where("model_type = ? AND model_id = ? AND field = ?", attachment.ModelType, attachment.ModelId, attachment.Field).
+			Find(&previous)
+	}
+	return previous
+}
+
+// publicURL returns the permanent URL of an attachment; private files have none
+func (as *ActiveStorage) publicURL(attachment *Attachment) string {
+	if attachment.Private {
+		return ""
+	}
+	return as.providerFor(attachment.Provider).GetURL(attachment.Path)
+}
+
+// uploadPath returns the storage folder of a model field
+func uploadPath(config AttachmentConfig, modelName, field string) string {
+	folder := path.Join(config.Path, modelName, field)
+	if config.Private {
+		folder = PrivatePrefix + folder
+	}
+	return folder
 }
 
 func (as *ActiveStorage) getConfig(modelName, field string) (AttachmentConfig, error) {
@@ -149,13 +381,15 @@ func (as *ActiveStorage) getConfig(modelName, field string) (AttachmentConfig, e
 	return config, nil
 }
 
-func (as *ActiveStorage) validateFile(file *multipart.FileHeader, config AttachmentConfig) error {
-	if file.Size > config.MaxFileSize {
+func (as *ActiveStorage) validateFile(filename string, size int64, config AttachmentConfig) error {
+	if size > config.MaxFileSize {
 		return fmt.Errorf("file size exceeds maximum allowed size of %d bytes", config.MaxFileSize)
 	}
 
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if len(config.AllowedExtensions) > 0 && !strings.Contains(strings.Join(config.AllowedExtensions, ","), ext) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if len(config.AllowedExtensions) > 0 && !slices.ContainsFunc(config.AllowedExtensions, func(allowed string) bool {
+		return strings.ToLower(allowed) == ext
+	}) {
 		return fmt.Errorf("file extension %s is not allowed", ext)
 	}
 
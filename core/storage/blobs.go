@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DedupeReport summarizes a Dedupe run
+type DedupeReport struct {
+	Scanned   int      `json:"scanned"`
+	Hashed    int      `json:"hashed"`    // Attachments that got a checksum
+	Moved     int      `json:"moved"`     // Files moved to their blob path
+	Collapsed int      `json:"collapsed"` // Files replaced by a blob stored for another attachment
+	Freed     int64    `json:"freed"`     // Bytes of duplicate files removed
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// blobPath returns the storage key of content in a field folder. Files are named by
+// their checksum, so the same content is stored once per field.
+func blobPath(folder, checksum, filename string) string {
+	return path.Join(folder, checksum+strings.ToLower(path.Ext(filename)))
+}
+
+// hashContent returns the SHA-256 of the content and rewinds it
+func hashContent(content io.ReadSeeker) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// references counts the attachments that use a file stored on a provider
+func (as *ActiveStorage) references(provider, key string) int64 {
+	var count int64
+	as.db.Model(&Attachment{}).Where("provider = ? AND path = ?", provider, key).Count(&count)
+	return count
+}
+
+// store saves the attachment record and uploads the content as a blob named by its
+// checksum. A blob already stored for another attachment of the field is reused.
+func (as *ActiveStorage) store(attachment *Attachment, config AttachmentConfig, content io.ReadSeeker) error {
+	checksum, err := hashContent(content)
+	if err != nil {
+		return err
+	}
+	attachment.Checksum = checksum
+	attachment.Provider = as.name
+	attachment.Path = blobPath(uploadPath(config, attachment.ModelType, attachment.Field), checksum, attachment.Filename)
+	attachment.URL = as.publicURL(attachment)
+
+	// The record is created first, so a concurrent Delete of another attachment
+	// sharing the blob counts this one and keeps the file
+	if err := as.db.Create(attachment).Error; err != nil {
+		return err
+	}
+	if as.references(as.name, attachment.Path) > 1 {
+		return nil
+	}
+	if _, err := as.provider.Put(attachment.Path, content, attachment.Size, attachment.ContentType); err != nil {
+		as.db.Delete(attachment)
+		return err
+	}
+	return nil
+}
+
+// Dedupe backfills checksums of attachments stored before deduplication and moves
+// their files to blob paths, collapsing files with the same content in a field into
+// one. Attachments of models without a registered config are only hashed. It can be
+// run again after a failure; attachments already on their blob path are skipped.
+func (as *ActiveStorage) Dedupe(ctx context.Context) (*DedupeReport, error) {
+	report := &DedupeReport{}
+
+	var batch []*Attachment
+	err := as.db.WithContext(ctx).FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for _, attachment := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			report.Scanned++
+			if err := as.dedupe(attachment, report); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("attachment %d: %s", attachment.Id, err.Error()))
+			}
+		}
+		return nil
+	}).Error
+	return report, err
+}
+
+// dedupe hashes one attachment and moves its file to the blob path
+func (as *ActiveStorage) dedupe(attachment *Attachment, report *DedupeReport) error {
+	if attachment.Checksum == "" {
+		checksum, err := hashStored(as.providerFor(attachment.Provider), attachment.Path)
+		if err != nil {
+			return err
+		}
+		attachment.Checksum = checksum
+		if err := as.db.Model(attachment).Update("checksum", checksum).Error; err != nil {
+			return err
+		}
+		report.Hashed++
+	}
+
+	config, err := as.getConfig(attachment.ModelType, attachment.Field)
+	if err != nil {
+		return nil
+	}
+	key := blobPath(path.Dir(attachment.Path), attachment.Checksum, attachment.Filename)
+	if key == attachment.Path {
+		return nil
+	}
+
+	// Copy the file to the blob path unless another attachment already stored it there
+	collapsed := as.references(attachment.Provider, key) > 0
+	if !collapsed {
+		if err := as.copyStored(attachment.Provider, attachment.Path, key, attachment.Size, attachment.ContentType); err != nil {
+			return err
+		}
+	}
+
+	previous := *attachment
+	attachment.Path = key
+	attachment.URL = as.publicURL(attachment)
+	if err := as.db.Model(attachment).Select("path", "url").Updates(attachment).Error; err != nil {
+		return err
+	}
+	if err := as.attachVariants(attachment, config); err != nil {
+		return err
+	}
+	if config.OnUpdate != nil {
+		if err := config.OnUpdate(attachment); err != nil {
+			return err
+		}
+	}
+
+	if collapsed {
+		report.Collapsed++
+	} else {
+		report.Moved++
+	}
+	if as.references(previous.Provider, previous.Path) == 0 {
+		if err := as.providerFor(previous.Provider).Delete(previous.Path); err != nil {
+			return err
+		}
+		as.deleteVariants(&previous, previous.Provider)
+		if collapsed {
+			report.Freed += previous.Size
+		}
+	}
+	return nil
+}
+
+// hashStored returns the SHA-256 of a file stored on a provider
+func hashStored(provider Provider, key string) (string, error) {
+	reader, err := provider.Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", key, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyStored copies a file stored on a provider to another key
+func (as *ActiveStorage) copyStored(provider, from, to string, size int64, contentType string) error {
+	reader, err := as.providerFor(provider).Open(from)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if contentType == "" {
+		contentType = as.ContentType(from)
+	}
+	_, err = as.providerFor(provider).Put(to, reader, size, contentType)
+	return err
+}
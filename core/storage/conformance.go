@@ -0,0 +1,425 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConformanceOptions controls a RunConformance run
+type ConformanceOptions struct {
+	// Prefix is the folder the checks write to, removed afterwards; defaults to a
+	// unique folder under "conformance/"
+	Prefix string
+	// FetchURLs downloads public and signed URLs over HTTP. Local signed URLs are
+	// served by the app, so it must be running.
+	FetchURLs  bool
+	HTTPClient *http.Client
+}
+
+// ConformanceResult is the outcome of one conformance check
+type ConformanceResult struct {
+	Check  string `json:"check"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ConformanceReport summarizes a RunConformance run
+type ConformanceReport struct {
+	Provider string              `json:"provider"`
+	Passed   int                 `json:"passed"`
+	Failed   int                 `json:"failed"`
+	Results  []ConformanceResult `json:"results"`
+}
+
+// conformance holds the state shared by the checks of a run
+type conformance struct {
+	provider Provider
+	prefix   string
+	options  ConformanceOptions
+}
+
+// conformanceChecks lists the behaviour every Provider must have, in run order
+var conformanceChecks = []struct {
+	name string
+	run  func(c *conformance) error
+}{
+	{"put and open", (*conformance).checkPutOpen},
+	{"put overwrites", (*conformance).checkOverwrite},
+	{"put private", (*conformance).checkPrivate},
+	{"upload", (*conformance).checkUpload},
+	{"open missing", (*conformance).checkOpenMissing},
+	{"list", (*conformance).checkList},
+	{"list stops on error", (*conformance).checkListStops},
+	{"list missing prefix", (*conformance).checkListMissing},
+	{"delete", (*conformance).checkDelete},
+	{"delete many", (*conformance).checkDeleteMany},
+	{"public url", (*conformance).checkPublicURL},
+	{"signed url", (*conformance).checkSignedURL},
+}
+
+// RunConformance checks that a provider behaves the way ActiveStorage relies on,
+// such as a cloud bucket or a local stand-in for one like MinIO or fake-gcs-server.
+// Every check runs even if earlier ones fail; the files written are removed at the end.
+func RunConformance(ctx context.Context, name string, provider Provider, options ConformanceOptions) (*ConformanceReport, error) {
+	if options.Prefix == "" {
+		options.Prefix = fmt.Sprintf("conformance/%d", time.Now().UnixNano())
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	c := &conformance{provider: provider, prefix: strings.Trim(options.Prefix, "/"), options: options}
+	report := &ConformanceReport{Provider: name}
+	defer c.cleanup()
+
+	for _, check := range conformanceChecks {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		result := ConformanceResult{Check: check.name, Passed: true}
+		if err := check.run(c); err != nil {
+			result.Passed = false
+			result.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Passed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// RunConformance checks the primary provider, or the fallback one if fallback is set
+func (as *ActiveStorage) RunConformance(ctx context.Context, fallback bool, options ConformanceOptions) (*ConformanceReport, error) {
+	if fallback {
+		if as.fallback == nil {
+			return nil, ErrNoFallback
+		}
+		return RunConformance(ctx, as.fallbackName, as.fallback, options)
+	}
+	return RunConformance(ctx, as.name, as.provider, options)
+}
+
+// key returns a storage key in the folder of the run
+func (c *conformance) key(name string) string {
+	return c.prefix + "/" + name
+}
+
+// put stores content and checks the result describes it
+func (c *conformance) put(key string, content []byte) error {
+	result, err := c.provider.Put(key, bytes.NewReader(content), int64(len(content)), "text/plain")
+	if err != nil {
+		return fmt.Errorf("put %s: %w", key, err)
+	}
+	if result.Path != key {
+		return fmt.Errorf("put %s returned path %q", key, result.Path)
+	}
+	if result.Size != int64(len(content)) {
+		return fmt.Errorf("put %s returned size %d, want %d", key, result.Size, len(content))
+	}
+	return nil
+}
+
+// expectContent reads a stored file and compares it with content
+func (c *conformance) expectContent(key string, content []byte) error {
+	reader, err := c.provider.Open(key)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", key, err)
+	}
+	defer reader.Close()
+	stored, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", key, err)
+	}
+	if !bytes.Equal(stored, content) {
+		return fmt.Errorf("%s holds %q, want %q", key, stored, content)
+	}
+	return nil
+}
+
+// expectMissing checks a stored file can no longer be opened
+func (c *conformance) expectMissing(key string) error {
+	reader, err := c.provider.Open(key)
+	if err != nil {
+		return nil
+	}
+	reader.Close()
+	return fmt.Errorf("%s can still be opened", key)
+}
+
+// list returns the files under a prefix by path
+func (c *conformance) list(prefix string) (map[string]FileInfo, error) {
+	files := make(map[string]FileInfo)
+	err := c.provider.List(prefix, func(file FileInfo) error {
+		files[file.Path] = file
+		return nil
+	})
+	return files, err
+}
+
+// fetch downloads a URL and compares the body with content
+func (c *conformance) fetch(url string, content []byte) error {
+	response, err := c.options.HTTPClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch %s: status %d", url, response.StatusCode)
+	}
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("fetch %s: %w", url, err)
+	}
+	if !bytes.Equal(body, content) {
+		return fmt.Errorf("fetch %s returned %q, want %q", url, body, content)
+	}
+	return nil
+}
+
+func (c *conformance) checkPutOpen() error {
+	// Spaces and non-ASCII characters must survive the round trip
+	key := c.key("put/hello wörld.txt")
+	content := []byte("hello world")
+	if err := c.put(key, content); err != nil {
+		return err
+	}
+	return c.expectContent(key, content)
+}
+
+func (c *conformance) checkOverwrite() error {
+	key := c.key("put/overwrite.txt")
+	if err := c.put(key, []byte("first version")); err != nil {
+		return err
+	}
+	if err := c.put(key, []byte("second")); err != nil {
+		return err
+	}
+	return c.expectContent(key, []byte("second"))
+}
+
+func (c *conformance) checkPrivate() error {
+	key := PrivatePrefix + c.key("secret.txt")
+	content := []byte("private content")
+	if err := c.put(key, content); err != nil {
+		return err
+	}
+	if err := c.expectContent(key, content); err != nil {
+		return err
+	}
+	// Private files must not show up among the public ones
+	files, err := c.list(c.prefix + "/")
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	for path := range files {
+		if strings.HasSuffix(path, "secret.txt") {
+			return fmt.Errorf("private file listed as %s", path)
+		}
+	}
+	return nil
+}
+
+func (c *conformance) checkUpload() error {
+	content := []byte("uploaded through a form")
+	file, err := conformanceFileHeader("report.txt", content)
+	if err != nil {
+		return err
+	}
+	folder := c.key("uploads")
+	result, err := c.provider.Upload(file, UploadConfig{UploadPath: folder, MaxFileSize: int64(len(content))})
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	key := filepath.ToSlash(result.Path)
+	if !strings.HasPrefix(key, folder+"/") || !strings.HasSuffix(key, ".txt") {
+		return fmt.Errorf("upload stored %q outside %s", key, folder)
+	}
+	if result.Size != int64(len(content)) {
+		return fmt.Errorf("upload returned size %d, want %d", result.Size, len(content))
+	}
+	return c.expectContent(key, content)
+}
+
+func (c *conformance) checkOpenMissing() error {
+	return c.expectMissing(c.key("missing.txt"))
+}
+
+func (c *conformance) checkList() error {
+	want := map[string][]byte{
+		c.key("list/a.txt"):     []byte("a"),
+		c.key("list/sub/b.txt"): []byte("bb"),
+	}
+	for key, content := range want {
+		if err := c.put(key, content); err != nil {
+			return err
+		}
+	}
+	// A sibling sharing the folder name as a prefix must not be listed
+	if err := c.put(c.key("listed/c.txt"), []byte("ccc")); err != nil {
+		return err
+	}
+
+	files, err := c.list(c.key("list/"))
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if len(files) != len(want) {
+		return fmt.Errorf("listed %v, want the %d files put", paths, len(want))
+	}
+	for key, content := range want {
+		file, ok := files[key]
+		if !ok {
+			return fmt.Errorf("listed %v, missing %s", paths, key)
+		}
+		if file.Size != int64(len(content)) {
+			return fmt.Errorf("listed %s with size %d, want %d", key, file.Size, len(content))
+		}
+		if file.ModTime.IsZero() || time.Since(file.ModTime) > time.Hour {
+			return fmt.Errorf("listed %s with modification time %s", key, file.ModTime)
+		}
+	}
+	return nil
+}
+
+func (c *conformance) checkListStops() error {
+	stop := errors.New("stop")
+	calls := 0
+	err := c.provider.List(c.key("list/"), func(file FileInfo) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		return fmt.Errorf("list returned %v, want the callback error", err)
+	}
+	if calls != 1 {
+		return fmt.Errorf("list called back %d times after an error", calls)
+	}
+	return nil
+}
+
+func (c *conformance) checkListMissing() error {
+	files, err := c.list(c.key("nothing-here/"))
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(files) > 0 {
+		return fmt.Errorf("listed %d files under an empty prefix", len(files))
+	}
+	return nil
+}
+
+func (c *conformance) checkDelete() error {
+	key := c.key("delete/one.txt")
+	if err := c.put(key, []byte("delete me")); err != nil {
+		return err
+	}
+	if err := c.provider.Delete(key); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return c.expectMissing(key)
+}
+
+func (c *conformance) checkDeleteMany() error {
+	keys := []string{c.key("delete/a.txt"), c.key("delete/b.txt"), PrivatePrefix + c.key("delete/c.txt")}
+	for _, key := range keys {
+		if err := c.put(key, []byte(key)); err != nil {
+			return err
+		}
+	}
+	// Files already gone are skipped
+	if err := c.provider.DeleteMany(append(keys, c.key("delete/missing.txt"))); err != nil {
+		return fmt.Errorf("delete many: %w", err)
+	}
+	for _, key := range keys {
+		if err := c.expectMissing(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *conformance) checkPublicURL() error {
+	key := c.key("public.txt")
+	content := []byte("public content")
+	if err := c.put(key, content); err != nil {
+		return err
+	}
+	url := c.provider.GetURL(key)
+	if !strings.Contains(url, key) {
+		return fmt.Errorf("url %q does not name %s", url, key)
+	}
+	if c.options.FetchURLs {
+		return c.fetch(url, content)
+	}
+	return nil
+}
+
+func (c *conformance) checkSignedURL() error {
+	key := PrivatePrefix + c.key("signed.txt")
+	content := []byte("signed content")
+	if err := c.put(key, content); err != nil {
+		return err
+	}
+	url, err := c.provider.SignedURL(key, 5*time.Minute)
+	if err != nil {
+		return fmt.Errorf("signed url: %w", err)
+	}
+	if url == "" {
+		return fmt.Errorf("signed url is empty")
+	}
+	if c.options.FetchURLs {
+		return c.fetch(url, content)
+	}
+	return nil
+}
+
+// cleanup removes the files written by the run
+func (c *conformance) cleanup() {
+	for _, prefix := range []string{c.prefix + "/", PrivatePrefix + c.prefix + "/"} {
+		files, err := c.list(prefix)
+		if err != nil || len(files) == 0 {
+			continue
+		}
+		paths := make([]string, 0, len(files))
+		for path := range files {
+			paths = append(paths, path)
+		}
+		_ = c.provider.DeleteMany(paths)
+	}
+}
+
+// conformanceFileHeader builds a multipart file header as a form upload would
+func conformanceFileHeader(filename string, content []byte) (*multipart.FileHeader, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(int64(body.Len()) + 1024)
+	if err != nil {
+		return nil, err
+	}
+	return form.File["file"][0], nil
+}
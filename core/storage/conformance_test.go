@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// The cloud providers are checked against local stand-ins, started as in the
+// README. Their tests are skipped when nothing listens at the endpoint.
+const (
+	conformanceBucket = "conformance"
+	minioEndpoint     = "http://localhost:9000" // STORAGE_TEST_S3_ENDPOINT
+	minioUser         = "minio"                 // STORAGE_TEST_S3_KEY
+	minioPassword     = "minio123"              // STORAGE_TEST_S3_SECRET
+	fakeGCSEndpoint   = "http://localhost:4443" // STORAGE_TEST_GCS_ENDPOINT
+)
+
+func TestConformanceLocal(t *testing.T) {
+	dir := t.TempDir()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider, err := NewLocalProvider(LocalConfig{
+		BasePath:      filepath.Join(dir, "public"),
+		BaseURL:       server.URL + "/public",
+		PrivatePath:   filepath.Join(dir, "private"),
+		SigningKey:    "conformance-signing-key",
+		SignedURLBase: server.URL + "/signed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The app serves public files statically and private ones on signed URLs
+	mux.Handle("/public/", http.StripPrefix("/public/", http.FileServer(http.Dir(filepath.Join(dir, "public")))))
+	mux.HandleFunc("/signed/", func(w http.ResponseWriter, r *http.Request) {
+		file, err := provider.(*localProvider).signedFile(strings.TrimPrefix(r.URL.Path, "/signed/"),
+			r.URL.Query().Get("expires"), r.URL.Query().Get("signature"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.ServeFile(w, r, file)
+	})
+
+	runConformance(t, "local", provider)
+}
+
+func TestConformanceS3Compat(t *testing.T) {
+	endpoint := standIn(t, "STORAGE_TEST_S3_ENDPOINT", minioEndpoint)
+	provider, err := NewS3CompatProvider(S3CompatConfig{
+		AccessKeyID:     env("STORAGE_TEST_S3_KEY", minioUser),
+		AccessKeySecret: env("STORAGE_TEST_S3_SECRET", minioPassword),
+		Endpoint:        endpoint,
+		Bucket:          conformanceBucket,
+		UniformAccess:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A bucket anyone may download from, as `mc anonymous set download` makes it
+	client := provider.(*s3CompatProvider).client
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(conformanceBucket)}); err != nil {
+		var awsErr awserr.Error
+		if !errors.As(err, &awsErr) || (awsErr.Code() != s3.ErrCodeBucketAlreadyOwnedByYou && awsErr.Code() != s3.ErrCodeBucketAlreadyExists) {
+			t.Fatalf("create bucket: %v", err)
+		}
+	}
+	policy, _ := json.Marshal(map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect":    "Allow",
+			"Principal": map[string]any{"AWS": []string{"*"}},
+			"Action":    []string{"s3:GetObject"},
+			"Resource":  []string{"arn:aws:s3:::" + conformanceBucket + "/*"},
+		}},
+	})
+	if _, err := client.PutBucketPolicy(&s3.PutBucketPolicyInput{
+		Bucket: aws.String(conformanceBucket),
+		Policy: aws.String(string(policy)),
+	}); err != nil {
+		t.Fatalf("set bucket policy: %v", err)
+	}
+
+	runConformance(t, "s3compat", provider)
+}
+
+func TestConformanceGCS(t *testing.T) {
+	endpoint := standIn(t, "STORAGE_TEST_GCS_ENDPOINT", fakeGCSEndpoint)
+
+	// fake-gcs-server takes no credentials; a generated key only signs URLs
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "conformance@example.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	})
+	credentialsFile := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(credentialsFile, credentials, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	bucket, _ := json.Marshal(map[string]string{"name": conformanceBucket})
+	response, err := http.Post(strings.TrimRight(endpoint, "/")+"/storage/v1/b", "application/json", bytes.NewReader(bucket))
+	if err != nil {
+		t.Fatalf("create bucket: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusConflict {
+		t.Fatalf("create bucket: status %d", response.StatusCode)
+	}
+
+	provider, err := NewGCSProvider(GCSConfig{
+		CredentialsFile: credentialsFile,
+		Endpoint:        endpoint,
+		Bucket:          conformanceBucket,
+		UniformAccess:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runConformance(t, "gcs", provider)
+}
+
+// runConformance runs the checks with URL downloads and reports each as a subtest
+func runConformance(t *testing.T, name string, provider Provider) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	report, err := RunConformance(ctx, name, provider, ConformanceOptions{FetchURLs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != len(conformanceChecks) {
+		t.Fatalf("%d checks ran, want %d", len(report.Results), len(conformanceChecks))
+	}
+	for _, result := range report.Results {
+		t.Run(result.Check, func(t *testing.T) {
+			if !result.Passed {
+				t.Error(result.Error)
+			}
+		})
+	}
+}
+
+// standIn returns the endpoint of a local stand-in for a cloud provider, and skips
+// the test when nothing listens there
+func standIn(t *testing.T, variable, fallback string) string {
+	t.Helper()
+	endpoint := env(variable, fallback)
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" {
+		t.Fatalf("invalid %s: %q", variable, endpoint)
+	}
+	conn, err := net.DialTimeout("tcp", parsed.Host, time.Second)
+	if err != nil {
+		t.Skipf("no stand-in listens at %s; start one as the README shows or set %s", endpoint, variable)
+	}
+	conn.Close()
+	return endpoint
+}
+
+func env(variable, fallback string) string {
+	if value := os.Getenv(variable); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// gpsInfoTag is the EXIF tag pointing to the GPS directory
+const gpsInfoTag = 0x8825
+
+// exifHeader prefixes the EXIF data in JPEG segments and in some WebP chunks
+var exifHeader = []byte("Exif\x00\x00")
+
+// stripGPS erases the GPS location from the EXIF metadata of a JPEG, PNG or WebP image
+// in place. The rest of the metadata, such as the orientation, is kept and the file
+// keeps its size. It reports whether a location was found.
+func stripGPS(data []byte) bool {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return stripJPEGGPS(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return stripPNGGPS(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebPGPS(data)
+	}
+	return false
+}
+
+// stripJPEGGPS walks the segments before the image data looking for EXIF in APP1
+func stripJPEGGPS(data []byte) bool {
+	found := false
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break // Start of scan or end of image
+		}
+		if marker == 0xFF {
+			pos++ // Fill byte
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		if segment := data[pos+4 : end]; marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			found = stripTIFFGPS(segment[len(exifHeader):]) || found
+		}
+		pos = end
+	}
+	return found
+}
+
+// stripPNGGPS erases GPS data from eXIf chunks and updates their checksums
+func stripPNGGPS(data []byte) bool {
+	found := false
+	pos := 8
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			break
+		}
+		kind := string(data[pos+4 : pos+8])
+		if kind == "IEND" {
+			break
+		}
+		if kind == "eXIf" && stripTIFFGPS(data[pos+8:pos+8+length]) {
+			binary.BigEndian.PutUint32(data[pos+8+length:], crc32.ChecksumIEEE(data[pos+4:pos+8+length]))
+			found = true
+		}
+		pos = end
+	}
+	return found
+}
+
+// stripWebPGPS erases GPS data from EXIF chunks of extended WebP files
+func stripWebPGPS(data []byte) bool {
+	found := false
+	pos := 12
+	for pos+8 <= len(data) {
+		length := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + length
+		if length < 0 || end > len(data) {
+			break
+		}
+		if string(data[pos:pos+4]) == "EXIF" {
+			chunk := data[pos+8 : end]
+			if bytes.HasPrefix(chunk, exifHeader) {
+				chunk = chunk[len(exifHeader):]
+			}
+			found = stripTIFFGPS(chunk) || found
+		}
+		pos = end + length%2 // Chunks are padded to an even size
+	}
+	return found
+}
+
+// stripTIFFGPS erases the GPS directory of TIFF structured EXIF data. The directory
+// and the values it points to are zeroed, which leaves it with no entries.
+func stripTIFFGPS(tiff []byte) bool {
+	if len(tiff) < 8 {
+		return false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return false
+	}
+
+	ifd := uint64(order.Uint32(tiff[4:]))
+	if ifd+2 > uint64(len(tiff)) {
+		return false
+	}
+	count := uint64(order.Uint16(tiff[ifd:]))
+	for i := uint64(0); i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > uint64(len(tiff)) {
+			return false
+		}
+		if order.Uint16(tiff[entry:]) == gpsInfoTag {
+			return clearIFD(tiff, uint64(order.Uint32(tiff[entry+8:])), order)
+		}
+	}
+	return false
+}
+
+// tiffTypeSizes holds the byte size of each TIFF value type
+var tiffTypeSizes = map[uint16]uint64{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// clearIFD zeroes a TIFF directory, its entry count and the values stored outside it
+func clearIFD(tiff []byte, ifd uint64, order binary.ByteOrder) bool {
+	if ifd+2 > uint64(len(tiff)) {
+		return false
+	}
+	count := uint64(order.Uint16(tiff[ifd:]))
+	end := ifd + 2 + count*12
+	if end > uint64(len(tiff)) {
+		return false
+	}
+
+	for i := uint64(0); i < count; i++ {
+		entry := ifd + 2 + i*12
+		size := tiffTypeSizes[order.Uint16(tiff[entry+2:])] * uint64(order.Uint32(tiff[entry+4:]))
+		if size > 4 {
+			offset := uint64(order.Uint32(tiff[entry+8:]))
+			if offset+size <= uint64(len(tiff)) {
+				clear(tiff[offset : offset+size])
+			}
+		}
+	}
+	clear(tiff[ifd:end])
+	return true
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultGCRetention is how long files of soft-deleted records and files without an
+// attachment are kept before they are garbage collected
+const DefaultGCRetention = 30 * 24 * time.Hour
+
+// DefaultGCBatchSize is the number of attachments or files removed at a time
+const DefaultGCBatchSize = 100
+
+// GCOptions controls a CollectGarbage run
+type GCOptions struct {
+	Retention time.Duration
+	BatchSize int
+	DryRun    bool // Report what would be removed without removing it
+}
+
+// GCReport summarizes a CollectGarbage run
+type GCReport struct {
+	DryRun      bool     `json:"dry_run"`
+	Attachments int      `json:"attachments"` // Attachments of missing or soft-deleted records
+	Files       int      `json:"files"`       // Stored files without an attachment
+	Bytes       int64    `json:"bytes"`       // Storage freed
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// CollectGarbage removes attachments whose record no longer exists or was soft-deleted
+// longer than the retention ago, then removes files in the attachment folders of the
+// primary provider that no attachment references. Files younger than the retention
+// are left alone, so uploads in progress are never touched.
+func (as *ActiveStorage) CollectGarbage(ctx context.Context, options GCOptions) (*GCReport, error) {
+	if options.Retention <= 0 {
+		options.Retention = DefaultGCRetention
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultGCBatchSize
+	}
+	cutoff := time.Now().Add(-options.Retention)
+	report := &GCReport{DryRun: options.DryRun}
+
+	for modelName, fields := range as.configs {
+		table := modelName
+		for _, config := range fields {
+			if config.Table != "" {
+				table = config.Table
+			}
+		}
+		if err := as.collectAttachments(ctx, modelName, table, cutoff, options, report); err != nil {
+			return report, err
+		}
+	}
+
+	if err := as.collectFiles(ctx, cutoff, options, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// collectAttachments removes the attachments of a model whose records are gone
+func (as *ActiveStorage) collectAttachments(ctx context.Context, modelName, table string, cutoff time.Time, options GCOptions, report *GCReport) error {
+	// Without the table every attachment would look orphaned
+	if !as.db.Migrator().HasTable(table) {
+		return nil
+	}
+	owners := as.db.Table(table).Select("1").Where(table + ".id = attachments.model_id")
+	if as.db.Migrator().HasColumn(table, "deleted_at") {
+		owners = owners.Where("("+table+".deleted_at IS NULL OR "+table+".deleted_at > ?)", cutoff)
+	}
+
+	var lastId uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var batch []*Attachment
+		err := as.db.WithContext(ctx).
+			Where("model_type = ? AND created_at < ? AND id > ?", modelName, cutoff, lastId).
+			Where("NOT EXISTS (?)", owners).
+			Order("id").
+			Limit(options.BatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return fmt.Errorf("failed to find orphaned %s attachments: %w", modelName, err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, attachment := range batch {
+			lastId = attachment.Id
+			freed := as.references(attachment.Provider, attachment.Path) == 1
+			if !options.DryRun {
+				if err := as.Delete(attachment); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("attachment %d: %s", attachment.Id, err.Error()))
+					continue
+				}
+			}
+			report.Attachments++
+			if freed {
+				report.Bytes += attachment.Size
+			}
+		}
+	}
+}
+
+// collectFiles removes files in attachment folders that no attachment references
+func (as *ActiveStorage) collectFiles(ctx context.Context, cutoff time.Time, options GCOptions, report *GCReport) error {
+	folders := make(map[string]bool)
+	for modelName, fields := range as.configs {
+		for field, config := range fields {
+			folders[uploadPath(config, modelName, field)+"/"] = true
+		}
+	}
+
+	var batch []string
+	var batchSize int64
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if !options.DryRun {
+			if err := as.provider.DeleteMany(batch); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+				batch, batchSize = batch[:0], 0
+				return
+			}
+		}
+		report.Files += len(batch)
+		report.Bytes += batchSize
+		batch, batchSize = batch[:0], 0
+	}
+
+	for folder := range folders {
+		err := as.provider.List(folder, func(file FileInfo) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if file.ModTime.After(cutoff) || as.referenced(file.Path) {
+				return nil
+			}
+			batch = append(batch, file.Path)
+			batchSize += file.Size
+			if len(batch) >= options.BatchSize {
+				flush()
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", folder, err)
+		}
+	}
+	flush()
+	return nil
+}
+
+// referenced reports whether an attachment on the primary provider uses a stored
+// file. Variants are kept as long as their original is.
+func (as *ActiveStorage) referenced(key string) bool {
+	if i := strings.LastIndex(key, "/variants/"); i >= 0 {
+		name := path.Base(key)
+		base := strings.TrimSuffix(name, path.Ext(name))
+		var count int64
+		as.db.Model(&Attachment{}).Where("provider = ? AND path LIKE ?", as.name, key[:i]+"/"+base+".%").Count(&count)
+		return count > 0
+	}
+	return as.references(as.name, key) > 0
+}
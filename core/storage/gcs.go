@@ -0,0 +1,322 @@
+package storage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	gcs "google.golang.org/api/storage/v1"
+)
+
+// gcsMaxSignedExpiry is the longest lifetime GCS accepts for a V4 signed URL
+const gcsMaxSignedExpiry = 7 * 24 * time.Hour
+
+// GCSConfig holds configuration for Google Cloud Storage
+type GCSConfig struct {
+	CredentialsFile string // Service account key; application default credentials when empty
+	Endpoint        string // JSON API endpoint, e.g. of fake-gcs-server; defaults to Google's
+	Bucket          string
+	BaseURL         string // Public URL of the bucket, e.g. a CDN
+	UniformAccess   bool   // The bucket grants public reads by policy instead of object ACLs
+}
+
+type gcsProvider struct {
+	service       *gcs.Service
+	bucket        string
+	baseURL       string
+	scheme        string
+	host          string
+	uniformAccess bool
+
+	// Service account used to sign URLs, nil without a key file
+	clientEmail string
+	signingKey  *rsa.PrivateKey
+}
+
+func NewGCSProvider(config GCSConfig) (Provider, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("gcs storage requires a bucket")
+	}
+
+	var options []option.ClientOption
+	scheme, host := "https", "storage.googleapis.com"
+	if config.Endpoint != "" {
+		endpoint, err := url.Parse(config.Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid gcs endpoint: %s", config.Endpoint)
+		}
+		scheme, host = endpoint.Scheme, endpoint.Host
+		if endpoint.Path == "" || endpoint.Path == "/" {
+			endpoint.Path = "/storage/v1/"
+		}
+		options = append(options, option.WithEndpoint(endpoint.String()))
+	}
+
+	provider := &gcsProvider{
+		bucket:        config.Bucket,
+		baseURL:       strings.TrimRight(config.BaseURL, "/"),
+		scheme:        scheme,
+		host:          host,
+		uniformAccess: config.UniformAccess,
+	}
+
+	switch {
+	case scheme == "http":
+		// Plain HTTP endpoints are local stand-ins such as fake-gcs-server, which
+		// take no credentials; a key file may still be given to sign URLs
+		options = append(options, option.WithoutAuthentication())
+	case config.CredentialsFile != "":
+		options = append(options, option.WithCredentialsFile(config.CredentialsFile))
+	}
+	if config.CredentialsFile != "" {
+		if err := provider.loadSigningKey(config.CredentialsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	service, err := gcs.NewService(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcs client: %w", err)
+	}
+	provider.service = service
+	return provider, nil
+}
+
+// loadSigningKey reads the service account email and private key used for signed URLs
+func (p *gcsProvider) loadSigningKey(credentialsFile string) error {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read gcs credentials: %w", err)
+	}
+	var credentials struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return fmt.Errorf("failed to parse gcs credentials: %w", err)
+	}
+	if credentials.ClientEmail == "" || credentials.PrivateKey == "" {
+		// Not a service account key, e.g. user credentials; URLs cannot be signed
+		return nil
+	}
+
+	block, _ := pem.Decode([]byte(credentials.PrivateKey))
+	if block == nil {
+		return fmt.Errorf("gcs credentials hold no PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse gcs private key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("gcs private key is not an RSA key")
+	}
+	p.clientEmail = credentials.ClientEmail
+	p.signingKey = rsaKey
+	return nil
+}
+
+func (p *gcsProvider) Upload(file *multipart.FileHeader, config UploadConfig) (*UploadResult, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer src.Close()
+
+	filename := generateUniqueFilename(file.Filename)
+	key := fmt.Sprintf("%s/%s", config.UploadPath, filename)
+	if _, err := p.Put(key, src, file.Size, file.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+
+	return &UploadResult{
+		Filename: filename,
+		Path:     key,
+		Size:     file.Size,
+	}, nil
+}
+
+// Put streams the reader to the bucket; large bodies are sent as a resumable upload
+func (p *gcsProvider) Put(key string, reader io.Reader, size int64, contentType string) (*UploadResult, error) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	call := p.service.Objects.Insert(p.bucket, &gcs.Object{Name: key, ContentType: contentType}).
+		Media(reader, googleapi.ContentType(contentType))
+	if !p.uniformAccess && !IsPrivate(key) {
+		call = call.PredefinedAcl("publicRead")
+	}
+	object, err := call.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+
+	return &UploadResult{
+		Filename: path.Base(key),
+		Path:     key,
+		Size:     int64(object.Size),
+	}, nil
+}
+
+func (p *gcsProvider) Open(path string) (io.ReadCloser, error) {
+	response, err := p.service.Objects.Get(p.bucket, path).Download()
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+func (p *gcsProvider) Delete(path string) error {
+	return p.service.Objects.Delete(p.bucket, path).Do()
+}
+
+// DeleteMany removes objects one by one, as the JSON API has no bulk delete;
+// objects already gone are skipped
+func (p *gcsProvider) DeleteMany(paths []string) error {
+	for _, path := range paths {
+		if err := p.Delete(path); err != nil && !isGCSNotFound(err) {
+			return fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// List pages through the objects whose names start with prefix
+func (p *gcsProvider) List(prefix string, fn func(file FileInfo) error) error {
+	var fnErr error
+	err := p.service.Objects.List(p.bucket).Prefix(prefix).Pages(context.Background(), func(page *gcs.Objects) error {
+		for _, object := range page.Items {
+			modTime, _ := time.Parse(time.RFC3339, object.Updated)
+			if fnErr = fn(FileInfo{Path: object.Name, Size: int64(object.Size), ModTime: modTime}); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+	return nil
+}
+
+func (p *gcsProvider) GetURL(path string) string {
+	if p.baseURL != "" {
+		return fmt.Sprintf("%s/%s", p.baseURL, path)
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", p.scheme, p.host, p.bucket, escapeObjectPath(path))
+}
+
+// SignedURL returns a V4 signed GET URL, signed with the service account key
+func (p *gcsProvider) SignedURL(path string, expires time.Duration) (string, error) {
+	if p.signingKey == nil {
+		return "", fmt.Errorf("gcs signed URLs need a service account key file")
+	}
+	if expires > gcsMaxSignedExpiry {
+		expires = gcsMaxSignedExpiry
+	}
+
+	now := time.Now().UTC()
+	scope := now.Format("20060102") + "/auto/storage/goog4_request"
+	query := url.Values{
+		"X-Goog-Algorithm":     {"GOOG4-RSA-SHA256"},
+		"X-Goog-Credential":    {p.clientEmail + "/" + scope},
+		"X-Goog-Date":          {now.Format("20060102T150405Z")},
+		"X-Goog-Expires":       {fmt.Sprintf("%d", int64(expires.Seconds()))},
+		"X-Goog-SignedHeaders": {"host"},
+	}
+	resource := "/" + p.bucket + "/" + escapeObjectPath(path)
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		resource,
+		canonicalQuery,
+		"host:" + p.host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"GOOG4-RSA-SHA256",
+		query.Get("X-Goog-Date"),
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.signingKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GCS URL: %w", err)
+	}
+	return fmt.Sprintf("%s://%s%s?%s&X-Goog-Signature=%s",
+		p.scheme, p.host, resource, canonicalQuery, hex.EncodeToString(signature)), nil
+}
+
+// canonicalQueryString sorts and percent-encodes query parameters as V4 signing expects
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, escapeSigned(key, false)+"="+escapeSigned(query.Get(key), false))
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapeObjectPath percent-encodes an object name, keeping its slashes
+func escapeObjectPath(name string) string {
+	return escapeSigned(name, true)
+}
+
+// escapeSigned percent-encodes everything but unreserved characters, and slashes
+// when keepSlash is set
+func escapeSigned(value string, keepSlash bool) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', keepSlash && c == '/':
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
+
+// isGCSNotFound reports whether a GCS call failed because the object does not exist
+func isGCSNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
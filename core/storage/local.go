@@ -3,20 +3,31 @@ package storage
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // LocalConfig holds configuration for local storage
 type LocalConfig struct {
-	BasePath string
-	BaseURL  string
+	BasePath      string
+	BaseURL       string
+	PrivatePath   string // Directory for private files, outside the statically served one
+	SigningKey    string
+	SignedURLBase string // URL of the endpoint that serves signed private files
 }
 
 type localProvider struct {
-	basePath string
-	baseURL  string
+	basePath      string
+	baseURL       string
+	privatePath   string
+	signingKey    []byte
+	signedURLBase string
 }
 
 func NewLocalProvider(config LocalConfig) (Provider, error) {
@@ -25,15 +36,31 @@ func NewLocalProvider(config LocalConfig) (Provider, error) {
 		return nil, fmt.Errorf("failed to create base directory: %w", err)
 	}
 
+	privatePath := config.PrivatePath
+	if privatePath == "" {
+		privatePath = filepath.Join(filepath.Dir(config.BasePath), "private")
+	}
+
 	return &localProvider{
-		basePath: config.BasePath,
-		baseURL:  config.BaseURL,
+		basePath:      config.BasePath,
+		baseURL:       config.BaseURL,
+		privatePath:   privatePath,
+		signingKey:    []byte(config.SigningKey),
+		signedURLBase: strings.TrimRight(config.SignedURLBase, "/"),
 	}, nil
 }
 
+// fullPath maps a storage key to a file; private keys live under the private directory
+func (p *localProvider) fullPath(path string) string {
+	if IsPrivate(path) {
+		return filepath.Join(p.privatePath, strings.TrimPrefix(path, PrivatePrefix))
+	}
+	return filepath.Join(p.basePath, path)
+}
+
 func (p *localProvider) Upload(file *multipart.FileHeader, config UploadConfig) (*UploadResult, error) {
 	// Create upload directory
-	uploadPath := filepath.Join(p.basePath, config.UploadPath)
+	uploadPath := p.fullPath(config.UploadPath)
 	if err := os.MkdirAll(uploadPath, os.ModePerm); err != nil {
 		return nil, fmt.Errorf("failed to create upload directory: %w", err)
 	}
@@ -70,11 +97,104 @@ func (p *localProvider) Upload(file *multipart.FileHeader, config UploadConfig)
 	}, nil
 }
 
+func (p *localProvider) Put(path string, reader io.Reader, size int64, contentType string) (*UploadResult, error) {
+	dst := p.fullPath(path)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination file: %w", err)
+	}
+	defer out.Close()
+
+	written, err := io.Copy(out, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	return &UploadResult{
+		Filename: filepath.Base(path),
+		Path:     path,
+		Size:     written,
+	}, nil
+}
+
+func (p *localProvider) Open(path string) (io.ReadCloser, error) {
+	return os.Open(p.fullPath(path))
+}
+
 func (p *localProvider) Delete(path string) error {
-	fullPath := filepath.Join(p.basePath, path)
-	return os.Remove(fullPath)
+	return os.Remove(p.fullPath(path))
+}
+
+// DeleteMany removes files one by one; files already gone are skipped
+func (p *localProvider) DeleteMany(paths []string) error {
+	for _, path := range paths {
+		if err := os.Remove(p.fullPath(path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List walks the files whose keys start with prefix, which must be a folder
+func (p *localProvider) List(prefix string, fn func(file FileInfo) error) error {
+	root := p.fullPath(prefix)
+	keyPrefix := strings.TrimSuffix(prefix, "/")
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		return fn(FileInfo{
+			Path:    path.Join(keyPrefix, filepath.ToSlash(rel)),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
 }
 
 func (p *localProvider) GetURL(path string) string {
 	return fmt.Sprintf("%s/%s", p.baseURL, path)
 }
+
+// SignedURL links to the signed files endpoint with an HMAC of the path and expiry
+func (p *localProvider) SignedURL(path string, expires time.Duration) (string, error) {
+	if len(p.signingKey) == 0 {
+		return "", fmt.Errorf("storage signing key is not configured")
+	}
+	expiresAt := time.Now().Add(expires).Unix()
+	return fmt.Sprintf("%s/%s?expires=%d&signature=%s",
+		p.signedURLBase, path, expiresAt, signPath(p.signingKey, path, expiresAt)), nil
+}
+
+// signedFile verifies a signed URL and returns the file it points to
+func (p *localProvider) signedFile(path, expires, signature string) (string, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	if err := verifyPath(p.signingKey, path, expiresAt, signature); err != nil {
+		return "", err
+	}
+	if !IsPrivate(path) || strings.Contains(path, "..") {
+		return "", ErrInvalidSignature
+	}
+	return p.fullPath(path), nil
+}
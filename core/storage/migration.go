@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoFallback is returned when migrating without a fallback provider to copy from
+var ErrNoFallback = errors.New("no fallback storage provider configured")
+
+// MigrateReport summarizes a Migrate run
+type MigrateReport struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	DryRun  bool     `json:"dry_run"`
+	Scanned int      `json:"scanned"`
+	Copied  int      `json:"copied"` // Files copied, or to copy on a dry run
+	Shared  int      `json:"shared"` // Attachments whose blob was already copied for another one
+	Bytes   int64    `json:"bytes"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// dualProvider is the primary provider during a migration. Reads of files it does
+// not have yet, such as for attachments looked up just before they were copied,
+// fall back to the previous provider; everything else goes to the primary one.
+type dualProvider struct {
+	Provider
+	fallback Provider
+}
+
+func (p dualProvider) Open(path string) (io.ReadCloser, error) {
+	reader, err := p.Provider.Open(path)
+	if err != nil {
+		if fallback, fallbackErr := p.fallback.Open(path); fallbackErr == nil {
+			return fallback, nil
+		}
+	}
+	return reader, err
+}
+
+// providerFor returns the provider recorded on attachments by its name
+func (as *ActiveStorage) providerFor(name string) Provider {
+	if as.fallback == nil {
+		return as.provider
+	}
+	if name == as.fallbackName {
+		return as.fallback
+	}
+	return dualProvider{Provider: as.provider, fallback: as.fallback}
+}
+
+// locate returns the name of the provider holding an attachment. The attachment row
+// is checked first, since copies of attachments kept on records may have been made
+// before the file was migrated.
+func (as *ActiveStorage) locate(attachment *Attachment) string {
+	if as.fallback != nil && attachment.Id != 0 {
+		var row Attachment
+		if err := as.db.Select("provider").First(&row, attachment.Id).Error; err == nil {
+			return row.Provider
+		}
+	}
+	if attachment.Provider == "" && as.fallback != nil {
+		return as.fallbackName
+	}
+	return attachment.Provider
+}
+
+// Migrate copies the files of attachments on the fallback provider to the primary
+// provider. Each copy is verified against the checksum of its source before the
+// attachment is switched over, so reads use the fallback until then. Files are not
+// removed from the fallback. An interrupted run resumes with the attachments left;
+// a dry run only reports what would be copied.
+func (as *ActiveStorage) Migrate(ctx context.Context, dryRun bool) (*MigrateReport, error) {
+	if as.fallback == nil {
+		return nil, ErrNoFallback
+	}
+	report := &MigrateReport{From: as.fallbackName, To: as.name, DryRun: dryRun}
+	planned := make(map[string]bool) // Files a dry run counted as copied
+
+	var batch []*Attachment
+	err := as.db.WithContext(ctx).Where("provider = ?", as.fallbackName).FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for _, attachment := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			report.Scanned++
+			if err := as.migrate(attachment, dryRun, planned, report); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("attachment %d: %s", attachment.Id, err.Error()))
+			}
+		}
+		return nil
+	}).Error
+	return report, err
+}
+
+// migrate copies one attachment to the primary provider and switches it over
+func (as *ActiveStorage) migrate(attachment *Attachment, dryRun bool, planned map[string]bool, report *MigrateReport) error {
+	// Attachments sharing a blob need it copied once
+	shared := as.references(as.name, attachment.Path) > 0
+
+	if dryRun {
+		if shared || planned[attachment.Path] {
+			report.Shared++
+			return nil
+		}
+		reader, err := as.fallback.Open(attachment.Path)
+		if err != nil {
+			return err
+		}
+		reader.Close()
+		planned[attachment.Path] = true
+		report.Copied++
+		report.Bytes += attachment.Size
+		return nil
+	}
+
+	if !shared {
+		checksum, err := as.transfer(attachment)
+		if err != nil {
+			return err
+		}
+		attachment.Checksum = checksum
+	}
+
+	attachment.Provider = as.name
+	attachment.URL = as.publicURL(attachment)
+	if err := as.db.Model(attachment).Select("provider", "url", "checksum").Updates(attachment).Error; err != nil {
+		return err
+	}
+
+	if config, err := as.getConfig(attachment.ModelType, attachment.Field); err == nil {
+		if err := as.attachVariants(attachment, config); err != nil {
+			return err
+		}
+		if config.OnUpdate != nil {
+			if err := config.OnUpdate(attachment); err != nil {
+				return err
+			}
+		}
+	}
+
+	if shared {
+		report.Shared++
+	} else {
+		report.Copied++
+		report.Bytes += attachment.Size
+	}
+	return nil
+}
+
+// transfer copies the file of an attachment from the fallback to the primary
+// provider and returns its checksum. The source must match the recorded checksum
+// and the copy must read back the same, otherwise the copy is removed.
+func (as *ActiveStorage) transfer(attachment *Attachment) (string, error) {
+	reader, err := as.fallback.Open(attachment.Path)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = as.ContentType(attachment.Path)
+	}
+
+	hash := sha256.New()
+	if _, err := as.provider.Put(attachment.Path, io.TeeReader(reader, hash), attachment.Size, contentType); err != nil {
+		return "", err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	if attachment.Checksum != "" && checksum != attachment.Checksum {
+		_ = as.provider.Delete(attachment.Path)
+		return "", fmt.Errorf("source file does not match its checksum")
+	}
+	copied, err := hashStored(as.provider, attachment.Path)
+	if err != nil {
+		return "", err
+	}
+	if copied != checksum {
+		_ = as.provider.Delete(attachment.Path)
+		return "", fmt.Errorf("copied file does not match its source")
+	}
+	return checksum, nil
+}
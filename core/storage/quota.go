@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"base/core/errors"
+
+	"gorm.io/gorm"
+)
+
+// QuotaUser is the subject type of per-user usage
+const QuotaUser = "user"
+
+// Usage is the storage taken by the files of a user or an owner record
+type Usage struct {
+	SubjectType string `json:"subject_type"` // QuotaUser or an owner type such as "course"
+	SubjectId   uint   `json:"subject_id"`
+	Files       int64  `json:"files"`
+	Bytes       int64  `json:"bytes"`
+	Limit       int64  `json:"limit"` // 0 when there is no quota
+}
+
+// UserUsage returns the storage taken by the files a user uploaded
+func (as *ActiveStorage) UserUsage(userId uint) (*Usage, error) {
+	usage := &Usage{}
+	err := as.db.Model(&Attachment{}).Select("COUNT(*) AS files, COALESCE(SUM(size), 0) AS bytes").
+		Where("uploaded_by = ?", userId).Scan(usage).Error
+	usage.SubjectType, usage.SubjectId, usage.Limit = QuotaUser, userId, as.userQuota
+	return usage, err
+}
+
+// OwnerUsage returns the storage taken by the files of an owner record
+func (as *ActiveStorage) OwnerUsage(ownerType string, ownerId uint) (*Usage, error) {
+	usage := &Usage{}
+	err := as.db.Model(&Attachment{}).Select("COUNT(*) AS files, COALESCE(SUM(size), 0) AS bytes").
+		Where("owner_type = ? AND owner_id = ?", ownerType, ownerId).Scan(usage).Error
+	usage.SubjectType, usage.SubjectId, usage.Limit = ownerType, ownerId, as.ownerQuotas[ownerType]
+	return usage, err
+}
+
+// TopConsumers returns the users, or the owners of a type, taking the most storage
+func (as *ActiveStorage) TopConsumers(subjectType string, limit int) ([]*Usage, error) {
+	query := as.db.Model(&Attachment{}).Limit(limit).Order("bytes DESC")
+	quota := as.userQuota
+	if subjectType == QuotaUser {
+		query = query.Select("uploaded_by AS subject_id, COUNT(*) AS files, COALESCE(SUM(size), 0) AS bytes").
+			Where("uploaded_by > 0").Group("uploaded_by")
+	} else {
+		query = query.Select("owner_id AS subject_id, COUNT(*) AS files, COALESCE(SUM(size), 0) AS bytes").
+			Where("owner_type = ?", subjectType).Group("owner_id")
+		quota = as.ownerQuotas[subjectType]
+	}
+
+	var usages []*Usage
+	if err := query.Scan(&usages).Error; err != nil {
+		return nil, err
+	}
+	for _, usage := range usages {
+		usage.SubjectType = subjectType
+		usage.Limit = quota
+	}
+	return usages, nil
+}
+
+// OwnerTypes lists the owner types of the registered attachment configs
+func (as *ActiveStorage) OwnerTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, fields := range as.configs {
+		for _, config := range fields {
+			if config.Owner != nil && !seen[config.OwnerType] {
+				seen[config.OwnerType] = true
+				types = append(types, config.OwnerType)
+			}
+		}
+	}
+	return types
+}
+
+// assignOwner records the uploader and owner of a new attachment
+func (as *ActiveStorage) assignOwner(attachment *Attachment, config AttachmentConfig, uploadedBy uint) error {
+	attachment.UploadedBy = uploadedBy
+	if config.Owner == nil {
+		return nil
+	}
+	ownerId, err := config.Owner(attachment.ModelId)
+	if err != nil {
+		return fmt.Errorf("failed to find owner of %s %d: %w", attachment.ModelType, attachment.ModelId, err)
+	}
+	attachment.OwnerType = config.OwnerType
+	attachment.OwnerId = ownerId
+	return nil
+}
+
+// CheckQuota checks that a file of size bytes fits in the quotas before it is
+// uploaded, such as when a resumable upload starts
+func (as *ActiveStorage) CheckQuota(modelName, field string, modelId, uploadedBy uint, size int64) error {
+	config, err := as.getConfig(modelName, field)
+	if err != nil {
+		return err
+	}
+	attachment := &Attachment{ModelType: modelName, ModelId: modelId, Field: field, Size: size}
+	if err := as.assignOwner(attachment, config, uploadedBy); err != nil {
+		return err
+	}
+	return as.checkQuota(attachment, as.replaced(attachment, config))
+}
+
+// checkQuota fails with errors.CodeStorageQuotaExceeded when storing an attachment
+// would take its uploader or owner over quota. Files it replaces are not counted.
+func (as *ActiveStorage) checkQuota(attachment *Attachment, replaced []*Attachment) error {
+	if attachment.UploadedBy != 0 && as.userQuota > 0 {
+		usage, err := as.UserUsage(attachment.UploadedBy)
+		if err != nil {
+			return err
+		}
+		for _, old := range replaced {
+			if old.UploadedBy == attachment.UploadedBy {
+				usage.Bytes -= old.Size
+			}
+		}
+		if usage.Bytes+attachment.Size > usage.Limit {
+			return quotaError(usage, attachment.Size)
+		}
+	}
+
+	if limit := as.ownerQuotas[attachment.OwnerType]; attachment.OwnerType != "" && limit > 0 {
+		usage, err := as.OwnerUsage(attachment.OwnerType, attachment.OwnerId)
+		if err != nil {
+			return err
+		}
+		for _, old := range replaced {
+			if old.OwnerType == attachment.OwnerType && old.OwnerId == attachment.OwnerId {
+				usage.Bytes -= old.Size
+			}
+		}
+		if usage.Bytes+attachment.Size > usage.Limit {
+			return quotaError(usage, attachment.Size)
+		}
+	}
+	return nil
+}
+
+// quotaError describes an upload that does not fit in a quota
+func quotaError(usage *Usage, size int64) *errors.Error {
+	return errors.New(errors.CodeStorageQuotaExceeded, "Storage quota exceeded").
+		WithDetails(fmt.Sprintf("%s quota of %d bytes has %d bytes left", usage.SubjectType, usage.Limit, max(usage.Limit-usage.Bytes, 0))).
+		WithMetadata("subject_type", usage.SubjectType).
+		WithMetadata("subject_id", usage.SubjectId).
+		WithMetadata("limit", usage.Limit).
+		WithMetadata("used", usage.Bytes).
+		WithMetadata("size", size)
+}
+
+// QuotaStatus returns the HTTP status of a quota error: 413 for a file larger than the
+// whole quota, which can never be stored, and 507 for a quota that is used up
+func QuotaStatus(err error) (int, bool) {
+	var quotaErr *errors.Error
+	if !stderrors.As(err, &quotaErr) || quotaErr.Code != errors.CodeStorageQuotaExceeded {
+		return 0, false
+	}
+	if size, ok := quotaErr.Metadata["size"].(int64); ok {
+		if limit, ok := quotaErr.Metadata["limit"].(int64); ok && size > limit {
+			return http.StatusRequestEntityTooLarge, true
+		}
+	}
+	return quotaErr.HTTPStatus(), true
+}
+
+// BackfillOwners records the owners of attachments stored before owners were tracked
+func (as *ActiveStorage) BackfillOwners(ctx context.Context) (int, error) {
+	filled := 0
+	for modelName, fields := range as.configs {
+		for field, config := range fields {
+			if config.Owner == nil {
+				continue
+			}
+			var batch []*Attachment
+			err := as.db.WithContext(ctx).
+				Where("model_type = ? AND field = ? AND (owner_type = ? OR owner_type IS NULL)", modelName, field, "").
+				FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+					for _, attachment := range batch {
+						if err := as.assignOwner(attachment, config, attachment.UploadedBy); err != nil {
+							continue
+						}
+						if err := as.db.Model(attachment).Select("owner_type", "owner_id").Updates(attachment).Error; err != nil {
+							return err
+						}
+						filled++
+					}
+					return nil
+				}).Error
+			if err != nil {
+				return filled, err
+			}
+		}
+	}
+	return filled, nil
+}
@@ -2,13 +2,17 @@ package storage
 
 import (
 	"fmt"
+	"io"
 	"mime/multipart"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 // R2Config holds configuration for Cloudflare R2 storage
@@ -89,6 +93,37 @@ func (p *r2Provider) Upload(file *multipart.FileHeader, config UploadConfig) (*U
 	}, nil
 }
 
+// Put streams the reader to the bucket; large bodies are sent as a multipart upload
+func (p *r2Provider) Put(key string, reader io.Reader, size int64, contentType string) (*UploadResult, error) {
+	uploader := s3manager.NewUploaderWithClient(p.client)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(p.bucket),
+		Key:         aws.String(key),
+		Body:        reader,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to R2: %w", err)
+	}
+
+	return &UploadResult{
+		Filename: path.Base(key),
+		Path:     key,
+		Size:     size,
+	}, nil
+}
+
+func (p *r2Provider) Open(path string) (io.ReadCloser, error) {
+	output, err := p.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
 func (p *r2Provider) Delete(path string) error {
 	_, err := p.client.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: aws.String(p.bucket),
@@ -97,6 +132,14 @@ func (p *r2Provider) Delete(path string) error {
 	return err
 }
 
+func (p *r2Provider) DeleteMany(paths []string) error {
+	return deleteObjects(p.client, p.bucket, paths)
+}
+
+func (p *r2Provider) List(prefix string, fn func(file FileInfo) error) error {
+	return listObjects(p.client, p.bucket, prefix, fn)
+}
+
 func (p *r2Provider) GetURL(path string) string {
 	// Always prefer CDN for R2 storage
 	if p.cdn != "" {
@@ -109,3 +152,16 @@ func (p *r2Provider) GetURL(path string) string {
 	// Last resort: use R2 URL
 	return fmt.Sprintf("https://%s/%s/%s", p.endpoint, p.bucket, path)
 }
+
+// SignedURL returns a presigned GET URL for the object
+func (p *r2Provider) SignedURL(path string, expires time.Duration) (string, error) {
+	req, _ := p.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(path),
+	})
+	url, err := req.Presign(expires)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign R2 URL: %w", err)
+	}
+	return url, nil
+}
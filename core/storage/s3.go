@@ -2,12 +2,16 @@ package storage
 
 import (
 	"fmt"
+	"io"
 	"mime/multipart"
+	"path"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 // S3Config holds configuration for S3 storage
@@ -71,7 +75,7 @@ func (p *s3Provider) Upload(file *multipart.FileHeader, config UploadConfig) (*U
 		Bucket: aws.String(p.bucket),
 		Key:    aws.String(key),
 		Body:   src,
-		ACL:    aws.String("public-read"),
+		ACL:    objectACL(key),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload to S3: %w", err)
@@ -84,6 +88,46 @@ func (p *s3Provider) Upload(file *multipart.FileHeader, config UploadConfig) (*U
 	}, nil
 }
 
+// Put streams the reader to the bucket; large bodies are sent as a multipart upload
+func (p *s3Provider) Put(key string, reader io.Reader, size int64, contentType string) (*UploadResult, error) {
+	uploader := s3manager.NewUploaderWithClient(p.client)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(p.bucket),
+		Key:         aws.String(key),
+		Body:        reader,
+		ContentType: aws.String(contentType),
+		ACL:         objectACL(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to S3: %w", err)
+	}
+
+	return &UploadResult{
+		Filename: path.Base(key),
+		Path:     key,
+		Size:     size,
+	}, nil
+}
+
+// objectACL makes objects publicly readable unless they are private
+func objectACL(key string) *string {
+	if IsPrivate(key) {
+		return nil
+	}
+	return aws.String("public-read")
+}
+
+func (p *s3Provider) Open(path string) (io.ReadCloser, error) {
+	output, err := p.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
 func (p *s3Provider) Delete(path string) error {
 	_, err := p.client.DeleteObject(&s3.DeleteObjectInput{
 		Bucket: aws.String(p.bucket),
@@ -92,6 +136,78 @@ func (p *s3Provider) Delete(path string) error {
 	return err
 }
 
+func (p *s3Provider) DeleteMany(paths []string) error {
+	return deleteObjects(p.client, p.bucket, paths)
+}
+
+func (p *s3Provider) List(prefix string, fn func(file FileInfo) error) error {
+	return listObjects(p.client, p.bucket, prefix, fn)
+}
+
 func (p *s3Provider) GetURL(path string) string {
 	return fmt.Sprintf("https://%s/%s/%s", p.endpoint, p.bucket, path)
 }
+
+// SignedURL returns a presigned GET URL for the object
+func (p *s3Provider) SignedURL(path string, expires time.Duration) (string, error) {
+	req, _ := p.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(path),
+	})
+	url, err := req.Presign(expires)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign S3 URL: %w", err)
+	}
+	return url, nil
+}
+
+// deleteObjectsLimit is the most keys a DeleteObjects request accepts
+const deleteObjectsLimit = 1000
+
+// deleteObjects removes objects with as few DeleteObjects requests as possible
+func deleteObjects(client *s3.S3, bucket string, paths []string) error {
+	for start := 0; start < len(paths); start += deleteObjectsLimit {
+		chunk := paths[start:min(start+deleteObjectsLimit, len(paths))]
+		objects := make([]*s3.ObjectIdentifier, len(chunk))
+		for i, key := range chunk {
+			objects[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+		}
+		output, err := client.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects: %w", err)
+		}
+		if len(output.Errors) > 0 {
+			failed := output.Errors[0]
+			return fmt.Errorf("failed to delete %d objects, first %s: %s", len(output.Errors), aws.StringValue(failed.Key), aws.StringValue(failed.Message))
+		}
+	}
+	return nil
+}
+
+// listObjects pages through the objects whose keys start with prefix
+func listObjects(client *s3.S3, bucket, prefix string, fn func(file FileInfo) error) error {
+	var fnErr error
+	err := client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			fnErr = fn(FileInfo{
+				Path:    aws.StringValue(object.Key),
+				Size:    aws.Int64Value(object.Size),
+				ModTime: aws.TimeValue(object.LastModified),
+			})
+			if fnErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+	return fnErr
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3CompatConfig holds configuration for S3 compatible servers such as MinIO or Ceph
+type S3CompatConfig struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	Endpoint        string // Server URL with its scheme, e.g. http://localhost:9000
+	Region          string // Defaults to us-east-1, which most servers accept
+	Bucket          string
+	BaseURL         string // Public URL of the bucket, e.g. a CDN
+	UniformAccess   bool   // The bucket grants public reads by policy instead of object ACLs
+}
+
+type s3CompatProvider struct {
+	client        *s3.S3
+	bucket        string
+	endpoint      string
+	baseURL       string
+	uniformAccess bool
+}
+
+// NewS3CompatProvider creates a provider for a self-hosted S3 API. Buckets are
+// addressed by path, as such servers rarely have wildcard DNS, and requests go
+// through a client with timeouts so a stalled server cannot hang uploads.
+func NewS3CompatProvider(config S3CompatConfig) (Provider, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("s3compat storage requires an http(s) endpoint, got %q", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3compat storage requires a bucket")
+	}
+	if config.AccessKeyID == "" || config.AccessKeySecret == "" {
+		return nil, fmt.Errorf("s3compat storage requires an access key and secret")
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: time.Minute,
+			ExpectContinueTimeout: time.Second,
+			MaxIdleConnsPerHost:   16,
+		},
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      credentials.NewStaticCredentials(config.AccessKeyID, config.AccessKeySecret, ""),
+		Endpoint:         aws.String(strings.TrimRight(endpoint.String(), "/")),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(true),
+		DisableSSL:       aws.Bool(endpoint.Scheme == "http"),
+		HTTPClient:       httpClient,
+		MaxRetries:       aws.Int(3),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &s3CompatProvider{
+		client:        s3.New(sess),
+		bucket:        config.Bucket,
+		endpoint:      strings.TrimRight(endpoint.String(), "/"),
+		baseURL:       strings.TrimRight(config.BaseURL, "/"),
+		uniformAccess: config.UniformAccess,
+	}, nil
+}
+
+func (p *s3CompatProvider) Upload(file *multipart.FileHeader, config UploadConfig) (*UploadResult, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer src.Close()
+
+	filename := generateUniqueFilename(file.Filename)
+	key := fmt.Sprintf("%s/%s", config.UploadPath, filename)
+	if _, err := p.Put(key, src, file.Size, file.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+
+	return &UploadResult{
+		Filename: filename,
+		Path:     key,
+		Size:     file.Size,
+	}, nil
+}
+
+// Put streams the reader to the bucket; large bodies are sent as a multipart upload
+func (p *s3CompatProvider) Put(key string, reader io.Reader, size int64, contentType string) (*UploadResult, error) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	input := &s3manager.UploadInput{
+		Bucket:      aws.String(p.bucket),
+		Key:         aws.String(key),
+		Body:        reader,
+		ContentType: aws.String(contentType),
+	}
+	if !p.uniformAccess {
+		input.ACL = objectACL(key)
+	}
+
+	uploader := s3manager.NewUploaderWithClient(p.client)
+	if _, err := uploader.Upload(input); err != nil {
+		return nil, fmt.Errorf("failed to upload to %s: %w", p.endpoint, err)
+	}
+
+	return &UploadResult{
+		Filename: path.Base(key),
+		Path:     key,
+		Size:     size,
+	}, nil
+}
+
+func (p *s3CompatProvider) Open(path string) (io.ReadCloser, error) {
+	output, err := p.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (p *s3CompatProvider) Delete(path string) error {
+	_, err := p.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(path),
+	})
+	return err
+}
+
+func (p *s3CompatProvider) DeleteMany(paths []string) error {
+	return deleteObjects(p.client, p.bucket, paths)
+}
+
+func (p *s3CompatProvider) List(prefix string, fn func(file FileInfo) error) error {
+	return listObjects(p.client, p.bucket, prefix, fn)
+}
+
+func (p *s3CompatProvider) GetURL(path string) string {
+	if p.baseURL != "" {
+		return fmt.Sprintf("%s/%s", p.baseURL, path)
+	}
+	return fmt.Sprintf("%s/%s/%s", p.endpoint, p.bucket, escapeObjectPath(path))
+}
+
+// SignedURL returns a presigned GET URL for the object
+func (p *s3CompatProvider) SignedURL(path string, expires time.Duration) (string, error) {
+	req, _ := p.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(path),
+	})
+	url, err := req.Presign(expires)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign URL: %w", err)
+	}
+	return url, nil
+}
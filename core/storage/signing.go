@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrivatePrefix marks storage keys of private files. They are kept out of the
+// public storage location and are only reachable through signed URLs.
+const PrivatePrefix = "private/"
+
+var (
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+	ErrNotPrivate       = errors.New("file is not private")
+)
+
+// IsPrivate reports whether a storage key belongs to a private file
+func IsPrivate(path string) bool {
+	return strings.HasPrefix(path, PrivatePrefix)
+}
+
+// signPath returns the HMAC-SHA256 signature of a path and its expiry time
+func signPath(key []byte, path string, expires int64) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyPath checks a signature created by signPath and that it has not expired
+func verifyPath(key []byte, path string, expires int64, signature string) error {
+	if len(key) == 0 || time.Now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+	expected := signPath(key, path, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
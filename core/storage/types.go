@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/textproto"
 	"os"
@@ -22,9 +23,26 @@ type Attachment struct {
 	Filename  string    `json:"filename"`
 	Path      string    `json:"path"`
 	Size      int64     `json:"size"`
-	URL       string    `json:"url"`
+	URL       string    `json:"url"` // Empty for private files
+	Private   bool      `json:"private"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// ContentType is detected from the file content, not taken from the client
+	ContentType string `json:"content_type"`
+	// Checksum is the SHA-256 of the content; attachments with the same content in
+	// the same field share one stored blob
+	Checksum string `json:"checksum" gorm:"size:64;index"`
+	// Provider names the storage provider holding the file, see ProviderName
+	Provider string `json:"provider" gorm:"size:128;index"`
+	// UploadedBy is the user the file counts against; 0 when it was not uploaded by a user
+	UploadedBy uint `json:"uploaded_by,omitempty" gorm:"index"`
+	// OwnerType and OwnerId name the record whose quota the file counts against, such
+	// as the course of a lesson video, see AttachmentConfig.Owner
+	OwnerType string `json:"owner_type,omitempty" gorm:"size:64;index:idx_attachments_owner"`
+	OwnerId   uint   `json:"owner_id,omitempty" gorm:"index:idx_attachments_owner"`
+	// Variants maps variant names to their URLs; private files have none
+	Variants map[string]string `json:"variants,omitempty" gorm:"serializer:json"`
 }
 
 // Value implements the driver.Valuer interface
@@ -75,8 +93,29 @@ type AttachmentConfig struct {
 	Field             string
 	Path              string
 	AllowedExtensions []string
+	AllowedTypes      []string // Content types, e.g. "image/*"; defaults to the types of AllowedExtensions
 	MaxFileSize       int64
 	Multiple          bool
+	Private           bool   // Private files are only reachable through signed URLs
+	Table             string // Table of the records files belong to; defaults to the model name
+
+	// Authorize, if set, checks that a user may attach files to a record.
+	// Fields without it are not available for resumable uploads.
+	Authorize func(userId, modelId uint) error
+	// OnAttach, if set, runs after a streamed file has been attached
+	OnAttach func(attachment *Attachment) error
+	// OnUpdate, if set, runs when the stored file of an attachment moves, such as when
+	// duplicates are collapsed, so copies of the attachment kept on records follow it
+	OnUpdate func(attachment *Attachment) error
+	// OwnerType and Owner, if set, count the files of a record against the quota of
+	// the record that owns it, e.g. "course" and the course of a lesson
+	OwnerType string
+	Owner     func(modelId uint) (uint, error)
+
+	// Variants are resized copies of image files, such as thumbnails
+	Variants []Variant
+	// LazyVariants generates variants on their first request instead of on upload
+	LazyVariants bool
 }
 
 // Config holds storage service configuration
@@ -91,6 +130,22 @@ type Config struct {
 	Bucket    string
 	CDN       string
 	Region    string
+
+	CredentialsFile string // Service account key file of the gcs provider
+	UniformAccess   bool   // Public reads are granted by bucket policy, not object ACLs (gcs, s3compat)
+
+	PrivatePath   string // Local directory for private files
+	SigningKey    string // HMAC key for local signed URLs
+	SignedURLBase string // URL of the endpoint that serves local signed files
+
+	VariantURLBase string // URL of the endpoint that generates lazy variants
+
+	UserQuota   int64            // Bytes a user may upload; 0 for no limit
+	OwnerQuotas map[string]int64 // Bytes the files of an owner may take by owner type
+
+	// Fallback is the previous provider while files are migrated off it. Attachments
+	// not yet copied are read from it; new files always go to the primary provider.
+	Fallback *Config
 }
 
 // Attachable interface for models that can have attachments
@@ -102,16 +157,29 @@ type Attachable interface {
 // Provider interface for storage providers
 type Provider interface {
 	Upload(file *multipart.FileHeader, config UploadConfig) (*UploadResult, error)
+	Put(path string, reader io.Reader, size int64, contentType string) (*UploadResult, error)
+	Open(path string) (io.ReadCloser, error)
 	Delete(path string) error
+	DeleteMany(paths []string) error
+	List(prefix string, fn func(file FileInfo) error) error
 	GetURL(path string) string
+	SignedURL(path string, expires time.Duration) (string, error)
 }
 
 // ActiveStorage handles file storage operations
 type ActiveStorage struct {
 	db          *gorm.DB
 	provider    Provider
+	name        string
 	defaultPath string
+	variantURL  string
 	configs     map[string]map[string]AttachmentConfig
+	userQuota   int64
+	ownerQuotas map[string]int64
+
+	// Previous provider during a migration, nil otherwise
+	fallback     Provider
+	fallbackName string
 }
 
 // UploadConfig holds configuration for file uploads
@@ -127,3 +195,10 @@ type UploadResult struct {
 	Path     string
 	Size     int64
 }
+
+// FileInfo describes a stored file found by Provider.List
+type FileInfo struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
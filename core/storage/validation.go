@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrFileRejected is returned for files whose content is not allowed by their
+// attachment config or that are unsafe to store
+var ErrFileRejected = errors.New("file is not allowed")
+
+// Upload safety limits
+var (
+	MaxImagePixels      = 50_000_000     // Width times height of uploaded images
+	MaxImageDimension   = 20_000         // Pixels on either side of uploaded images
+	MaxArchiveEntries   = 10_000         // Files in an uploaded archive
+	MaxArchiveSize      = int64(2 << 30) // Total uncompressed size of an uploaded archive
+	MaxCompressionRatio = int64(100)     // Uncompressed to compressed size of an archive
+)
+
+// sniffLen is the number of leading bytes used to detect the content type
+const sniffLen = 512
+
+// oleType is the container of legacy Office documents
+const oleType = "application/x-ole-storage"
+
+// extensionTypes maps file extensions to the content type their files must have
+var extensionTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".csv":  "text/csv",
+	".zip":  "application/zip",
+	".epub": "application/epub+zip",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wave",
+	".ogg":  "application/ogg",
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+}
+
+// containerTypes lists the formats that magic bytes only identify by their container,
+// such as a .docx being a zip archive. The extension decides the specific type.
+var containerTypes = map[string][]string{
+	"application/zip": {".docx", ".xlsx", ".pptx", ".epub"},
+	oleType:           {".doc", ".xls", ".ppt"},
+	"video/mp4":       {".m4v", ".mov"},
+	"video/webm":      {".mkv"},
+	"text/plain":      {".md", ".csv"},
+}
+
+// DetectContentType returns the content type of a file from its leading bytes. When
+// the bytes only identify a container format, the extension picks the specific type.
+func DetectContentType(data []byte, filename string) string {
+	var detected string
+	switch {
+	case bytes.HasPrefix(data, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}):
+		detected = oleType
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		// ISO media files; the standard sniffer only knows the mp4 brands
+		detected = "video/mp4"
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE6 == 0xE2:
+		// MPEG audio frame without an ID3 tag
+		detected = "audio/mpeg"
+	default:
+		detected = http.DetectContentType(data)
+	}
+
+	base, params, err := mime.ParseMediaType(detected)
+	if err != nil {
+		return detected
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	if slices.Contains(containerTypes[base], ext) {
+		base = extensionTypes[ext]
+	}
+	return mime.FormatMediaType(base, params)
+}
+
+// mediaType strips the parameters, such as the charset, from a content type
+func mediaType(contentType string) string {
+	base, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return base
+}
+
+// allowedTypes returns the content types a field accepts: its AllowedTypes, or else
+// the types of its allowed extensions
+func allowedTypes(config AttachmentConfig) []string {
+	if len(config.AllowedTypes) > 0 {
+		return config.AllowedTypes
+	}
+	var types []string
+	for _, ext := range config.AllowedExtensions {
+		if contentType, ok := extensionTypes[strings.ToLower(ext)]; ok {
+			types = append(types, contentType)
+		}
+	}
+	return types
+}
+
+// typeAllowed matches a content type against an allow-list; entries like "image/*"
+// accept every subtype. An empty list accepts everything.
+func typeAllowed(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	contentType = mediaType(contentType)
+	for _, entry := range allowed {
+		entry = strings.ToLower(entry)
+		if entry == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(entry, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// inspect sniffs the content of a file and checks it against the attachment config.
+// It returns the content to store, with location metadata removed from photos, and
+// the detected content type.
+func (as *ActiveStorage) inspect(config AttachmentConfig, filename string, file io.ReaderAt, size int64) (io.ReadSeeker, string, error) {
+	head := make([]byte, min(size, sniffLen))
+	if _, err := file.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	contentType := DetectContentType(head, filename)
+	if !typeAllowed(allowedTypes(config), contentType) {
+		return nil, "", fmt.Errorf("%w: content type %s", ErrFileRejected, mediaType(contentType))
+	}
+
+	content := io.NewSectionReader(file, 0, size)
+	switch mediaType(contentType) {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		if err := checkImage(content); err != nil {
+			return nil, "", err
+		}
+		data := make([]byte, size)
+		if _, err := file.ReadAt(data, 0); err != nil && err != io.EOF {
+			return nil, "", fmt.Errorf("failed to read file: %w", err)
+		}
+		stripGPS(data)
+		return bytes.NewReader(data), contentType, nil
+	case "application/zip", "application/epub+zip", extensionTypes[".docx"], extensionTypes[".xlsx"], extensionTypes[".pptx"]:
+		if err := checkArchive(file, size); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return content, contentType, nil
+}
+
+// checkImage rejects files that do not decode as images and images too large to
+// decode safely
+func checkImage(reader io.Reader) error {
+	cfg, _, err := image.DecodeConfig(reader)
+	if err != nil {
+		return fmt.Errorf("%w: not a valid image", ErrFileRejected)
+	}
+	if cfg.Width > MaxImageDimension || cfg.Height > MaxImageDimension || cfg.Width*cfg.Height > MaxImagePixels {
+		return fmt.Errorf("%w: image of %dx%d pixels exceeds maximum dimensions", ErrFileRejected, cfg.Width, cfg.Height)
+	}
+	return nil
+}
+
+// checkArchive rejects zip bombs. Entry sizes in the central directory can be forged,
+// so the entries are also inflated, up to the limit, to count the real size.
+func checkArchive(file io.ReaderAt, size int64) error {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return fmt.Errorf("%w: not a valid archive", ErrFileRejected)
+	}
+	if len(archive.File) > MaxArchiveEntries {
+		return fmt.Errorf("%w: archive has more than %d files", ErrFileRejected, MaxArchiveEntries)
+	}
+
+	limit := min(MaxArchiveSize, max(size, 1<<20)*MaxCompressionRatio)
+	var declared uint64
+	for _, entry := range archive.File {
+		declared += entry.UncompressedSize64
+	}
+	if declared > uint64(limit) {
+		return fmt.Errorf("%w: archive exceeds maximum uncompressed size", ErrFileRejected)
+	}
+
+	var total int64
+	for _, entry := range archive.File {
+		rc, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("%w: not a valid archive", ErrFileRejected)
+		}
+		n, err := io.Copy(io.Discard, io.LimitReader(rc, limit-total+1))
+		rc.Close()
+		total += n
+		if total > limit {
+			return fmt.Errorf("%w: archive exceeds maximum uncompressed size", ErrFileRejected)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: not a valid archive", ErrFileRejected)
+		}
+	}
+	return nil
+}
+
+// readerAt returns random access to a stream, spooling it to a temporary file when it
+// does not support it. The cleanup function removes the spooled file.
+func readerAt(reader io.Reader) (io.ReaderAt, func(), error) {
+	if file, ok := reader.(io.ReaderAt); ok {
+		return file, func() {}, nil
+	}
+
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	if _, err := io.Copy(tmp, reader); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return tmp, cleanup, nil
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	_ "image/gif"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Variant fit modes
+const (
+	FitContain = "contain" // Scale to fit inside the box, keeping the aspect ratio
+	FitCover   = "cover"   // Scale to fill the box and crop the overflow from the center
+)
+
+// Variant output formats
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp" // Lossless
+)
+
+// DefaultVariantQuality is the JPEG quality used when a variant does not set one
+const DefaultVariantQuality = 85
+
+var (
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrNotImage        = errors.New("attachment is not an image")
+)
+
+// Variant is a named transformation of an image attachment. Zero Width or Height
+// leaves that side free, so {Name: "medium", Width: 800} scales to 800 pixels wide.
+// Images are never scaled up.
+type Variant struct {
+	Name    string
+	Width   int
+	Height  int
+	Fit     string // FitContain (default) or FitCover
+	Format  string // FormatJPEG, FormatPNG or FormatWebP; empty keeps the original format
+	Quality int    // JPEG quality from 1 to 100
+}
+
+// imageFormats maps the image extensions variants can be made from to their format
+var imageFormats = map[string]string{
+	".jpg":  FormatJPEG,
+	".jpeg": FormatJPEG,
+	".png":  FormatPNG,
+	".gif":  FormatPNG, // Only the first frame is kept
+	".webp": FormatWebP,
+}
+
+// isImage reports whether variants can be generated from a file
+func isImage(filename string) bool {
+	_, ok := imageFormats[strings.ToLower(path.Ext(filename))]
+	return ok
+}
+
+// format returns the output format of the variant for an original file
+func (v Variant) format(filename string) string {
+	if v.Format != "" {
+		return v.Format
+	}
+	return imageFormats[strings.ToLower(path.Ext(filename))]
+}
+
+// variantPath returns the storage key of a variant, next to the original file:
+// avatars/users/avatar/variants/thumb/<file>.jpg
+func variantPath(original string, v Variant) string {
+	base := strings.TrimSuffix(path.Base(original), path.Ext(original))
+	ext := "." + v.format(original)
+	if ext == ".jpeg" {
+		ext = ".jpg"
+	}
+	return path.Join(path.Dir(original), "variants", v.Name, base+ext)
+}
+
+// attachVariants generates the variants of a freshly attached image, unless they are
+// lazy, and stores their URLs on the attachment. A file that cannot be decoded keeps
+// its upload; its variants are simply left out.
+func (as *ActiveStorage) attachVariants(attachment *Attachment, config AttachmentConfig) error {
+	if len(config.Variants) == 0 || !isImage(attachment.Filename) {
+		return nil
+	}
+
+	provider := as.providerFor(attachment.Provider)
+	urls := make(map[string]string, len(config.Variants))
+	if config.LazyVariants {
+		for _, v := range config.Variants {
+			urls[v.Name] = fmt.Sprintf("%s/%d/%s", as.variantURL, attachment.Id, v.Name)
+		}
+	} else {
+		// Variants of a blob shared with another attachment are already stored
+		var src image.Image
+		for _, v := range config.Variants {
+			key := variantPath(attachment.Path, v)
+			if !exists(provider, key) {
+				if src == nil {
+					var err error
+					if src, err = openImage(provider, attachment.Path); err != nil {
+						break
+					}
+				}
+				if err := putVariant(provider, attachment, src, v); err != nil {
+					continue
+				}
+			}
+			urls[v.Name] = provider.GetURL(key)
+		}
+	}
+
+	// Private variants are reached through SignedVariantURL
+	if attachment.Private || len(urls) == 0 {
+		return nil
+	}
+	attachment.Variants = urls
+	return as.db.Model(attachment).Select("variants").Updates(attachment).Error
+}
+
+// VariantURL returns the URL of a variant of an attachment, generating and storing
+// the variant first if it has not been requested before
+func (as *ActiveStorage) VariantURL(id uint, name string) (string, error) {
+	attachment := &Attachment{}
+	if err := as.db.First(attachment, id).Error; err != nil {
+		return "", err
+	}
+	if attachment.Private {
+		return "", ErrVariantNotFound
+	}
+
+	provider := as.providerFor(attachment.Provider)
+	key, err := as.ensureVariant(attachment, provider, name)
+	if err != nil {
+		return "", err
+	}
+	return provider.GetURL(key), nil
+}
+
+// SignedVariantURL returns an expiring URL for a variant of a private attachment
+func (as *ActiveStorage) SignedVariantURL(attachment *Attachment, name string, expires time.Duration) (string, error) {
+	if attachment == nil {
+		return "", fmt.Errorf("attachment is required")
+	}
+	provider := as.providerFor(as.locate(attachment))
+	key, err := as.ensureVariant(attachment, provider, name)
+	if err != nil {
+		return "", err
+	}
+	if !attachment.Private {
+		return provider.GetURL(key), nil
+	}
+	return provider.SignedURL(key, expires)
+}
+
+// ensureVariant generates a variant next to the original on its provider unless it
+// is already stored, and returns its key
+func (as *ActiveStorage) ensureVariant(attachment *Attachment, provider Provider, name string) (string, error) {
+	config, err := as.getConfig(attachment.ModelType, attachment.Field)
+	if err != nil {
+		return "", err
+	}
+	if !isImage(attachment.Filename) {
+		return "", ErrNotImage
+	}
+
+	for _, v := range config.Variants {
+		if v.Name != name {
+			continue
+		}
+		key := variantPath(attachment.Path, v)
+		if exists(provider, key) {
+			return key, nil
+		}
+
+		src, err := openImage(provider, attachment.Path)
+		if err != nil {
+			return "", err
+		}
+		if err := putVariant(provider, attachment, src, v); err != nil {
+			return "", err
+		}
+		return key, nil
+	}
+	return "", ErrVariantNotFound
+}
+
+// exists reports whether a file is stored on a provider
+func exists(provider Provider, key string) bool {
+	reader, err := provider.Open(key)
+	if err != nil {
+		return false
+	}
+	reader.Close()
+	return true
+}
+
+// deleteVariants removes the variants of an attachment stored on a provider. Lazy
+// variants that were never requested do not exist, so missing files are not an error.
+func (as *ActiveStorage) deleteVariants(attachment *Attachment, provider string) {
+	config, err := as.getConfig(attachment.ModelType, attachment.Field)
+	if err != nil || !isImage(attachment.Filename) {
+		return
+	}
+	for _, v := range config.Variants {
+		_ = as.providerFor(provider).Delete(variantPath(attachment.Path, v))
+	}
+}
+
+// openImage reads and decodes an image stored on a provider
+func openImage(provider Provider, key string) (image.Image, error) {
+	reader, err := provider.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+	if cfg.Width*cfg.Height > MaxImagePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large for variants", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+	return img, nil
+}
+
+// putVariant transforms an image and stores it as a variant of the attachment
+func putVariant(provider Provider, attachment *Attachment, src image.Image, v Variant) error {
+	format := v.format(attachment.Filename)
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, resizeImage(src, v, format), format, v.Quality); err != nil {
+		return err
+	}
+	_, err := provider.Put(variantPath(attachment.Path, v), &buf, int64(buf.Len()), "image/"+format)
+	return err
+}
+
+// resizeImage applies the size and fit of a variant to an image
+func resizeImage(src image.Image, v Variant, format string) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return src
+	}
+
+	srcRect := bounds
+	dstWidth, dstHeight := width, height
+	if v.Fit == FitCover && v.Width > 0 && v.Height > 0 {
+		// Crop the source to the aspect ratio of the box, centered
+		if width*v.Height > height*v.Width {
+			cropWidth := height * v.Width / v.Height
+			srcRect.Min.X += (width - cropWidth) / 2
+			srcRect.Max.X = srcRect.Min.X + cropWidth
+		} else {
+			cropHeight := width * v.Height / v.Width
+			srcRect.Min.Y += (height - cropHeight) / 2
+			srcRect.Max.Y = srcRect.Min.Y + cropHeight
+		}
+		dstWidth, dstHeight = min(v.Width, srcRect.Dx()), min(v.Height, srcRect.Dy())
+	} else {
+		scale := 1.0
+		if v.Width > 0 {
+			scale = min(scale, float64(v.Width)/float64(width))
+		}
+		if v.Height > 0 {
+			scale = min(scale, float64(v.Height)/float64(height))
+		}
+		dstWidth = max(1, int(float64(width)*scale+0.5))
+		dstHeight = max(1, int(float64(height)*scale+0.5))
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	if format == FormatJPEG {
+		// JPEG has no alpha channel; put transparent areas on white instead of black
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Over, nil)
+	return dst
+}
+
+// encodeImage writes an image in a variant format
+func encodeImage(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case FormatJPEG:
+		if quality <= 0 || quality > 100 {
+			quality = DefaultVariantQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatWebP:
+		return nativewebp.Encode(w, img, nil)
+	default:
+		return fmt.Errorf("unsupported variant format: %q", format)
+	}
+}
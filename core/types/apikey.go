@@ -0,0 +1,38 @@
+package types
+
+import (
+	"errors"
+)
+
+// ErrInvalidAPIKey is returned for API keys that are unknown, revoked or expired
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// APIKeyIdentity is the user an issued API key acts for, and the scopes it is
+// limited to, such as "course:read" or "enrollment:create"
+type APIKeyIdentity struct {
+	KeyId  uint
+	UserId uint
+	Scopes []string
+}
+
+// HasScope reports whether the key may take any of the actions on a resource,
+// matched as for OAuth tokens, see ScopesAllow
+func (k *APIKeyIdentity) HasScope(resource string, actions ...string) bool {
+	return ScopesAllow(k.Scopes, resource, actions...)
+}
+
+// apiKeyResolver looks up issued API keys, see SetAPIKeyResolver
+var apiKeyResolver func(key string) (*APIKeyIdentity, error)
+
+// SetAPIKeyResolver sets the lookup ResolveAPIKey uses for issued API keys
+func SetAPIKeyResolver(resolver func(key string) (*APIKeyIdentity, error)) {
+	apiKeyResolver = resolver
+}
+
+// ResolveAPIKey returns the identity of an issued API key
+func ResolveAPIKey(key string) (*APIKeyIdentity, error) {
+	if apiKeyResolver == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return apiKeyResolver(key)
+}
@@ -2,45 +2,230 @@ package types
 
 import (
 	"base/core/config"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ErrTokenRevoked is returned for access tokens revoked before they expired
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// ErrTokenPurpose is returned for challenge tokens used for another purpose, or as access tokens
+var ErrTokenPurpose = errors.New("token cannot be used for this purpose")
+
+// Claims are the claims of an access token
+type Claims struct {
+	UserId    uint
+	SessionId string   // Session the token was issued for; empty for tokens without one
+	TokenId   string   // Unique id (jti) used to revoke the token
+	ClientId  string   // OAuth client the token was issued to; empty for first-party tokens
+	Scopes    []string // Permissions an OAuth token is limited to, such as "profile:read"
+	ExpiresAt time.Time
+	Extend    any
+}
+
+// revocationCheck rejects revoked tokens, see SetRevocationCheck
+var revocationCheck func(claims *Claims) error
+
+// SetRevocationCheck sets the check ParseJWT runs on every valid token, such as
+// a lookup of revoked sessions and token ids
+func SetRevocationCheck(check func(claims *Claims) error) {
+	revocationCheck = check
+}
+
+// clientCheck rejects tokens of OAuth clients that no longer exist, see SetClientCheck
+var clientCheck func(clientID string) error
+
+// SetClientCheck sets the check ParseJWT runs on tokens issued to OAuth clients,
+// such as a lookup of the client
+func SetClientCheck(check func(clientID string) error) {
+	clientCheck = check
+}
+
 // GenerateJWT creates a new JWT token for the given user ID
 func GenerateJWT(userID uint, extend any) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+	token, _, err := GenerateAccessToken(userID, "", extend)
+	return token, err
+}
+
+// GenerateAccessToken creates a short-lived access token for a user's session
+func GenerateAccessToken(userID uint, sessionID string, extend any) (string, *Claims, error) {
 	cfg := config.NewConfig()
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = userID
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
-	claims["extend"] = extend
+	tokenId, err := randomId()
+	if err != nil {
+		return "", nil, err
+	}
+	claims := &Claims{
+		UserId:    userID,
+		SessionId: sessionID,
+		TokenId:   tokenId,
+		ExpiresAt: time.Now().Add(cfg.AccessTokenTTL),
+		Extend:    extend,
+	}
 
-	tokenString, err := token.SignedString([]byte(cfg.JWTSecret))
+	mapClaims := jwt.MapClaims{
+		"user_id": userID,
+		"jti":     tokenId,
+		"iat":     time.Now().Unix(),
+		"exp":     claims.ExpiresAt.Unix(),
+		"extend":  extend,
+	}
+	if sessionID != "" {
+		mapClaims["sid"] = sessionID
+	}
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims).SignedString([]byte(cfg.JWTSecret))
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return tokenString, nil
+	return tokenString, claims, nil
 }
 
-// ValidateJWT validates a JWT token and returns the user ID
-func ValidateJWT(tokenString string) (uint, error) {
+// GenerateChallengeToken creates a short-lived token for a user who passed the
+// first step of a login, such as the password check of a two-factor login. It
+// only works with ParseChallengeToken for the same purpose, never as an access token.
+func GenerateChallengeToken(userID uint, purpose string, ttl time.Duration) (string, *Claims, error) {
+	cfg := config.NewConfig()
+
+	tokenId, err := randomId()
+	if err != nil {
+		return "", nil, err
+	}
+	claims := &Claims{
+		UserId:    userID,
+		TokenId:   tokenId,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"jti":     tokenId,
+		"iat":     time.Now().Unix(),
+		"exp":     claims.ExpiresAt.Unix(),
+		"purpose": purpose,
+	}).SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, claims, nil
+}
+
+// GenerateOAuthToken creates an access token issued to an OAuth client, acting for
+// a user within scopes named after authorization permissions ("resource:action")
+func GenerateOAuthToken(userID uint, clientID string, scopes []string, ttl time.Duration) (string, *Claims, error) {
+	cfg := config.NewConfig()
+
+	tokenId, err := randomId()
+	if err != nil {
+		return "", nil, err
+	}
+	claims := &Claims{
+		UserId:    userID,
+		TokenId:   tokenId,
+		ClientId:  clientID,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   userID,
+		"jti":       tokenId,
+		"iat":       time.Now().Unix(),
+		"exp":       claims.ExpiresAt.Unix(),
+		"client_id": clientID,
+		"scope":     strings.Join(scopes, " "),
+	}).SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, claims, nil
+}
+
+// ParseJWT validates a JWT token, including that it was not revoked, and returns its claims
+func ParseJWT(tokenString string) (*Claims, error) {
+	return parseToken(tokenString, "")
+}
+
+// ParseChallengeToken validates a challenge token issued for purpose and returns its claims
+func ParseChallengeToken(tokenString, purpose string) (*Claims, error) {
+	return parseToken(tokenString, purpose)
+}
+
+// parseToken validates a token with the given purpose; access tokens have none
+func parseToken(tokenString, purpose string) (*Claims, error) {
 	cfg := config.NewConfig()
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
 		return []byte(cfg.JWTSecret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID := uint(claims["user_id"].(float64))
-		return userID, nil
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	if tokenPurpose, _ := mapClaims["purpose"].(string); tokenPurpose != purpose {
+		return nil, ErrTokenPurpose
+	}
+	userID, ok := mapClaims["user_id"].(float64)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
-	return 0, jwt.ErrSignatureInvalid
+	claims := &Claims{UserId: uint(userID), Extend: mapClaims["extend"]}
+	claims.SessionId, _ = mapClaims["sid"].(string)
+	claims.TokenId, _ = mapClaims["jti"].(string)
+	if clientID, _ := mapClaims["client_id"].(string); clientID != "" {
+		claims.ClientId = clientID
+		scope, _ := mapClaims["scope"].(string)
+		claims.Scopes = strings.Fields(scope)
+	}
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+
+	if revocationCheck != nil {
+		if err := revocationCheck(claims); err != nil {
+			return nil, err
+		}
+	}
+	if claims.ClientId != "" {
+		// Without the OAuth server nothing vouches for the client
+		if clientCheck == nil {
+			return nil, ErrTokenRevoked
+		}
+		if err := clientCheck(claims.ClientId); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// ValidateJWT validates a JWT token and returns the user ID
+func ValidateJWT(tokenString string) (uint, error) {
+	claims, err := ParseJWT(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserId, nil
+}
+
+// randomId returns a random 128-bit hex id
+func randomId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RequestPermission is the permission a request needs from an OAuth token or an
+// API key: the resource named by the first path segment after /api, and the
+// action of its method. For example DELETE /api/media/4 needs a scope for
+// deleting media.
+func RequestPermission(method, path string) (resource string, actions []string) {
+	path, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return "", nil
+	}
+	resource, _, _ = strings.Cut(path, "/")
+
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		actions = []string{"read", "list"}
+	case http.MethodPost:
+		actions = []string{"create"}
+	case http.MethodPut, http.MethodPatch:
+		actions = []string{"update"}
+	case http.MethodDelete:
+		actions = []string{"delete"}
+	}
+	return resource, actions
+}
+
+// HasScope reports whether an OAuth token may take any of the actions on a
+// resource, see ScopesAllow. Tokens issued outside OAuth are not limited by scopes.
+func (c *Claims) HasScope(resource string, actions ...string) bool {
+	if c.ClientId == "" {
+		return true
+	}
+	return ScopesAllow(c.Scopes, resource, actions...)
+}
+
+// ScopesAllow reports whether scopes allow any of the actions on a resource.
+// OAuth tokens and API keys share these scopes: each names a permission as
+// resource:action, its resource type matching a path segment as is or in its
+// plural form, with hyphens read as underscores ("user:read" covers /api/users,
+// "course_resource:read" /api/course-resources).
+func ScopesAllow(scopes []string, resource string, actions ...string) bool {
+	if resource == "" {
+		return false
+	}
+	resource = normalizeResource(resource)
+	for _, scope := range scopes {
+		scopeResource, scopeAction, ok := strings.Cut(scope, ":")
+		scopeResource = normalizeResource(scopeResource)
+		if !ok || (scopeResource != resource && scopeResource+"s" != resource) {
+			continue
+		}
+		for _, action := range actions {
+			if scopeAction == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeResource spells a resource with underscores, as permissions name it;
+// some paths separate words with hyphens instead
+func normalizeResource(resource string) string {
+	return strings.ReplaceAll(resource, "-", "_")
+}
@@ -3,6 +3,7 @@ package main
 import (
 	appmodules "base/app"
 	coremodules "base/core/app"
+	"base/core/app/profile"
 	"base/core/config"
 	"base/core/database"
 	"base/core/email"
@@ -14,6 +15,9 @@ import (
 	"base/core/storage"
 	_ "base/core/translation"
 	"base/core/websocket"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"os"
@@ -147,9 +151,53 @@ func (app *App) initInfrastructure() *App {
 		BaseURL:   app.config.StorageBaseURL,
 		APIKey:    app.config.StorageAPIKey,
 		APISecret: app.config.StorageAPISecret,
+		AccountID: app.config.StorageAccountID,
 		Endpoint:  app.config.StorageEndpoint,
+		Region:    app.config.StorageRegion,
 		Bucket:    app.config.StorageBucket,
 		CDN:       app.config.CDN,
+
+		CredentialsFile: app.config.StorageCredentialsFile,
+		UniformAccess:   app.config.StorageUniformAccess,
+
+		// Private files are served by the signed files endpoint
+		PrivatePath:   app.config.StoragePrivatePath,
+		SigningKey:    app.config.StorageSigningKey,
+		SignedURLBase: strings.TrimRight(app.config.BaseURL, "/") + "/api/files",
+
+		// Lazy image variants are generated by the variants endpoint on first request
+		VariantURLBase: strings.TrimRight(app.config.BaseURL, "/") + "/api/variants",
+
+		UserQuota:   app.config.StorageQuotaUser,
+		OwnerQuotas: map[string]int64{"course": app.config.StorageQuotaCourse},
+	}
+	// Download URLs must not be signed with the key of session tokens; without a key
+	// of their own they get one derived from the JWT secret for this use only
+	if storageConfig.SigningKey == "" {
+		mac := hmac.New(sha256.New, []byte(app.config.JWTSecret))
+		mac.Write([]byte("storage-signed-url"))
+		storageConfig.SigningKey = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	// Files not yet migrated off the previous provider are read from it
+	if app.config.StorageFallbackProvider != "" {
+		storageConfig.Fallback = &storage.Config{
+			Provider:  app.config.StorageFallbackProvider,
+			Path:      app.config.StorageFallbackPath,
+			BaseURL:   app.config.StorageFallbackBaseURL,
+			APIKey:    app.config.StorageFallbackAPIKey,
+			APISecret: app.config.StorageFallbackAPISecret,
+			AccountID: app.config.StorageFallbackAccountID,
+			Endpoint:  app.config.StorageFallbackEndpoint,
+			Region:    app.config.StorageFallbackRegion,
+			Bucket:    app.config.StorageFallbackBucket,
+
+			CredentialsFile: app.config.StorageFallbackCredentialsFile,
+
+			PrivatePath:   storageConfig.PrivatePath,
+			SigningKey:    storageConfig.SigningKey,
+			SignedURLBase: storageConfig.SignedURLBase,
+		}
 	}
 
 	activeStorage, err := storage.NewActiveStorage(app.db.DB, storageConfig)
@@ -268,6 +316,12 @@ func (app *App) registerCoreModules() {
 		Config:      app.config,
 	}
 
+	// Existing accounts are marked verified before any module migration can add
+	// the verification columns without them
+	if err := profile.MigrateEmailVerification(app.db.DB); err != nil {
+		app.logger.Error("Failed to migrate email verification", logger.String("error", err.Error()))
+	}
+
 	// Initialize core modules via orchestrator to ensure proper init/migrate/routes
 	initializer := module.NewInitializer(app.logger)
 	coreProvider := coremodules.NewCoreModules()
@@ -418,6 +472,15 @@ func main() {
 	// Initialize the Base application
 	app := New()
 
+	// Maintenance commands, e.g. `go run . storage:dedupe`
+	if len(os.Args) > 1 {
+		if err := app.RunCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Printf("\n❌ Command failed:\n%v\n\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Normal application startup
 	if err := app.Start(); err != nil {
 		// Print user-friendly error message instead of panicking